@@ -0,0 +1,307 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/jcdorr003/windash-agent/internal/auth"
+	"github.com/jcdorr003/windash-agent/internal/config"
+	"github.com/jcdorr003/windash-agent/internal/hostid"
+	"github.com/jcdorr003/windash-agent/internal/links"
+	"github.com/jcdorr003/windash-agent/internal/selftest"
+	"github.com/jcdorr003/windash-agent/internal/state"
+	"github.com/jcdorr003/windash-agent/pkg/units"
+	"go.uber.org/zap"
+)
+
+// runCommand dispatches subcommands (windash-agent <command> ...) and
+// returns the process exit code
+func runCommand(args []string, overrides config.Overrides) int {
+	switch args[0] {
+	case "config":
+		return runConfigCommand(args[1:], overrides)
+	case "status":
+		return runStatusCommand(args[1:], overrides)
+	case "open":
+		return runOpenCommand(args[1:], overrides)
+	case "export-history":
+		return runExportCommand(args[1:])
+	case "selftest":
+		return runSelfTestCommand(args[1:], overrides)
+	default:
+		fmt.Fprintf(os.Stderr, "Unknown command %q\n", args[0])
+		fmt.Fprintln(os.Stderr, "Commands: config show [--effective], status [--check], open [--print] host|alerts|pair|dashboard, export-history [--from T] [--to T] [--format csv] [--out FILE], selftest [--run]")
+		return 2
+	}
+}
+
+// runStatusCommand prints the active environment and endpoints, which
+// environments have a stored pairing token and the persisted agent state.
+// With --check it exits 1 when the state says the agent is unhealthy, for
+// watchdog scripts.
+func runStatusCommand(args []string, overrides config.Overrides) int {
+	fs := flag.NewFlagSet("status", flag.ContinueOnError)
+	check := fs.Bool("check", false, "Exit with code 1 if the agent is not running healthily")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+
+	cfg, err := config.Peek(overrides)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "❌ Failed to resolve config:", err)
+		return 1
+	}
+	deviceID, err := auth.GetMachineID()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "❌", err)
+		return 1
+	}
+	store := auth.NewTokenStore(zap.NewNop().Sugar(), cfg.Env)
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(w, "Environment:\t%s\n", cfg.Env)
+	fmt.Fprintf(w, "Dashboard:\t%s\n", cfg.DashboardURL)
+	fmt.Fprintf(w, "API:\t%s\n", cfg.APIURL)
+	fmt.Fprintf(w, "Device ID:\t%s\n", deviceID)
+	fmt.Fprintln(w, "Tokens:\t")
+	for _, env := range config.Envs {
+		state := "not paired"
+		if store.HasToken(env, deviceID) {
+			state = "paired"
+		}
+		if env == cfg.Env {
+			state += " (active)"
+		}
+		fmt.Fprintf(w, "  %s\t%s\n", env, state)
+	}
+	if p, err := auth.ReadPairingState(config.GetPairingFile()); err != nil {
+		fmt.Fprintf(w, "Pairing:\tunavailable (%v)\n", err)
+	} else {
+		switch p.State {
+		case auth.PairingPending:
+			fmt.Fprintf(w, "Pairing:\tcode %s pending in %s, expires %s\n", p.Code, p.Env, p.ExpiresAt.Local().Format(time.DateTime))
+		case auth.PairingPaired:
+			fmt.Fprintf(w, "Pairing:\tpaired in %s via %s\n", p.Env, p.Endpoint)
+			fmt.Fprintf(w, "Paired at:\t%s\n", formatTime(p.PairedAt))
+		default:
+			fmt.Fprintf(w, "Pairing:\t%s\n", p.State)
+		}
+	}
+
+	healthy := false
+	st, err := state.Read(config.GetStateFile())
+	if err != nil {
+		fmt.Fprintf(w, "Agent state:\tunavailable (%v)\n", err)
+	} else {
+		var reason string
+		healthy, reason = st.Healthy(time.Now())
+		if healthy {
+			fmt.Fprintln(w, "Health:\tok")
+		} else {
+			fmt.Fprintf(w, "Health:\tunhealthy - %s\n", reason)
+		}
+		fmt.Fprintf(w, "Started:\t%s\n", formatTime(st.StartedAt))
+		fmt.Fprintf(w, "Last connect:\t%s\n", formatTime(st.LastConnect))
+		fmt.Fprintf(w, "Last upload:\t%s\n", formatTime(st.LastUpload))
+		fmt.Fprintf(w, "Last ack:\t%s\n", formatTime(st.LastAck))
+		fmt.Fprintf(w, "Starts:\t%d\n", st.Starts)
+		fmt.Fprintf(w, "Connects:\t%d (%d reconnects)\n", st.Connects, st.Reconnects)
+		fmt.Fprintf(w, "Dropped samples:\t%d\n", st.DroppedSamples)
+		usage := st.TrafficUsage(time.Now())
+		fmt.Fprintf(w, "Traffic today:\t%s\n", formatTraffic(usage.Today))
+		month := formatTraffic(usage.Month)
+		if budget := uint64(max(cfg.Traffic.MonthlyBudgetMB, 0)) << 20; budget > 0 {
+			used := usage.Month.Sent + usage.Month.Received
+			month += fmt.Sprintf(" - %s of the %s budget", units.Percent(float64(used)/float64(budget)*100), units.Bytes(budget))
+		}
+		fmt.Fprintf(w, "Traffic this month:\t%s\n", month)
+	}
+	w.Flush()
+
+	if *check && !healthy {
+		return 1
+	}
+	return 0
+}
+
+// formatTime renders a state timestamp for status output
+func formatTime(t time.Time) string {
+	if t.IsZero() {
+		return "never"
+	}
+	return fmt.Sprintf("%s (%s ago)", t.Local().Format(time.DateTime), units.Duration(time.Since(t)))
+}
+
+// formatTraffic renders sent and received byte counts for status output
+func formatTraffic(t state.Traffic) string {
+	return fmt.Sprintf("%s sent, %s received", units.Bytes(t.Sent), units.Bytes(t.Received))
+}
+
+// runSelfTestCommand implements `selftest [--run]`: it prints the stored
+// self-test results and how the latest compares with the runs before it,
+// running the tests first with --run
+func runSelfTestCommand(args []string, overrides config.Overrides) int {
+	fs := flag.NewFlagSet("selftest", flag.ContinueOnError)
+	run := fs.Bool("run", false, "Run the self-tests now and add the result to the history")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+
+	cfg, err := config.Peek(overrides)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "❌ Failed to resolve config:", err)
+		return 1
+	}
+	runner := selftest.NewRunner(zap.NewNop().Sugar(), "", cfg.APIURL, config.GetSelfTestFile(), cfg.SelfTest)
+	if *run {
+		if err := config.EnsureDirs(); err != nil {
+			fmt.Fprintln(os.Stderr, "❌", err)
+			return 1
+		}
+		fmt.Println("🧪 Running self-tests...")
+		if _, err := runner.RunOnce(context.Background()); err != nil {
+			fmt.Fprintln(os.Stderr, "❌ Self-test failed:", err)
+			return 1
+		}
+	}
+
+	history, err := runner.History()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "❌", err)
+		return 1
+	}
+	if len(history) == 0 {
+		fmt.Println("No self-test results yet (run with --run, or enable selfTest.enabled)")
+		return 0
+	}
+
+	// Failed tests have no value; show them as "-"
+	rate := func(bytesPerSec float64) string {
+		if bytesPerSec == 0 {
+			return "-"
+		}
+		return units.Bytes(uint64(bytesPerSec)) + "/s"
+	}
+	ms := func(v float64, prec int) string {
+		if v == 0 {
+			return "-"
+		}
+		return units.Number(v, prec) + " ms"
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "Run\tDisk write\tDisk read\tDisk sync\tMemory\tBackend RTT\tErrors")
+	for _, r := range history {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%d\n", r.TS.Local().Format(time.DateTime),
+			rate(r.DiskWriteMBps*1e6), rate(r.DiskReadMBps*1e6), ms(r.DiskSyncMs, 2),
+			rate(r.MemGBps*1e9), ms(r.BackendRTTMs, 1), len(r.Errors))
+	}
+	w.Flush()
+
+	latest := history[len(history)-1]
+	if len(latest.Errors) > 0 {
+		fmt.Println()
+	}
+	for name, msg := range latest.Errors {
+		fmt.Printf("⚠️  %s test failed: %s\n", name, msg)
+	}
+	trends := selftest.Trends(history[:len(history)-1], &latest)
+	if len(trends) == 0 {
+		fmt.Println("\nNo trend yet: the latest run has nothing to compare with")
+		return 0
+	}
+	fmt.Println("\nLatest run compared with the median of the runs before it:")
+	for _, t := range trends {
+		change := units.Number(t.ChangePct, 1) + "%"
+		if t.ChangePct > 0 {
+			change = "+" + change
+		}
+		if t.Slower {
+			change += "  🐢 slower"
+		}
+		fmt.Printf("  %-14s %s\n", t.Metric, change)
+	}
+	return 0
+}
+
+// runOpenCommand implements `open [--print] <view>`: it opens a dashboard
+// view for this host in the browser, or just prints its link
+func runOpenCommand(args []string, overrides config.Overrides) int {
+	fs := flag.NewFlagSet("open", flag.ContinueOnError)
+	printOnly := fs.Bool("print", false, "Print the link instead of opening it")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	view := "host"
+	if fs.NArg() > 0 {
+		view = fs.Arg(0)
+	}
+
+	cfg, err := config.Peek(overrides)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "❌ Failed to resolve config:", err)
+		return 1
+	}
+	hostID, _, err := hostid.Resolve(context.Background(), zap.NewNop().Sugar(), cfg.HostID)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "❌ Failed to get host ID:", err)
+		return 1
+	}
+	link, err := links.View(view, cfg.DashboardURL, hostID)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "❌", err)
+		return 2
+	}
+
+	fmt.Println(link)
+	if *printOnly {
+		return 0
+	}
+	if err := links.Open(link); err != nil {
+		fmt.Fprintln(os.Stderr, "❌ Failed to open browser:", err)
+		return 1
+	}
+	return 0
+}
+
+// runConfigCommand implements `config show [--effective]`
+func runConfigCommand(args []string, overrides config.Overrides) int {
+	if len(args) == 0 || args[0] != "show" {
+		fmt.Fprintln(os.Stderr, "Usage: windash-agent config show [--effective]")
+		return 2
+	}
+
+	fs := flag.NewFlagSet("config show", flag.ContinueOnError)
+	effective := fs.Bool("effective", false, "Show the merged configuration with the source of each setting")
+	if err := fs.Parse(args[1:]); err != nil {
+		return 2
+	}
+
+	if !*effective {
+		data, err := config.RedactedFile()
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "❌ Failed to read config file:", err)
+			return 1
+		}
+		fmt.Printf("# %s\n%s\n", config.GetConfigFile(), data)
+		return 0
+	}
+
+	settings, err := config.Effective(overrides)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "❌ Failed to resolve config:", err)
+		return 1
+	}
+
+	fmt.Printf("# Effective configuration (file: %s)\n", config.GetConfigFile())
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "KEY\tVALUE\tSOURCE")
+	for _, s := range settings {
+		fmt.Fprintf(w, "%s\t%v\t%s\n", s.Key, s.Value, s.Source)
+	}
+	w.Flush()
+	return 0
+}
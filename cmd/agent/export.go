@@ -0,0 +1,179 @@
+package main
+
+import (
+	"encoding/csv"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/jcdorr003/windash-agent/internal/config"
+	"github.com/jcdorr003/windash-agent/internal/metrics"
+	"github.com/jcdorr003/windash-agent/internal/spool"
+)
+
+// exportTimeLayouts are the accepted --from/--to formats (local time unless
+// the value carries an offset)
+var exportTimeLayouts = []string{time.RFC3339, "2006-01-02 15:04", exportDateLayout}
+
+// exportDateLayout is the date-only --from/--to format
+const exportDateLayout = "2006-01-02"
+
+// runExportCommand implements `export-history [--from T] [--to T] [--format
+// csv] [--out FILE]`: it dumps the local history plus any spooled samples
+// not yet sent, one row per sample
+func runExportCommand(args []string) int {
+	fs := flag.NewFlagSet("export-history", flag.ContinueOnError)
+	fromFlag := fs.String("from", "", "Start time, e.g. 2026-10-01 or 2026-10-01 08:00 (default: oldest)")
+	toFlag := fs.String("to", "", "End time (default: now)")
+	format := fs.String("format", "csv", "Output format (only csv is supported)")
+	out := fs.String("out", "", "Write to this file instead of stdout")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+
+	from, err := parseExportTime(*fromFlag, false)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "❌ Invalid --from:", err)
+		return 2
+	}
+	to, err := parseExportTime(*toFlag, true)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "❌ Invalid --to:", err)
+		return 2
+	}
+	switch *format {
+	case "csv":
+	default:
+		fmt.Fprintf(os.Stderr, "❌ Unknown format %q (want csv)\n", *format)
+		return 2
+	}
+
+	var samples []*metrics.SampleV1
+	for _, dir := range []string{config.GetHistoryDir(), config.GetSpoolDir()} {
+		if _, err := os.Stat(dir); os.IsNotExist(err) {
+			continue
+		}
+		read, err := spool.Read(dir, from, to)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "❌ Failed to read history:", err)
+			return 1
+		}
+		samples = append(samples, read...)
+	}
+	samples = dedupeSamples(samples)
+	if len(samples) == 0 {
+		fmt.Fprintln(os.Stderr, "No samples in range. Enable history.enabled to keep a local history.")
+	}
+
+	if *out == "" {
+		if err := writeCSV(os.Stdout, samples); err != nil {
+			fmt.Fprintln(os.Stderr, "❌ Export failed:", err)
+			return 1
+		}
+		return 0
+	}
+
+	f, err := os.Create(*out)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "❌", err)
+		return 1
+	}
+	err = writeCSV(f, samples)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "❌ Export failed:", err)
+		return 1
+	}
+	fmt.Fprintf(os.Stderr, "✅ Exported %d samples to %s\n", len(samples), *out)
+	return 0
+}
+
+// parseExportTime parses a --from/--to value; "" is the zero time. With
+// endOfDay a date without a time means the last instant of that day, so
+// --to 2026-10-01 includes the whole day.
+func parseExportTime(s string, endOfDay bool) (time.Time, error) {
+	if s == "" {
+		return time.Time{}, nil
+	}
+	for _, layout := range exportTimeLayouts {
+		if t, err := time.ParseInLocation(layout, s, time.Local); err == nil {
+			if endOfDay && layout == exportDateLayout {
+				t = t.AddDate(0, 0, 1).Add(-time.Nanosecond)
+			}
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("%q is not a time like 2026-10-01, 2026-10-01 08:00 or RFC 3339", s)
+}
+
+// dedupeSamples sorts samples by time and drops duplicates (a spooled
+// sample is also in the history)
+func dedupeSamples(samples []*metrics.SampleV1) []*metrics.SampleV1 {
+	sort.SliceStable(samples, func(i, j int) bool { return samples[i].TS.Before(samples[j].TS) })
+	out := samples[:0]
+	for i, s := range samples {
+		if i > 0 && s.TS.Equal(samples[i-1].TS) {
+			continue
+		}
+		out = append(out, s)
+	}
+	return out
+}
+
+// writeCSV writes one row per sample with a pair of columns per volume
+func writeCSV(w io.Writer, samples []*metrics.SampleV1) error {
+	volumes := map[string]bool{}
+	for _, s := range samples {
+		for _, d := range s.Disks {
+			volumes[d.Name] = true
+		}
+	}
+	names := make([]string, 0, len(volumes))
+	for name := range volumes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	header := []string{"time", "cpuPercent", "memUsedBytes", "memTotalBytes", "netTxBps", "netRxBps", "uptimeSec", "procCount", "health"}
+	for _, name := range names {
+		header = append(header, "diskUsed "+name, "diskTotal "+name)
+	}
+
+	cw := csv.NewWriter(w)
+	if err := cw.Write(header); err != nil {
+		return err
+	}
+	u := func(v uint64) string { return strconv.FormatUint(v, 10) }
+	for _, s := range samples {
+		row := []string{
+			s.TS.Local().Format(time.DateTime),
+			strconv.FormatFloat(s.CPU.Total, 'f', 2, 64),
+			u(s.Mem.Used), u(s.Mem.Total),
+			u(s.Net.TxBps), u(s.Net.RxBps),
+			u(s.UptimeSec), u(s.ProcCount),
+			strconv.Itoa(s.Health),
+		}
+		disks := make(map[string]metrics.DiskUsage, len(s.Disks))
+		for _, d := range s.Disks {
+			disks[d.Name] = d
+		}
+		for _, name := range names {
+			if d, ok := disks[name]; ok {
+				row = append(row, u(d.Used), u(d.Total))
+			} else {
+				row = append(row, "", "")
+			}
+		}
+		if err := cw.Write(row); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
@@ -2,18 +2,47 @@ package main
 
 import (
 	"context"
+	"encoding/json"
 	"flag"
 	"fmt"
 	"os"
 	"os/signal"
+	"path/filepath"
+	"strings"
 	"syscall"
 	"time"
 
+	"github.com/jcdorr003/windash-agent/internal/alerts"
 	"github.com/jcdorr003/windash-agent/internal/auth"
+	"github.com/jcdorr003/windash-agent/internal/chaos"
+	"github.com/jcdorr003/windash-agent/internal/checks"
 	"github.com/jcdorr003/windash-agent/internal/config"
+	"github.com/jcdorr003/windash-agent/internal/devices"
+	"github.com/jcdorr003/windash-agent/internal/handles"
+	"github.com/jcdorr003/windash-agent/internal/hostid"
+	"github.com/jcdorr003/windash-agent/internal/incident"
+	"github.com/jcdorr003/windash-agent/internal/inventory"
+	"github.com/jcdorr003/windash-agent/internal/ipc"
+	"github.com/jcdorr003/windash-agent/internal/maintenance"
 	"github.com/jcdorr003/windash-agent/internal/metrics"
+	"github.com/jcdorr003/windash-agent/internal/netprobe"
+	"github.com/jcdorr003/windash-agent/internal/notify"
+	"github.com/jcdorr003/windash-agent/internal/outbound"
+	"github.com/jcdorr003/windash-agent/internal/peers"
+	"github.com/jcdorr003/windash-agent/internal/printers"
+	"github.com/jcdorr003/windash-agent/internal/security"
+	"github.com/jcdorr003/windash-agent/internal/selftest"
+	"github.com/jcdorr003/windash-agent/internal/snapshot"
+	"github.com/jcdorr003/windash-agent/internal/spool"
+	"github.com/jcdorr003/windash-agent/internal/state"
+	"github.com/jcdorr003/windash-agent/internal/storage"
+	"github.com/jcdorr003/windash-agent/internal/summary"
+	"github.com/jcdorr003/windash-agent/internal/tray"
+	"github.com/jcdorr003/windash-agent/internal/watch"
 	"github.com/jcdorr003/windash-agent/internal/ws"
 	"github.com/jcdorr003/windash-agent/pkg/log"
+	"github.com/jcdorr003/windash-agent/pkg/units"
+	"go.uber.org/zap"
 )
 
 var (
@@ -28,9 +57,34 @@ func main() {
 	debugFlag := flag.Bool("debug", false, "Enable debug logging")
 	versionFlag := flag.Bool("version", false, "Show version and exit")
 	resetFlag := flag.Bool("reset", false, "Delete stored token and force re-pairing")
-	envFlag := flag.String("env", "", "Set agent environment (localdev, localprod, remoteprod)")
+	envFlag := flag.String("env", "", "Set agent environment (localdev, localprod, localdockerprod, remoteprod, or auto to pick the first reachable)")
+	noBrowserFlag := flag.Bool("no-browser", false, "Never open a browser; print the pairing code and link instead (headless machines)")
+	logStdoutFlag := flag.Bool("log-stdout-only", false, "Log to stdout only, without writing log files (containers, read-only filesystems)")
+	watchFlag := flag.Bool("watch", false, "Show a live one-line summary (CPU, memory, network, connection) instead of info logs on the console")
+	syntheticFlag := flag.Bool("synthetic", false, "Send generated fake metrics (for dashboard development)")
+	recordFlag := flag.String("record-control", "", "Append received control messages to this JSONL file")
+	replayFlag := flag.String("replay-control", "", "Replay a control message recording, print the agent's replies and exit")
+	chaosFlag := flag.String("chaos", "", "Simulate failures for QA, e.g. drop=30s,slow=3s,keychain,buffers")
+	overrides := config.Overrides{}
+	flag.Var(settingFlag(overrides), "set", "Override a setting, e.g. --set metricsIntervalMs=5000 (repeatable)")
+	flag.Usage = usage
 	flag.Parse()
 
+	// Dedicated flags map onto settings; they beat --set, env and file
+	if *envFlag != "" {
+		overrides["env"] = *envFlag
+	}
+	if *syntheticFlag {
+		overrides["collectors.synthetic.enabled"] = "true"
+	}
+	if *logStdoutFlag {
+		overrides["logging.stdoutOnly"] = "true"
+	}
+	if *noBrowserFlag {
+		overrides["openOnPair"] = "false"
+		overrides["openOnStart"] = "false"
+	}
+
 	// Show version and exit
 	if *versionFlag {
 		fmt.Printf("WinDash Agent %s\n", version)
@@ -39,9 +93,21 @@ func main() {
 		os.Exit(0)
 	}
 
-	// Initialize logger
-	logger := log.New(*debugFlag)
-	defer logger.Sync()
+	// Subcommands (e.g. `config show --effective`) run and exit
+	if args := flag.Args(); len(args) > 0 {
+		os.Exit(runCommand(args, overrides))
+	}
+
+	// Initialize logger (logging settings are resolved ahead of the full config)
+	logging := config.LoadLogging(overrides)
+	agentLog := log.New(log.Options{Debug: *debugFlag, Dir: logging.FileDir(), Compress: logging.Compress, Quiet: *watchFlag})
+	logger := agentLog.SugaredLogger
+
+	if *replayFlag != "" {
+		code := runReplay(*replayFlag, logger)
+		agentLog.Close()
+		os.Exit(code)
+	}
 
 	// Welcome message
 	logger.Info("🚀 WinDash Agent starting", "version", version)
@@ -51,30 +117,26 @@ func main() {
 	fmt.Println("╚══════════════════════════════════════╝")
 	fmt.Println()
 
+	// Simulated failures start before pairing so keychain faults hit it too
+	if *chaosFlag != "" {
+		faults, err := chaos.Parse(*chaosFlag)
+		if err != nil {
+			logger.Fatal("Invalid --chaos", "error", err)
+		}
+		chaos.Enable(faults)
+		logger.Warn("🐒 Chaos testing enabled - simulating failures", "faults", faults)
+	}
+
 	// Load configuration
-	cfg, err := config.Load()
+	cfg, err := config.Load(logger, overrides)
 	if err != nil {
 		logger.Fatal("Failed to load config", "error", err)
 	}
-
-	// Override env from CLI flag if provided
-	if *envFlag != "" {
-		cfg.Env = *envFlag
-		// Re-apply endpoint selection logic
-		switch cfg.Env {
-		case "localdev":
-			cfg.DashboardURL = config.DashboardURLLocalDev
-			cfg.APIURL = config.APIURLLocalDev
-		case "localprod":
-			cfg.DashboardURL = config.DashboardURLLocalProd
-			cfg.APIURL = config.APIURLLocalProd
-		case "remoteprod":
-			cfg.DashboardURL = config.DashboardURLRemoteProd
-			cfg.APIURL = config.APIURLRemoteProd
-		default:
-			cfg.DashboardURL = config.DashboardURLLocalDev
-			cfg.APIURL = config.APIURLLocalDev
-		}
+	cfg.AgentVersion = version
+	if system, err := units.ParseSystem(cfg.Units); err != nil {
+		logger.Warn("Invalid units setting, using binary", "error", err)
+	} else {
+		units.SetSystem(system)
 	}
 
 	logger.Info("📁 Configuration loaded",
@@ -91,9 +153,15 @@ func main() {
 		logger.Fatal("Failed to create directories", "error", err)
 	}
 
+	// Persisted runtime state (counters, last connect/upload)
+	agentState := state.Open(logger, config.GetStateFile())
+	agentState.SetTrafficBudget(uint64(max(cfg.Traffic.MonthlyBudgetMB, 0)) << 20)
+
 	// Initialize pairing components
-	pairingAPI := auth.NewRealPairingAPI(logger, cfg.DashboardURL)
-	tokenStore := auth.NewTokenStore(logger)
+	pairingAPI := auth.NewRealPairingAPI(logger, cfg.DashboardURL, cfg.RequestHeaders())
+	pairingAPI.SetFleetGroup(cfg.SiteID, cfg.GroupID)
+	tokenStore := auth.NewTokenStore(logger, cfg.Env)
+	pairingStore := auth.OpenPairingStore(logger, config.GetPairingFile())
 
 	// Handle reset flag - force fresh pairing
 	if *resetFlag {
@@ -105,20 +173,28 @@ func main() {
 				logger.Info("🔄 No existing token to delete (first run)")
 			} else {
 				logger.Info("🔄 Deleted stored token - forcing fresh pairing")
-				fmt.Println("🔄 Reset successful - will trigger pairing flow\n")
+				fmt.Print("🔄 Reset successful - will trigger pairing flow\n\n")
 			}
 		}
+		if err := pairingStore.Transition(func(p *auth.PairingState) error {
+			p.Reset(time.Now())
+			return nil
+		}); err != nil {
+			logger.Warn("Failed to reset pairing state", "error", err)
+		}
 	}
 
 	// Ensure device is paired
-	token, firstRun, err := auth.EnsurePaired(context.Background(), pairingAPI, tokenStore, cfg, logger)
+	token, firstRun, err := auth.EnsurePaired(context.Background(), pairingAPI, tokenStore, pairingStore, cfg, logger)
 	if err != nil {
 		fmt.Println("\n❌ Pairing failed:", err)
 		fmt.Println("\nPress Enter to exit...")
 		fmt.Scanln()
 		logger.Fatal("Pairing failed", "error", err)
-	} // Open browser if configured
-	if cfg.OpenOnStart {
+	}
+
+	// Open browser if configured and someone can see it
+	if cfg.OpenOnStart && auth.HeadlessReason() == "" {
 		if err := auth.OpenDashboard(cfg.DashboardURL); err != nil {
 			logger.Warn("Failed to open browser", "error", err)
 		} else {
@@ -131,16 +207,17 @@ func main() {
 	}
 
 	// Get host information
-	hostID, err := metrics.GetHostID()
+	hostID, provider, err := hostid.Resolve(context.Background(), logger, cfg.HostID)
 	if err != nil {
 		logger.Fatal("Failed to get host ID", "error", err)
 	}
 
-	logger.Info("🖥️  Host identified", "hostId", hostID)
+	logger.Info("🖥️  Host identified", "hostId", hostID, "provider", provider)
 
 	// Create context for graceful shutdown
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
+	go agentState.Run(ctx)
 
 	// Start metrics collector
 	collector := metrics.NewCollector(
@@ -148,35 +225,330 @@ func main() {
 		hostID,
 		time.Duration(cfg.MetricsIntervalMs)*time.Millisecond,
 	)
+	collector.SetSources(cfg.Collectors)
+	collector.SetCPUOptions(cfg.Collectors.CPU)
+	collector.SetSuppression(cfg.Suppress)
+	collector.SetQuarantine(config.GetQuarantineFile())
+	if cfg.Pipeline != nil {
+		if err := collector.SetPipeline(cfg.Pipeline); err != nil {
+			logger.Warn("Invalid pipeline, using the default", "error", err, "default", metrics.DefaultPipeline)
+		}
+	}
+	if cfg.HighResolution {
+		collector.EnableHighResolution()
+	}
+	if cfg.Privacy.HideProcessNames {
+		collector.HideProcessNames()
+	}
+	if cfg.Privacy.HashSSIDs {
+		collector.HashSSIDs()
+	}
+	if cfg.Collectors.Synthetic.Enabled {
+		collector.UseSynthetic(cfg.Collectors.Synthetic)
+	}
+	if cfg.Incidents.Enabled {
+		collector.KeepHistory(incident.Size(cfg.Incidents))
+	}
+	if cfg.SlowStart.Enabled {
+		lastLogon, err := security.LatestLogon()
+		if err != nil {
+			logger.Debug("Could not read logon times for slow start", "error", err)
+		}
+		collector.SlowStart(cfg.SlowStart, lastLogon)
+	}
+	openAlerts := alerts.NewOpenSet()
+	collector.SetHealth(cfg.Health, openAlerts)
 	sampleChan := make(chan *metrics.SampleV1, 100)
 
-	go collector.Start(ctx, sampleChan)
-
 	// Start WebSocket client
-	wsClient := ws.NewClient(cfg.APIURL, token, hostID, logger)
+	wsClient := ws.NewClient(cfg, token, hostID, collector, logger)
+	wsClient.SetNotifier(notify.New(logger))
+	if cfg.Security.ReadOnly {
+		logger.Info("🔒 Read-only mode: remote actions from the server are refused")
+	}
+	if cfg.Peers.Enabled {
+		mesh := peers.NewMesh(logger, hostID, cfg.Peers)
+		wsClient.SetPeerMesh(mesh)
+		go mesh.Run(ctx, wsClient)
+	}
+	if cfg.NetProbe.Enabled {
+		prober := netprobe.New(logger, hostID, cfg.NetProbe)
+		collector.SetNetQuality(prober)
+		go prober.Run(ctx, wsClient)
+	}
+	wsClient.SetTracker(agentState)
+	if *recordFlag != "" {
+		recorder, err := ws.NewRecorder(*recordFlag)
+		if err != nil {
+			logger.Fatal("Failed to start control message recording", "error", err)
+		}
+		defer recorder.Close()
+		wsClient.SetRecorder(recorder)
+		logger.Info("⏺️  Recording control messages", "file", *recordFlag)
+	}
+	inv := inventory.NewProvider(logger, hostID, config.GetInventoryCacheFile())
+	inv.SetFleetGroup(cfg.SiteID, cfg.GroupID)
+	wsClient.OnConnect(func() { inv.OnConnect(wsClient) })
+	wsClient.OnConnect(func() { wsClient.Send("fields", metrics.NewFieldsMessage(version)) })
+	go inv.WatchDisplays(ctx, wsClient)
+
+	// Alerts from the watchers below go out as incidents when enabled
+	var alertSender outbound.Sender = wsClient
+	if cfg.Incidents.Enabled {
+		alertSender = incident.NewBundler(logger, wsClient, collector.Recent, cfg.Incidents)
+	}
+
+	// Local daily summary, fed by every sample and alert
+	if cfg.Summary.Enabled {
+		dir := ""
+		if cfg.LogDir != "" {
+			dir = filepath.Join(cfg.LogDir, "summaries")
+		} else if !cfg.Summary.Upload {
+			logger.Warn("Daily summary has nowhere to go: logging to stdout only and upload is off")
+		}
+		reporter := summary.NewReporter(logger, hostID, dir, cfg.Summary, alertSender)
+		collector.Observe(reporter.Observe)
+		alertSender = reporter
+		go reporter.Run(ctx)
+	}
+
+	// Samples that can't be sent are kept on disk and replayed as backfill
+	var sampleSpool *spool.Spool
+	if cfg.Spool.Enabled {
+		sp, err := spool.Open(logger, config.GetSpoolDir(), cfg.Spool)
+		if err != nil {
+			logger.Warn("Sample spool disabled", "error", err)
+		} else {
+			sampleSpool = sp
+			wsClient.SetSpool(sp)
+			go sp.Run(ctx, wsClient)
+		}
+	}
+
+	// Local history for export-history
+	var history *spool.Spool
+	if cfg.History.Enabled {
+		h, err := spool.OpenHistory(logger, config.GetHistoryDir(), cfg.History, cfg.Spool.MinFreeMB)
+		if err != nil {
+			logger.Warn("Local history disabled", "error", err)
+		} else {
+			history = h
+			collector.Observe(h.Add)
+			go h.Run(ctx, nil)
+		}
+	}
+
+	collector.SendVolumeEvents(wsClient)
+	go collector.Start(ctx, sampleChan)
 	go wsClient.Run(ctx, sampleChan)
 
+	// Start local scripting API
+	if cfg.IPC.Enabled {
+		go ipc.NewServer(logger, cfg, hostID, collector, wsClient).Run(ctx)
+	}
+
+	// Start the tray icon where the session has a taskbar
+	trayQuit := make(chan struct{})
+	var trayManager *tray.Manager
+	if cfg.Tray.Enabled {
+		trayManager = tray.NewManager(logger, cfg.DashboardURL, hostID, wsClient, collector.Latest, collector)
+		if err := trayManager.Start(func() { close(trayQuit) }); err != nil {
+			logger.Info("🖱️  Running without a tray icon", "reason", err)
+		}
+	}
+
+	// Start process/service watch
+	if len(cfg.Watch.Processes) > 0 || len(cfg.Watch.Services) > 0 {
+		watcher := watch.NewWatcher(logger, hostID, cfg.Watch)
+		watcher.SetOpenAlerts(openAlerts)
+		go watcher.Run(ctx, alertSender)
+	}
+
+	// Start daily report scheduler
+	if cfg.Snapshot.DailyAt != "" {
+		scheduler, err := snapshot.NewScheduler(logger, hostID, cfg.Snapshot.DailyAt, collector.Latest)
+		if err != nil {
+			logger.Warn("Daily report disabled", "error", err)
+		} else {
+			if cfg.Privacy.HideProcessNames {
+				scheduler.HideProcessNames()
+			}
+			go scheduler.Run(ctx, wsClient)
+		}
+	}
+
+	// Start storage health reporting
+	if cfg.Storage.Enabled {
+		storageMonitor := storage.NewMonitor(logger, hostID, cfg.Storage)
+		storageMonitor.SetOpenAlerts(openAlerts)
+		go storageMonitor.Run(ctx, alertSender)
+	}
+
+	// Start opt-in USB device events
+	if cfg.Devices.USB {
+		go devices.NewMonitor(logger, hostID, cfg.Devices).Run(ctx, wsClient)
+	}
+
+	// Start opt-in print queue monitoring
+	if cfg.Printers.Enabled {
+		printerMonitor := printers.NewMonitor(logger, hostID, cfg.Printers)
+		printerMonitor.SetOpenAlerts(openAlerts)
+		go printerMonitor.Run(ctx, alertSender)
+	}
+
+	// Start opt-in HTTP checks
+	if len(cfg.Checks) > 0 {
+		checkMonitor := checks.NewMonitor(logger, hostID, cfg.Checks)
+		checkMonitor.SetOpenAlerts(openAlerts)
+		go checkMonitor.Run(ctx, alertSender)
+	}
+
+	// Start opt-in security signals
+	if cfg.Security.RemoteSessions || cfg.Security.UserSessions || cfg.Security.Antivirus || cfg.Security.FailedLogons {
+		monitor := security.NewMonitor(logger, hostID, cfg.Security)
+		monitor.SetOpenAlerts(openAlerts)
+		go monitor.Run(ctx, alertSender)
+	}
+
+	// Start opt-in handle consumer report
+	if cfg.Handles.Enabled {
+		go handles.NewMonitor(logger, hostID, cfg.Handles, cfg.Privacy).Run(ctx, wsClient)
+	}
+
+	// Start opt-in installed software inventory
+	if cfg.Software.Enabled {
+		go inventory.NewSoftwareScanner(logger, hostID, config.GetSoftwareFile(), cfg.Software).Run(ctx, wsClient)
+	}
+
+	// Start opt-in scheduled self-tests
+	if cfg.SelfTest.Enabled {
+		go selftest.NewRunner(logger, hostID, cfg.APIURL, config.GetSelfTestFile(), cfg.SelfTest).Run(ctx, wsClient)
+	}
+
+	// Scheduled restarts and the memory cap
+	restarter := maintenance.NewRestarter(logger, cfg.Maintenance)
+	go restarter.Run(ctx)
+	go maintenance.NewMemoryGuard(logger, hostID, cfg.Maintenance, restarter).Run(ctx, wsClient)
+
 	// Success message
 	logger.Info("✅ Agent running successfully")
 	fmt.Println("✅ WinDash Agent is running!")
 	fmt.Println("📊 Sending metrics to your dashboard")
 	fmt.Println("🌐 Dashboard:", cfg.DashboardURL)
-	fmt.Printf("📈 Collecting metrics every %dms\n", cfg.MetricsIntervalMs)
+	fmt.Printf("📈 Collecting metrics every %s\n", units.Duration(time.Duration(cfg.MetricsIntervalMs)*time.Millisecond))
 	fmt.Println("\nPress Ctrl+C to stop")
-	fmt.Printf("\n📝 Logs: %s\\agent.log\n\n", cfg.LogDir)
+	if cfg.LogDir != "" {
+		fmt.Printf("\n📝 Logs: %s\n\n", filepath.Join(cfg.LogDir, "agent.log"))
+	} else {
+		fmt.Print("\n📝 Logging to stdout only\n\n")
+	}
+	if *watchFlag {
+		go runWatchLine(ctx, os.Stdout, collector, wsClient)
+	}
 
-	// Wait for interrupt signal
+	// Wait for interrupt signal or a restart request
 	sigChan := make(chan os.Signal, 1)
 	signal.Notify(sigChan, os.Interrupt, syscall.SIGTERM)
-	<-sigChan
+	var restartReason string
+	select {
+	case <-sigChan:
+	case <-trayQuit:
+	case restartReason = <-restarter.Requested():
+	}
+	if trayManager != nil {
+		trayManager.Stop()
+	}
 
 	// Graceful shutdown
 	logger.Info("👋 Shutting down gracefully...")
 	fmt.Println("\n\n👋 Shutting down...")
 
+	if restartReason != "" {
+		// Stop sampling and send what is queued while still connected
+		collector.Pause()
+		drainCtx, drainCancel := context.WithTimeout(ctx, restarter.DrainTimeout())
+		if !wsClient.Drain(drainCtx) {
+			logger.Warn("Restarting with unsent data", "timeout", restarter.DrainTimeout())
+		}
+		drainCancel()
+	}
+
 	cancel()
 	time.Sleep(500 * time.Millisecond) // Give goroutines time to clean up
+	if sampleSpool != nil {
+		wsClient.SpillBuffered()
+		sampleSpool.Close()
+	}
+	if history != nil {
+		history.Close()
+	}
+
+	exitCode := 0
+	if restartReason != "" {
+		exitCode = restarter.Restart()
+	}
 
 	logger.Info("✅ Goodbye!")
 	fmt.Println("✅ Stopped. Goodbye!")
+	if err := agentLog.Close(); err != nil {
+		fmt.Fprintln(os.Stderr, "Failed to close log file:", err)
+	}
+	if exitCode != 0 {
+		os.Exit(exitCode)
+	}
+}
+
+// runReplay replays a control message recording against an offline client
+// and prints every message the agent would have sent in response
+func runReplay(path string, logger *zap.SugaredLogger) int {
+	cfg := &config.Config{MetricsIntervalMs: 2000}
+	collector := metrics.NewCollector(logger, "replay", 2*time.Second)
+	client := ws.NewClient(cfg, "", "replay", collector, logger)
+	client.SetPeerMesh(peers.NewMesh(logger, "replay", cfg.Peers)) // Validates setPeers; never probes
+
+	replies, err := ws.Replay(context.Background(), client, path)
+	for _, msg := range replies {
+		data, _ := json.Marshal(msg.Payload)
+		fmt.Println(string(data))
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "❌ Replay failed:", err)
+		return 1
+	}
+	return 0
+}
+
+// hiddenFlags work but are left out of --help: QA tooling, not for users
+var hiddenFlags = map[string]bool{"chaos": true}
+
+// usage prints the flag help without hiddenFlags
+func usage() {
+	visible := flag.NewFlagSet(os.Args[0], flag.ContinueOnError)
+	visible.SetOutput(flag.CommandLine.Output())
+	flag.VisitAll(func(f *flag.Flag) {
+		if !hiddenFlags[f.Name] {
+			visible.Var(f.Value, f.Name, f.Usage)
+		}
+	})
+	fmt.Fprintf(flag.CommandLine.Output(), "Usage of %s:\n", os.Args[0])
+	visible.PrintDefaults()
+}
+
+// settingFlag parses repeatable --set key=value flags into config overrides
+type settingFlag config.Overrides
+
+func (f settingFlag) String() string {
+	return ""
+}
+
+func (f settingFlag) Set(value string) error {
+	key, val, ok := strings.Cut(value, "=")
+	if !ok {
+		return fmt.Errorf("expected key=value, got %q", value)
+	}
+	if !config.IsSettingKey(key) {
+		return fmt.Errorf("unknown setting %q (valid: %s)", key, strings.Join(config.SettingKeys(), ", "))
+	}
+	f[key] = val
+	return nil
 }
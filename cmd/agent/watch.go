@@ -0,0 +1,65 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/jcdorr003/windash-agent/internal/metrics"
+	"github.com/jcdorr003/windash-agent/internal/ws"
+	"github.com/jcdorr003/windash-agent/pkg/units"
+)
+
+// watchRefresh is how often the --watch line is redrawn. Samples may arrive
+// less often; the connection state can change in between.
+const watchRefresh = time.Second
+
+// runWatchLine redraws a one-line summary of the latest sample and the
+// connection state in place until ctx is cancelled. The line is rewritten
+// with a carriage return and padded over the previous one rather than using
+// ANSI erase sequences, which older Windows consoles print literally.
+func runWatchLine(ctx context.Context, w io.Writer, collector *metrics.Collector, client *ws.Client) {
+	ticker := time.NewTicker(watchRefresh)
+	defer ticker.Stop()
+
+	width := 0
+	for {
+		line := watchLine(collector.Latest(), collector.Paused(), client.ConnectionState())
+		n := utf8.RuneCountInString(line)
+		fmt.Fprintf(w, "\r%s%s", line, strings.Repeat(" ", max(width-n, 0)))
+		width = n
+
+		select {
+		case <-ctx.Done():
+			fmt.Fprintln(w)
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// watchLine formats the summary, e.g.
+// "CPU 12% │ Mem 7.2 GiB/15.9 GiB │ Net ↓1.4 Mbps ↑220 kbps │ WS: connected"
+func watchLine(sample *metrics.SampleV1, paused bool, conn ws.ConnectionState) string {
+	wsState := conn.Phase
+	if conn.Phase == ws.PhaseDisconnected && !conn.RetryAt.IsZero() {
+		wsState = fmt.Sprintf("disconnected, retry in %s", max(time.Until(conn.RetryAt), 0).Round(time.Second))
+	}
+	if sample == nil {
+		return "Waiting for the first sample │ WS: " + wsState
+	}
+
+	parts := []string{
+		"CPU " + units.Percent(sample.CPU.Total),
+		fmt.Sprintf("Mem %s/%s", units.Bytes(sample.Mem.Used), units.Bytes(sample.Mem.Total)),
+		fmt.Sprintf("Net ↓%s ↑%s", units.BitsPerSec(sample.Net.RxBps), units.BitsPerSec(sample.Net.TxBps)),
+		"WS: " + wsState,
+	}
+	if paused {
+		parts = append(parts, "paused")
+	}
+	return strings.Join(parts, " │ ")
+}
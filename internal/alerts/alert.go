@@ -0,0 +1,43 @@
+package alerts
+
+import (
+	"fmt"
+	"time"
+)
+
+// Severity levels for alerts
+const (
+	SeverityInfo     = "info"
+	SeverityWarning  = "warning"
+	SeverityCritical = "critical"
+)
+
+// Alert is an agent-side detection sent to the backend as an "alert" message
+type Alert struct {
+	Type     string            `json:"type"` // always "alert"
+	ID       string            `json:"id"`
+	TS       time.Time         `json:"ts"`
+	HostID   string            `json:"hostId"`
+	Source   string            `json:"source"`   // Subsystem that raised it, e.g. "watch"
+	Severity string            `json:"severity"` // info, warning or critical
+	Title    string            `json:"title"`
+	Detail   string            `json:"detail,omitempty"`
+	Labels   map[string]string `json:"labels,omitempty"`
+
+	IncidentID string `json:"incidentId,omitempty"` // Set when sent inside an incident
+}
+
+// New creates an alert stamped with the current time and a unique ID
+func New(hostID, source, severity, title, detail string) *Alert {
+	now := time.Now()
+	return &Alert{
+		Type:     "alert",
+		ID:       fmt.Sprintf("%s-%d", source, now.UnixNano()),
+		TS:       now,
+		HostID:   hostID,
+		Source:   source,
+		Severity: severity,
+		Title:    title,
+		Detail:   detail,
+	}
+}
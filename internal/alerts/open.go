@@ -0,0 +1,40 @@
+package alerts
+
+import "sync"
+
+// OpenSet tracks which alert conditions are currently raised, so overall
+// alert state (e.g. for the health score) is visible outside the subsystem
+// that raised them. A nil *OpenSet ignores updates and reports zero.
+type OpenSet struct {
+	mu   sync.Mutex
+	open map[string]struct{}
+}
+
+// NewOpenSet creates an empty set
+func NewOpenSet() *OpenSet {
+	return &OpenSet{open: make(map[string]struct{})}
+}
+
+// Set marks the condition identified by key as raised or cleared
+func (s *OpenSet) Set(key string, raised bool) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if raised {
+		s.open[key] = struct{}{}
+	} else {
+		delete(s.open, key)
+	}
+}
+
+// Count returns the number of raised conditions
+func (s *OpenSet) Count() int {
+	if s == nil {
+		return 0
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.open)
+}
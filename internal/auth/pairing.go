@@ -1,22 +1,51 @@
 package auth
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
+	"strings"
 	"time"
 
+	"github.com/jcdorr003/windash-agent/internal/clipboard"
 	"github.com/jcdorr003/windash-agent/internal/config"
+	"github.com/jcdorr003/windash-agent/internal/httpx"
+	"github.com/jcdorr003/windash-agent/internal/links"
 	"github.com/pkg/browser"
+	"github.com/zalando/go-keyring"
 	"go.uber.org/zap"
 )
 
-// PairingAPI defines the interface for device pairing operations
+// Bounds for the pairing code lifetime reported by the server
+const (
+	defaultCodeLifetime = 10 * time.Minute // Used when the server's value is missing or implausible
+	maxCodeLifetime     = 1 * time.Hour
+)
+
+// ErrCodeExpired is returned by ExchangeCode when the code expired before
+// it was approved
+var ErrCodeExpired = errors.New("device code expired")
+
+// ErrTokenRejected is returned by ValidateToken when the backend no longer
+// accepts a stored token (revoked, or the host was deleted)
+var ErrTokenRejected = errors.New("token rejected by backend")
+
+// validateTimeout bounds the startup token check, so an unreachable backend
+// delays the start only briefly
+const validateTimeout = 15 * time.Second
+
+// PairingAPI defines the interface for device pairing operations.
+// RequestCode returns how long the code is valid rather than a wall-clock
+// expiry, so the polling deadline is unaffected by clock skew or the local
+// clock changing while waiting.
 type PairingAPI interface {
-	RequestCode(ctx context.Context) (code string, expiresAt time.Time, err error)
+	RequestCode(ctx context.Context) (code string, expiresIn time.Duration, err error)
 	ExchangeCode(ctx context.Context, code string) (token string, err error)
+	ValidateToken(ctx context.Context, token string) error
 }
 
 // RealPairingAPI implements device pairing with the WinDash backend
@@ -24,23 +53,53 @@ type RealPairingAPI struct {
 	logger     *zap.SugaredLogger
 	httpClient *http.Client
 	baseURL    string
+	headers    http.Header
+	siteID     string // Fleet placement sent with the code request
+	groupID    string
 }
 
-// NewRealPairingAPI creates a new real pairing API client
-func NewRealPairingAPI(logger *zap.SugaredLogger, baseURL string) *RealPairingAPI {
+// NewRealPairingAPI creates a new real pairing API client.
+// headers (User-Agent and any configured extras) are sent on every request.
+func NewRealPairingAPI(logger *zap.SugaredLogger, baseURL string, headers http.Header) *RealPairingAPI {
 	return &RealPairingAPI{
 		logger: logger,
 		httpClient: &http.Client{
 			Timeout: 10 * time.Second,
 		},
 		baseURL: baseURL, // This should be DashboardURL from config, which is set per env
+		headers: headers,
+	}
+}
+
+// SetFleetGroup sets the site and group sent when requesting a pairing
+// code, so the backend can file the host as soon as it is approved
+func (r *RealPairingAPI) SetFleetGroup(siteID, groupID string) {
+	r.siteID, r.groupID = siteID, groupID
+}
+
+// newRequest builds a request carrying the configured outbound headers
+func (r *RealPairingAPI) newRequest(ctx context.Context, method, url string, body io.Reader) (*http.Request, error) {
+	req, err := http.NewRequestWithContext(ctx, method, url, body)
+	if err != nil {
+		return nil, err
 	}
+	for name, values := range r.headers {
+		req.Header[name] = values
+	}
+	return req, nil
+}
+
+// deviceCodeRequest is the body of POST /api/device-codes
+type deviceCodeRequest struct {
+	SiteID  string `json:"siteId,omitempty"`
+	GroupID string `json:"groupId,omitempty"`
 }
 
 // deviceCodeResponse represents the response from POST /api/device-codes
 type deviceCodeResponse struct {
 	Code      string    `json:"code"`
 	ExpiresAt time.Time `json:"expiresAt"`
+	ExpiresIn int       `json:"expiresIn,omitempty"` // Seconds; preferred over expiresAt when present
 }
 
 // deviceTokenResponse represents the response from GET /api/device-token
@@ -51,35 +110,68 @@ type deviceTokenResponse struct {
 }
 
 // RequestCode requests a new device pairing code from the backend
-func (r *RealPairingAPI) RequestCode(ctx context.Context) (string, time.Time, error) {
+func (r *RealPairingAPI) RequestCode(ctx context.Context) (string, time.Duration, error) {
 	r.logger.Info("🔐 Requesting device code from backend...")
 
 	url := r.baseURL + "/api/device-codes"
-	req, err := http.NewRequestWithContext(ctx, "POST", url, nil)
+	body, err := json.Marshal(deviceCodeRequest{SiteID: r.siteID, GroupID: r.groupID})
 	if err != nil {
-		return "", time.Time{}, fmt.Errorf("failed to create request: %w", err)
+		return "", 0, fmt.Errorf("failed to encode request: %w", err)
+	}
+	req, err := r.newRequest(ctx, "POST", url, bytes.NewReader(body))
+	if err != nil {
+		return "", 0, fmt.Errorf("failed to create request: %w", err)
 	}
 
 	req.Header.Set("Content-Type", "application/json")
 
-	resp, err := r.httpClient.Do(req)
+	resp, err := httpx.Do(ctx, r.httpClient, req, httpx.DefaultRetryPolicy)
 	if err != nil {
-		return "", time.Time{}, fmt.Errorf("request failed: %w", err)
+		return "", 0, fmt.Errorf("request failed: %w", err)
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusCreated {
 		body, _ := io.ReadAll(resp.Body)
-		return "", time.Time{}, fmt.Errorf("unexpected status %d: %s", resp.StatusCode, string(body))
+		return "", 0, fmt.Errorf("unexpected status %d: %s", resp.StatusCode, string(body))
 	}
 
 	var result deviceCodeResponse
 	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
-		return "", time.Time{}, fmt.Errorf("failed to decode response: %w", err)
+		return "", 0, fmt.Errorf("failed to decode response: %w", err)
 	}
 
-	r.logger.Info("✅ Device code received", "code", result.Code, "expiresAt", result.ExpiresAt.Format("15:04:05"))
-	return result.Code, result.ExpiresAt, nil
+	expiresIn := codeLifetime(&result, resp.Header.Get("Date"))
+	r.logger.Info("✅ Device code received", "code", result.Code, "expiresIn", expiresIn)
+	return result.Code, r.checkLifetime(expiresIn), nil
+}
+
+// codeLifetime works out how long a code is valid. expiresIn is used when
+// the server sends it; otherwise expiresAt is measured against the
+// response's Date header, both being server clock, and only as a last
+// resort against the local clock.
+func codeLifetime(result *deviceCodeResponse, date string) time.Duration {
+	if result.ExpiresIn != 0 {
+		return time.Duration(result.ExpiresIn) * time.Second
+	}
+	if result.ExpiresAt.IsZero() {
+		return 0
+	}
+	if serverNow, err := http.ParseTime(date); err == nil {
+		return result.ExpiresAt.Sub(serverNow)
+	}
+	return time.Until(result.ExpiresAt)
+}
+
+// checkLifetime replaces a missing, negative or absurdly long lifetime with
+// the default
+func (r *RealPairingAPI) checkLifetime(expiresIn time.Duration) time.Duration {
+	if expiresIn > 0 && expiresIn <= maxCodeLifetime {
+		return expiresIn
+	}
+	r.logger.Warn("Implausible pairing code lifetime from server, using default",
+		"expiresIn", expiresIn, "default", defaultCodeLifetime)
+	return defaultCodeLifetime
 }
 
 // ExchangeCode polls the backend for device approval and token
@@ -95,14 +187,18 @@ func (r *RealPairingAPI) ExchangeCode(ctx context.Context, code string) (string,
 		case <-ctx.Done():
 			return "", ctx.Err()
 		case <-ticker.C:
-			req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+			req, err := r.newRequest(ctx, "GET", url, nil)
 			if err != nil {
 				r.logger.Warn("Failed to create request", "error", err)
 				continue
 			}
 
-			resp, err := r.httpClient.Do(req)
+			// Rate-limited polls wait out Retry-After before the next tick
+			resp, err := httpx.Do(ctx, r.httpClient, req, httpx.DefaultRetryPolicy)
 			if err != nil {
+				if ctx.Err() != nil {
+					return "", ctx.Err()
+				}
 				r.logger.Warn("Request failed", "error", err)
 				continue
 			}
@@ -127,7 +223,7 @@ func (r *RealPairingAPI) ExchangeCode(ctx context.Context, code string) (string,
 			case http.StatusGone:
 				// Code expired
 				resp.Body.Close()
-				return "", fmt.Errorf("device code expired - please restart the agent")
+				return "", ErrCodeExpired
 
 			default:
 				body, _ := io.ReadAll(resp.Body)
@@ -138,6 +234,36 @@ func (r *RealPairingAPI) ExchangeCode(ctx context.Context, code string) (string,
 	}
 }
 
+// ValidateToken checks a stored token against /api/agent/validate. It
+// returns ErrTokenRejected on 401/403, and nil when the token is accepted
+// or the backend doesn't have the endpoint (404).
+func (r *RealPairingAPI) ValidateToken(ctx context.Context, token string) error {
+	req, err := r.newRequest(ctx, "GET", r.baseURL+"/api/agent/validate", nil)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+
+	resp, err := httpx.Do(ctx, r.httpClient, req, httpx.DefaultRetryPolicy)
+	if err != nil {
+		return fmt.Errorf("request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK, http.StatusNoContent:
+		return nil
+	case http.StatusUnauthorized, http.StatusForbidden:
+		return ErrTokenRejected
+	case http.StatusNotFound:
+		r.logger.Debug("Backend has no token validation endpoint, skipping check")
+		return nil
+	default:
+		body, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("unexpected status %d: %s", resp.StatusCode, string(body))
+	}
+}
+
 // MockPairingAPI simulates the pairing flow for development/testing
 type MockPairingAPI struct {
 	logger *zap.SugaredLogger
@@ -149,15 +275,13 @@ func NewMockPairingAPI(logger *zap.SugaredLogger) *MockPairingAPI {
 }
 
 // RequestCode simulates requesting a device code from the backend
-func (m *MockPairingAPI) RequestCode(ctx context.Context) (string, time.Time, error) {
+func (m *MockPairingAPI) RequestCode(ctx context.Context) (string, time.Duration, error) {
 	m.logger.Info("🔐 [MOCK] Requesting device code from backend...")
 	time.Sleep(500 * time.Millisecond) // Simulate network delay
 
 	code := fmt.Sprintf("%04d-%04d", time.Now().Unix()%10000, time.Now().Unix()%10000)
-	expiresAt := time.Now().Add(10 * time.Minute)
-
-	m.logger.Info("✅ [MOCK] Device code generated", "code", code, "expiresAt", expiresAt.Format("15:04:05"))
-	return code, expiresAt, nil
+	m.logger.Info("✅ [MOCK] Device code generated", "code", code, "expiresIn", defaultCodeLifetime)
+	return code, defaultCodeLifetime, nil
 }
 
 // ExchangeCode simulates polling for device approval
@@ -180,20 +304,42 @@ func (m *MockPairingAPI) ExchangeCode(ctx context.Context, code string) (string,
 	return token, nil
 }
 
-// EnsurePaired ensures the device is paired with the WinDash backend
+// ValidateToken accepts every token
+func (m *MockPairingAPI) ValidateToken(ctx context.Context, token string) error {
+	return nil
+}
+
+// EnsurePaired ensures the device is paired with the WinDash backend,
+// recording every step in pairing
 // Returns (token, firstRun, error)
-func EnsurePaired(ctx context.Context, api PairingAPI, store *TokenStore, cfg *config.Config, logger *zap.SugaredLogger) (token string, firstRun bool, err error) {
+func EnsurePaired(ctx context.Context, api PairingAPI, store *TokenStore, pairing *PairingStore, cfg *config.Config, logger *zap.SugaredLogger) (token string, firstRun bool, err error) {
 	// Get device ID
 	deviceID, err := GetMachineID()
 	if err != nil {
 		return "", false, fmt.Errorf("failed to get device ID: %w", err)
 	}
 
-	// Check if already paired
+	// Check if already paired. A keychain that can't be read is reported
+	// rather than treated as unpaired, or the new token couldn't be saved either.
 	token, err = store.GetToken(deviceID)
-	if err == nil && token != "" {
-		logger.Debug("Device already paired", "deviceId", deviceID)
-		return token, false, nil
+	if err != nil && !errors.Is(err, keyring.ErrNotFound) {
+		return "", false, fmt.Errorf("failed to read token from keychain: %w", err)
+	}
+	if token != "" {
+		if validateStoredToken(ctx, api, store, deviceID, token, logger) {
+			logger.Debug("Device already paired", "deviceId", deviceID)
+			if st := pairing.State(); !st.Paired(deviceID, cfg.Env) {
+				recordPairing(pairing, logger, func(p *PairingState) error {
+					return p.Adopt(deviceID, cfg.Env, cfg.DashboardURL, time.Now())
+				})
+			}
+			return token, false, nil
+		}
+		if pairing.State().State == PairingPaired {
+			recordPairing(pairing, logger, func(p *PairingState) error { return p.Revoke(time.Now()) })
+		}
+		fmt.Println()
+		fmt.Println("🔑 This device's pairing was revoked - let's pair it again.")
 	}
 
 	// First run - need to pair
@@ -202,28 +348,141 @@ func EnsurePaired(ctx context.Context, api PairingAPI, store *TokenStore, cfg *c
 	fmt.Println("🆕 First time setup - Let's pair your device!")
 	fmt.Println()
 
+	// Each cycle uses a fresh code; an expired one is replaced automatically
+	cycles := max(cfg.PairingCycles, 1)
+	for cycle := 1; ; cycle++ {
+		token, err = pairOnce(ctx, api, pairing, deviceID, cfg, logger, cycle > 1)
+		if !errors.Is(err, ErrCodeExpired) {
+			break
+		}
+		recordPairing(pairing, logger, func(p *PairingState) error { return p.Expire(time.Now()) })
+		if cycle == cycles {
+			return "", true, fmt.Errorf("pairing code expired %d times without approval - restart the agent to try again", cycles)
+		}
+		logger.Info("⌛ Pairing code expired, requesting a new one", "cycle", cycle+1, "of", cycles)
+		fmt.Printf("\n⌛ The pairing code expired before it was approved - here is a new one (%d of %d)\n\n", cycle+1, cycles)
+	}
+	if err != nil {
+		return "", true, err
+	}
+
+	// Store token securely
+	if err := store.SaveToken(deviceID, token); err != nil {
+		return "", true, fmt.Errorf("failed to save token: %w", err)
+	}
+	recordPairing(pairing, logger, func(p *PairingState) error { return p.Approve(time.Now()) })
+
+	logger.Info("✅ Pairing complete!")
+	fmt.Println()
+	fmt.Println("✅ Device paired successfully!")
+	fmt.Println()
+
+	return token, true, nil
+}
+
+// recordPairing applies a pairing state transition, logging rather than
+// failing when it can't be recorded: the keychain token is what lets the
+// agent connect
+func recordPairing(pairing *PairingStore, logger *zap.SugaredLogger, fn func(p *PairingState) error) {
+	if err := pairing.Transition(fn); err != nil {
+		logger.Warn("Failed to record pairing state", "error", err)
+	}
+}
+
+// validateStoredToken checks the stored token with the backend before the
+// agent connects with it. A rejected token is deleted and false returned so
+// the device re-pairs right away; if the check itself fails (offline
+// backend) the token is kept and the WebSocket client retries as usual.
+func validateStoredToken(ctx context.Context, api PairingAPI, store *TokenStore, deviceID, token string, logger *zap.SugaredLogger) bool {
+	ctx, cancel := context.WithTimeout(ctx, validateTimeout)
+	defer cancel()
+
+	err := api.ValidateToken(ctx, token)
+	switch {
+	case err == nil:
+		return true
+	case errors.Is(err, ErrTokenRejected):
+		logger.Warn("🔑 Stored token was rejected by the backend, re-pairing")
+		if err := store.DeleteToken(deviceID); err != nil {
+			logger.Warn("Failed to delete rejected token", "error", err)
+		}
+		return false
+	default:
+		logger.Warn("Could not validate stored token, using it anyway", "error", err)
+		return true
+	}
+}
+
+// pairOnce requests a code, shows it and waits for approval. It returns
+// ErrCodeExpired when the code expires first.
+func pairOnce(ctx context.Context, api PairingAPI, pairing *PairingStore, deviceID string, cfg *config.Config, logger *zap.SugaredLogger, retry bool) (string, error) {
 	// Request device code from backend
-	code, expiresAt, err := api.RequestCode(ctx)
+	code, expiresIn, err := api.RequestCode(ctx)
 	if err != nil {
 		fmt.Printf("\n❌ Failed to request device code from backend:\n")
 		fmt.Printf("   Error: %v\n", err)
 		fmt.Printf("   Backend URL: %s/api/device-codes\n\n", cfg.DashboardURL)
-		return "", true, fmt.Errorf("failed to request device code: %w", err)
+		return "", fmt.Errorf("failed to request device code: %w", err)
 	}
 
-	// Save device code to config
-	cfg.DeviceCode = code
-	if err := cfg.Save(); err != nil {
-		logger.Warn("Failed to save device code to config", "error", err)
-	}
+	// Deadline on the monotonic clock: time.Now() carries a monotonic
+	// reading, so wall-clock jumps while waiting don't move it
+	pollCtx, cancel := context.WithTimeout(ctx, expiresIn)
+	defer cancel()
+	expiresAt := time.Now().Add(expiresIn)
+
+	recordPairing(pairing, logger, func(p *PairingState) error {
+		return p.Issue(deviceID, cfg.Env, cfg.DashboardURL, code, expiresAt, time.Now())
+	})
 
 	// Build pairing URL
-	pairingURL := fmt.Sprintf("%s/pair?code=%s", cfg.DashboardURL, code)
+	pairingURL := links.Pairing(cfg.DashboardURL, code)
+
+	// Copy the link so it can be pasted if the browser doesn't open
+	copied := false
+	if cfg.CopyOnPair {
+		if err := clipboard.Copy(pairingURL); err != nil {
+			logger.Debug("Could not copy pairing link to clipboard", "error", err)
+		} else {
+			copied = true
+		}
+	}
+
+	// Without a desktop (service, SSH) the code is approved from another
+	// device. A replacement code is never opened in yet another browser tab.
+	openBrowser := cfg.OpenOnPair && !retry
+	if reason := HeadlessReason(); reason != "" && openBrowser {
+		logger.Info("🖥️  No interactive desktop, not opening a browser", "reason", reason)
+		openBrowser = false
+	}
+	if !openBrowser {
+		printHeadlessInstructions(logger, code, pairingURL, expiresAt)
+	} else {
+		showPairingInstructions(logger, code, pairingURL, expiresAt, copied)
+	}
 
-	// Show user-friendly instructions
+	// Poll for token
+	fmt.Println("⏳ Waiting for approval...")
+	token, err := api.ExchangeCode(pollCtx, code)
+	switch {
+	case err == nil:
+		return token, nil
+	case errors.Is(err, ErrCodeExpired), ctx.Err() == nil && errors.Is(err, context.DeadlineExceeded):
+		return "", ErrCodeExpired
+	default:
+		return "", fmt.Errorf("pairing failed: %w", err)
+	}
+}
+
+// showPairingInstructions prints the pairing steps and opens the pairing page
+func showPairingInstructions(logger *zap.SugaredLogger, code, pairingURL string, expiresAt time.Time, copied bool) {
 	fmt.Printf("🔐 Your pairing code: %s\n\n", code)
 	fmt.Printf("📋 To complete setup:\n")
-	fmt.Printf("   1. Your browser will open automatically\n")
+	if copied {
+		fmt.Printf("   1. Your browser will open automatically (or paste the link, already on your clipboard)\n")
+	} else {
+		fmt.Printf("   1. Your browser will open automatically\n")
+	}
 	fmt.Printf("   2. Log in to your WinDash account\n")
 	fmt.Printf("   3. Approve this device\n\n")
 	fmt.Printf("⏱️  Code expires at: %s\n\n", expiresAt.Format("15:04:05"))
@@ -236,28 +495,27 @@ func EnsurePaired(ctx context.Context, api PairingAPI, store *TokenStore, cfg *c
 		fmt.Printf("⚠️  Could not open browser automatically.\n")
 		fmt.Printf("   Please visit: %s\n\n", pairingURL)
 	}
+}
 
-	// Poll for token
-	fmt.Println("⏳ Waiting for approval...")
-	pollCtx, cancel := context.WithDeadline(ctx, expiresAt)
-	defer cancel()
-
-	token, err = api.ExchangeCode(pollCtx, code)
-	if err != nil {
-		return "", true, fmt.Errorf("pairing failed: %w", err)
-	}
-
-	// Store token securely
-	if err := store.SaveToken(deviceID, token); err != nil {
-		return "", true, fmt.Errorf("failed to save token: %w", err)
-	}
+// printHeadlessInstructions prints the code and link prominently for
+// approval from another device, without opening a browser. They are
+// logged too, since a service's console output is seen by no one.
+func printHeadlessInstructions(logger *zap.SugaredLogger, code, pairingURL string, expiresAt time.Time) {
+	logger.Info("🔐 Waiting for pairing approval", "code", code, "url", pairingURL, "expiresAt", expiresAt)
 
-	logger.Info("✅ Pairing complete!")
+	rule := strings.Repeat("=", 60)
+	fmt.Println(rule)
+	fmt.Printf("  🔐 PAIRING CODE:  %s\n", code)
 	fmt.Println()
-	fmt.Println("✅ Device paired successfully!")
+	fmt.Printf("  Open this link on any device with a browser:\n")
+	fmt.Printf("  %s\n", pairingURL)
+	fmt.Println(rule)
 	fmt.Println()
-
-	return token, true, nil
+	fmt.Printf("📋 To complete setup:\n")
+	fmt.Printf("   1. Open the link above (or go to the dashboard and enter the code)\n")
+	fmt.Printf("   2. Log in to your WinDash account\n")
+	fmt.Printf("   3. Approve this device\n\n")
+	fmt.Printf("⏱️  Code expires at: %s\n\n", expiresAt.Format("15:04:05"))
 }
 
 // OpenDashboard opens the WinDash dashboard in the default browser
@@ -0,0 +1,207 @@
+package auth
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// Pairing states
+const (
+	PairingUnpaired = "unpaired" // No pairing in progress and none completed
+	PairingPending  = "pending"  // A code was issued and awaits approval
+	PairingPaired   = "paired"   // A code was approved; the token is in the keychain
+)
+
+// PairingState is the device's pairing progress (pairing.json). It changes
+// only through its transition methods, each of which checks that it is
+// allowed from the current state. The token itself stays in the keychain.
+type PairingState struct {
+	State     string    `json:"state"`
+	DeviceID  string    `json:"deviceId,omitempty"`
+	Env       string    `json:"env,omitempty"`      // Environment the code was issued in
+	Endpoint  string    `json:"endpoint,omitempty"` // Dashboard URL the code was issued by
+	Code      string    `json:"code,omitempty"`     // Pending: the code awaiting approval
+	ExpiresAt time.Time `json:"expiresAt,omitzero"` // Pending: when the code expires
+	PairedAt  time.Time `json:"pairedAt,omitzero"`  // Paired: when the code was approved
+	UpdatedAt time.Time `json:"updatedAt,omitzero"` // Last transition
+}
+
+// transitionError reports a transition that isn't allowed from the current
+// state
+func (p *PairingState) transitionError(action string) error {
+	return fmt.Errorf("pairing: cannot %s while %s", action, p.state())
+}
+
+// state returns the current state, treating an empty one as unpaired
+func (p *PairingState) state() string {
+	if p.State == "" {
+		return PairingUnpaired
+	}
+	return p.State
+}
+
+// Issue records a new code awaiting approval, replacing a pending one.
+// A paired device has to be revoked or reset first.
+func (p *PairingState) Issue(deviceID, env, endpoint, code string, expiresAt, now time.Time) error {
+	if p.state() == PairingPaired {
+		return p.transitionError("issue a code")
+	}
+	*p = PairingState{
+		State:     PairingPending,
+		DeviceID:  deviceID,
+		Env:       env,
+		Endpoint:  endpoint,
+		Code:      code,
+		ExpiresAt: expiresAt,
+		UpdatedAt: now,
+	}
+	return nil
+}
+
+// Approve records that the pending code was approved
+func (p *PairingState) Approve(now time.Time) error {
+	if p.state() != PairingPending {
+		return p.transitionError("approve")
+	}
+	p.State = PairingPaired
+	p.Code, p.ExpiresAt = "", time.Time{}
+	p.PairedAt, p.UpdatedAt = now, now
+	return nil
+}
+
+// Expire drops a pending code that ran out before it was approved
+func (p *PairingState) Expire(now time.Time) error {
+	if p.state() != PairingPending {
+		return p.transitionError("expire")
+	}
+	p.State = PairingUnpaired
+	p.Code, p.ExpiresAt = "", time.Time{}
+	p.UpdatedAt = now
+	return nil
+}
+
+// Revoke records that the backend no longer accepts the device's token
+func (p *PairingState) Revoke(now time.Time) error {
+	if p.state() != PairingPaired {
+		return p.transitionError("revoke")
+	}
+	p.State = PairingUnpaired
+	p.PairedAt = time.Time{}
+	p.UpdatedAt = now
+	return nil
+}
+
+// Adopt records a token found in the keychain for deviceID in env while
+// not paired, e.g. one stored by an agent version without pairing.json.
+// When it was approved is unknown.
+func (p *PairingState) Adopt(deviceID, env, endpoint string, now time.Time) error {
+	if p.state() == PairingPaired && p.DeviceID == deviceID && p.Env == env {
+		return p.transitionError("adopt a token")
+	}
+	*p = PairingState{
+		State:     PairingPaired,
+		DeviceID:  deviceID,
+		Env:       env,
+		Endpoint:  endpoint,
+		UpdatedAt: now,
+	}
+	return nil
+}
+
+// Reset forgets all pairing progress (--reset). Allowed from every state.
+func (p *PairingState) Reset(now time.Time) {
+	*p = PairingState{State: PairingUnpaired, DeviceID: p.DeviceID, UpdatedAt: now}
+}
+
+// Paired reports whether the state records a completed pairing of deviceID
+// in env
+func (p *PairingState) Paired(deviceID, env string) bool {
+	return p.state() == PairingPaired && p.DeviceID == deviceID && p.Env == env
+}
+
+// ReadPairingState loads the pairing state file at path. A missing file is
+// the unpaired state.
+func ReadPairingState(path string) (*PairingState, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return &PairingState{State: PairingUnpaired}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var p PairingState
+	if err := json.Unmarshal(data, &p); err != nil {
+		return nil, fmt.Errorf("parse %s: %w", path, err)
+	}
+	p.State = p.state()
+	return &p, nil
+}
+
+// PairingStore holds the pairing state and saves every transition to
+// pairing.json, so it is the one place pairing progress is recorded
+type PairingStore struct {
+	logger *zap.SugaredLogger
+	path   string
+
+	mu    sync.Mutex
+	state PairingState
+}
+
+// OpenPairingStore loads the pairing state at path, starting unpaired if
+// it is missing or unreadable. A pending code that has expired since the
+// last run is dropped.
+func OpenPairingStore(logger *zap.SugaredLogger, path string) *PairingStore {
+	s := &PairingStore{logger: logger, path: path, state: PairingState{State: PairingUnpaired}}
+	p, err := ReadPairingState(path)
+	if err != nil {
+		logger.Warn("Ignoring unreadable pairing state", "path", path, "error", err)
+		return s
+	}
+	s.state = *p
+
+	now := time.Now()
+	if s.state.State == PairingPending && !now.Before(s.state.ExpiresAt) {
+		logger.Debug("Dropping expired pairing code", "expiredAt", s.state.ExpiresAt)
+		if err := s.Transition(func(p *PairingState) error { return p.Expire(now) }); err != nil {
+			logger.Warn("Failed to clear expired pairing code", "error", err)
+		}
+	}
+	return s
+}
+
+// State returns a copy of the current pairing state
+func (s *PairingStore) State() PairingState {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.state
+}
+
+// Transition applies fn (one of the PairingState transitions) and saves the
+// result. If fn fails the state is left unchanged; if saving fails the new
+// state is kept in memory and the error returned.
+func (s *PairingStore) Transition(fn func(p *PairingState) error) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	next := s.state
+	if err := fn(&next); err != nil {
+		return err
+	}
+	s.state = next
+
+	data, err := json.MarshalIndent(next, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := s.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, s.path)
+}
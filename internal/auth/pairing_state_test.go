@@ -0,0 +1,223 @@
+package auth
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+var (
+	testNow     = time.Date(2026, 10, 15, 12, 0, 0, 0, time.UTC)
+	testExpires = testNow.Add(10 * time.Minute)
+)
+
+// pairingIn returns a state in the named state for device-1 in remoteprod
+func pairingIn(state string) PairingState {
+	switch state {
+	case PairingPending:
+		return PairingState{State: PairingPending, DeviceID: "device-1", Env: "remoteprod", Code: "ABCD-1234", ExpiresAt: testExpires}
+	case PairingPaired:
+		return PairingState{State: PairingPaired, DeviceID: "device-1", Env: "remoteprod", PairedAt: testNow.Add(-time.Hour)}
+	default:
+		return PairingState{State: state}
+	}
+}
+
+func TestPairingTransitions(t *testing.T) {
+	transitions := map[string]func(p *PairingState) error{
+		"issue": func(p *PairingState) error {
+			return p.Issue("device-1", "remoteprod", "https://dash", "WXYZ-9876", testExpires, testNow)
+		},
+		"approve": func(p *PairingState) error { return p.Approve(testNow) },
+		"expire":  func(p *PairingState) error { return p.Expire(testNow) },
+		"revoke":  func(p *PairingState) error { return p.Revoke(testNow) },
+	}
+	tests := []struct {
+		from       string
+		transition string
+		to         string // "" = rejected
+	}{
+		{PairingUnpaired, "issue", PairingPending},
+		{PairingUnpaired, "approve", ""},
+		{PairingUnpaired, "expire", ""},
+		{PairingUnpaired, "revoke", ""},
+		{"", "issue", PairingPending}, // An empty state is unpaired
+		{"", "approve", ""},
+		{PairingPending, "issue", PairingPending},
+		{PairingPending, "approve", PairingPaired},
+		{PairingPending, "expire", PairingUnpaired},
+		{PairingPending, "revoke", ""},
+		{PairingPaired, "issue", ""},
+		{PairingPaired, "approve", ""},
+		{PairingPaired, "expire", ""},
+		{PairingPaired, "revoke", PairingUnpaired},
+	}
+	for _, tt := range tests {
+		t.Run(tt.transition+" from "+tt.from, func(t *testing.T) {
+			p := pairingIn(tt.from)
+			before := p
+			err := transitions[tt.transition](&p)
+			if tt.to == "" {
+				if err == nil || !strings.Contains(err.Error(), "pairing: cannot") {
+					t.Fatalf("error = %v, want a transition error", err)
+				}
+				if p != before {
+					t.Errorf("rejected transition changed the state to %+v", p)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if p.State != tt.to {
+				t.Errorf("state = %s, want %s", p.State, tt.to)
+			}
+			if !p.UpdatedAt.Equal(testNow) {
+				t.Errorf("updatedAt = %v, want %v", p.UpdatedAt, testNow)
+			}
+		})
+	}
+}
+
+func TestPairingTransitionFields(t *testing.T) {
+	p := pairingIn(PairingUnpaired)
+	if err := p.Issue("device-1", "remoteprod", "https://dash", "WXYZ-9876", testExpires, testNow); err != nil {
+		t.Fatal(err)
+	}
+	if p.Code != "WXYZ-9876" || !p.ExpiresAt.Equal(testExpires) || p.Endpoint != "https://dash" {
+		t.Errorf("issued state = %+v", p)
+	}
+	if err := p.Approve(testNow); err != nil {
+		t.Fatal(err)
+	}
+	if p.Code != "" || !p.ExpiresAt.IsZero() || !p.PairedAt.Equal(testNow) {
+		t.Errorf("approved state = %+v, want the code cleared and pairedAt set", p)
+	}
+	if !p.Paired("device-1", "remoteprod") || p.Paired("device-1", "localdev") || p.Paired("device-2", "remoteprod") {
+		t.Errorf("Paired doesn't match only device-1 in remoteprod")
+	}
+	if err := p.Revoke(testNow); err != nil {
+		t.Fatal(err)
+	}
+	if !p.PairedAt.IsZero() || p.Paired("device-1", "remoteprod") {
+		t.Errorf("revoked state = %+v", p)
+	}
+}
+
+func TestPairingAdopt(t *testing.T) {
+	tests := []struct {
+		name     string
+		from     PairingState
+		deviceID string
+		env      string
+		allowed  bool
+	}{
+		{"unpaired", pairingIn(PairingUnpaired), "device-1", "remoteprod", true},
+		{"pending", pairingIn(PairingPending), "device-1", "remoteprod", true},
+		{"paired, same device and env", pairingIn(PairingPaired), "device-1", "remoteprod", false},
+		{"paired, other env", pairingIn(PairingPaired), "device-1", "localdev", true},
+		{"paired, other device", pairingIn(PairingPaired), "device-2", "remoteprod", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := tt.from
+			err := p.Adopt(tt.deviceID, tt.env, "https://dash", testNow)
+			if !tt.allowed {
+				if err == nil {
+					t.Fatal("adopt succeeded, want a transition error")
+				}
+				if p != tt.from {
+					t.Errorf("rejected adopt changed the state to %+v", p)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !p.Paired(tt.deviceID, tt.env) || !p.PairedAt.IsZero() || p.Code != "" {
+				t.Errorf("adopted state = %+v", p)
+			}
+		})
+	}
+}
+
+func TestPairingReset(t *testing.T) {
+	for _, state := range []string{PairingUnpaired, PairingPending, PairingPaired} {
+		p := pairingIn(state)
+		p.DeviceID = "device-1"
+		p.Reset(testNow)
+		want := PairingState{State: PairingUnpaired, DeviceID: "device-1", UpdatedAt: testNow}
+		if p != want {
+			t.Errorf("reset from %s = %+v, want %+v", state, p, want)
+		}
+	}
+}
+
+func TestPairingStoreRoundTrip(t *testing.T) {
+	logger := zap.NewNop().Sugar()
+	path := filepath.Join(t.TempDir(), "pairing.json")
+
+	s := OpenPairingStore(logger, path)
+	if got := s.State().State; got != PairingUnpaired {
+		t.Fatalf("missing file opened as %s, want unpaired", got)
+	}
+	expires := time.Now().Add(time.Hour).Truncate(time.Second)
+	err := s.Transition(func(p *PairingState) error {
+		return p.Issue("device-1", "remoteprod", "https://dash", "ABCD-1234", expires, time.Now())
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	reopened := OpenPairingStore(logger, path).State()
+	if reopened.State != PairingPending || reopened.Code != "ABCD-1234" || !reopened.ExpiresAt.Equal(expires) {
+		t.Errorf("reloaded state = %+v", reopened)
+	}
+
+	// A rejected transition leaves both memory and file unchanged
+	if err := s.Transition(func(p *PairingState) error { return p.Revoke(time.Now()) }); err == nil {
+		t.Error("revoke while pending succeeded")
+	}
+	if got, _ := ReadPairingState(path); got.State != PairingPending {
+		t.Errorf("file state after a rejected transition = %s", got.State)
+	}
+	if _, err := os.Stat(path + ".tmp"); !os.IsNotExist(err) {
+		t.Errorf("temporary file left behind: %v", err)
+	}
+}
+
+func TestPairingStoreExpiresOnOpen(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "pairing.json")
+	expired := PairingState{State: PairingPending, DeviceID: "device-1", Env: "remoteprod",
+		Code: "ABCD-1234", ExpiresAt: time.Now().Add(-time.Minute)}
+	data, err := json.Marshal(expired)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	s := OpenPairingStore(zap.NewNop().Sugar(), path)
+	if got := s.State(); got.State != PairingUnpaired || got.Code != "" {
+		t.Errorf("expired code opened as %+v, want unpaired", got)
+	}
+	if got, err := ReadPairingState(path); err != nil || got.State != PairingUnpaired {
+		t.Errorf("file after expiry on open = %+v, %v", got, err)
+	}
+}
+
+func TestPairingStoreUnreadable(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "pairing.json")
+	if err := os.WriteFile(path, []byte("{not json"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if got := OpenPairingStore(zap.NewNop().Sugar(), path).State().State; got != PairingUnpaired {
+		t.Errorf("unreadable file opened as %s, want unpaired", got)
+	}
+}
@@ -0,0 +1,12 @@
+package auth
+
+import "os"
+
+// HeadlessReason explains why no browser can be shown to the user (a
+// service or SSH session), or returns "" in an interactive desktop session
+func HeadlessReason() string {
+	if os.Getenv("SSH_CONNECTION") != "" || os.Getenv("SSH_CLIENT") != "" {
+		return "SSH session"
+	}
+	return desktopHeadlessReason()
+}
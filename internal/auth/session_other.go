@@ -0,0 +1,19 @@
+//go:build !windows
+
+package auth
+
+import (
+	"os"
+	"runtime"
+)
+
+// desktopHeadlessReason reports a missing display server on Linux and BSD
+func desktopHeadlessReason() string {
+	if runtime.GOOS == "darwin" {
+		return ""
+	}
+	if os.Getenv("DISPLAY") == "" && os.Getenv("WAYLAND_DISPLAY") == "" {
+		return "no display"
+	}
+	return ""
+}
@@ -0,0 +1,15 @@
+//go:build windows
+
+package auth
+
+import "golang.org/x/sys/windows"
+
+// desktopHeadlessReason reports session 0, where services run with no
+// desktop the user can see
+func desktopHeadlessReason() string {
+	var session uint32
+	if err := windows.ProcessIdToSessionId(windows.GetCurrentProcessId(), &session); err == nil && session == 0 {
+		return "service session"
+	}
+	return ""
+}
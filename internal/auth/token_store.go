@@ -1,40 +1,58 @@
 package auth
 
 import (
+	"errors"
 	"fmt"
 
 	"github.com/denisbrodbeck/machineid"
+	"github.com/jcdorr003/windash-agent/internal/chaos"
 	"github.com/jcdorr003/windash-agent/internal/config"
 	"github.com/zalando/go-keyring"
 	"go.uber.org/zap"
 )
 
 // TokenStore manages secure storage of authentication tokens
-// Uses Windows DPAPI via go-keyring
+// Uses Windows DPAPI via go-keyring. Tokens are stored per environment so
+// switching between e.g. localdev and remoteprod never reuses the wrong one.
 type TokenStore struct {
 	logger *zap.SugaredLogger
+	env    string
 }
 
-// NewTokenStore creates a new token store
-func NewTokenStore(logger *zap.SugaredLogger) *TokenStore {
-	return &TokenStore{logger: logger}
+// NewTokenStore creates a token store for the given environment
+func NewTokenStore(logger *zap.SugaredLogger, env string) *TokenStore {
+	return &TokenStore{logger: logger, env: env}
+}
+
+// tokenKey returns the keychain account name for a device in an environment
+func tokenKey(env, deviceID string) string {
+	return env + "|" + deviceID
 }
 
 // SaveToken stores the authentication token securely in the OS keychain
 func (s *TokenStore) SaveToken(deviceID, token string) error {
-	s.logger.Debug("Saving token to keychain", "deviceId", deviceID)
-	err := keyring.Set(config.KeychainService, deviceID, token)
+	s.logger.Debug("Saving token to keychain", "deviceId", deviceID, "env", s.env)
+	if err := chaos.KeychainError(); err != nil {
+		return fmt.Errorf("keychain save failed: %w", err)
+	}
+	err := keyring.Set(config.KeychainService, tokenKey(s.env, deviceID), token)
 	if err != nil {
 		return fmt.Errorf("keychain save failed: %w", err)
 	}
-	s.logger.Info("🔐 Token saved securely to Windows Credential Manager")
+	s.logger.Info("🔐 Token saved securely to Windows Credential Manager", "env", s.env)
 	return nil
 }
 
 // GetToken retrieves the authentication token from the OS keychain
 func (s *TokenStore) GetToken(deviceID string) (string, error) {
-	s.logger.Debug("Retrieving token from keychain", "deviceId", deviceID)
-	token, err := keyring.Get(config.KeychainService, deviceID)
+	s.logger.Debug("Retrieving token from keychain", "deviceId", deviceID, "env", s.env)
+	if err := chaos.KeychainError(); err != nil {
+		return "", err
+	}
+	token, err := keyring.Get(config.KeychainService, tokenKey(s.env, deviceID))
+	if errors.Is(err, keyring.ErrNotFound) {
+		token, err = s.migrateLegacy(deviceID)
+	}
 	if err != nil {
 		return "", err
 	}
@@ -42,10 +60,40 @@ func (s *TokenStore) GetToken(deviceID string) (string, error) {
 	return token, nil
 }
 
+// HasToken reports whether a token is stored for the device in env
+func (s *TokenStore) HasToken(env, deviceID string) bool {
+	if chaos.KeychainError() != nil {
+		return false
+	}
+	token, err := keyring.Get(config.KeychainService, tokenKey(env, deviceID))
+	return err == nil && token != ""
+}
+
 // DeleteToken removes the authentication token from the OS keychain
 func (s *TokenStore) DeleteToken(deviceID string) error {
-	s.logger.Debug("Deleting token from keychain", "deviceId", deviceID)
-	return keyring.Delete(config.KeychainService, deviceID)
+	s.logger.Debug("Deleting token from keychain", "deviceId", deviceID, "env", s.env)
+	if err := chaos.KeychainError(); err != nil {
+		return err
+	}
+	return keyring.Delete(config.KeychainService, tokenKey(s.env, deviceID))
+}
+
+// migrateLegacy moves a token stored under the bare device ID (before tokens
+// were per environment) to the current environment's key. The environment
+// it was issued for isn't recorded, so the one in use now is assumed.
+func (s *TokenStore) migrateLegacy(deviceID string) (string, error) {
+	token, err := keyring.Get(config.KeychainService, deviceID)
+	if err != nil {
+		return "", err
+	}
+	if err := keyring.Set(config.KeychainService, tokenKey(s.env, deviceID), token); err != nil {
+		return "", fmt.Errorf("keychain migrate failed: %w", err)
+	}
+	if err := keyring.Delete(config.KeychainService, deviceID); err != nil {
+		s.logger.Warn("Failed to remove legacy token entry", "error", err)
+	}
+	s.logger.Info("🔐 Migrated stored token to per-environment key", "env", s.env)
+	return token, nil
 }
 
 // GetMachineID returns a stable unique identifier for this machine
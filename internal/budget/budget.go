@@ -0,0 +1,103 @@
+package budget
+
+import (
+	"sync/atomic"
+
+	"github.com/jcdorr003/windash-agent/internal/chaos"
+)
+
+// Budget tracks approximate memory held by in-memory queues (sample buffer,
+// outbox, caches) against a global limit. Components charge what they hold
+// with Add and release it with a negative Add; when Exceeded reports true
+// they are expected to shed or downsample data. A nil *Budget is unlimited.
+type Budget struct {
+	limit int64
+	used  atomic.Int64
+
+	// Degradation counters, reported in status messages
+	downsampled atomic.Uint64
+	shed        atomic.Uint64
+}
+
+// Usage is a point-in-time snapshot of a Budget
+type Usage struct {
+	UsedBytes   int64  `json:"usedBytes"`
+	LimitBytes  int64  `json:"limitBytes"`
+	Degraded    bool   `json:"degraded"`
+	Downsampled uint64 `json:"downsampled"` // Items thinned out to save memory
+	Shed        uint64 `json:"shed"`        // Items dropped outright to save memory
+}
+
+// New creates a budget of limitBytes (0 or less means unlimited)
+func New(limitBytes int64) *Budget {
+	return &Budget{limit: limitBytes}
+}
+
+// Add charges n bytes to the budget (negative n releases)
+func (b *Budget) Add(n int64) {
+	if b == nil {
+		return
+	}
+	b.used.Add(n)
+}
+
+// Exceeded reports whether usage is over the limit, or always while the
+// chaos buffers fault is on
+func (b *Budget) Exceeded() bool {
+	if b == nil {
+		return false
+	}
+	if chaos.Current().Buffers {
+		return true
+	}
+	if b.limit <= 0 {
+		return false
+	}
+	return b.used.Load() > b.limit
+}
+
+// Relieved reports whether usage is back under three quarters of the
+// limit, the point where components undo their degradation. The gap to
+// Exceeded keeps them from flapping around the limit.
+func (b *Budget) Relieved() bool {
+	if b == nil {
+		return true
+	}
+	if chaos.Current().Buffers {
+		return false
+	}
+	if b.limit <= 0 {
+		return true
+	}
+	return b.used.Load() <= b.limit/4*3
+}
+
+// RecordDownsampled counts items thinned out due to memory pressure
+func (b *Budget) RecordDownsampled(n int) {
+	if b == nil {
+		return
+	}
+	b.downsampled.Add(uint64(n))
+}
+
+// RecordShed counts items dropped due to memory pressure
+func (b *Budget) RecordShed(n int) {
+	if b == nil {
+		return
+	}
+	b.shed.Add(uint64(n))
+}
+
+// Usage returns a snapshot for status reporting
+func (b *Budget) Usage() Usage {
+	if b == nil {
+		return Usage{}
+	}
+	return Usage{
+		UsedBytes:   b.used.Load(),
+		LimitBytes:  b.limit,
+		Degraded:    b.Exceeded(),
+		Downsampled: b.downsampled.Load(),
+		Shed:        b.shed.Load(),
+	}
+}
@@ -0,0 +1,147 @@
+// Package chaos simulates failures at runtime so the agent's resilience
+// features (reconnect backoff, spool, acks, subsystem error budgets, buffer
+// degradation) can be exercised end to end in QA without breaking networks
+// or keychains for real. Nothing is simulated unless the agent was started
+// with --chaos.
+package chaos
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// Faults are the failures being simulated. The zero value simulates none.
+type Faults struct {
+	Drop     time.Duration // Cut the backend connection this long after it opens
+	Slow     time.Duration // Delay added to every metrics collection subsystem
+	Keychain bool          // Token store reads and writes fail
+	Buffers  bool          // The memory budget reports itself exhausted
+}
+
+// ErrNotEnabled is returned by Set when the agent wasn't started with --chaos
+var ErrNotEnabled = errors.New("chaos testing is not enabled (start the agent with --chaos)")
+
+// ErrKeychain is what token store operations fail with under the keychain
+// fault
+var ErrKeychain = errors.New("chaos: simulated keychain failure")
+
+var (
+	enabled atomic.Bool
+	current atomic.Pointer[Faults]
+)
+
+// Parse reads a comma-separated fault list such as
+// "drop=30s,slow=3s,keychain,buffers". "off" (or an empty spec) is no faults.
+func Parse(spec string) (Faults, error) {
+	var f Faults
+	spec = strings.TrimSpace(spec)
+	if spec == "" || spec == "off" {
+		return f, nil
+	}
+	for _, item := range strings.Split(spec, ",") {
+		name, value, hasValue := strings.Cut(strings.TrimSpace(item), "=")
+		switch name {
+		case "drop", "slow":
+			d, err := time.ParseDuration(value)
+			if !hasValue || err != nil || d <= 0 {
+				return Faults{}, fmt.Errorf("%s needs a positive duration, e.g. %s=30s", name, name)
+			}
+			if name == "drop" {
+				f.Drop = d
+			} else {
+				f.Slow = d
+			}
+		case "keychain", "buffers":
+			if hasValue {
+				return Faults{}, fmt.Errorf("%s takes no value", name)
+			}
+			if name == "keychain" {
+				f.Keychain = true
+			} else {
+				f.Buffers = true
+			}
+		default:
+			return Faults{}, fmt.Errorf("unknown fault %q (valid: drop=<duration>, slow=<duration>, keychain, buffers, off)", name)
+		}
+	}
+	return f, nil
+}
+
+// String formats f the way Parse reads it
+func (f Faults) String() string {
+	var parts []string
+	if f.Drop > 0 {
+		parts = append(parts, "drop="+f.Drop.String())
+	}
+	if f.Slow > 0 {
+		parts = append(parts, "slow="+f.Slow.String())
+	}
+	if f.Keychain {
+		parts = append(parts, "keychain")
+	}
+	if f.Buffers {
+		parts = append(parts, "buffers")
+	}
+	if len(parts) == 0 {
+		return "off"
+	}
+	return strings.Join(parts, ",")
+}
+
+// Enable turns chaos testing on, starting with f. Only after Enable can
+// the faults be changed with Set.
+func Enable(f Faults) {
+	current.Store(&f)
+	enabled.Store(true)
+}
+
+// Enabled reports whether the agent was started with --chaos
+func Enabled() bool {
+	return enabled.Load()
+}
+
+// Set replaces the simulated faults (the chaos control message)
+func Set(f Faults) error {
+	if !enabled.Load() {
+		return ErrNotEnabled
+	}
+	current.Store(&f)
+	return nil
+}
+
+// Current returns the faults being simulated
+func Current() Faults {
+	if f := current.Load(); f != nil {
+		return *f
+	}
+	return Faults{}
+}
+
+// KeychainError returns ErrKeychain while keychain failures are simulated
+func KeychainError() error {
+	if Current().Keychain {
+		return ErrKeychain
+	}
+	return nil
+}
+
+// Delay waits out the slow-collector fault. It returns ctx's error if ctx
+// ends first, so a delay longer than the subsystem timeout reads as one.
+func Delay(ctx context.Context) error {
+	d := Current().Slow
+	if d <= 0 {
+		return nil
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
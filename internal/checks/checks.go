@@ -0,0 +1,217 @@
+// Package checks runs the HTTP(S) health checks from agent.json and reports
+// whether each endpoint is up and how fast it answered, so the agent can
+// double as an uptime monitor for services on the LAN.
+package checks
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/jcdorr003/windash-agent/internal/alerts"
+	"github.com/jcdorr003/windash-agent/internal/config"
+	"github.com/jcdorr003/windash-agent/internal/outbound"
+	"go.uber.org/zap"
+)
+
+const (
+	defaultInterval = time.Minute
+	defaultTimeout  = 10 * time.Second
+
+	// downAfter is how many failures in a row raise an alert, so a single
+	// dropped request doesn't
+	downAfter = 2
+
+	// maxBodyRead is how much of a response is drained so the connection
+	// can be reused; the body itself is not inspected
+	maxBodyRead = 64 << 10
+)
+
+// Report is the "checks" message, sent after every check run
+type Report struct {
+	Type   string    `json:"type"` // always "checks"
+	TS     time.Time `json:"ts"`
+	HostID string    `json:"hostId"`
+	Checks []Result  `json:"checks"`
+}
+
+// Result is the outcome of one check run
+type Result struct {
+	Name       string    `json:"name"`
+	URL        string    `json:"url"` // Password, if any, redacted
+	Up         bool      `json:"up"`
+	Status     int       `json:"status,omitempty"`     // HTTP status, when there was a response
+	ResponseMs float64   `json:"responseMs,omitempty"` // Time to the response headers
+	Error      string    `json:"error,omitempty"`      // Why the check failed
+	Since      time.Time `json:"since"`                // When the check last changed between up and down
+}
+
+// check is a validated check with its state between runs
+type check struct {
+	cfg      config.CheckConfig
+	name     string
+	url      string // cfg.URL with any password redacted, for reports and alerts
+	interval time.Duration
+	client   *http.Client
+
+	up       bool
+	since    time.Time
+	failures int  // Failed runs in a row
+	alerted  bool // Counted as down (failed downAfter times in a row)
+}
+
+// Monitor runs every configured check on its own interval
+type Monitor struct {
+	logger *zap.SugaredLogger
+	hostID string
+	checks []*check
+	open   *alerts.OpenSet // Shared open-alert state (may be nil)
+}
+
+// NewMonitor creates a monitor for cfg. Checks with an invalid URL are
+// logged and left out.
+func NewMonitor(logger *zap.SugaredLogger, hostID string, cfg []config.CheckConfig) *Monitor {
+	m := &Monitor{logger: logger, hostID: hostID}
+	for _, c := range cfg {
+		u, err := url.Parse(c.URL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			logger.Warn("Ignoring check with an invalid URL", "name", c.Name)
+			continue
+		}
+		name := c.Name
+		if name == "" {
+			name = u.Redacted()
+		}
+		interval := time.Duration(c.IntervalSec) * time.Second
+		if interval <= 0 {
+			interval = defaultInterval
+		}
+		timeout := time.Duration(c.TimeoutSec) * time.Second
+		if timeout <= 0 {
+			timeout = defaultTimeout
+		}
+		transport := http.DefaultTransport.(*http.Transport).Clone()
+		if c.SkipVerify {
+			transport.TLSClientConfig = &tls.Config{InsecureSkipVerify: true} // Self-signed LAN services, opted into per check
+		}
+		m.checks = append(m.checks, &check{
+			cfg:      c,
+			name:     name,
+			url:      u.Redacted(),
+			interval: interval,
+			client:   &http.Client{Timeout: timeout, Transport: transport},
+			up:       true, // Until shown otherwise, so startup doesn't count as a recovery
+		})
+	}
+	return m
+}
+
+// SetOpenAlerts shares open-alert state with other subsystems. Must be called before Run.
+func (m *Monitor) SetOpenAlerts(open *alerts.OpenSet) {
+	m.open = open
+}
+
+// Run runs the checks until ctx is cancelled
+func (m *Monitor) Run(ctx context.Context, sender outbound.Sender) {
+	if len(m.checks) == 0 {
+		return
+	}
+	m.logger.Info("🩺 HTTP checks started", "checks", len(m.checks))
+	for _, c := range m.checks {
+		go m.runCheck(ctx, c, sender)
+	}
+	<-ctx.Done()
+}
+
+// runCheck runs one check every interval
+func (m *Monitor) runCheck(ctx context.Context, c *check, sender outbound.Sender) {
+	ticker := time.NewTicker(c.interval)
+	defer ticker.Stop()
+	for {
+		result := m.probe(ctx, c)
+		if ctx.Err() != nil {
+			return
+		}
+		m.update(c, &result, sender)
+		sender.Send("checks", &Report{Type: "checks", TS: time.Now(), HostID: m.hostID, Checks: []Result{result}})
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// probe requests the check's URL once
+func (m *Monitor) probe(ctx context.Context, c *check) Result {
+	result := Result{Name: c.name, URL: c.url}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.cfg.URL, nil)
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	req.Header.Set("User-Agent", "WinDash-Agent health check")
+
+	start := time.Now()
+	resp, err := c.client.Do(req)
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	result.ResponseMs = float64(time.Since(start).Microseconds()) / 1000
+	io.Copy(io.Discard, io.LimitReader(resp.Body, maxBodyRead))
+	resp.Body.Close()
+
+	result.Status = resp.StatusCode
+	if expect := c.cfg.ExpectStatus; expect > 0 {
+		result.Up = resp.StatusCode == expect
+	} else {
+		result.Up = resp.StatusCode < 400
+	}
+	if !result.Up {
+		result.Error = fmt.Sprintf("unexpected status %d", resp.StatusCode)
+	}
+	return result
+}
+
+// update tracks up/down changes, alerting when a check has failed downAfter
+// times in a row and clearing the alert once it is up again
+func (m *Monitor) update(c *check, result *Result, sender outbound.Sender) {
+	now := time.Now()
+	if result.Up != c.up || c.since.IsZero() {
+		c.up, c.since = result.Up, now
+	}
+	result.Since = c.since
+	if result.Up {
+		c.failures = 0
+	} else {
+		c.failures++
+	}
+
+	down := c.failures >= downAfter
+	if down == c.alerted {
+		return
+	}
+	c.alerted = down
+	if c.cfg.Alert {
+		m.open.Set("check:"+c.name, down)
+	}
+	if !down {
+		m.logger.Info("🩺 Check up again", "check", c.name)
+		return
+	}
+
+	m.logger.Warn("🩺 Check down", "check", c.name, "error", result.Error)
+	if !c.cfg.Alert {
+		return
+	}
+	alert := alerts.New(m.hostID, "checks", alerts.SeverityWarning,
+		fmt.Sprintf("%s is down", c.name), result.Error)
+	alert.Labels = map[string]string{"check": c.name, "url": c.url}
+	sender.Send("alert", alert)
+}
@@ -0,0 +1,12 @@
+package clipboard
+
+import "errors"
+
+// errUnsupported is returned where no clipboard is available (no clipboard
+// tool installed, no desktop session)
+var errUnsupported = errors.New("no clipboard available")
+
+// Copy places text on the system clipboard
+func Copy(text string) error {
+	return write(text)
+}
@@ -0,0 +1,54 @@
+//go:build !windows
+
+package clipboard
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+	"time"
+)
+
+// toolTimeout bounds how long a clipboard tool may take
+const toolTimeout = 5 * time.Second
+
+// write pipes text into the platform's clipboard tool
+func write(text string) error {
+	name, args := tool()
+	if name == "" {
+		return errUnsupported
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), toolTimeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, name, args...)
+	cmd.Stdin = strings.NewReader(text)
+	return cmd.Run()
+}
+
+// tool picks the first available clipboard tool for this platform
+func tool() (string, []string) {
+	if runtime.GOOS == "darwin" {
+		return "pbcopy", nil
+	}
+
+	candidates := [][]string{
+		{"xclip", "-selection", "clipboard"},
+		{"xsel", "--clipboard", "--input"},
+	}
+	switch {
+	case os.Getenv("WAYLAND_DISPLAY") != "":
+		candidates = append([][]string{{"wl-copy"}}, candidates...)
+	case os.Getenv("DISPLAY") == "":
+		return "", nil
+	}
+	for _, c := range candidates {
+		if _, err := exec.LookPath(c[0]); err == nil {
+			return c[0], c[1:]
+		}
+	}
+	return "", nil
+}
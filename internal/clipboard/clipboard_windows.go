@@ -0,0 +1,85 @@
+//go:build windows
+
+package clipboard
+
+import (
+	"fmt"
+	"time"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+)
+
+var (
+	moduser32            = windows.NewLazySystemDLL("user32.dll")
+	procOpenClipboard    = moduser32.NewProc("OpenClipboard")
+	procCloseClipboard   = moduser32.NewProc("CloseClipboard")
+	procEmptyClipboard   = moduser32.NewProc("EmptyClipboard")
+	procSetClipboardData = moduser32.NewProc("SetClipboardData")
+
+	modkernel32      = windows.NewLazySystemDLL("kernel32.dll")
+	procGlobalAlloc  = modkernel32.NewProc("GlobalAlloc")
+	procGlobalFree   = modkernel32.NewProc("GlobalFree")
+	procGlobalLock   = modkernel32.NewProc("GlobalLock")
+	procGlobalUnlock = modkernel32.NewProc("GlobalUnlock")
+	procMoveMemory   = modkernel32.NewProc("RtlMoveMemory")
+)
+
+const (
+	cfUnicodeText = 13
+	gmemMoveable  = 0x0002
+
+	// Another program may hold the clipboard briefly
+	openAttempts = 5
+	openRetry    = 20 * time.Millisecond
+)
+
+// write copies text to the clipboard as CF_UNICODETEXT
+func write(text string) error {
+	utf16, err := windows.UTF16FromString(text)
+	if err != nil {
+		return err
+	}
+
+	if err := openClipboard(); err != nil {
+		return err
+	}
+	defer procCloseClipboard.Call()
+
+	if r, _, err := procEmptyClipboard.Call(); r == 0 {
+		return fmt.Errorf("EmptyClipboard: %w", err)
+	}
+
+	size := uintptr(len(utf16)) * unsafe.Sizeof(utf16[0])
+	mem, _, err := procGlobalAlloc.Call(gmemMoveable, size)
+	if mem == 0 {
+		return fmt.Errorf("GlobalAlloc: %w", err)
+	}
+	ptr, _, err := procGlobalLock.Call(mem)
+	if ptr == 0 {
+		procGlobalFree.Call(mem)
+		return fmt.Errorf("GlobalLock: %w", err)
+	}
+	procMoveMemory.Call(ptr, uintptr(unsafe.Pointer(&utf16[0])), size)
+	procGlobalUnlock.Call(mem)
+
+	// On success the clipboard owns the memory
+	if r, _, err := procSetClipboardData.Call(cfUnicodeText, mem); r == 0 {
+		procGlobalFree.Call(mem)
+		return fmt.Errorf("SetClipboardData: %w", err)
+	}
+	return nil
+}
+
+// openClipboard opens the clipboard, retrying while another program has it open
+func openClipboard() error {
+	var err error
+	for range openAttempts {
+		var r uintptr
+		if r, _, err = procOpenClipboard.Call(0); r != 0 {
+			return nil
+		}
+		time.Sleep(openRetry)
+	}
+	return fmt.Errorf("OpenClipboard: %w", err)
+}
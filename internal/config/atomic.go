@@ -0,0 +1,84 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// writeFileAtomic replaces path with data without ever leaving a partially
+// written file behind: data goes to a temp file in the same directory which
+// is fsynced and renamed over path. The previous contents are kept as
+// path.bak if they were valid JSON.
+func writeFileAtomic(path string, data []byte, perm os.FileMode) error {
+	dir := filepath.Dir(path)
+	tmp, err := os.CreateTemp(dir, "."+filepath.Base(path)+".tmp*")
+	if err != nil {
+		return fmt.Errorf("failed to create temp file: %w", err)
+	}
+	tmpName := tmp.Name()
+	defer os.Remove(tmpName) // No-op once renamed
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write temp file: %w", err)
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to sync temp file: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to close temp file: %w", err)
+	}
+	if err := os.Chmod(tmpName, perm); err != nil {
+		return fmt.Errorf("failed to set permissions: %w", err)
+	}
+
+	// Keep the last known-good version around for recovery
+	if current, err := os.ReadFile(path); err == nil && json.Valid(current) {
+		if err := os.WriteFile(backupPath(path), current, perm); err != nil {
+			return fmt.Errorf("failed to write backup: %w", err)
+		}
+	}
+
+	if err := os.Rename(tmpName, path); err != nil {
+		return fmt.Errorf("failed to replace %s: %w", filepath.Base(path), err)
+	}
+	return nil
+}
+
+// backupPath returns the location of the backup kept by writeFileAtomic
+func backupPath(path string) string {
+	return path + ".bak"
+}
+
+// quarantine moves a corrupt file aside (agent.json.corrupt-<timestamp>) so
+// it can be inspected later, returning the new path
+func quarantine(path string) (string, error) {
+	dest := fmt.Sprintf("%s.corrupt-%s", path, time.Now().Format("20060102-150405"))
+	if err := os.Rename(path, dest); err != nil {
+		return "", err
+	}
+	return dest, nil
+}
+
+// restoreBackup replaces a corrupt config file with its backup. It returns
+// false if there is no usable backup, in which case path is moved aside.
+func restoreBackup(path string) (restored bool, corruptPath string, err error) {
+	corruptPath, err = quarantine(path)
+	if err != nil {
+		return false, "", fmt.Errorf("failed to move corrupt config aside: %w", err)
+	}
+
+	backup, err := os.ReadFile(backupPath(path))
+	if err != nil || !json.Valid(backup) {
+		return false, corruptPath, nil
+	}
+
+	if err := os.WriteFile(path, backup, 0644); err != nil {
+		return false, corruptPath, fmt.Errorf("failed to restore backup: %w", err)
+	}
+	return true, corruptPath, nil
+}
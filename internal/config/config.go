@@ -2,9 +2,16 @@ package config
 
 import (
 	"encoding/json"
+	"fmt"
+	"maps"
+	"net/http"
 	"os"
+	"runtime"
+	"slices"
+	"strings"
 
 	"github.com/spf13/viper"
+	"go.uber.org/zap"
 )
 
 const (
@@ -20,6 +27,9 @@ const (
 	KeychainService             = "com.windash.agent"
 )
 
+// Envs lists the built-in environments
+var Envs = []string{"localdev", "localprod", "localdockerprod", "remoteprod"}
+
 // Config holds the agent configuration
 type Config struct {
 	Env               string `json:"env" mapstructure:"env"`
@@ -27,44 +37,690 @@ type Config struct {
 	APIURL            string `json:"apiUrl" mapstructure:"apiUrl"`
 	MetricsIntervalMs int    `json:"metricsIntervalMs" mapstructure:"metricsIntervalMs"`
 	OpenOnStart       bool   `json:"openOnStart" mapstructure:"openOnStart"`
-	DeviceCode        string `json:"deviceCode,omitempty" mapstructure:"deviceCode"`
-	ConfigDir         string `json:"-"`
-	LogDir            string `json:"-"`
+	OpenOnPair        bool   `json:"openOnPair" mapstructure:"openOnPair"`                   // Open the pairing page in a browser (default true; off without a desktop)
+	CopyOnPair        bool   `json:"copyOnPair" mapstructure:"copyOnPair"`                   // Copy the pairing link to the clipboard (default true)
+	PairingCycles     int    `json:"pairingCycles,omitempty" mapstructure:"pairingCycles"`   // Pairing codes to try before giving up (default 3)
+	MemoryBudgetMB    int    `json:"memoryBudgetMB,omitempty" mapstructure:"memoryBudgetMB"` // Cap on queued data held in memory (0 = unlimited)
+	HighResolution    bool   `json:"highResolution,omitempty" mapstructure:"highResolution"` // Allow metricsIntervalMs down to 100
+	StrictDecode      bool   `json:"strictDecode,omitempty" mapstructure:"strictDecode"`     // Reject unknown config keys and control message fields instead of warning
+	Units             string `json:"units,omitempty" mapstructure:"units"`                   // Byte units in console output and reports: binary (GiB, default) or decimal (GB)
+
+	// ControlLimits overrides the per-type limits on server commands, as
+	// "N/duration" (e.g. "setRate": "5/1m"); "*" covers unlisted types
+	ControlLimits map[string]string `json:"controlLimits,omitempty" mapstructure:"controlLimits"`
+
+	// SiteID and GroupID place the host in the fleet. They are sent when
+	// requesting a pairing code and in the status and inventory messages so
+	// the backend can file new hosts without manual assignment.
+	SiteID  string `json:"siteId,omitempty" mapstructure:"siteId"`
+	GroupID string `json:"groupId,omitempty" mapstructure:"groupId"`
+
+	// Tags are free-form host labels reported in the status message
+	Tags map[string]string `json:"tags,omitempty" mapstructure:"tags"`
+
+	// HostID chooses where the ID the agent reports itself under comes from
+	HostID HostIDConfig `json:"hostId,omitzero" mapstructure:"hostId"`
+
+	// IPC controls the local scripting API (named pipe)
+	IPC IPCConfig `json:"ipc,omitzero" mapstructure:"ipc"`
+
+	// Tray controls the notification area icon
+	Tray TrayConfig `json:"tray,omitzero" mapstructure:"tray"`
+
+	// Logging controls the agent's log files
+	Logging LoggingConfig `json:"logging,omitzero" mapstructure:"logging"`
+
+	// Headers are added to every outbound request (pairing calls and the
+	// WebSocket handshake), e.g. for proxy or WAF allowlisting
+	Headers map[string]string `json:"headers,omitempty" mapstructure:"headers"`
+
+	// Connection customizes the WebSocket handshake (reverse proxies, tenants)
+	Connection ConnectionConfig `json:"connection,omitzero" mapstructure:"connection"`
+
+	// Collectors selects and tunes metric sources
+	Collectors CollectorsConfig `json:"collectors,omitzero" mapstructure:"collectors"`
+
+	// Watch lists processes and services whose uptime and restarts are reported
+	Watch WatchConfig `json:"watch,omitzero" mapstructure:"watch"`
+
+	// Health weights the factors of the per-sample health score
+	Health HealthConfig `json:"health,omitzero" mapstructure:"health"`
+
+	// Pipeline orders the sample processing stages (nil = default order)
+	Pipeline []string `json:"pipeline,omitempty" mapstructure:"pipeline"`
+
+	// Suppress skips sending samples that barely differ from the last one sent
+	Suppress SuppressConfig `json:"suppress,omitzero" mapstructure:"suppress"`
+
+	// SlowStart eases sampling in when the agent starts right after boot or login
+	SlowStart SlowStartConfig `json:"slowStart,omitzero" mapstructure:"slowStart"`
+
+	// Incidents bundles alerts with the samples that led up to them
+	Incidents IncidentsConfig `json:"incidents,omitzero" mapstructure:"incidents"`
+
+	// Snapshot schedules detailed reports independent of live sampling
+	Snapshot SnapshotConfig `json:"snapshot,omitzero" mapstructure:"snapshot"`
+
+	// Storage reports Storage Spaces pool and volume health
+	Storage StorageConfig `json:"storage,omitzero" mapstructure:"storage"`
+
+	// Devices enables opt-in USB attach/detach events
+	Devices DevicesConfig `json:"devices,omitzero" mapstructure:"devices"`
+
+	// Printers enables print queue and stuck-job reporting
+	Printers PrintersConfig `json:"printers,omitzero" mapstructure:"printers"`
+
+	// Security enables opt-in security signals (remote sessions, failed
+	// logons) and read-only mode
+	Security SecurityConfig `json:"security,omitzero" mapstructure:"security"`
+
+	// Summary writes a local daily digest of the machine's day
+	Summary SummaryConfig `json:"summary,omitzero" mapstructure:"summary"`
+
+	// Maintenance schedules clean restarts of the agent process
+	Maintenance MaintenanceConfig `json:"maintenance,omitzero" mapstructure:"maintenance"`
+
+	// Spool keeps samples that couldn't be sent on disk until they can be
+	Spool SpoolConfig `json:"spool,omitzero" mapstructure:"spool"`
+
+	// History keeps every sample locally for export-history
+	History HistoryConfig `json:"history,omitzero" mapstructure:"history"`
+
+	// Handles enables the top handle consumers report
+	Handles HandlesConfig `json:"handles,omitzero" mapstructure:"handles"`
+
+	// Peers enables RTT and loss measurements to other agents on the LAN
+	Peers PeersConfig `json:"peers,omitzero" mapstructure:"peers"`
+
+	// NetProbe pings the gateway and other targets for RTT and loss
+	NetProbe NetProbeConfig `json:"netProbe,omitzero" mapstructure:"netProbe"`
+
+	// Checks are HTTP(S) endpoints to report up/down and response time for
+	Checks []CheckConfig `json:"checks,omitempty" mapstructure:"checks"`
+
+	// Software sends the installed programs list, then only its changes
+	Software SoftwareConfig `json:"software,omitzero" mapstructure:"software"`
+
+	// SelfTest schedules disk, memory and backend latency benchmarks
+	SelfTest SelfTestConfig `json:"selfTest,omitzero" mapstructure:"selfTest"`
+
+	// Traffic sets a monthly budget for the agent's own backend traffic
+	Traffic TrafficConfig `json:"traffic,omitzero" mapstructure:"traffic"`
+
+	// Privacy limits what is reported about the machine's users
+	Privacy PrivacyConfig `json:"privacy,omitzero" mapstructure:"privacy"`
+
+	ConfigDir    string `json:"-"`
+	LogDir       string `json:"-"`
+	AgentVersion string `json:"-"`
+}
+
+// HostIDConfig lists host ID providers in order of preference. The first
+// that yields an ID wins.
+type HostIDConfig struct {
+	Providers []string `json:"providers,omitempty" mapstructure:"providers"` // machine, ec2, azure, file, command (default ["machine"])
+	File      string   `json:"file,omitempty" mapstructure:"file"`           // Read by the file provider; its first line is the ID
+	Command   string   `json:"command,omitempty" mapstructure:"command"`     // Run by the command provider through the shell; its output is the ID
+}
+
+// LoggingConfig controls where logs are written and how they rotate
+type LoggingConfig struct {
+	Dir        string `json:"dir,omitempty" mapstructure:"dir"`               // Log directory (default %ProgramData%\WinDash\logs)
+	StdoutOnly bool   `json:"stdoutOnly,omitempty" mapstructure:"stdoutOnly"` // Log to stdout only, no files (containers, read-only filesystems)
+	Compress   bool   `json:"compress" mapstructure:"compress"`               // Gzip rotated log files (default true)
+}
+
+// FileDir returns the directory log files are written to, or "" when
+// logging to stdout only
+func (l LoggingConfig) FileDir() string {
+	switch {
+	case l.StdoutOnly:
+		return ""
+	case l.Dir != "":
+		return l.Dir
+	default:
+		return GetLogDir()
+	}
+}
+
+// ConnectionConfig holds extra settings applied when dialing the WebSocket.
+// Values may reference environment variables as ${VAR} so secrets such as
+// tenant tokens don't need to live in agent.json.
+type ConnectionConfig struct {
+	// Path replaces the path component of APIURL when set (e.g. "/windash/agent")
+	Path string `json:"path,omitempty" mapstructure:"path"`
+
+	// Query is appended to the URL alongside hostId, which can't be
+	// overridden here. A list is used rather
+	// than a map because viper lowercases map keys and query names are
+	// case-sensitive.
+	Query []QueryParam `json:"query,omitempty" mapstructure:"query"`
+
+	// Headers are added to the WebSocket handshake request
+	Headers map[string]string `json:"headers,omitempty" mapstructure:"headers"`
+}
+
+// CollectorsConfig selects and tunes metric sources
+type CollectorsConfig struct {
+	Enable    EnableConfig    `json:"enable,omitzero" mapstructure:"enable"`
+	CPU       CPUConfig       `json:"cpu,omitzero" mapstructure:"cpu"`
+	GPU       GPUConfig       `json:"gpu,omitzero" mapstructure:"gpu"`
+	TopProcs  TopProcsConfig  `json:"topProcs,omitzero" mapstructure:"topProcs"`
+	DPC       DPCConfig       `json:"dpc,omitzero" mapstructure:"dpc"`
+	Synthetic SyntheticConfig `json:"synthetic,omitzero" mapstructure:"synthetic"`
+	Audio     bool            `json:"audio,omitempty" mapstructure:"audio"` // Report the default audio device and playback
+}
+
+// Source modes for collectors.enable
+const (
+	SourceOn   = "on"
+	SourceOff  = "off"
+	SourceAuto = "auto" // On if supported on this machine, without complaint if not
+)
+
+// EnableConfig turns individual metric sources on or off to trim the sample
+// payload. Each value is true/false (or "on"/"off") or "auto"; unset core
+// sources are on and unset optional sources (gpu, gpuDevices, audio, temps,
+// topProcs, dpc, tcp, hyperv, wifi, links) are off.
+type EnableConfig struct {
+	CPU        string `json:"cpu,omitempty" mapstructure:"cpu"`
+	Mem        string `json:"mem,omitempty" mapstructure:"mem"`
+	Disk       string `json:"disk,omitempty" mapstructure:"disk"`
+	Net        string `json:"net,omitempty" mapstructure:"net"`
+	Uptime     string `json:"uptime,omitempty" mapstructure:"uptime"`
+	Procs      string `json:"procs,omitempty" mapstructure:"procs"`
+	GPU        string `json:"gpu,omitempty" mapstructure:"gpu"`
+	GPUDevices string `json:"gpuDevices,omitempty" mapstructure:"gpuDevices"`
+	Audio      string `json:"audio,omitempty" mapstructure:"audio"`
+	Temps      string `json:"temps,omitempty" mapstructure:"temps"`
+	TopProcs   string `json:"topProcs,omitempty" mapstructure:"topProcs"`
+	DPC        string `json:"dpc,omitempty" mapstructure:"dpc"`
+	TCP        string `json:"tcp,omitempty" mapstructure:"tcp"`
+	HyperV     string `json:"hyperv,omitempty" mapstructure:"hyperv"` // Needs administrator or Hyper-V Administrators
+	WiFi       string `json:"wifi,omitempty" mapstructure:"wifi"`
+	Links      string `json:"links,omitempty" mapstructure:"links"`
+}
+
+// SourceModes returns the mode of every metric source by subsystem name.
+// The older switches collectors.gpu.enabled (gpu and gpuDevices) and
+// collectors.audio count as "on" when collectors.enable leaves the source
+// unset.
+func (c CollectorsConfig) SourceModes() map[string]string {
+	optional := func(value string, legacy bool) string {
+		if legacy {
+			return sourceMode(value, SourceOn)
+		}
+		return sourceMode(value, SourceOff)
+	}
+	e := c.Enable
+	return map[string]string{
+		"cpu":        sourceMode(e.CPU, SourceOn),
+		"mem":        sourceMode(e.Mem, SourceOn),
+		"disk":       sourceMode(e.Disk, SourceOn),
+		"net":        sourceMode(e.Net, SourceOn),
+		"uptime":     sourceMode(e.Uptime, SourceOn),
+		"procs":      sourceMode(e.Procs, SourceOn),
+		"gpu":        optional(e.GPU, c.GPU.Enabled),
+		"gpuDevices": optional(e.GPUDevices, c.GPU.Enabled),
+		"audio":      optional(e.Audio, c.Audio),
+		"temps":      optional(e.Temps, false),
+		"topProcs":   optional(e.TopProcs, false),
+		"dpc":        optional(e.DPC, false),
+		"tcp":        optional(e.TCP, false),
+		"hyperv":     optional(e.HyperV, false),
+		"wifi":       optional(e.WiFi, false),
+		"links":      optional(e.Links, false),
+	}
+}
+
+// sourceMode normalizes a collectors.enable value. JSON booleans arrive as
+// "1"/"0" or "true"/"false" depending on the source; unknown values and
+// empty strings give def.
+func sourceMode(value, def string) string {
+	switch strings.ToLower(strings.TrimSpace(value)) {
+	case "1", "true", "on", "yes":
+		return SourceOn
+	case "0", "false", "off", "no":
+		return SourceOff
+	case "auto":
+		return SourceAuto
+	default:
+		return def
+	}
+}
+
+// CPUConfig controls how per-core CPU data is sent on many-core machines
+type CPUConfig struct {
+	PerCoreLimit int `json:"perCoreLimit,omitempty" mapstructure:"perCoreLimit"` // Trim perCore above this many cores (default 32, -1 never)
+	TopCores     int `json:"topCores,omitempty" mapstructure:"topCores"`         // Busiest cores sent when trimmed (default 8)
+	PerCoreEvery int `json:"perCoreEvery,omitempty" mapstructure:"perCoreEvery"` // Send the full array every N samples when trimmed (default 0 = never)
+}
+
+// GPUConfig controls GPU collection
+type GPUConfig struct {
+	Enabled      bool `json:"enabled,omitempty" mapstructure:"enabled"`           // Collect GPU usage (Windows GPU performance counters)
+	TopProcesses int  `json:"topProcesses,omitempty" mapstructure:"topProcesses"` // GPU-consuming processes to report (default 5)
+}
+
+// TopProcsConfig tunes the top processes report (collectors.enable.topProcs).
+// Walking every process is costlier than the other sources, so it runs on
+// its own, slower interval.
+type TopProcsConfig struct {
+	Count       int `json:"count,omitempty" mapstructure:"count"`             // Processes per list, by CPU and by memory (default 5)
+	IntervalSec int `json:"intervalSec,omitempty" mapstructure:"intervalSec"` // Time between reports (default 30)
+}
+
+// DPCConfig tunes the DPC and interrupt time source (collectors.enable.dpc)
+type DPCConfig struct {
+	SpikePct float64 `json:"spikePct,omitempty" mapstructure:"spikePct"` // DPC + interrupt share on one core flagged as a spike (default 15)
+}
+
+// SyntheticConfig replaces real metrics with generated ones for dashboard
+// development. Zero values fall back to sensible defaults.
+type SyntheticConfig struct {
+	Enabled               bool    `json:"enabled" mapstructure:"enabled"`
+	Cores                 int     `json:"cores,omitempty" mapstructure:"cores"`                                 // Number of fake cores
+	CPUBase               float64 `json:"cpuBase,omitempty" mapstructure:"cpuBase"`                             // Center of the CPU sine wave (%)
+	CPUAmplitude          float64 `json:"cpuAmplitude,omitempty" mapstructure:"cpuAmplitude"`                   // Height of the CPU sine wave (%)
+	CPUPeriodSec          int     `json:"cpuPeriodSec,omitempty" mapstructure:"cpuPeriodSec"`                   // Length of one CPU wave
+	SpikeChance           float64 `json:"spikeChance,omitempty" mapstructure:"spikeChance"`                     // Probability per sample of a CPU spike
+	DiskGrowthBytesPerSec uint64  `json:"diskGrowthBytesPerSec,omitempty" mapstructure:"diskGrowthBytesPerSec"` // Fake disk fill rate
+}
+
+// WatchConfig selects processes and services to watch for crashes and restarts
+type WatchConfig struct {
+	Processes       []string `json:"processes,omitempty" mapstructure:"processes"`             // Executable names, case-insensitive (e.g. "plex.exe")
+	Services        []string `json:"services,omitempty" mapstructure:"services"`               // Windows service names (e.g. "Spooler")
+	IntervalSec     int      `json:"intervalSec,omitempty" mapstructure:"intervalSec"`         // Scan interval (default 30)
+	ServiceGraceSec int      `json:"serviceGraceSec,omitempty" mapstructure:"serviceGraceSec"` // Time after boot before a stopped Automatic service alerts (default 300)
+}
+
+// HealthConfig holds the relative weights of the health score factors.
+// Weights need not sum to 1; factors without data (e.g. no temperature
+// sensors) are left out and the rest rescaled. All zero means defaults.
+type HealthConfig struct {
+	CPU    float64 `json:"cpu,omitempty" mapstructure:"cpu"`       // CPU headroom
+	Memory float64 `json:"memory,omitempty" mapstructure:"memory"` // Memory pressure
+	Disk   float64 `json:"disk,omitempty" mapstructure:"disk"`     // Free space on the fullest volume
+	Temps  float64 `json:"temps,omitempty" mapstructure:"temps"`   // Hottest sensor
+	Alerts float64 `json:"alerts,omitempty" mapstructure:"alerts"` // Open alerts
+}
+
+// SuppressConfig controls idle-send suppression: a sample whose values are
+// all within these tolerances of the last sent sample is not uploaded.
+// Zero tolerances fall back to defaults.
+type SuppressConfig struct {
+	Enabled        bool    `json:"enabled" mapstructure:"enabled"`
+	CPU            float64 `json:"cpu,omitempty" mapstructure:"cpu"`                       // Total CPU change in percentage points (default 2)
+	Memory         float64 `json:"memory,omitempty" mapstructure:"memory"`                 // Used memory change as % of total (default 1)
+	Disk           float64 `json:"disk,omitempty" mapstructure:"disk"`                     // Used space change per volume as % of total (default 0.1)
+	NetBps         uint64  `json:"netBps,omitempty" mapstructure:"netBps"`                 // Network rate change in bytes/sec (default 10240)
+	KeepaliveEvery int     `json:"keepaliveEvery,omitempty" mapstructure:"keepaliveEvery"` // Always send at least every N intervals (default 30)
+}
+
+// SlowStartConfig controls slow start: when the agent starts within
+// WindowSec of boot or of a user's login, it samples at IntervalMs with only
+// the core sources (CPU, memory, disk, network, uptime, processes) and ramps
+// to the configured interval and sources over RampSec, so it doesn't add to
+// the post-login CPU and disk storm.
+type SlowStartConfig struct {
+	Enabled    bool `json:"enabled" mapstructure:"enabled"`                 // default true
+	WindowSec  int  `json:"windowSec,omitempty" mapstructure:"windowSec"`   // How recent boot or login must be (default 600)
+	RampSec    int  `json:"rampSec,omitempty" mapstructure:"rampSec"`       // Time to reach the configured settings (default 300)
+	IntervalMs int  `json:"intervalMs,omitempty" mapstructure:"intervalMs"` // Interval to start at (default 30000)
+}
+
+// IncidentsConfig controls incident bundling: warning and critical alerts
+// are sent as "incident" messages carrying the preceding samples
+type IncidentsConfig struct {
+	Enabled bool `json:"enabled" mapstructure:"enabled"`
+	Samples int  `json:"samples,omitempty" mapstructure:"samples"` // Samples before the trigger to include (default 30)
+}
+
+// IPCConfig controls the local scripting API
+type IPCConfig struct {
+	Enabled bool `json:"enabled" mapstructure:"enabled"` // Serve the named pipe (default true)
+}
+
+// TrayConfig controls the notification area icon
+type TrayConfig struct {
+	Enabled bool `json:"enabled" mapstructure:"enabled"` // Show the icon when the session has a taskbar (default true)
+}
+
+// SnapshotConfig schedules detailed host reports
+type SnapshotConfig struct {
+	DailyAt string `json:"dailyAt,omitempty" mapstructure:"dailyAt"` // Local time "HH:MM" for the daily report (empty disables)
+}
+
+// SecurityConfig enables security signals for internet-exposed hosts. Each
+// is off by default because it reports on who is using the machine.
+// ReadOnly locks the agent down for machines the user administers but
+// doesn't own.
+type SecurityConfig struct {
+	ReadOnly         bool `json:"readOnly,omitempty" mapstructure:"readOnly"`                 // Refuse every remote action (settings, commands, power, processes, logs)
+	RemoteSessions   bool `json:"remoteSessions,omitempty" mapstructure:"remoteSessions"`     // Report RDP sessions and alert on new remote logins
+	UserSessions     bool `json:"userSessions,omitempty" mapstructure:"userSessions"`         // Report signed-in users with session type, logon and idle time
+	Antivirus        bool `json:"antivirus,omitempty" mapstructure:"antivirus"`               // Report Defender / third-party antivirus protection status
+	FailedLogons     bool `json:"failedLogons,omitempty" mapstructure:"failedLogons"`         // Count failed logons (event 4625); requires elevation
+	FailedLogonBurst int  `json:"failedLogonBurst,omitempty" mapstructure:"failedLogonBurst"` // Failed logons per interval that raise an alert (default 10)
+	IntervalSec      int  `json:"intervalSec,omitempty" mapstructure:"intervalSec"`           // Scan interval (default 60)
+}
+
+// StorageConfig controls storage health reporting
+type StorageConfig struct {
+	Enabled     bool `json:"enabled" mapstructure:"enabled"`                   // Report pool/space/volume health (default true)
+	IntervalSec int  `json:"intervalSec,omitempty" mapstructure:"intervalSec"` // Report interval (default 300)
+}
+
+// DevicesConfig controls USB device events
+type DevicesConfig struct {
+	USB         bool     `json:"usb,omitempty" mapstructure:"usb"`                 // Send an event when a USB device is attached or removed
+	Classes     []string `json:"classes,omitempty" mapstructure:"classes"`         // PnP classes to report, e.g. ["DiskDrive", "WPD"] (empty = all)
+	IntervalSec int      `json:"intervalSec,omitempty" mapstructure:"intervalSec"` // Poll interval (default 5)
+}
+
+// PrintersConfig controls print queue monitoring
+type PrintersConfig struct {
+	Enabled      bool `json:"enabled,omitempty" mapstructure:"enabled"`           // Report print queues
+	StuckMinutes int  `json:"stuckMinutes,omitempty" mapstructure:"stuckMinutes"` // Age at which a job counts as stuck (default 10)
+	Alert        bool `json:"alert,omitempty" mapstructure:"alert"`               // Raise an alert when a printer has stuck jobs
+	IntervalSec  int  `json:"intervalSec,omitempty" mapstructure:"intervalSec"`   // Report interval (default 60)
+}
+
+// SummaryConfig controls the local daily summary
+type SummaryConfig struct {
+	Enabled  bool   `json:"enabled,omitempty" mapstructure:"enabled"`   // Write a summary of each day to the log directory
+	Format   string `json:"format,omitempty" mapstructure:"format"`     // "markdown" (default) or "html"
+	Upload   bool   `json:"upload,omitempty" mapstructure:"upload"`     // Also send it as a "summary" message
+	KeepDays int    `json:"keepDays,omitempty" mapstructure:"keepDays"` // Delete summaries older than this (default 30)
+}
+
+// MaintenanceConfig controls planned restarts of the agent process
+type MaintenanceConfig struct {
+	RestartDays int    `json:"restartDays,omitempty" mapstructure:"restartDays"` // Restart every N days (0 = never)
+	RestartMode string `json:"restartMode,omitempty" mapstructure:"restartMode"` // "exec" (start a new copy) or "exit" (exit code 75 for a supervisor)
+	DrainSec    int    `json:"drainSec,omitempty" mapstructure:"drainSec"`       // Time allowed to send queued data before exiting (default 10)
+
+	MaxRSSMB      int `json:"maxRssMB,omitempty" mapstructure:"maxRssMB"`           // Restart when the agent's resident memory exceeds this (0 = no cap)
+	MaxRSSMinutes int `json:"maxRssMinutes,omitempty" mapstructure:"maxRssMinutes"` // ...for this many minutes in a row (default 5)
+}
+
+// SpoolConfig controls the on-disk sample spool
+type SpoolConfig struct {
+	Enabled   bool `json:"enabled" mapstructure:"enabled"`               // Spool samples during outages and replay them as backfill (default true)
+	MaxMB     int  `json:"maxMB,omitempty" mapstructure:"maxMB"`         // Size cap for spooled data (default 256)
+	MinFreeMB int  `json:"minFreeMB,omitempty" mapstructure:"minFreeMB"` // Free space to always leave on the disk (default 2048; 0 = no reserve)
+}
+
+// HistoryConfig controls the local sample history. It shares the spool's
+// minFreeMB reserve.
+type HistoryConfig struct {
+	Enabled  bool `json:"enabled,omitempty" mapstructure:"enabled"`   // Keep every sample on disk (see export-history)
+	KeepDays int  `json:"keepDays,omitempty" mapstructure:"keepDays"` // Delete history older than this (default 7)
+	MaxMB    int  `json:"maxMB,omitempty" mapstructure:"maxMB"`       // Size cap (default 256)
+}
+
+// HandlesConfig controls the top handle consumers report
+type HandlesConfig struct {
+	Enabled     bool `json:"enabled,omitempty" mapstructure:"enabled"`         // Report the processes holding the most handles
+	Top         int  `json:"top,omitempty" mapstructure:"top"`                 // Processes per report (default 10)
+	IntervalSec int  `json:"intervalSec,omitempty" mapstructure:"intervalSec"` // Report interval (default 300)
+}
+
+// PeersConfig controls the peer latency mesh. Peers are assigned by the
+// server with the setPeers command.
+type PeersConfig struct {
+	Enabled     bool `json:"enabled,omitempty" mapstructure:"enabled"`         // Answer and send peer probes
+	Port        int  `json:"port,omitempty" mapstructure:"port"`               // UDP port for probes (default 47810)
+	IntervalSec int  `json:"intervalSec,omitempty" mapstructure:"intervalSec"` // Time between probe rounds (default 60)
+}
+
+// NetProbeConfig controls the network latency probes
+type NetProbeConfig struct {
+	Enabled     bool     `json:"enabled,omitempty" mapstructure:"enabled"`         // Probe the targets and send netprobe messages
+	Targets     []string `json:"targets,omitempty" mapstructure:"targets"`         // "gateway", hosts to ping, or host:port for TCP connects (default ["gateway", "8.8.8.8"])
+	IntervalSec int      `json:"intervalSec,omitempty" mapstructure:"intervalSec"` // Time between rounds (default 60)
+	Count       int      `json:"count,omitempty" mapstructure:"count"`             // Probes per target per round (default 4)
+	DNSName     string   `json:"dnsName,omitempty" mapstructure:"dnsName"`         // Name resolved each round to time DNS (default www.msftconnecttest.com)
+}
+
+// CheckConfig is one HTTP(S) health check
+type CheckConfig struct {
+	Name         string `json:"name,omitempty" mapstructure:"name"`                 // Label in reports and alerts (default the URL)
+	URL          string `json:"url" mapstructure:"url"`                             // http:// or https:// URL to GET
+	IntervalSec  int    `json:"intervalSec,omitempty" mapstructure:"intervalSec"`   // Time between checks (default 60)
+	ExpectStatus int    `json:"expectStatus,omitempty" mapstructure:"expectStatus"` // Status that counts as up (default any below 400)
+	TimeoutSec   int    `json:"timeoutSec,omitempty" mapstructure:"timeoutSec"`     // Request timeout (default 10)
+	SkipVerify   bool   `json:"skipVerify,omitempty" mapstructure:"skipVerify"`     // Accept any TLS certificate, e.g. self-signed LAN services
+	Alert        bool   `json:"alert,omitempty" mapstructure:"alert"`               // Raise an alert when the check is down
 }
 
-// Load reads configuration from file, environment variables, and defaults
+// SoftwareConfig controls the installed software inventory
+type SoftwareConfig struct {
+	Enabled       bool `json:"enabled,omitempty" mapstructure:"enabled"`             // List installed programs and send changes
+	IntervalHours int  `json:"intervalHours,omitempty" mapstructure:"intervalHours"` // Time between scans (default 24)
+}
+
+// SelfTestConfig schedules the self-tests, whose results are kept locally so
+// slowdowns show up as trends
+type SelfTestConfig struct {
+	Enabled       bool   `json:"enabled,omitempty" mapstructure:"enabled"`             // Run the self-tests on a schedule
+	IntervalHours int    `json:"intervalHours,omitempty" mapstructure:"intervalHours"` // Time between runs (default 168, weekly)
+	DiskMB        int    `json:"diskMB,omitempty" mapstructure:"diskMB"`               // Size of the disk benchmark file (default 64)
+	Dir           string `json:"dir,omitempty" mapstructure:"dir"`                     // Where the benchmark file goes (default the temp directory)
+}
+
+// TrafficConfig sets a budget for the agent's backend traffic, which is
+// always counted (state.json)
+type TrafficConfig struct {
+	MonthlyBudgetMB int `json:"monthlyBudgetMB,omitempty" mapstructure:"monthlyBudgetMB"` // Warn when sent + received bytes this month exceed it (0 = no budget)
+}
+
+// PrivacyConfig controls what is reported about the machine's users
+type PrivacyConfig struct {
+	HideProcessNames bool `json:"hideProcessNames,omitempty" mapstructure:"hideProcessNames"` // Send process IDs only, never names (GPU, top processes, handles, reports)
+	HashSSIDs        bool `json:"hashSsids,omitempty" mapstructure:"hashSsids"`               // Send Wi-Fi network names and access point MACs as host-salted hashes
+}
 
-func Load() (*Config, error) {
+// QueryParam is a single extra query parameter for the WebSocket URL
+type QueryParam struct {
+	Name  string `json:"name" mapstructure:"name"`
+	Value string `json:"value" mapstructure:"value"`
+}
+
+// UserAgent returns the User-Agent string sent on all outbound requests
+func UserAgent(version string) string {
+	return fmt.Sprintf("%s/%s (%s; %s)", AppID, version, runtime.GOOS, runtime.GOARCH)
+}
+
+// RequestHeaders returns the User-Agent plus configured extra headers
+func (c *Config) RequestHeaders() http.Header {
+	version := c.AgentVersion
+	if version == "" {
+		version = "dev"
+	}
+
+	header := http.Header{}
+	for name, value := range c.Headers {
+		header.Set(name, ExpandEnv(value))
+	}
+	header.Set("User-Agent", UserAgent(version))
+	return header
+}
+
+// ExpandEnv resolves ${VAR} references in a config value from the environment
+func ExpandEnv(value string) string {
+	if !strings.Contains(value, "$") {
+		return value
+	}
+	return os.ExpandEnv(value)
+}
+
+// Load reads configuration with precedence flags > environment > file > defaults.
+// A corrupt config file is restored from its backup (or moved aside so
+// defaults apply) rather than preventing the agent from starting.
+func Load(logger *zap.SugaredLogger, overrides Overrides) (*Config, error) {
 	// Ensure directories exist first
 	if err := EnsureDirs(); err != nil {
 		return nil, err
 	}
 
+	v, configFile := newViper()
+
+	// Read existing config (ignore error if file doesn't exist)
+	if err := v.ReadInConfig(); err != nil {
+		if _, statErr := os.Stat(configFile); statErr == nil {
+			recoverConfig(v, configFile, err, logger)
+		}
+	}
+
+	removeRetiredKeys(v, logger)
+
+	cfg, err := resolve(v, overrides, logger)
+	if err != nil {
+		return nil, err
+	}
+	if !cfg.StrictDecode {
+		if err := v.UnmarshalExact(&Config{}); err != nil {
+			logger.Warn("⚠️  Config has keys this agent version ignores", "file", configFile, "error", err)
+		}
+	}
+
+	// Set runtime paths
+	cfg.ConfigDir = GetConfigDir()
+	cfg.LogDir = cfg.Logging.FileDir()
+
+	// Create default config file if it doesn't exist
+	if _, err := os.Stat(configFile); os.IsNotExist(err) {
+		if err := writeDefaultConfig(configFile); err != nil {
+			return nil, err
+		}
+	}
+
+	return cfg, nil
+}
+
+// retiredKeys are settings older versions wrote into agent.json that now
+// live elsewhere
+var retiredKeys = []string{
+	"deviceCode", // Pairing progress is in pairing.json (auth.PairingStore)
+}
+
+// removeRetiredKeys deletes retiredKeys from the config file, so they
+// neither trip strict decoding nor show up as ignored keys
+func removeRetiredKeys(v *viper.Viper, logger *zap.SugaredLogger) {
+	values := map[string]any{}
+	for _, key := range retiredKeys {
+		if v.InConfig(key) {
+			values[key] = nil
+		}
+	}
+	if len(values) == 0 {
+		return
+	}
+	if err := UpdateFile(values); err != nil {
+		logger.Warn("Failed to remove retired keys from config", "error", err)
+		return
+	}
+	if err := v.ReadInConfig(); err != nil {
+		logger.Warn("Failed to re-read config", "error", err)
+	}
+	logger.Info("🧹 Removed retired keys from config", "keys", slices.Sorted(maps.Keys(values)))
+}
+
+// Peek resolves the configuration with the same precedence as Load but
+// never writes or repairs anything, for commands that only inspect it.
+// An unreadable config file is ignored.
+func Peek(overrides Overrides) (*Config, error) {
+	return peek(overrides, zap.NewNop().Sugar())
+}
+
+// peek is Peek with env=auto detection logged to logger, or skipped when it
+// is nil
+func peek(overrides Overrides, logger *zap.SugaredLogger) (*Config, error) {
+	v, _ := newViper()
+	_ = v.ReadInConfig()
+	return resolve(v, overrides, logger)
+}
+
+// LoadLogging resolves just the logging settings, so the logger can be built
+// before the full config is loaded. On error defaults are returned.
+func LoadLogging(overrides Overrides) LoggingConfig {
+	cfg, err := peek(overrides, nil) // Logging doesn't depend on env; don't probe twice
+	if err != nil {
+		return LoggingConfig{Compress: true}
+	}
+	return cfg.Logging
+}
+
+// newViper creates a viper instance with defaults and the config file location
+func newViper() (*viper.Viper, string) {
 	v := viper.New()
 
 	// Set defaults
 	v.SetDefault("env", EnvDefault)
 	v.SetDefault("metricsIntervalMs", 2000)
 	v.SetDefault("openOnStart", true)
+	v.SetDefault("openOnPair", true)
+	v.SetDefault("copyOnPair", true)
+	v.SetDefault("pairingCycles", 3)
+	v.SetDefault("memoryBudgetMB", 32)
+	v.SetDefault("highResolution", false)
+	v.SetDefault("logging.compress", true)
+	v.SetDefault("ipc.enabled", true)
+	v.SetDefault("tray.enabled", true)
+	v.SetDefault("storage.enabled", true)
+	v.SetDefault("spool.enabled", true)
+	v.SetDefault("spool.minFreeMB", 2048)
+	v.SetDefault("slowStart.enabled", true)
 
 	// Configure config file
 	configFile := GetConfigFile()
 	v.SetConfigFile(configFile)
 	v.SetConfigType("json")
 
-	// Read existing config (ignore error if file doesn't exist)
-	_ = v.ReadInConfig()
+	return v, configFile
+}
 
-	// Environment variables override (e.g., WINDASH_ENV)
-	v.SetEnvPrefix("WINDASH")
-	v.AutomaticEnv()
+// resolve layers environment variables and flag overrides on top of the
+// file and defaults already loaded into v, then fills in env endpoints.
+// env=auto is resolved by probing the backends, logged to logger; with a nil
+// logger it is left to fall back to the default endpoints.
+func resolve(v *viper.Viper, overrides Overrides, logger *zap.SugaredLogger) (*Config, error) {
+	// Environment variables override the file (e.g., WINDASH_METRICS_INTERVAL_MS)
+	bindEnv(v)
 
-	// Unmarshal into struct
+	// Command-line flags override everything
+	for key, value := range overrides {
+		v.Set(key, value)
+	}
+
+	// Unmarshal into struct. Strict mode turns keys this version doesn't
+	// know (typos, settings from a newer agent) into an error.
 	cfg := &Config{}
-	if err := v.Unmarshal(cfg); err != nil {
+	if v.GetBool("strictDecode") {
+		if err := v.UnmarshalExact(cfg); err != nil {
+			return nil, fmt.Errorf("strict config decoding (set strictDecode to false to ignore unknown keys): %w", err)
+		}
+	} else if err := v.Unmarshal(cfg); err != nil {
 		return nil, err
 	}
 
+	// An env chosen by flag or environment variable beats endpoints stored in
+	// the file, otherwise switching env would keep the old URLs
+	if overrides.has("env") || envSet("env") {
+		if !overrides.has("dashboardUrl") && !envSet("dashboardUrl") {
+			cfg.DashboardURL = ""
+		}
+		if !overrides.has("apiUrl") && !envSet("apiUrl") {
+			cfg.APIURL = ""
+		}
+	}
+
+	if cfg.Env == EnvAuto && logger != nil {
+		cfg.Env = detectEnv(logger)
+	}
+
 	// Set endpoints based on env, unless overridden in config
 	switch cfg.Env {
 	case "localdev":
@@ -105,28 +761,37 @@ func Load() (*Config, error) {
 		}
 	}
 
-	// Set runtime paths
-	cfg.ConfigDir = GetConfigDir()
-	cfg.LogDir = GetLogDir()
+	return cfg, nil
+}
 
-	// Create default config file if it doesn't exist
-	if _, err := os.Stat(configFile); os.IsNotExist(err) {
-		if err := writeDefaultConfig(configFile); err != nil {
-			return nil, err
+// UpdateFile sets top-level keys in the config file, leaving everything
+// else in it untouched; a nil value removes the key. It never writes
+// values that came from flags or environment variables.
+func UpdateFile(values map[string]any) error {
+	configFile := GetConfigFile()
+
+	doc := map[string]any{}
+	if data, err := os.ReadFile(configFile); err == nil {
+		if err := json.Unmarshal(data, &doc); err != nil {
+			return fmt.Errorf("config file is unreadable: %w", err)
 		}
+	} else if !os.IsNotExist(err) {
+		return err
 	}
 
-	return cfg, nil
-}
+	for key, value := range values {
+		if value == nil {
+			delete(doc, key)
+		} else {
+			doc[key] = value
+		}
+	}
 
-// Save writes the current configuration to file
-func (c *Config) Save() error {
-	configFile := GetConfigFile()
-	data, err := json.MarshalIndent(c, "", "  ")
+	data, err := json.MarshalIndent(doc, "", "  ")
 	if err != nil {
 		return err
 	}
-	return os.WriteFile(configFile, data, 0644)
+	return writeFileAtomic(configFile, data, 0644)
 }
 
 // writeDefaultConfig creates a new config file with defaults and helpful comments
@@ -137,6 +802,8 @@ func writeDefaultConfig(path string) error {
 		APIURL:            APIURLRemoteProd,
 		MetricsIntervalMs: 2000,
 		OpenOnStart:       true,
+		OpenOnPair:        true,
+		CopyOnPair:        true,
 	}
 
 	// Marshal to JSON
@@ -145,5 +812,31 @@ func writeDefaultConfig(path string) error {
 		return err
 	}
 
-	return os.WriteFile(path, data, 0644)
+	return writeFileAtomic(path, data, 0644)
+}
+
+// recoverConfig handles an unreadable config file by restoring the backup
+// kept by Save, falling back to defaults if there is none
+func recoverConfig(v *viper.Viper, configFile string, readErr error, logger *zap.SugaredLogger) {
+	logger.Error("⚠️  Config file is corrupt", "file", configFile, "error", readErr)
+
+	restored, corruptPath, err := restoreBackup(configFile)
+	if err != nil {
+		logger.Error("Failed to recover config", "error", err)
+		return
+	}
+	if corruptPath != "" {
+		logger.Warn("Moved corrupt config aside", "file", corruptPath)
+	}
+
+	if !restored {
+		logger.Warn("No usable config backup - continuing with defaults")
+		return
+	}
+
+	if err := v.ReadInConfig(); err != nil {
+		logger.Error("Restored config backup is unreadable - continuing with defaults", "error", err)
+		return
+	}
+	logger.Warn("♻️  Restored config from backup", "backup", backupPath(configFile))
 }
@@ -0,0 +1,77 @@
+package config
+
+import (
+	"net"
+	"net/url"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// EnvAuto picks the first reachable backend at startup instead of a fixed env
+const EnvAuto = "auto"
+
+const (
+	localProbeTimeout  = 500 * time.Millisecond
+	remoteProbeTimeout = 3 * time.Second
+)
+
+// autoCandidates are the envs env=auto tries, in order. localdockerprod
+// lives on a LAN address that only one setup has, so it is never guessed.
+var autoCandidates = []struct {
+	env       string
+	endpoints []string
+	timeout   time.Duration
+}{
+	{"localdev", []string{DashboardURLLocalDev, APIURLLocalDev}, localProbeTimeout},
+	{"localprod", []string{DashboardURLLocalProd, APIURLLocalProd}, localProbeTimeout},
+	{"remoteprod", []string{APIURLRemoteProd}, remoteProbeTimeout},
+}
+
+// detectEnv returns the first env whose dashboard and API ports accept a
+// TCP connection, falling back to EnvDefault when none do
+func detectEnv(logger *zap.SugaredLogger) string {
+	for _, c := range autoCandidates {
+		addr, err := reachable(c.endpoints, c.timeout)
+		if err != nil {
+			logger.Debug("Backend not reachable", "env", c.env, "address", addr, "error", err)
+			continue
+		}
+		logger.Info("🔎 env=auto picked a backend", "env", c.env)
+		return c.env
+	}
+	logger.Warn("⚠️  env=auto found no reachable backend, using the default", "env", EnvDefault)
+	return EnvDefault
+}
+
+// reachable dials every endpoint, returning the first address that failed
+func reachable(endpoints []string, timeout time.Duration) (string, error) {
+	for _, endpoint := range endpoints {
+		addr, err := endpointAddr(endpoint)
+		if err != nil {
+			return endpoint, err
+		}
+		conn, err := net.DialTimeout("tcp", addr, timeout)
+		if err != nil {
+			return addr, err
+		}
+		conn.Close()
+	}
+	return "", nil
+}
+
+// endpointAddr returns the host:port an http(s) or ws(s) URL connects to
+func endpointAddr(rawURL string) (string, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return "", err
+	}
+	port := u.Port()
+	if port == "" {
+		port = "80"
+		if u.Scheme == "https" || u.Scheme == "wss" {
+			port = "443"
+		}
+	}
+	return net.JoinHostPort(u.Hostname(), port), nil
+}
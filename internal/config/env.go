@@ -0,0 +1,227 @@
+package config
+
+import (
+	"os"
+	"strings"
+	"unicode"
+
+	"github.com/spf13/viper"
+)
+
+// EnvPrefix is prepended to every environment variable the agent reads
+const EnvPrefix = "WINDASH"
+
+// settingKeys lists every scalar setting that can be overridden from the
+// environment or command line. Nested keys use dots; the matching
+// environment variable is derived by envName (connection.path ->
+// WINDASH_CONNECTION_PATH). Maps and lists (headers, query) are file-only.
+var settingKeys = []string{
+	"env",
+	"dashboardUrl",
+	"apiUrl",
+	"metricsIntervalMs",
+	"openOnStart",
+	"openOnPair",
+	"copyOnPair",
+	"pairingCycles",
+	"siteId",
+	"groupId",
+	"memoryBudgetMB",
+	"highResolution",
+	"strictDecode",
+	"units",
+	"hostId.file",
+	"hostId.command",
+	"ipc.enabled",
+	"tray.enabled",
+	"logging.dir",
+	"logging.stdoutOnly",
+	"logging.compress",
+	"connection.path",
+	"collectors.enable.cpu",
+	"collectors.enable.mem",
+	"collectors.enable.disk",
+	"collectors.enable.net",
+	"collectors.enable.uptime",
+	"collectors.enable.procs",
+	"collectors.enable.gpu",
+	"collectors.enable.gpuDevices",
+	"collectors.enable.audio",
+	"collectors.enable.temps",
+	"collectors.enable.topProcs",
+	"collectors.enable.dpc",
+	"collectors.enable.tcp",
+	"collectors.enable.hyperv",
+	"collectors.enable.wifi",
+	"collectors.enable.links",
+	"collectors.cpu.perCoreLimit",
+	"collectors.cpu.topCores",
+	"collectors.cpu.perCoreEvery",
+	"collectors.gpu.enabled",
+	"collectors.gpu.topProcesses",
+	"collectors.topProcs.count",
+	"collectors.topProcs.intervalSec",
+	"collectors.dpc.spikePct",
+	"collectors.audio",
+	"collectors.synthetic.enabled",
+	"collectors.synthetic.cores",
+	"collectors.synthetic.cpuBase",
+	"collectors.synthetic.cpuAmplitude",
+	"collectors.synthetic.cpuPeriodSec",
+	"collectors.synthetic.spikeChance",
+	"collectors.synthetic.diskGrowthBytesPerSec",
+	"watch.intervalSec",
+	"watch.serviceGraceSec",
+	"health.cpu",
+	"health.memory",
+	"health.disk",
+	"health.temps",
+	"health.alerts",
+	"suppress.enabled",
+	"suppress.cpu",
+	"suppress.memory",
+	"suppress.disk",
+	"suppress.netBps",
+	"suppress.keepaliveEvery",
+	"slowStart.enabled",
+	"slowStart.windowSec",
+	"slowStart.rampSec",
+	"slowStart.intervalMs",
+	"incidents.enabled",
+	"incidents.samples",
+	"snapshot.dailyAt",
+	"summary.enabled",
+	"summary.format",
+	"summary.upload",
+	"summary.keepDays",
+	"storage.enabled",
+	"storage.intervalSec",
+	"devices.usb",
+	"devices.intervalSec",
+	"printers.enabled",
+	"printers.stuckMinutes",
+	"printers.alert",
+	"printers.intervalSec",
+	"security.readOnly",
+	"security.remoteSessions",
+	"security.userSessions",
+	"security.antivirus",
+	"security.failedLogons",
+	"security.failedLogonBurst",
+	"security.intervalSec",
+	"maintenance.restartDays",
+	"maintenance.restartMode",
+	"maintenance.drainSec",
+	"maintenance.maxRssMB",
+	"maintenance.maxRssMinutes",
+	"spool.enabled",
+	"spool.maxMB",
+	"spool.minFreeMB",
+	"history.enabled",
+	"history.keepDays",
+	"history.maxMB",
+	"handles.enabled",
+	"handles.top",
+	"handles.intervalSec",
+	"peers.enabled",
+	"peers.port",
+	"peers.intervalSec",
+	"netProbe.enabled",
+	"netProbe.intervalSec",
+	"netProbe.count",
+	"netProbe.dnsName",
+	"software.enabled",
+	"software.intervalHours",
+	"selfTest.enabled",
+	"selfTest.intervalHours",
+	"selfTest.diskMB",
+	"selfTest.dir",
+	"traffic.monthlyBudgetMB",
+	"privacy.hideProcessNames",
+	"privacy.hashSsids",
+}
+
+// Overrides holds command-line values keyed by setting (e.g. "metricsIntervalMs").
+// They take precedence over environment variables, the config file and defaults.
+type Overrides map[string]string
+
+// has reports whether key was overridden (keys are case-insensitive, as in viper)
+func (o Overrides) has(key string) bool {
+	for k := range o {
+		if strings.EqualFold(k, key) {
+			return true
+		}
+	}
+	return false
+}
+
+// SettingKeys returns every key accepted by Overrides and the environment
+func SettingKeys() []string {
+	return append([]string(nil), settingKeys...)
+}
+
+// IsSettingKey reports whether key names a known scalar setting
+func IsSettingKey(key string) bool {
+	for _, k := range settingKeys {
+		if strings.EqualFold(k, key) {
+			return true
+		}
+	}
+	return false
+}
+
+// envName maps a setting key to its environment variable:
+// metricsIntervalMs -> WINDASH_METRICS_INTERVAL_MS. A run of capitals is
+// one word, so memoryBudgetMB -> WINDASH_MEMORY_BUDGET_MB.
+func envName(key string) string {
+	var b strings.Builder
+	b.WriteString(EnvPrefix)
+	for _, part := range strings.Split(key, ".") {
+		b.WriteByte('_')
+		runes := []rune(part)
+		for i, r := range runes {
+			if i > 0 && unicode.IsUpper(r) {
+				prevLower := !unicode.IsUpper(runes[i-1])
+				nextLower := i+1 < len(runes) && unicode.IsLower(runes[i+1])
+				if prevLower || nextLower {
+					b.WriteByte('_')
+				}
+			}
+			b.WriteRune(unicode.ToUpper(r))
+		}
+	}
+	return b.String()
+}
+
+// EnvName returns the environment variable that overrides key
+func EnvName(key string) string {
+	return envName(key)
+}
+
+// bindEnv binds every setting to its environment variable. Top-level keys
+// also accept the legacy AutomaticEnv spelling (WINDASH_APIURL) for
+// backwards compatibility.
+func bindEnv(v *viper.Viper) {
+	for _, key := range settingKeys {
+		names := []string{envName(key)}
+		if !strings.Contains(key, ".") {
+			legacy := EnvPrefix + "_" + strings.ToUpper(key)
+			if legacy != names[0] {
+				names = append(names, legacy)
+			}
+		}
+		v.BindEnv(append([]string{key}, names...)...)
+	}
+}
+
+// envSet reports whether key is set via its environment variable(s)
+func envSet(key string) bool {
+	if _, ok := os.LookupEnv(envName(key)); ok {
+		return true
+	}
+	if !strings.Contains(key, ".") {
+		_, ok := os.LookupEnv(EnvPrefix + "_" + strings.ToUpper(key))
+		return ok
+	}
+	return false
+}
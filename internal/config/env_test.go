@@ -0,0 +1,167 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/spf13/viper"
+)
+
+func TestEnvName(t *testing.T) {
+	tests := []struct {
+		key  string
+		want string
+	}{
+		{"env", "WINDASH_ENV"},
+		{"apiUrl", "WINDASH_API_URL"},
+		{"metricsIntervalMs", "WINDASH_METRICS_INTERVAL_MS"},
+		{"connection.path", "WINDASH_CONNECTION_PATH"},
+		{"memoryBudgetMB", "WINDASH_MEMORY_BUDGET_MB"}, // A run of capitals is one word
+		{"spool.minFreeMB", "WINDASH_SPOOL_MIN_FREE_MB"},
+		{"maintenance.maxRssMB", "WINDASH_MAINTENANCE_MAX_RSS_MB"},
+		{"traffic.monthlyBudgetMB", "WINDASH_TRAFFIC_MONTHLY_BUDGET_MB"},
+		{"httpURLPath", "WINDASH_HTTP_URL_PATH"},
+		{"netProbe.dnsName", "WINDASH_NET_PROBE_DNS_NAME"},
+		{"collectors.enable.gpuDevices", "WINDASH_COLLECTORS_ENABLE_GPU_DEVICES"},
+		{"slowStart.intervalMs", "WINDASH_SLOW_START_INTERVAL_MS"},
+	}
+	for _, tt := range tests {
+		if got := envName(tt.key); got != tt.want {
+			t.Errorf("envName(%q) = %q, want %q", tt.key, got, tt.want)
+		}
+	}
+}
+
+func TestSettingKeysUnique(t *testing.T) {
+	seen := make(map[string]string)
+	for _, key := range settingKeys {
+		name := envName(key)
+		if other, dup := seen[name]; dup {
+			t.Errorf("%s and %s both map to %s", other, key, name)
+		}
+		seen[name] = key
+	}
+}
+
+// configKeys collects the mapstructure paths of every field in typ
+func configKeys(typ reflect.Type, prefix string, keys map[string]bool) {
+	for i := range typ.NumField() {
+		f := typ.Field(i)
+		name, _, _ := strings.Cut(f.Tag.Get("mapstructure"), ",")
+		if name == "" || name == "-" {
+			continue
+		}
+		key := prefix + name
+		keys[strings.ToLower(key)] = true
+		ft := f.Type
+		if ft.Kind() == reflect.Pointer {
+			ft = ft.Elem()
+		}
+		if ft.Kind() == reflect.Struct && ft.PkgPath() == typ.PkgPath() {
+			configKeys(ft, key+".", keys)
+		}
+	}
+}
+
+func TestSettingKeysBound(t *testing.T) {
+	fields := make(map[string]bool)
+	configKeys(reflect.TypeFor[Config](), "", fields)
+
+	v := viper.New()
+	bindEnv(v)
+	for _, key := range settingKeys {
+		if !fields[strings.ToLower(key)] {
+			t.Errorf("setting %s has no Config field", key)
+		}
+		t.Setenv(envName(key), "from-env")
+		if got := v.GetString(key); got != "from-env" {
+			t.Errorf("%s = %q with %s set, want it bound", key, got, envName(key))
+		}
+	}
+}
+
+func TestLegacyEnvName(t *testing.T) {
+	v := viper.New()
+	bindEnv(v)
+	t.Setenv("WINDASH_APIURL", "wss://legacy.example/agent")
+	if got := v.GetString("apiUrl"); got != "wss://legacy.example/agent" {
+		t.Errorf("apiUrl = %q, want the legacy WINDASH_APIURL value", got)
+	}
+}
+
+func TestPrecedence(t *testing.T) {
+	tests := []struct {
+		name     string
+		file     bool
+		env      bool
+		flag     bool
+		interval int
+		dnsName  string
+	}{
+		{"defaults", false, false, false, 2000, ""},
+		{"file", true, false, false, 3000, "file.example"},
+		{"env over file", true, true, false, 4000, "env.example"},
+		{"flag over env and file", true, true, true, 5000, "flag.example"},
+		{"flag over defaults", false, false, true, 5000, "flag.example"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			t.Setenv("LOCALAPPDATA", dir)
+			if tt.file {
+				path := filepath.Join(dir, AppName, "agent.json")
+				if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+					t.Fatal(err)
+				}
+				data := `{"metricsIntervalMs": 3000, "netProbe": {"dnsName": "file.example"}}`
+				if err := os.WriteFile(path, []byte(data), 0600); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if tt.env {
+				t.Setenv("WINDASH_METRICS_INTERVAL_MS", "4000")
+				t.Setenv("WINDASH_NET_PROBE_DNS_NAME", "env.example")
+			}
+			overrides := Overrides{}
+			if tt.flag {
+				overrides["metricsIntervalMs"] = "5000"
+				overrides["netProbe.dnsName"] = "flag.example"
+			}
+
+			cfg, err := peek(overrides, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if cfg.MetricsIntervalMs != tt.interval {
+				t.Errorf("metricsIntervalMs = %d, want %d", cfg.MetricsIntervalMs, tt.interval)
+			}
+			if cfg.NetProbe.DNSName != tt.dnsName {
+				t.Errorf("netProbe.dnsName = %q, want %q", cfg.NetProbe.DNSName, tt.dnsName)
+			}
+		})
+	}
+}
+
+func TestEnvSwitchDropsFileEndpoints(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("LOCALAPPDATA", dir)
+	path := filepath.Join(dir, AppName, "agent.json")
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		t.Fatal(err)
+	}
+	data := `{"env": "remoteprod", "apiUrl": "wss://stored.example/agent"}`
+	if err := os.WriteFile(path, []byte(data), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg, err := peek(Overrides{"env": "localdev"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.APIURL != APIURLLocalDev {
+		t.Errorf("apiUrl = %q, want the localdev endpoint %q", cfg.APIURL, APIURLLocalDev)
+	}
+}
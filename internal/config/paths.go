@@ -41,13 +41,48 @@ func GetConfigFile() string {
 	return filepath.Join(GetConfigDir(), "agent.json")
 }
 
-// EnsureDirs creates config and log directories if they don't exist
+// GetInventoryCacheFile returns the path of the cached host inventory
+func GetInventoryCacheFile() string {
+	return filepath.Join(GetConfigDir(), "inventory.json")
+}
+
+// GetStateFile returns the path of the persisted agent state
+func GetStateFile() string {
+	return filepath.Join(GetConfigDir(), "state.json")
+}
+
+// GetPairingFile returns the path of the pairing state
+func GetPairingFile() string {
+	return filepath.Join(GetConfigDir(), "pairing.json")
+}
+
+// GetSpoolDir returns the directory of spooled samples
+func GetSpoolDir() string {
+	return filepath.Join(GetConfigDir(), "spool")
+}
+
+// GetHistoryDir returns the directory of the local sample history
+func GetHistoryDir() string {
+	return filepath.Join(GetConfigDir(), "history")
+}
+
+// GetSoftwareFile returns the path of the last installed software list sent
+func GetSoftwareFile() string {
+	return filepath.Join(GetConfigDir(), "software.json")
+}
+
+// GetSelfTestFile returns the path of the self-test history
+func GetSelfTestFile() string {
+	return filepath.Join(GetConfigDir(), "selftest.json")
+}
+
+// GetQuarantineFile returns the path of the samples rejected by validation
+func GetQuarantineFile() string {
+	return filepath.Join(GetConfigDir(), "quarantine.jsonl")
+}
+
+// EnsureDirs creates the config directory if it doesn't exist. The log
+// directory is created by the log writer, and only when logging to files.
 func EnsureDirs() error {
-	dirs := []string{GetConfigDir(), GetLogDir()}
-	for _, dir := range dirs {
-		if err := os.MkdirAll(dir, 0755); err != nil {
-			return err
-		}
-	}
-	return nil
+	return os.MkdirAll(GetConfigDir(), 0755)
 }
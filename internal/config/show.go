@@ -0,0 +1,249 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+
+	"go.uber.org/zap"
+)
+
+// Sources reported by Effective
+const (
+	SourceDefault = "default"
+	SourceFile    = "file"
+	SourceEnv     = "env"
+	SourceFlag    = "flag"
+	SourceDerived = "derived from env"
+)
+
+// redacted replaces secret values in Effective output
+const redacted = "<redacted>"
+
+// Setting is one resolved configuration value and where it came from
+type Setting struct {
+	Key    string
+	Value  any
+	Source string // One of the Source* constants; env includes the variable name
+}
+
+// Effective resolves configuration exactly like Load (without writing or
+// repairing anything) and reports every setting with its source. Values that
+// may carry secrets (headers, query parameters, the host ID command, URL
+// credentials) are redacted unless they are ${VAR} references. There is no
+// Group Policy layer; settings come from defaults, the file, the environment
+// and flags only.
+func Effective(overrides Overrides) ([]Setting, error) {
+	v, configFile := newViper()
+	if err := v.ReadInConfig(); err != nil {
+		// Report corrupt files instead of silently showing defaults
+		if _, statErr := os.Stat(configFile); statErr == nil {
+			return nil, fmt.Errorf("config file is unreadable: %w", err)
+		}
+	}
+
+	cfg, err := resolve(v, overrides, zap.NewNop().Sugar())
+	if err != nil {
+		return nil, err
+	}
+
+	values, err := flatten(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	envExplicit := overrides.has("env") || envSet("env")
+
+	var settings []Setting
+	for _, key := range settingKeys {
+		s := Setting{Key: key, Value: values[strings.ToLower(key)]}
+		if s.Value == nil {
+			// Zero values are omitted from the marshaled config
+			s.Value = v.Get(key)
+		}
+		if s.Value == nil {
+			s.Value = ""
+		}
+		switch {
+		case overrides.has(key):
+			s.Source = SourceFlag
+		case envSet(key):
+			s.Source = SourceEnv + " (" + envName(key) + ")"
+		case isEndpointKey(key) && (envExplicit || !v.InConfig(key)):
+			s.Source = SourceDerived
+		case v.InConfig(key):
+			s.Source = SourceFile
+		default:
+			s.Source = SourceDefault
+		}
+		if str, ok := s.Value.(string); ok {
+			switch {
+			case isEndpointKey(key):
+				s.Value = redactURL(str)
+			case secretKeys[key]:
+				s.Value = redact(str)
+			}
+		}
+		settings = append(settings, s)
+	}
+
+	// Map and list settings only come from the file
+	for _, name := range sortedKeys(cfg.Headers) {
+		settings = append(settings, Setting{Key: "headers." + name, Value: redact(cfg.Headers[name]), Source: SourceFile})
+	}
+	for _, p := range cfg.Connection.Query {
+		settings = append(settings, Setting{Key: "connection.query." + p.Name, Value: redact(p.Value), Source: SourceFile})
+	}
+	for _, name := range sortedKeys(cfg.Connection.Headers) {
+		settings = append(settings, Setting{Key: "connection.headers." + name, Value: redact(cfg.Connection.Headers[name]), Source: SourceFile})
+	}
+
+	return settings, nil
+}
+
+// RedactedFile returns agent.json with the values Effective redacts hidden,
+// re-indented. An unreadable file is reported rather than shown, since its
+// secrets can't be found.
+func RedactedFile() ([]byte, error) {
+	data, err := os.ReadFile(GetConfigFile())
+	if err != nil {
+		return nil, err
+	}
+	var tree map[string]any
+	if err := json.Unmarshal(data, &tree); err != nil {
+		return nil, fmt.Errorf("config file is unreadable: %w", err)
+	}
+
+	for key, val := range tree {
+		switch {
+		case isEndpointKey(key):
+			tree[key] = redactValue(val, redactURL)
+		case strings.EqualFold(key, "headers"):
+			tree[key] = redactMap(val)
+		case strings.EqualFold(key, "hostId"):
+			if m, ok := val.(map[string]any); ok {
+				for k, v := range m {
+					if strings.EqualFold(k, "command") {
+						m[k] = redactValue(v, redact)
+					}
+				}
+			}
+		case strings.EqualFold(key, "connection"):
+			m, ok := val.(map[string]any)
+			if !ok {
+				continue
+			}
+			for k, v := range m {
+				switch {
+				case strings.EqualFold(k, "headers"):
+					m[k] = redactMap(v)
+				case strings.EqualFold(k, "query"):
+					if params, ok := v.([]any); ok {
+						for _, p := range params {
+							if p, ok := p.(map[string]any); ok {
+								p["value"] = redactValue(p["value"], redact)
+							}
+						}
+					}
+				}
+			}
+		case strings.EqualFold(key, "checks"):
+			if checks, ok := val.([]any); ok {
+				for _, c := range checks {
+					if c, ok := c.(map[string]any); ok {
+						c["url"] = redactValue(c["url"], redactURL)
+					}
+				}
+			}
+		}
+	}
+	return json.MarshalIndent(tree, "", "  ")
+}
+
+// secretKeys are scalar settings whose values are redacted outright
+var secretKeys = map[string]bool{"hostId.command": true}
+
+// redactValue applies fn to val if it is a string
+func redactValue(val any, fn func(string) string) any {
+	if s, ok := val.(string); ok {
+		return fn(s)
+	}
+	return val
+}
+
+// redactMap redacts every string value of a JSON object
+func redactMap(val any) any {
+	m, ok := val.(map[string]any)
+	if !ok {
+		return val
+	}
+	for k, v := range m {
+		m[k] = redactValue(v, redact)
+	}
+	return m
+}
+
+// flatten converts cfg into a map of lowercased dotted keys to values
+func flatten(cfg *Config) (map[string]any, error) {
+	data, err := json.Marshal(cfg)
+	if err != nil {
+		return nil, err
+	}
+	var tree map[string]any
+	if err := json.Unmarshal(data, &tree); err != nil {
+		return nil, err
+	}
+
+	out := make(map[string]any)
+	var walk func(prefix string, node map[string]any)
+	walk = func(prefix string, node map[string]any) {
+		for k, val := range node {
+			key := strings.ToLower(prefix + k)
+			if child, ok := val.(map[string]any); ok {
+				walk(key+".", child)
+				continue
+			}
+			out[key] = val
+		}
+	}
+	walk("", tree)
+	return out, nil
+}
+
+// isEndpointKey reports whether key is one of the env-derived endpoints
+func isEndpointKey(key string) bool {
+	return key == "dashboardUrl" || key == "apiUrl"
+}
+
+// redact hides a secret value unless it is an environment reference
+func redact(value string) string {
+	if strings.Contains(value, "${") {
+		return value
+	}
+	if value == "" {
+		return ""
+	}
+	return redacted
+}
+
+// redactURL hides passwords embedded in a URL
+func redactURL(raw string) string {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return raw
+	}
+	return u.Redacted()
+}
+
+// sortedKeys returns map keys in a stable order for display
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,121 @@
+package devices
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/jcdorr003/windash-agent/internal/config"
+	"github.com/jcdorr003/windash-agent/internal/outbound"
+	"go.uber.org/zap"
+)
+
+const defaultInterval = 5 * time.Second
+
+// errUnsupported is returned by listUSBDevices off Windows
+var errUnsupported = errors.New("USB device events are only supported on Windows")
+
+// Device is a USB device as reported by Plug and Play
+type Device struct {
+	ID           string `json:"id"`              // PnP device instance ID
+	Name         string `json:"name"`            // Friendly name
+	Class        string `json:"class,omitempty"` // PnP class, e.g. DiskDrive, HIDClass, Image
+	Manufacturer string `json:"manufacturer,omitempty"`
+}
+
+// Event is an "event" message for a device being attached or removed
+type Event struct {
+	Type   string    `json:"type"` // always "event"
+	Kind   string    `json:"kind"` // usbAttached or usbDetached
+	TS     time.Time `json:"ts"`
+	HostID string    `json:"hostId"`
+	Device Device    `json:"device"`
+}
+
+// Monitor polls the USB device list and sends an event for each change
+type Monitor struct {
+	logger   *zap.SugaredLogger
+	hostID   string
+	interval time.Duration
+	classes  []string // Lower-cased PnP classes to report (empty = all)
+
+	known  map[string]Device
+	primed bool // First scan establishes the baseline and sends no events
+}
+
+// NewMonitor creates a USB device monitor
+func NewMonitor(logger *zap.SugaredLogger, hostID string, cfg config.DevicesConfig) *Monitor {
+	interval := time.Duration(cfg.IntervalSec) * time.Second
+	if interval <= 0 {
+		interval = defaultInterval
+	}
+	m := &Monitor{logger: logger, hostID: hostID, interval: interval, known: make(map[string]Device)}
+	for _, class := range cfg.Classes {
+		m.classes = append(m.classes, strings.ToLower(class))
+	}
+	return m
+}
+
+// Run polls until ctx is cancelled, stopping early if devices can't be
+// listed on this machine
+func (m *Monitor) Run(ctx context.Context, sender outbound.Sender) {
+	m.logger.Info("🔌 USB device monitor started", "interval", m.interval, "classes", m.classes)
+
+	ticker := time.NewTicker(m.interval)
+	defer ticker.Stop()
+
+	for {
+		devices, err := listUSBDevices()
+		switch {
+		case errors.Is(err, errUnsupported):
+			m.logger.Info("USB device events unavailable, stopping", "error", err)
+			return
+		case err != nil:
+			m.logger.Warn("USB device scan failed", "error", err)
+		default:
+			m.diff(devices, sender)
+		}
+
+		select {
+		case <-ctx.Done():
+			m.logger.Info("🔌 USB device monitor stopped")
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// diff sends events for devices attached or removed since the last scan
+func (m *Monitor) diff(devices []Device, sender outbound.Sender) {
+	current := make(map[string]Device, len(devices))
+	for _, d := range devices {
+		if !m.wanted(d) {
+			continue
+		}
+		current[d.ID] = d
+		if _, seen := m.known[d.ID]; !seen && m.primed {
+			m.send(sender, "usbAttached", d)
+		}
+	}
+	if m.primed {
+		for id, d := range m.known {
+			if _, still := current[id]; !still {
+				m.send(sender, "usbDetached", d)
+			}
+		}
+	}
+	m.known = current
+	m.primed = true
+}
+
+// wanted reports whether d's class is being monitored
+func (m *Monitor) wanted(d Device) bool {
+	return len(m.classes) == 0 || slices.Contains(m.classes, strings.ToLower(d.Class))
+}
+
+func (m *Monitor) send(sender outbound.Sender, kind string, d Device) {
+	m.logger.Info("🔌 USB device change", "kind", kind, "name", d.Name, "class", d.Class)
+	sender.Send("event", &Event{Type: "event", Kind: kind, TS: time.Now(), HostID: m.hostID, Device: d})
+}
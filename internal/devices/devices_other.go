@@ -0,0 +1,8 @@
+//go:build !windows
+
+package devices
+
+// listUSBDevices is not implemented outside Windows
+func listUSBDevices() ([]Device, error) {
+	return nil, errUnsupported
+}
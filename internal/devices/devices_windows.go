@@ -0,0 +1,37 @@
+//go:build windows
+
+package devices
+
+import (
+	"github.com/yusufpapurcu/wmi"
+)
+
+type win32PnPEntity struct {
+	DeviceID     string
+	Name         string
+	PNPClass     *string
+	Manufacturer *string
+}
+
+// listUSBDevices lists present Plug and Play devices enumerated by the USB
+// bus drivers (USB\ and USBSTOR\ instance IDs)
+func listUSBDevices() ([]Device, error) {
+	var entities []win32PnPEntity
+	query := "SELECT DeviceID, Name, PNPClass, Manufacturer FROM Win32_PnPEntity WHERE DeviceID LIKE 'USB%' AND Present = TRUE"
+	if err := wmi.Query(query, &entities); err != nil {
+		return nil, err
+	}
+
+	devices := make([]Device, 0, len(entities))
+	for _, e := range entities {
+		d := Device{ID: e.DeviceID, Name: e.Name}
+		if e.PNPClass != nil {
+			d.Class = *e.PNPClass
+		}
+		if e.Manufacturer != nil {
+			d.Manufacturer = *e.Manufacturer
+		}
+		devices = append(devices, d)
+	}
+	return devices, nil
+}
@@ -0,0 +1,126 @@
+package handles
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"time"
+
+	"github.com/jcdorr003/windash-agent/internal/config"
+	"github.com/jcdorr003/windash-agent/internal/metrics"
+	"github.com/jcdorr003/windash-agent/internal/outbound"
+	"go.uber.org/zap"
+)
+
+const (
+	defaultInterval = 5 * time.Minute
+	defaultTop      = 10
+)
+
+// Report is the periodic "handles" message
+type Report struct {
+	Type      string    `json:"type"` // always "handles"
+	TS        time.Time `json:"ts"`
+	HostID    string    `json:"hostId"`
+	Total     uint64    `json:"total"` // Handles held by all processes
+	Processes []Process `json:"processes"`
+}
+
+// Process is one of the processes holding the most handles
+type Process struct {
+	PID     int32  `json:"pid"`
+	Name    string `json:"name,omitempty"` // Omitted with privacy.hideProcessNames
+	Handles uint32 `json:"handles"`
+	Growth  int64  `json:"growth"` // Change since the previous report (0 for a new process)
+}
+
+// instance identifies a process across reports; PIDs alone get reused
+type instance struct {
+	pid     int32
+	created int64
+}
+
+// Monitor periodically reports the processes holding the most handles, so
+// handle leaks can be attributed to a process
+type Monitor struct {
+	logger    *zap.SugaredLogger
+	hostID    string
+	interval  time.Duration
+	top       int
+	hideNames bool
+	previous  map[instance]uint32
+}
+
+// NewMonitor creates a handle report monitor
+func NewMonitor(logger *zap.SugaredLogger, hostID string, cfg config.HandlesConfig, privacy config.PrivacyConfig) *Monitor {
+	interval := time.Duration(cfg.IntervalSec) * time.Second
+	if interval <= 0 {
+		interval = defaultInterval
+	}
+	top := cfg.Top
+	if top <= 0 {
+		top = defaultTop
+	}
+	return &Monitor{
+		logger:    logger,
+		hostID:    hostID,
+		interval:  interval,
+		top:       top,
+		hideNames: privacy.HideProcessNames,
+	}
+}
+
+// Run reports on every interval until ctx is cancelled, stopping early if
+// handle counts can't be read on this machine
+func (m *Monitor) Run(ctx context.Context, sender outbound.Sender) {
+	m.logger.Info("🔗 Handle report started", "interval", m.interval, "top", m.top)
+
+	ticker := time.NewTicker(m.interval)
+	defer ticker.Stop()
+
+	for {
+		procs, err := metrics.ListProcessHandles()
+		switch {
+		case errors.Is(err, errors.ErrUnsupported):
+			m.logger.Info("Handle report unavailable, stopping", "error", err)
+			return
+		case err != nil:
+			m.logger.Warn("Handle scan failed", "error", err)
+		default:
+			sender.Send("handles", m.report(procs, time.Now()))
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// report builds a report from the current handle counts and remembers them
+// for the next report's growth
+func (m *Monitor) report(procs []metrics.ProcessHandles, now time.Time) *Report {
+	report := &Report{Type: "handles", TS: now, HostID: m.hostID, Processes: []Process{}}
+
+	current := make(map[instance]uint32, len(procs))
+	for _, p := range procs {
+		report.Total += uint64(p.Handles)
+		current[instance{p.PID, p.Created}] = p.Handles
+	}
+
+	sort.Slice(procs, func(i, j int) bool { return procs[i].Handles > procs[j].Handles })
+	for _, p := range procs[:min(m.top, len(procs))] {
+		entry := Process{PID: p.PID, Handles: p.Handles}
+		if !m.hideNames {
+			entry.Name = p.Name
+		}
+		if prev, ok := m.previous[instance{p.PID, p.Created}]; ok {
+			entry.Growth = int64(p.Handles) - int64(prev)
+		}
+		report.Processes = append(report.Processes, entry)
+	}
+
+	m.previous = current
+	return report
+}
@@ -0,0 +1,197 @@
+// Package hostid resolves the ID the agent reports itself under. By default
+// it is derived from the OS machine ID; cloud VMs, whose machine ID resets
+// when they are re-imaged, can use their instance ID instead, and anything
+// else can supply its own ID through a file or a command.
+package hostid
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/denisbrodbeck/machineid"
+	"github.com/jcdorr003/windash-agent/internal/config"
+	"go.uber.org/zap"
+)
+
+// Provider names for hostId.providers
+const (
+	Machine = "machine" // OS machine ID, hashed (the default)
+	EC2     = "ec2"     // AWS EC2 instance ID from the instance metadata service
+	Azure   = "azure"   // Azure VM ID from the instance metadata service
+	File    = "file"    // First line of hostId.file
+	Command = "command" // Output of hostId.command
+)
+
+const (
+	metadataTimeout = 2 * time.Second // Off-cloud the metadata address doesn't answer at all
+	commandTimeout  = 10 * time.Second
+	maxIDLength     = 256
+
+	metadataURL = "http://169.254.169.254"
+)
+
+// Resolve tries the configured providers in order and returns the first ID
+// found along with the provider that supplied it. Providers that fail are
+// skipped, with a warning when a later one succeeds since the host then
+// reports under a different ID; an error is returned only when none does.
+func Resolve(ctx context.Context, logger *zap.SugaredLogger, cfg config.HostIDConfig) (id, provider string, err error) {
+	providers := cfg.Providers
+	if len(providers) == 0 {
+		providers = []string{Machine}
+	}
+
+	var errs []error
+	for _, name := range providers {
+		name = strings.ToLower(strings.TrimSpace(name))
+		id, err := lookup(ctx, name, cfg)
+		if err == nil {
+			id, err = clean(id)
+		}
+		if err != nil {
+			logger.Debug("Host ID provider failed, trying the next one", "provider", name, "error", err)
+			errs = append(errs, fmt.Errorf("%s: %w", name, err))
+			continue
+		}
+		if len(errs) > 0 {
+			logger.Warn("⚠️  Preferred host ID providers failed, using a fallback", "provider", name, "error", errors.Join(errs...))
+		}
+		return id, name, nil
+	}
+	return "", "", fmt.Errorf("no host ID provider succeeded: %w", errors.Join(errs...))
+}
+
+// lookup asks one provider for the ID
+func lookup(ctx context.Context, name string, cfg config.HostIDConfig) (string, error) {
+	switch name {
+	case Machine:
+		return machineid.ProtectedID("windash-agent")
+	case EC2:
+		return ec2InstanceID(ctx)
+	case Azure:
+		return azureVMID(ctx)
+	case File:
+		return readFile(cfg.File)
+	case Command:
+		return runCommand(ctx, cfg.Command)
+	default:
+		return "", fmt.Errorf("unknown provider (want %s, %s, %s, %s or %s)", Machine, EC2, Azure, File, Command)
+	}
+}
+
+// clean trims an ID and rejects empty, multi-line or overlong values, so a
+// misbehaving script can't report the host under garbage
+func clean(id string) (string, error) {
+	id = strings.TrimSpace(id)
+	switch {
+	case id == "":
+		return "", errors.New("empty ID")
+	case len(id) > maxIDLength:
+		return "", fmt.Errorf("ID longer than %d characters", maxIDLength)
+	case strings.ContainsAny(id, "\r\n"):
+		return "", errors.New("ID spans several lines")
+	}
+	return id, nil
+}
+
+// ec2InstanceID reads the instance ID using IMDSv2 (session token first)
+func ec2InstanceID(ctx context.Context) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, metadataTimeout)
+	defer cancel()
+
+	token, err := metadata(ctx, http.MethodPut, metadataURL+"/latest/api/token",
+		map[string]string{"X-aws-ec2-metadata-token-ttl-seconds": "60"})
+	if err != nil {
+		return "", fmt.Errorf("metadata token: %w", err)
+	}
+	return metadata(ctx, http.MethodGet, metadataURL+"/latest/meta-data/instance-id",
+		map[string]string{"X-aws-ec2-metadata-token": token})
+}
+
+// azureVMID reads the VM's unique ID from the Azure instance metadata service
+func azureVMID(ctx context.Context) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, metadataTimeout)
+	defer cancel()
+
+	return metadata(ctx, http.MethodGet, metadataURL+"/metadata/instance/compute/vmId?api-version=2021-02-01&format=text",
+		map[string]string{"Metadata": "true"})
+}
+
+// metadata makes one instance metadata request and returns the body. The
+// request bypasses any proxy: the link-local address is only reachable
+// directly.
+func metadata(ctx context.Context, method, url string, headers map[string]string) (string, error) {
+	req, err := http.NewRequestWithContext(ctx, method, url, nil)
+	if err != nil {
+		return "", err
+	}
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+	client := &http.Client{Transport: &http.Transport{Proxy: nil}}
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxIDLength+1))
+	if err != nil {
+		return "", err
+	}
+	return string(body), nil
+}
+
+// readFile returns the first line of path
+func readFile(path string) (string, error) {
+	if path == "" {
+		return "", errors.New("hostId.file is not set")
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	line, err := bufio.NewReader(io.LimitReader(f, maxIDLength+1)).ReadString('\n')
+	if err != nil && err != io.EOF {
+		return "", err
+	}
+	return line, nil
+}
+
+// runCommand runs command through the platform shell and returns its output
+func runCommand(ctx context.Context, command string) (string, error) {
+	if command == "" {
+		return "", errors.New("hostId.command is not set")
+	}
+	ctx, cancel := context.WithTimeout(ctx, commandTimeout)
+	defer cancel()
+
+	var cmd *exec.Cmd
+	if runtime.GOOS == "windows" {
+		cmd = exec.CommandContext(ctx, "cmd.exe", "/C", command)
+	} else {
+		cmd = exec.CommandContext(ctx, "sh", "-c", command)
+	}
+	out, err := cmd.Output()
+	if err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) && len(exitErr.Stderr) > 0 {
+			return "", fmt.Errorf("%w: %s", err, strings.TrimSpace(string(exitErr.Stderr)))
+		}
+		return "", err
+	}
+	return string(out), nil
+}
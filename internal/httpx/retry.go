@@ -0,0 +1,114 @@
+package httpx
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// RetryPolicy controls how Do retries rate-limited responses
+type RetryPolicy struct {
+	MaxAttempts  int           // Total attempts including the first (0 = 1)
+	DefaultDelay time.Duration // Wait used when the server sends no Retry-After
+	MaxDelay     time.Duration // Upper bound on any single wait (0 = unbounded)
+}
+
+// DefaultRetryPolicy is used by the pairing and ingestion HTTP calls
+var DefaultRetryPolicy = RetryPolicy{
+	MaxAttempts:  5,
+	DefaultDelay: 2 * time.Second,
+	MaxDelay:     2 * time.Minute,
+}
+
+// RetryAfterError reports that the server asked us to slow down
+type RetryAfterError struct {
+	StatusCode int
+	Delay      time.Duration
+}
+
+func (e *RetryAfterError) Error() string {
+	return fmt.Sprintf("rate limited (HTTP %d), retry after %s", e.StatusCode, e.Delay)
+}
+
+// Do sends req, retrying 429 (and 503 with Retry-After) responses after the
+// server-requested delay. Once attempts are exhausted the last response is
+// returned unread so the caller can handle its status as usual.
+func Do(ctx context.Context, client *http.Client, req *http.Request, policy RetryPolicy) (*http.Response, error) {
+	for attempt := 1; ; attempt++ {
+		r := req.Clone(ctx)
+		if req.GetBody != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, fmt.Errorf("failed to rewind request body: %w", err)
+			}
+			r.Body = body
+		}
+
+		resp, err := client.Do(r)
+		if err != nil {
+			return nil, err
+		}
+
+		if !ShouldRetry(resp) || attempt >= policy.MaxAttempts {
+			return resp, nil
+		}
+
+		delay := policy.clamp(RetryAfter(resp, policy.DefaultDelay))
+		io.Copy(io.Discard, resp.Body)
+		resp.Body.Close()
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(delay):
+		}
+	}
+}
+
+// ShouldRetry reports whether the response asks the client to back off and retry
+func ShouldRetry(resp *http.Response) bool {
+	switch resp.StatusCode {
+	case http.StatusTooManyRequests:
+		return true
+	case http.StatusServiceUnavailable:
+		return resp.Header.Get("Retry-After") != ""
+	default:
+		return false
+	}
+}
+
+// RetryAfter parses the Retry-After header (delta-seconds or HTTP-date),
+// returning fallback when it is missing or malformed
+func RetryAfter(resp *http.Response, fallback time.Duration) time.Duration {
+	value := resp.Header.Get("Retry-After")
+	if value == "" {
+		return fallback
+	}
+
+	if secs, err := strconv.Atoi(value); err == nil {
+		if secs < 0 {
+			return fallback
+		}
+		return time.Duration(secs) * time.Second
+	}
+
+	if at, err := http.ParseTime(value); err == nil {
+		if d := time.Until(at); d > 0 {
+			return d
+		}
+		return 0
+	}
+
+	return fallback
+}
+
+// clamp bounds a delay by the policy's MaxDelay
+func (p RetryPolicy) clamp(d time.Duration) time.Duration {
+	if p.MaxDelay > 0 && d > p.MaxDelay {
+		return p.MaxDelay
+	}
+	return d
+}
@@ -0,0 +1,77 @@
+package incident
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/jcdorr003/windash-agent/internal/alerts"
+	"github.com/jcdorr003/windash-agent/internal/config"
+	"github.com/jcdorr003/windash-agent/internal/metrics"
+	"github.com/jcdorr003/windash-agent/internal/outbound"
+	"go.uber.org/zap"
+)
+
+// defaultSamples is how many samples before the trigger are bundled
+const defaultSamples = 30
+
+// Incident bundles an alert with the samples around it so the dashboard can
+// show context without querying history
+type Incident struct {
+	Type       string              `json:"type"` // always "incident"
+	IncidentID string              `json:"incidentId"`
+	TS         time.Time           `json:"ts"`
+	HostID     string              `json:"hostId"`
+	Alert      *alerts.Alert       `json:"alert"`
+	Trigger    *metrics.SampleV1   `json:"trigger,omitempty"` // Latest sample when the alert fired
+	Samples    []*metrics.SampleV1 `json:"samples"`           // Samples before Trigger, oldest first
+}
+
+// Bundler is an outbound.Sender that replaces warning and critical alerts with
+// incidents and passes every other message through unchanged
+type Bundler struct {
+	logger  *zap.SugaredLogger
+	next    outbound.Sender
+	recent  func(n int) []*metrics.SampleV1
+	samples int
+}
+
+// NewBundler wraps next. recent returns the newest samples, oldest first
+// (see metrics.Collector.Recent), and must retain at least Size(cfg).
+func NewBundler(logger *zap.SugaredLogger, next outbound.Sender, recent func(n int) []*metrics.SampleV1, cfg config.IncidentsConfig) *Bundler {
+	return &Bundler{logger: logger, next: next, recent: recent, samples: Size(cfg) - 1}
+}
+
+// Size returns how many samples the history must retain for cfg: the
+// preceding samples plus the trigger
+func Size(cfg config.IncidentsConfig) int {
+	if cfg.Samples > 0 {
+		return cfg.Samples + 1
+	}
+	return defaultSamples + 1
+}
+
+// Send forwards msgType/payload, bundling warning and critical alerts
+func (b *Bundler) Send(msgType string, payload any) {
+	alert, ok := payload.(*alerts.Alert)
+	if msgType != "alert" || !ok || alert.Severity == alerts.SeverityInfo {
+		b.next.Send(msgType, payload)
+		return
+	}
+
+	inc := &Incident{
+		Type:       "incident",
+		IncidentID: fmt.Sprintf("inc-%s", alert.ID),
+		TS:         alert.TS,
+		HostID:     alert.HostID,
+		Alert:      alert,
+	}
+	alert.IncidentID = inc.IncidentID
+
+	history := b.recent(b.samples + 1)
+	if n := len(history); n > 0 {
+		inc.Trigger = history[n-1]
+		inc.Samples = history[:n-1]
+	}
+	b.logger.Debug("Bundled alert into incident", "incidentId", inc.IncidentID, "samples", len(inc.Samples))
+	b.next.Send("incident", inc)
+}
@@ -0,0 +1,37 @@
+package inventory
+
+import (
+	"errors"
+	"strings"
+)
+
+// errBitLockerUnsupported is returned by bitLockerVolumes off Windows
+var errBitLockerUnsupported = errors.New("BitLocker status is only available on Windows")
+
+// BitLocker is a volume's encryption state
+type BitLocker struct {
+	Status    string  `json:"status"`           // e.g. fullyEncrypted, encryptionInProgress, fullyDecrypted
+	Protected bool    `json:"protected"`        // Protection is on (keys are not exposed)
+	Suspended bool    `json:"suspended"`        // Encrypted, but protection is suspended (e.g. for an update)
+	Percent   float64 `json:"percent"`          // Share of the volume encrypted
+	Method    string  `json:"method,omitempty"` // e.g. XtsAes128
+}
+
+// addBitLocker fills in each volume's BitLocker state. Reading it needs
+// administrator rights, so failures just leave it out.
+func addBitLocker(volumes []Volume) {
+	states, err := bitLockerVolumes()
+	if err != nil {
+		return
+	}
+	for i := range volumes {
+		if state, ok := states[volumeKey(volumes[i].Mount)]; ok {
+			volumes[i].BitLocker = state
+		}
+	}
+}
+
+// volumeKey normalizes a mount point ("c:\" and "C:" are the same volume)
+func volumeKey(mount string) string {
+	return strings.ToUpper(strings.TrimRight(mount, `\`))
+}
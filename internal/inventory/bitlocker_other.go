@@ -0,0 +1,8 @@
+//go:build !windows
+
+package inventory
+
+// bitLockerVolumes is not implemented outside Windows
+func bitLockerVolumes() (map[string]*BitLocker, error) {
+	return nil, errBitLockerUnsupported
+}
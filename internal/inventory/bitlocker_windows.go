@@ -0,0 +1,82 @@
+//go:build windows
+
+package inventory
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os/exec"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// bitLockerScript lists BitLocker volumes as JSON with enums as names.
+// Get-BitLockerVolume requires an elevated process.
+const bitLockerScript = `
+$ErrorActionPreference = 'Stop'
+ConvertTo-Json -Compress -InputObject @(Get-BitLockerVolume | ForEach-Object {
+  [pscustomobject]@{
+    MountPoint = $_.MountPoint
+    VolumeStatus = "$($_.VolumeStatus)"
+    ProtectionStatus = "$($_.ProtectionStatus)"
+    EncryptionPercentage = [double]$_.EncryptionPercentage
+    EncryptionMethod = "$($_.EncryptionMethod)"
+  }
+})
+`
+
+const bitLockerTimeout = 30 * time.Second
+
+type bitLockerVolume struct {
+	MountPoint           string
+	VolumeStatus         string
+	ProtectionStatus     string
+	EncryptionPercentage float64
+	EncryptionMethod     string
+}
+
+// bitLockerVolumes returns BitLocker state keyed by volumeKey
+func bitLockerVolumes() (map[string]*BitLocker, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), bitLockerTimeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, "powershell.exe", "-NoProfile", "-NonInteractive", "-Command", bitLockerScript)
+	cmd.SysProcAttr = &syscall.SysProcAttr{HideWindow: true}
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("Get-BitLockerVolume: %w", err)
+	}
+
+	var volumes []bitLockerVolume
+	if err := json.Unmarshal(out, &volumes); err != nil {
+		return nil, fmt.Errorf("Get-BitLockerVolume output: %w", err)
+	}
+
+	states := make(map[string]*BitLocker, len(volumes))
+	for _, v := range volumes {
+		state := &BitLocker{
+			Status:    lowerFirst(v.VolumeStatus),
+			Protected: v.ProtectionStatus == "On",
+			Percent:   v.EncryptionPercentage,
+		}
+		if v.EncryptionMethod != "None" {
+			state.Method = v.EncryptionMethod
+		}
+		// Encrypted data with protection off means the keys are stored in
+		// the clear until protection is resumed
+		state.Suspended = !state.Protected && v.VolumeStatus != "FullyDecrypted"
+		states[volumeKey(v.MountPoint)] = state
+	}
+	return states, nil
+}
+
+// lowerFirst turns PowerShell enum names into JSON-style names
+// (FullyEncrypted -> fullyEncrypted)
+func lowerFirst(s string) string {
+	if s == "" {
+		return s
+	}
+	return strings.ToLower(s[:1]) + s[1:]
+}
@@ -0,0 +1,149 @@
+package inventory
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/jcdorr003/windash-agent/internal/outbound"
+	"go.uber.org/zap"
+)
+
+// minRefreshAge limits how often the inventory is recomputed, so a flapping
+// connection doesn't re-run expensive hardware queries on every reconnect
+const minRefreshAge = time.Hour
+
+// Message is the "inventory" message sent on connect and when it changes
+type Message struct {
+	Type      string     `json:"type"` // always "inventory"
+	TS        time.Time  `json:"ts"`   // When the inventory was computed
+	HostID    string     `json:"hostId"`
+	SiteID    string     `json:"siteId,omitempty"`  // Fleet site from agent.json
+	GroupID   string     `json:"groupId,omitempty"` // Fleet group from agent.json
+	Hash      string     `json:"hash"`              // Changes whenever the inventory does
+	Inventory *Inventory `json:"inventory"`
+}
+
+// Provider serves the inventory from an on-disk cache so it can be sent the
+// moment a connection opens, and refreshes it in the background
+type Provider struct {
+	logger  *zap.SugaredLogger
+	hostID  string
+	siteID  string
+	groupID string
+	path    string
+	collect func() *Inventory
+
+	mu         sync.Mutex
+	current    *Message
+	refreshing atomic.Bool
+}
+
+// NewProvider creates a provider backed by the cache file at path. A missing
+// or unreadable cache is ignored; the first refresh recreates it.
+func NewProvider(logger *zap.SugaredLogger, hostID, path string) *Provider {
+	p := &Provider{logger: logger, hostID: hostID, path: path, collect: Collect}
+
+	if data, err := os.ReadFile(path); err == nil {
+		var cached Message
+		if err := json.Unmarshal(data, &cached); err == nil && cached.Inventory != nil && cached.HostID == hostID {
+			p.current = &cached
+		}
+	}
+	return p
+}
+
+// SetFleetGroup sets the site and group reported with the inventory. A
+// cached inventory filed under another site or group is sent again on the
+// next connect. Must be called before OnConnect.
+func (p *Provider) SetFleetGroup(siteID, groupID string) {
+	p.siteID, p.groupID = siteID, groupID
+	if p.current != nil && (p.current.SiteID != siteID || p.current.GroupID != groupID) {
+		p.current = nil
+	}
+}
+
+// OnConnect sends the cached inventory immediately, then refreshes it in the
+// background and sends an update only if it changed
+func (p *Provider) OnConnect(sender outbound.Sender) {
+	p.mu.Lock()
+	cached := p.current
+	p.mu.Unlock()
+
+	if cached != nil {
+		sender.Send("inventory", cached)
+		if time.Since(cached.TS) < minRefreshAge {
+			return
+		}
+	}
+
+	if !p.refreshing.CompareAndSwap(false, true) {
+		return
+	}
+	go func() {
+		defer p.refreshing.Store(false)
+		p.Refresh(sender)
+	}()
+}
+
+// Refresh recomputes the inventory, sending and caching it if its hash changed
+func (p *Provider) Refresh(sender outbound.Sender) {
+	p.publish(p.collect(), time.Now(), sender)
+}
+
+// publish makes inv (computed at ts) current, sending and caching it if its
+// hash changed
+func (p *Provider) publish(inv *Inventory, ts time.Time, sender outbound.Sender) {
+	hash, err := hashInventory(inv)
+	if err != nil {
+		p.logger.Warn("Failed to hash inventory", "error", err)
+		return
+	}
+	msg := &Message{Type: "inventory", TS: ts, HostID: p.hostID, SiteID: p.siteID, GroupID: p.groupID, Hash: hash, Inventory: inv}
+
+	p.mu.Lock()
+	changed := p.current == nil || p.current.Hash != hash
+	p.current = msg
+	p.mu.Unlock()
+
+	if changed {
+		p.logger.Info("🧾 Inventory changed", "hash", hash)
+		sender.Send("inventory", msg)
+	}
+	// Save even when unchanged so TS records the last refresh
+	if err := p.save(msg); err != nil {
+		p.logger.Warn("Failed to cache inventory", "path", p.path, "error", err)
+	}
+}
+
+// save writes msg to the cache file
+func (p *Provider) save(msg *Message) error {
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	tmp := p.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, p.path)
+}
+
+// hashInventory returns a short content hash of inv
+func hashInventory(inv *Inventory) (string, error) {
+	data, err := json.Marshal(inv)
+	if err != nil {
+		return "", err
+	}
+	return hashBytes(data), nil
+}
+
+// hashBytes returns a short content hash of data
+func hashBytes(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:12])
+}
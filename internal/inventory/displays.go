@@ -0,0 +1,64 @@
+package inventory
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"time"
+
+	"github.com/jcdorr003/windash-agent/internal/outbound"
+)
+
+// displayCheckInterval is how often the display configuration is compared
+// against the cached inventory
+const displayCheckInterval = time.Minute
+
+// errDisplaysUnsupported is returned by listDisplays off Windows
+var errDisplaysUnsupported = errors.New("display enumeration is only supported on Windows")
+
+// Display is one monitor attached to the desktop of the agent's session
+type Display struct {
+	Name      string `json:"name"`              // Adapter output, e.g. \\.\DISPLAY1
+	Monitor   string `json:"monitor,omitempty"` // Monitor model
+	Width     int    `json:"width"`             // Pixels
+	Height    int    `json:"height"`            // Pixels
+	RefreshHz int    `json:"refreshHz,omitempty"`
+	Primary   bool   `json:"primary,omitempty"`
+}
+
+// WatchDisplays re-sends the inventory shortly after the display
+// configuration changes (a monitor plugged in, resolution changed) instead
+// of waiting for the next full refresh
+func (p *Provider) WatchDisplays(ctx context.Context, sender outbound.Sender) {
+	ticker := time.NewTicker(displayCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		displays, err := listDisplays()
+		if errors.Is(err, errDisplaysUnsupported) {
+			return
+		}
+		if err != nil {
+			p.logger.Debug("Display enumeration failed", "error", err)
+			continue
+		}
+
+		p.mu.Lock()
+		cached := p.current
+		p.mu.Unlock()
+		if cached == nil || slices.Equal(cached.Inventory.Displays, displays) {
+			continue
+		}
+
+		inv := *cached.Inventory
+		inv.Displays = displays
+		p.logger.Info("🖥️  Display configuration changed", "displays", len(displays))
+		p.publish(&inv, cached.TS, sender) // Only displays were refreshed
+	}
+}
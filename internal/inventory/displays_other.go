@@ -0,0 +1,8 @@
+//go:build !windows
+
+package inventory
+
+// listDisplays is not implemented outside Windows
+func listDisplays() ([]Display, error) {
+	return nil, errDisplaysUnsupported
+}
@@ -0,0 +1,97 @@
+//go:build windows
+
+package inventory
+
+import (
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+)
+
+var (
+	moduser32                = windows.NewLazySystemDLL("user32.dll")
+	procEnumDisplayDevicesW  = moduser32.NewProc("EnumDisplayDevicesW")
+	procEnumDisplaySettingsW = moduser32.NewProc("EnumDisplaySettingsW")
+)
+
+const (
+	displayAttachedToDesktop = 0x1
+	displayPrimaryDevice     = 0x4
+	enumCurrentSettings      = 0xFFFFFFFF
+)
+
+// displayDevice is DISPLAY_DEVICEW
+type displayDevice struct {
+	cb           uint32
+	deviceName   [32]uint16
+	deviceString [128]uint16
+	stateFlags   uint32
+	deviceID     [128]uint16
+	deviceKey    [128]uint16
+}
+
+// devMode is DEVMODEW (display variant)
+type devMode struct {
+	deviceName       [32]uint16
+	specVersion      uint16
+	driverVersion    uint16
+	size             uint16
+	driverExtra      uint16
+	fields           uint32
+	positionX        int32
+	positionY        int32
+	orientation      uint32
+	fixedOutput      uint32
+	color            int16
+	duplex           int16
+	yResolution      int16
+	ttOption         int16
+	collate          int16
+	formName         [32]uint16
+	logPixels        uint16
+	bitsPerPel       uint32
+	pelsWidth        uint32
+	pelsHeight       uint32
+	displayFlags     uint32
+	displayFrequency uint32
+	_                [8]uint32 // ICM and panning fields
+}
+
+// listDisplays lists the monitors attached to this session's desktop with
+// their current mode. A service in session 0 sees none.
+func listDisplays() ([]Display, error) {
+	var displays []Display
+	for i := uint32(0); ; i++ {
+		adapter := displayDevice{cb: uint32(unsafe.Sizeof(displayDevice{}))}
+		if r, _, _ := procEnumDisplayDevicesW.Call(0, uintptr(i), uintptr(unsafe.Pointer(&adapter)), 0); r == 0 {
+			break
+		}
+		if adapter.stateFlags&displayAttachedToDesktop == 0 {
+			continue
+		}
+
+		mode := devMode{size: uint16(unsafe.Sizeof(devMode{}))}
+		if r, _, _ := procEnumDisplaySettingsW.Call(uintptr(unsafe.Pointer(&adapter.deviceName[0])), enumCurrentSettings, uintptr(unsafe.Pointer(&mode))); r == 0 {
+			continue
+		}
+
+		d := Display{
+			Name:      windows.UTF16ToString(adapter.deviceName[:]),
+			Width:     int(mode.pelsWidth),
+			Height:    int(mode.pelsHeight),
+			RefreshHz: int(mode.displayFrequency),
+			Primary:   adapter.stateFlags&displayPrimaryDevice != 0,
+		}
+		if d.RefreshHz <= 1 {
+			d.RefreshHz = 0 // 0 and 1 mean "hardware default"
+		}
+
+		// The monitor on this output is the adapter's first child device
+		monitor := displayDevice{cb: uint32(unsafe.Sizeof(displayDevice{}))}
+		if r, _, _ := procEnumDisplayDevicesW.Call(uintptr(unsafe.Pointer(&adapter.deviceName[0])), 0, uintptr(unsafe.Pointer(&monitor)), 0); r != 0 {
+			d.Monitor = windows.UTF16ToString(monitor.deviceString[:])
+		}
+		displays = append(displays, d)
+	}
+	return displays, nil
+}
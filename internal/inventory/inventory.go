@@ -0,0 +1,83 @@
+package inventory
+
+import (
+	"runtime"
+	"time"
+
+	"github.com/shirou/gopsutil/v4/cpu"
+	"github.com/shirou/gopsutil/v4/disk"
+	"github.com/shirou/gopsutil/v4/host"
+	"github.com/shirou/gopsutil/v4/mem"
+)
+
+// Inventory describes the host's hardware and OS. It changes rarely and is
+// comparatively expensive to gather, so it is sent separately from samples.
+type Inventory struct {
+	Hostname        string    `json:"hostname"`
+	OS              string    `json:"os"`
+	Platform        string    `json:"platform"`
+	PlatformVersion string    `json:"platformVersion"`
+	KernelVersion   string    `json:"kernelVersion,omitempty"`
+	Arch            string    `json:"arch"`
+	CPUModel        string    `json:"cpuModel,omitempty"`
+	CPUCores        int       `json:"cpuCores"`   // Physical cores
+	CPUThreads      int       `json:"cpuThreads"` // Logical processors
+	MemTotal        uint64    `json:"memTotal"`   // Physical memory in bytes
+	BootTime        time.Time `json:"bootTime,omitzero"`
+	Volumes         []Volume  `json:"volumes,omitempty"`
+	Displays        []Display `json:"displays,omitempty"`
+}
+
+// Volume describes one mounted filesystem
+type Volume struct {
+	Mount     string     `json:"mount"`
+	FSType    string     `json:"fsType,omitempty"`
+	Total     uint64     `json:"total"`               // Size in bytes
+	BitLocker *BitLocker `json:"bitLocker,omitempty"` // Windows only, when running elevated
+}
+
+// Collect gathers the host inventory. Individual lookups that fail are left
+// empty rather than failing the whole inventory.
+func Collect() *Inventory {
+	inv := &Inventory{Arch: runtime.GOARCH, OS: runtime.GOOS}
+
+	if info, err := host.Info(); err == nil {
+		inv.Hostname = info.Hostname
+		inv.OS = info.OS
+		inv.Platform = info.Platform
+		inv.PlatformVersion = info.PlatformVersion
+		inv.KernelVersion = info.KernelVersion
+		inv.BootTime = time.Unix(int64(info.BootTime), 0)
+	}
+
+	if infos, err := cpu.Info(); err == nil && len(infos) > 0 {
+		inv.CPUModel = infos[0].ModelName
+	}
+	if n, err := cpu.Counts(false); err == nil {
+		inv.CPUCores = n
+	}
+	if n, err := cpu.Counts(true); err == nil {
+		inv.CPUThreads = n
+	}
+
+	if vm, err := mem.VirtualMemory(); err == nil {
+		inv.MemTotal = vm.Total
+	}
+
+	if partitions, err := disk.Partitions(false); err == nil {
+		for _, p := range partitions {
+			v := Volume{Mount: p.Mountpoint, FSType: p.Fstype}
+			if usage, err := disk.Usage(p.Mountpoint); err == nil {
+				v.Total = usage.Total
+			}
+			inv.Volumes = append(inv.Volumes, v)
+		}
+		addBitLocker(inv.Volumes)
+	}
+
+	if displays, err := listDisplays(); err == nil {
+		inv.Displays = displays
+	}
+
+	return inv
+}
@@ -0,0 +1,211 @@
+package inventory
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"time"
+
+	"github.com/jcdorr003/windash-agent/internal/config"
+	"github.com/jcdorr003/windash-agent/internal/outbound"
+	"go.uber.org/zap"
+)
+
+const (
+	defaultSoftwareInterval = 24 * time.Hour
+
+	// softwareStartDelay keeps an overdue scan clear of the agent's startup
+	softwareStartDelay = 5 * time.Minute
+
+	// softwareFullEvery is how often the whole list is sent again instead of
+	// a diff, so a backend that missed a diff catches up
+	softwareFullEvery = 7 * 24 * time.Hour
+)
+
+// errSoftwareUnsupported is returned by listPrograms outside Windows
+var errSoftwareUnsupported = errors.New("installed software inventory is only supported on Windows")
+
+// Program is one installed program as listed in Apps & features
+type Program struct {
+	Name      string `json:"name"`
+	Version   string `json:"version,omitempty"`
+	Publisher string `json:"publisher,omitempty"`
+	Installed string `json:"installed,omitempty"` // Install date (YYYY-MM-DD), when the installer recorded one
+}
+
+// key identifies a program in diffs; an upgrade is a removal and an addition
+func (p Program) key() string {
+	return p.Name + "\x00" + p.Version + "\x00" + p.Publisher
+}
+
+// SoftwareMessage is the "inventory" message with kind "software". The
+// first one (and one every week) carries the whole list in Programs; the
+// others only what was Added and Removed since the list with BaseHash.
+type SoftwareMessage struct {
+	Type     string    `json:"type"` // always "inventory"
+	Kind     string    `json:"kind"` // always "software"
+	TS       time.Time `json:"ts"`
+	HostID   string    `json:"hostId"`
+	Full     bool      `json:"full"`
+	Hash     string    `json:"hash"`               // Hash of the complete list after this message
+	BaseHash string    `json:"baseHash,omitempty"` // Diffs: hash of the list they apply to
+	Count    int       `json:"count"`              // Programs installed
+	Programs []Program `json:"programs,omitempty"` // Full: every program
+	Added    []Program `json:"added,omitempty"`
+	Removed  []Program `json:"removed,omitempty"`
+}
+
+// softwareCache is the last list sent (software.json)
+type softwareCache struct {
+	HostID   string    `json:"hostId"`
+	TS       time.Time `json:"ts"`     // Last scan
+	FullTS   time.Time `json:"fullTs"` // Last full list sent
+	Hash     string    `json:"hash"`
+	Programs []Program `json:"programs"`
+}
+
+// SoftwareScanner lists the installed programs on a slow schedule and sends
+// what changed since the previous scan
+type SoftwareScanner struct {
+	logger   *zap.SugaredLogger
+	hostID   string
+	path     string // Cache of the last list sent
+	interval time.Duration
+}
+
+// NewSoftwareScanner creates a scanner that keeps the last list sent in path
+func NewSoftwareScanner(logger *zap.SugaredLogger, hostID, path string, cfg config.SoftwareConfig) *SoftwareScanner {
+	interval := time.Duration(cfg.IntervalHours) * time.Hour
+	if interval <= 0 {
+		interval = defaultSoftwareInterval
+	}
+	return &SoftwareScanner{logger: logger, hostID: hostID, path: path, interval: interval}
+}
+
+// Run scans every interval until ctx is cancelled. The schedule follows the
+// last scan in the cache, so restarts don't cause extra scans.
+func (s *SoftwareScanner) Run(ctx context.Context, sender outbound.Sender) {
+	next := time.Now().Add(softwareStartDelay)
+	if cache := s.load(); cache != nil && cache.TS.Add(s.interval).After(next) {
+		next = cache.TS.Add(s.interval)
+	}
+
+	for {
+		s.logger.Debug("Next software inventory scheduled", "at", next)
+		timer := time.NewTimer(time.Until(next))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+
+		if err := s.Scan(sender); err != nil {
+			s.logger.Warn("Software inventory failed", "error", err)
+			if errors.Is(err, errSoftwareUnsupported) {
+				return
+			}
+		}
+		next = time.Now().Add(s.interval)
+	}
+}
+
+// Scan lists the installed programs now and sends the full list or the
+// changes since the cached one
+func (s *SoftwareScanner) Scan(sender outbound.Sender) error {
+	programs, err := listPrograms()
+	if err != nil {
+		return err
+	}
+	slices.SortFunc(programs, func(a, b Program) int { return cmp.Compare(a.key(), b.key()) })
+	programs = slices.CompactFunc(programs, func(a, b Program) bool { return a.key() == b.key() })
+	hash, err := hashPrograms(programs)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	cache := s.load()
+	msg := &SoftwareMessage{Type: "inventory", Kind: "software", TS: now, HostID: s.hostID, Hash: hash, Count: len(programs)}
+	switch {
+	case cache == nil || now.Sub(cache.FullTS) >= softwareFullEvery:
+		msg.Full, msg.Programs = true, programs
+		cache = &softwareCache{HostID: s.hostID, FullTS: now}
+	case cache.Hash == hash:
+		msg = nil // Nothing changed
+	default:
+		msg.BaseHash = cache.Hash
+		msg.Added, msg.Removed = diffPrograms(cache.Programs, programs)
+	}
+
+	if msg != nil {
+		s.logger.Info("🧾 Software inventory changed", "programs", len(programs), "full", msg.Full, "added", len(msg.Added), "removed", len(msg.Removed))
+		sender.Send("inventory", msg)
+	}
+	cache.TS, cache.Hash, cache.Programs = now, hash, programs
+	if err := s.save(cache); err != nil {
+		s.logger.Warn("Failed to cache software inventory", "path", s.path, "error", err)
+	}
+	return nil
+}
+
+// load returns the cached list, or nil if there is none for this host
+func (s *SoftwareScanner) load() *softwareCache {
+	data, err := os.ReadFile(s.path)
+	if err != nil {
+		return nil
+	}
+	var cache softwareCache
+	if err := json.Unmarshal(data, &cache); err != nil || cache.HostID != s.hostID {
+		return nil
+	}
+	return &cache
+}
+
+// save replaces the cache file
+func (s *SoftwareScanner) save(cache *softwareCache) error {
+	data, err := json.Marshal(cache)
+	if err != nil {
+		return err
+	}
+	tmp := s.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, s.path)
+}
+
+// diffPrograms returns the programs in next but not prev, and in prev but
+// not next
+func diffPrograms(prev, next []Program) (added, removed []Program) {
+	before := make(map[string]bool, len(prev))
+	for _, p := range prev {
+		before[p.key()] = true
+	}
+	after := make(map[string]bool, len(next))
+	for _, p := range next {
+		after[p.key()] = true
+		if !before[p.key()] {
+			added = append(added, p)
+		}
+	}
+	for _, p := range prev {
+		if !after[p.key()] {
+			removed = append(removed, p)
+		}
+	}
+	return added, removed
+}
+
+// hashPrograms returns a short content hash of a sorted program list
+func hashPrograms(programs []Program) (string, error) {
+	data, err := json.Marshal(programs)
+	if err != nil {
+		return "", fmt.Errorf("hash programs: %w", err)
+	}
+	return hashBytes(data), nil
+}
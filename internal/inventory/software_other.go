@@ -0,0 +1,8 @@
+//go:build !windows
+
+package inventory
+
+// listPrograms is not implemented outside Windows
+func listPrograms() ([]Program, error) {
+	return nil, errSoftwareUnsupported
+}
@@ -0,0 +1,88 @@
+//go:build windows
+
+package inventory
+
+import (
+	"errors"
+	"fmt"
+
+	"golang.org/x/sys/windows/registry"
+)
+
+const uninstallKey = `SOFTWARE\Microsoft\Windows\CurrentVersion\Uninstall`
+
+// uninstallRoots are the Uninstall keys Apps & features reads: machine-wide
+// 64- and 32-bit installs, and per-user installs of the agent's user
+var uninstallRoots = []struct {
+	root   registry.Key
+	access uint32
+}{
+	{registry.LOCAL_MACHINE, registry.WOW64_64KEY},
+	{registry.LOCAL_MACHINE, registry.WOW64_32KEY},
+	{registry.CURRENT_USER, 0},
+}
+
+// listPrograms reads the installed programs from the Uninstall keys,
+// leaving out system components and updates as Apps & features does
+func listPrograms() ([]Program, error) {
+	var programs []Program
+	opened := false
+	for _, r := range uninstallRoots {
+		k, err := registry.OpenKey(r.root, uninstallKey, registry.ENUMERATE_SUB_KEYS|registry.QUERY_VALUE|r.access)
+		if errors.Is(err, registry.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("open %s: %w", uninstallKey, err)
+		}
+		opened = true
+		names, err := k.ReadSubKeyNames(-1)
+		if err != nil {
+			k.Close()
+			return nil, fmt.Errorf("list %s: %w", uninstallKey, err)
+		}
+		for _, name := range names {
+			if p, ok := readProgram(k, name, r.access); ok {
+				programs = append(programs, p)
+			}
+		}
+		k.Close()
+	}
+	if !opened {
+		return nil, errSoftwareUnsupported
+	}
+	return programs, nil
+}
+
+// readProgram reads one Uninstall entry, reporting false for entries Apps &
+// features hides
+func readProgram(parent registry.Key, name string, access uint32) (Program, bool) {
+	k, err := registry.OpenKey(parent, name, registry.QUERY_VALUE|access)
+	if err != nil {
+		return Program{}, false
+	}
+	defer k.Close()
+
+	display, _, err := k.GetStringValue("DisplayName")
+	if err != nil || display == "" {
+		return Program{}, false
+	}
+	if v, _, err := k.GetIntegerValue("SystemComponent"); err == nil && v == 1 {
+		return Program{}, false
+	}
+	if parent, _, err := k.GetStringValue("ParentKeyName"); err == nil && parent != "" {
+		return Program{}, false // An update of another entry
+	}
+	switch release, _, _ := k.GetStringValue("ReleaseType"); release {
+	case "Update", "Hotfix", "Security Update", "Update Rollup":
+		return Program{}, false
+	}
+
+	p := Program{Name: display}
+	p.Version, _, _ = k.GetStringValue("DisplayVersion")
+	p.Publisher, _, _ = k.GetStringValue("Publisher")
+	if date, _, err := k.GetStringValue("InstallDate"); err == nil && len(date) == 8 {
+		p.Installed = date[:4] + "-" + date[4:6] + "-" + date[6:]
+	}
+	return p, true
+}
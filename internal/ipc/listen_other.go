@@ -0,0 +1,40 @@
+//go:build !windows
+
+package ipc
+
+import (
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+
+	"github.com/jcdorr003/windash-agent/internal/config"
+)
+
+// Address returns the path scripts connect to
+func Address() string {
+	return filepath.Join(config.GetConfigDir(), "agent.sock")
+}
+
+// socketListener serves the protocol on a Unix socket for development
+type socketListener struct {
+	net.Listener
+}
+
+func listen() (listener, error) {
+	path := Address()
+	os.Remove(path) // Stale socket from a previous run
+	l, err := net.Listen("unix", path)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.Chmod(path, 0600); err != nil {
+		l.Close()
+		return nil, err
+	}
+	return &socketListener{l}, nil
+}
+
+func (l *socketListener) Accept() (io.ReadWriteCloser, error) {
+	return l.Listener.Accept()
+}
@@ -0,0 +1,90 @@
+//go:build windows
+
+package ipc
+
+import (
+	"io"
+	"net"
+	"os"
+	"sync/atomic"
+
+	"golang.org/x/sys/windows"
+)
+
+// pipeName is the named pipe scripts connect to
+const pipeName = `\\.\pipe\windash-agent`
+
+// Address returns the path scripts connect to
+func Address() string {
+	return pipeName
+}
+
+// pipeListener creates a new pipe instance for each client. The pipe uses
+// the default security descriptor, so only the user running the agent,
+// administrators and SYSTEM can write to it, and remote clients are rejected.
+type pipeListener struct {
+	name   *uint16
+	first  bool
+	closed atomic.Bool
+}
+
+func listen() (listener, error) {
+	name, err := windows.UTF16PtrFromString(pipeName)
+	if err != nil {
+		return nil, err
+	}
+	return &pipeListener{name: name, first: true}, nil
+}
+
+// Accept waits for a client on a fresh pipe instance
+func (l *pipeListener) Accept() (io.ReadWriteCloser, error) {
+	flags := uint32(windows.PIPE_ACCESS_DUPLEX)
+	if l.first {
+		// Fail if another process already owns the name
+		flags |= windows.FILE_FLAG_FIRST_PIPE_INSTANCE
+		l.first = false
+	}
+	h, err := windows.CreateNamedPipe(l.name, flags,
+		windows.PIPE_TYPE_BYTE|windows.PIPE_READMODE_BYTE|windows.PIPE_WAIT|windows.PIPE_REJECT_REMOTE_CLIENTS,
+		windows.PIPE_UNLIMITED_INSTANCES, 4096, 4096, 0, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	err = windows.ConnectNamedPipe(h, nil)
+	if err != nil && err != windows.ERROR_PIPE_CONNECTED {
+		windows.CloseHandle(h)
+		return nil, err
+	}
+	if l.closed.Load() {
+		windows.CloseHandle(h)
+		return nil, net.ErrClosed
+	}
+	return &pipeConn{File: os.NewFile(uintptr(h), pipeName), h: h}, nil
+}
+
+// Close stops Accept. ConnectNamedPipe can't be cancelled, so a throwaway
+// client connection wakes it up.
+func (l *pipeListener) Close() error {
+	if l.closed.Swap(true) {
+		return nil
+	}
+	h, err := windows.CreateFile(l.name, windows.GENERIC_READ|windows.GENERIC_WRITE, 0, nil, windows.OPEN_EXISTING, 0, 0)
+	if err == nil {
+		windows.CloseHandle(h)
+	}
+	return nil
+}
+
+// pipeConn is one connected pipe instance
+type pipeConn struct {
+	*os.File
+	h windows.Handle
+}
+
+// Close flushes pending output to the client before disconnecting
+func (c *pipeConn) Close() error {
+	windows.FlushFileBuffers(c.h)
+	windows.DisconnectNamedPipe(c.h)
+	return c.File.Close()
+}
@@ -0,0 +1,238 @@
+package ipc
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"time"
+
+	"github.com/jcdorr003/windash-agent/internal/alerts"
+	"github.com/jcdorr003/windash-agent/internal/config"
+	"github.com/jcdorr003/windash-agent/internal/metrics"
+	"github.com/jcdorr003/windash-agent/internal/state"
+	"github.com/jcdorr003/windash-agent/internal/ws"
+	"go.uber.org/zap"
+)
+
+// maxRequestSize caps one request line
+const maxRequestSize = 64 * 1024
+
+// Request is one line of newline-delimited JSON sent by a script
+type Request struct {
+	ID         string            `json:"id,omitempty"` // Echoed in the response
+	Op         string            `json:"op"`           // status, fields, pause, resume, setInterval, tag, testAlert
+	IntervalMs int               `json:"intervalMs,omitempty"`
+	Tags       map[string]string `json:"tags,omitempty"` // tag: values to set; an empty value removes the tag
+	Severity   string            `json:"severity,omitempty"`
+	Title      string            `json:"title,omitempty"`
+}
+
+// Response answers a Request on the same connection
+type Response struct {
+	ID     string `json:"id,omitempty"`
+	OK     bool   `json:"ok"`
+	Result any    `json:"result,omitempty"`
+	Error  string `json:"error,omitempty"`
+}
+
+// Status is the result of the status op
+type Status struct {
+	Version   string            `json:"version"`
+	Env       string            `json:"env"`
+	HostID    string            `json:"hostId"`
+	Connected bool              `json:"connected"`
+	Paused    bool              `json:"paused"`
+	Buffered  int               `json:"buffered"`
+	Dropped   uint64            `json:"dropped"`
+	Tags      map[string]string `json:"tags,omitempty"`
+
+	Connection ws.ConnectionState  `json:"connection"`        // Phase, retries and last error of the backend connection
+	Traffic    *state.TrafficUsage `json:"traffic,omitempty"` // Backend traffic today and this month
+}
+
+// Collector controls sampling
+type Collector interface {
+	SetInterval(interval time.Duration) error
+	Pause()
+	Resume()
+	Paused() bool
+}
+
+// Uplink is the connection to the backend
+type Uplink interface {
+	Send(msgType string, payload any)
+	Status() *ws.StatusMessage
+	Connected() bool
+	ConnectionState() ws.ConnectionState
+	SetTags(tags map[string]string)
+}
+
+// listener accepts local connections (a named pipe on Windows, a Unix
+// socket elsewhere)
+type listener interface {
+	Accept() (io.ReadWriteCloser, error)
+	Close() error
+}
+
+// Server exposes agent operations to local scripts over newline-delimited
+// JSON, one response per request
+type Server struct {
+	logger    *zap.SugaredLogger
+	cfg       *config.Config
+	hostID    string
+	collector Collector
+	uplink    Uplink
+}
+
+// NewServer creates an IPC server
+func NewServer(logger *zap.SugaredLogger, cfg *config.Config, hostID string, collector Collector, uplink Uplink) *Server {
+	return &Server{logger: logger, cfg: cfg, hostID: hostID, collector: collector, uplink: uplink}
+}
+
+// Run serves connections until ctx is cancelled
+func (s *Server) Run(ctx context.Context) {
+	l, err := listen()
+	if err != nil {
+		s.logger.Warn("IPC disabled", "error", err)
+		return
+	}
+	s.logger.Info("🔌 IPC listening", "address", Address())
+
+	go func() {
+		<-ctx.Done()
+		l.Close()
+	}()
+
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			if ctx.Err() == nil {
+				s.logger.Warn("IPC accept failed, stopping", "error", err)
+			}
+			return
+		}
+		go s.serve(conn)
+	}
+}
+
+// serve answers requests on one connection until the client disconnects
+func (s *Server) serve(conn io.ReadWriteCloser) {
+	defer conn.Close()
+
+	scanner := bufio.NewScanner(conn)
+	scanner.Buffer(make([]byte, 4096), maxRequestSize)
+	enc := json.NewEncoder(conn)
+	for scanner.Scan() {
+		var req Request
+		resp := Response{OK: true}
+		if err := json.Unmarshal(scanner.Bytes(), &req); err != nil {
+			resp = Response{Error: fmt.Sprintf("invalid request: %v", err)}
+		} else {
+			resp.ID = req.ID
+			result, err := s.handle(&req)
+			if err != nil {
+				resp.OK = false
+				resp.Error = err.Error()
+			}
+			resp.Result = result
+		}
+		if err := enc.Encode(resp); err != nil {
+			return
+		}
+	}
+}
+
+// handle applies one request
+func (s *Server) handle(req *Request) (any, error) {
+	s.logger.Debug("IPC request", "op", req.Op)
+	switch req.Op {
+	case "status":
+		return s.status(), nil
+	case "fields":
+		return metrics.NewFieldsMessage(s.cfg.AgentVersion), nil
+	case "pause":
+		s.collector.Pause()
+		return map[string]bool{"paused": true}, nil
+	case "resume":
+		s.collector.Resume()
+		return map[string]bool{"paused": false}, nil
+	case "setInterval":
+		if err := s.collector.SetInterval(time.Duration(req.IntervalMs) * time.Millisecond); err != nil {
+			return nil, err
+		}
+		s.logger.Info("🔧 Metrics interval set over IPC", "intervalMs", req.IntervalMs)
+		return map[string]int{"intervalMs": req.IntervalMs}, nil
+	case "tag":
+		return s.tag(req.Tags)
+	case "testAlert":
+		return s.testAlert(req)
+	default:
+		return nil, fmt.Errorf("unknown op %q", req.Op)
+	}
+}
+
+// status reports the agent's state
+func (s *Server) status() *Status {
+	st := s.uplink.Status()
+	return &Status{
+		Version:    st.Version,
+		Env:        s.cfg.Env,
+		HostID:     s.hostID,
+		Connected:  s.uplink.Connected(),
+		Paused:     s.collector.Paused(),
+		Buffered:   st.Buffered,
+		Dropped:    st.Dropped,
+		Tags:       st.Tags,
+		Connection: s.uplink.ConnectionState(),
+		Traffic:    st.Traffic,
+	}
+}
+
+// tag merges tags into the host's tags, persists them to agent.json and
+// reports them to the server
+func (s *Server) tag(changes map[string]string) (any, error) {
+	if len(changes) == 0 {
+		return nil, errors.New("tags is required")
+	}
+	tags := maps.Clone(s.uplink.Status().Tags)
+	if tags == nil {
+		tags = map[string]string{}
+	}
+	for k, v := range changes {
+		if v == "" {
+			delete(tags, k)
+		} else {
+			tags[k] = v
+		}
+	}
+	if err := config.UpdateFile(map[string]any{"tags": tags}); err != nil {
+		return nil, fmt.Errorf("failed to save tags: %w", err)
+	}
+	s.uplink.SetTags(tags)
+	s.logger.Info("🏷️  Host tags updated over IPC", "tags", tags)
+	return map[string]any{"tags": tags}, nil
+}
+
+// testAlert sends an alert so scripts can check alert routing end to end
+func (s *Server) testAlert(req *Request) (any, error) {
+	severity := req.Severity
+	switch severity {
+	case "":
+		severity = alerts.SeverityInfo
+	case alerts.SeverityInfo, alerts.SeverityWarning, alerts.SeverityCritical:
+	default:
+		return nil, fmt.Errorf("invalid severity %q", severity)
+	}
+	title := req.Title
+	if title == "" {
+		title = "Test alert"
+	}
+	alert := alerts.New(s.hostID, "ipc", severity, title, "Fired from the local scripting API")
+	alert.Labels = map[string]string{"test": "true"}
+	s.uplink.Send("alert", alert)
+	return map[string]string{"id": alert.ID}, nil
+}
@@ -0,0 +1,57 @@
+package links
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/pkg/browser"
+)
+
+// Views lists the dashboard views that can be opened by name
+var Views = []string{"dashboard", "host", "alerts", "pair"}
+
+// Dashboard returns the dashboard root
+func Dashboard(dashboardURL string) string {
+	return strings.TrimRight(dashboardURL, "/")
+}
+
+// Host returns the dashboard page of one host
+func Host(dashboardURL, hostID string) string {
+	return Dashboard(dashboardURL) + "/hosts/" + url.PathEscape(hostID)
+}
+
+// Alerts returns the alerts view filtered to one host
+func Alerts(dashboardURL, hostID string) string {
+	return Dashboard(dashboardURL) + "/alerts?" + url.Values{"host": {hostID}}.Encode()
+}
+
+// Pairing returns the pairing page, pre-filled with code when it is set
+func Pairing(dashboardURL, code string) string {
+	u := Dashboard(dashboardURL) + "/pair"
+	if code != "" {
+		u += "?" + url.Values{"code": {code}}.Encode()
+	}
+	return u
+}
+
+// View returns the link for a view named in Views
+func View(name, dashboardURL, hostID string) (string, error) {
+	switch name {
+	case "dashboard":
+		return Dashboard(dashboardURL), nil
+	case "host":
+		return Host(dashboardURL, hostID), nil
+	case "alerts":
+		return Alerts(dashboardURL, hostID), nil
+	case "pair":
+		return Pairing(dashboardURL, ""), nil
+	default:
+		return "", fmt.Errorf("unknown view %q (valid: %s)", name, strings.Join(Views, ", "))
+	}
+}
+
+// Open opens link in the default browser
+func Open(link string) error {
+	return browser.OpenURL(link)
+}
@@ -0,0 +1,113 @@
+package maintenance
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/jcdorr003/windash-agent/internal/config"
+	"github.com/jcdorr003/windash-agent/internal/outbound"
+	"github.com/shirou/gopsutil/v4/process"
+	"go.uber.org/zap"
+)
+
+const (
+	memoryCheckInterval = time.Minute
+	defaultRSSMinutes   = 5
+)
+
+// AgentError is the "agentError" message reporting a problem with the agent itself
+type AgentError struct {
+	Type   string    `json:"type"` // always "agentError"
+	TS     time.Time `json:"ts"`
+	HostID string    `json:"hostId"`
+	Kind   string    `json:"kind"` // e.g. "memoryCap"
+	Detail string    `json:"detail"`
+
+	RSS      uint64 `json:"rss,omitempty"`      // Resident memory in bytes (memoryCap)
+	RSSLimit uint64 `json:"rssLimit,omitempty"` // Configured cap in bytes (memoryCap)
+}
+
+// MemoryGuard restarts the agent when its resident memory stays above
+// cfg.MaxRSSMB for cfg.MaxRSSMinutes, so a slow leak can't grow unbounded
+// on machines that are never rebooted
+type MemoryGuard struct {
+	logger    *zap.SugaredLogger
+	hostID    string
+	limit     uint64
+	grace     time.Duration
+	restarter *Restarter
+}
+
+// NewMemoryGuard creates a guard that requests restarts from restarter
+func NewMemoryGuard(logger *zap.SugaredLogger, hostID string, cfg config.MaintenanceConfig, restarter *Restarter) *MemoryGuard {
+	minutes := cfg.MaxRSSMinutes
+	if minutes <= 0 {
+		minutes = defaultRSSMinutes
+	}
+	return &MemoryGuard{
+		logger:    logger,
+		hostID:    hostID,
+		limit:     uint64(cfg.MaxRSSMB) << 20,
+		grace:     time.Duration(minutes) * time.Minute,
+		restarter: restarter,
+	}
+}
+
+// Run checks the agent's RSS every minute until ctx is cancelled. Returns
+// immediately when no cap is configured.
+func (g *MemoryGuard) Run(ctx context.Context, sender outbound.Sender) {
+	if g.limit == 0 {
+		return
+	}
+	self, err := process.NewProcess(int32(os.Getpid()))
+	if err != nil {
+		g.logger.Warn("Memory guard disabled", "error", err)
+		return
+	}
+	g.logger.Info("🛡️  Memory guard started", "maxRssMB", g.limit>>20, "grace", g.grace)
+
+	ticker := time.NewTicker(memoryCheckInterval)
+	defer ticker.Stop()
+
+	var over time.Time // When RSS first exceeded the cap (zero = under it)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		info, err := self.MemoryInfoWithContext(ctx)
+		if err != nil {
+			g.logger.Debug("Failed to read agent memory", "error", err)
+			continue
+		}
+		if info.RSS <= g.limit {
+			over = time.Time{}
+			continue
+		}
+		if over.IsZero() {
+			over = time.Now()
+			g.logger.Warn("⚠️  Agent memory above cap", "rssMB", info.RSS>>20, "maxRssMB", g.limit>>20)
+		}
+		if time.Since(over) < g.grace {
+			continue
+		}
+
+		detail := fmt.Sprintf("Agent RSS %d MB above %d MB for %s, restarting", info.RSS>>20, g.limit>>20, g.grace)
+		g.logger.Error("🛡️  " + detail)
+		sender.Send("agentError", &AgentError{
+			Type:     "agentError",
+			TS:       time.Now(),
+			HostID:   g.hostID,
+			Kind:     "memoryCap",
+			Detail:   detail,
+			RSS:      info.RSS,
+			RSSLimit: g.limit,
+		})
+		g.restarter.Request("memory cap exceeded")
+		return
+	}
+}
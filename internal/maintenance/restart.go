@@ -0,0 +1,125 @@
+package maintenance
+
+import (
+	"context"
+	"fmt"
+	"math/rand/v2"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/jcdorr003/windash-agent/internal/config"
+	"go.uber.org/zap"
+)
+
+const (
+	// Restart modes
+	ModeExec = "exec" // Start a fresh copy of the agent, then exit
+	ModeExit = "exit" // Exit with RestartExitCode and let a supervisor start the agent again
+
+	// RestartExitCode tells a supervisor (service recovery, watchdog) that the
+	// agent exited on purpose and wants to be started again
+	RestartExitCode = 75
+
+	defaultDrainSec = 10
+
+	// maxRestartJitter spreads scheduled restarts across a fleet so hosts
+	// installed together don't all reconnect at the same moment
+	maxRestartJitter = time.Hour
+)
+
+// Restarter collects restart requests (scheduled, or raised by other
+// subsystems) for the main loop, which drains and restarts the agent
+type Restarter struct {
+	logger   *zap.SugaredLogger
+	cfg      config.MaintenanceConfig
+	requests chan string
+}
+
+// NewRestarter creates a restarter for cfg
+func NewRestarter(logger *zap.SugaredLogger, cfg config.MaintenanceConfig) *Restarter {
+	return &Restarter{logger: logger, cfg: cfg, requests: make(chan string, 1)}
+}
+
+// Request asks for a restart for the given reason. Only the first request
+// counts; later ones are ignored while it is pending.
+func (r *Restarter) Request(reason string) {
+	select {
+	case r.requests <- reason:
+		r.logger.Info("♻️  Agent restart requested", "reason", reason)
+	default:
+	}
+}
+
+// Requested delivers the reason of a pending restart request
+func (r *Restarter) Requested() <-chan string {
+	return r.requests
+}
+
+// DrainTimeout returns how long queued messages may take to send before exit
+func (r *Restarter) DrainTimeout() time.Duration {
+	sec := r.cfg.DrainSec
+	if sec <= 0 {
+		sec = defaultDrainSec
+	}
+	return time.Duration(sec) * time.Second
+}
+
+// Run requests a restart every cfg.RestartDays days. Returns immediately
+// when scheduled restarts are disabled.
+func (r *Restarter) Run(ctx context.Context) {
+	if r.cfg.RestartDays <= 0 {
+		return
+	}
+
+	after := time.Duration(r.cfg.RestartDays)*24*time.Hour + rand.N(maxRestartJitter)
+	r.logger.Info("♻️  Scheduled agent restart", "at", time.Now().Add(after).Format(time.RFC3339))
+
+	timer := time.NewTimer(after)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+	case <-timer.C:
+		r.Request(fmt.Sprintf("scheduled (every %d days)", r.cfg.RestartDays))
+	}
+}
+
+// Restart replaces the current process according to the configured mode and
+// returns the exit code the caller should exit with. In exec mode a failure
+// to start the new copy is logged and reported as exit code 1.
+func (r *Restarter) Restart() int {
+	if r.cfg.RestartMode == ModeExit {
+		r.logger.Info("♻️  Exiting for restart by supervisor", "exitCode", RestartExitCode)
+		return RestartExitCode
+	}
+
+	exe, err := os.Executable()
+	if err != nil {
+		r.logger.Error("Failed to restart agent", "error", err)
+		return 1
+	}
+	cmd := exec.Command(exe, relaunchArgs(os.Args[1:])...)
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, os.Stdout, os.Stderr
+	if err := cmd.Start(); err != nil {
+		r.logger.Error("Failed to restart agent", "error", err)
+		return 1
+	}
+	r.logger.Info("♻️  Started new agent process", "pid", cmd.Process.Pid)
+	cmd.Process.Release()
+	return 0
+}
+
+// relaunchArgs returns the command line for the new copy: the original
+// flags without one-shot actions (--reset), and without opening the dashboard
+func relaunchArgs(args []string) []string {
+	out := []string{"--set", "openOnStart=false"}
+	for _, arg := range args {
+		name, _, _ := strings.Cut(strings.TrimLeft(arg, "-"), "=")
+		if strings.HasPrefix(arg, "-") && name == "reset" {
+			continue
+		}
+		out = append(out, arg)
+	}
+	return out
+}
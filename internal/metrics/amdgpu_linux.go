@@ -0,0 +1,84 @@
+//go:build linux
+
+package metrics
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	drmClassDir = "/sys/class/drm"
+	amdVendorID = "0x1002"
+)
+
+// amdGPU reads AMD card stats from the amdgpu driver's sysfs files
+type amdGPU struct{}
+
+// devices returns the stats of every card bound to amdgpu. Without one the
+// source is unsupported.
+func (a *amdGPU) devices() ([]GPUDevice, error) {
+	cards, _ := filepath.Glob(filepath.Join(drmClassDir, "card[0-9]*"))
+	sort.Strings(cards)
+
+	var devices []GPUDevice
+	for _, card := range cards {
+		if strings.Contains(filepath.Base(card), "-") {
+			continue // A connector such as card0-HDMI-A-1
+		}
+		dir := filepath.Join(card, "device")
+		if readSysfs(dir, "vendor") != amdVendorID {
+			continue
+		}
+		busy, err := strconv.ParseFloat(readSysfs(dir, "gpu_busy_percent"), 64)
+		if err != nil {
+			continue // Not amdgpu (e.g. the older radeon driver)
+		}
+		d := GPUDevice{
+			Index:    len(devices),
+			Vendor:   "amd",
+			Name:     readSysfs(dir, "product_name"),
+			Usage:    clampPercent(busy),
+			MemUsed:  parseSysfsUint(readSysfs(dir, "mem_info_vram_used")),
+			MemTotal: parseSysfsUint(readSysfs(dir, "mem_info_vram_total")),
+		}
+		d.MemUsed = min(d.MemUsed, d.MemTotal)
+		if hwmon, _ := filepath.Glob(filepath.Join(dir, "hwmon", "hwmon*")); len(hwmon) > 0 {
+			if milli := parseSysfsUint(readSysfs(hwmon[0], "temp1_input")); milli > 0 {
+				d.TempC = float64(milli) / 1000
+			}
+			power := readSysfs(hwmon[0], "power1_average")
+			if power == "" {
+				power = readSysfs(hwmon[0], "power1_input") // Newer kernels on some cards
+			}
+			if micro := parseSysfsUint(power); micro > 0 {
+				d.PowerW = float64(micro) / 1e6
+			}
+		}
+		devices = append(devices, d)
+	}
+	if len(devices) == 0 {
+		return nil, errors.ErrUnsupported
+	}
+	return devices, nil
+}
+
+// readSysfs returns the trimmed contents of a sysfs attribute, or "" if it
+// can't be read
+func readSysfs(dir, name string) string {
+	b, err := os.ReadFile(filepath.Join(dir, name))
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(b))
+}
+
+// parseSysfsUint parses a numeric sysfs attribute, 0 if it isn't one
+func parseSysfsUint(s string) uint64 {
+	n, _ := strconv.ParseUint(s, 10, 64)
+	return n
+}
@@ -0,0 +1,13 @@
+//go:build !windows && !linux
+
+package metrics
+
+import "errors"
+
+// amdGPU reads AMD card stats (Windows and Linux only)
+type amdGPU struct{}
+
+// devices is not implemented on this platform
+func (a *amdGPU) devices() ([]GPUDevice, error) {
+	return nil, errors.ErrUnsupported
+}
@@ -0,0 +1,238 @@
+//go:build windows
+
+package metrics
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"syscall"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+)
+
+const (
+	adlOK               = 0
+	adlErrNotSupported  = -8
+	adlMaxPath          = 256
+	adlPMLogMaxSensors  = 256
+	amdVendorID         = 0x1002
+	pmlogTemperatureGPU = 8  // PMLOG_TEMPERATURE_EDGE
+	pmlogActivityGFX    = 19 // PMLOG_INFO_ACTIVITY_GFX
+	pmlogASICPower      = 23 // PMLOG_ASIC_POWER
+)
+
+// adlAdapterInfo is AdapterInfo (Windows layout). ADL lists one entry per
+// display output, so a card usually appears several times.
+type adlAdapterInfo struct {
+	size           int32
+	adapterIndex   int32
+	udid           [adlMaxPath]byte
+	busNumber      int32
+	deviceNumber   int32
+	functionNumber int32
+	vendorID       int32
+	adapterName    [adlMaxPath]byte
+	displayName    [adlMaxPath]byte
+	present        int32
+	exist          int32
+	driverPath     [adlMaxPath]byte
+	driverPathExt  [adlMaxPath]byte
+	pnpString      [adlMaxPath]byte
+	osDisplayIndex int32
+}
+
+// adlMemoryInfo is ADLMemoryInfo
+type adlMemoryInfo struct {
+	memorySize    int64
+	memoryType    [adlMaxPath]byte
+	memoryBandwth int64
+}
+
+// adlPMLogData is ADLPMLogDataOutput (Overdrive 8 cards: Radeon VII and newer)
+type adlPMLogData struct {
+	size    int32
+	sensors [adlPMLogMaxSensors]struct{ supported, value int32 }
+}
+
+// adlPMActivity is ADLPMActivity (Overdrive 5 cards, the older generations)
+type adlPMActivity struct {
+	size                    int32
+	engineClock             int32
+	memoryClock             int32
+	vddc                    int32
+	activityPercent         int32
+	currentPerformanceLevel int32
+	currentBusSpeed         int32
+	currentBusLanes         int32
+	maximumBusLanes         int32
+	reserved                int32
+}
+
+// adlTemperature is ADLTemperature, in millidegrees Celsius
+type adlTemperature struct {
+	size        int32
+	temperature int32
+}
+
+var (
+	adlMallocOnce sync.Once
+	adlMalloc     uintptr
+)
+
+// amdGPU reads AMD card stats through ADL (atiadlxx.dll), which ships with
+// the Radeon driver. Like NVML it is loaded once and kept for the life of
+// the process.
+type amdGPU struct {
+	loaded  bool
+	initErr error
+	procs   map[string]uintptr
+	context uintptr
+}
+
+var adlProcNames = []string{
+	"ADL2_Main_Control_Create",
+	"ADL2_Adapter_NumberOfAdapters_Get",
+	"ADL2_Adapter_AdapterInfo_Get",
+	"ADL2_Adapter_MemoryInfo_Get",
+	"ADL2_Adapter_VRAMUsage_Get",
+	"ADL2_New_QueryPMLogData_Get",
+	"ADL2_Overdrive5_CurrentActivity_Get",
+	"ADL2_Overdrive5_Temperature_Get",
+}
+
+// devices returns the stats of every AMD card
+func (a *amdGPU) devices() ([]GPUDevice, error) {
+	if !a.loaded {
+		a.loaded = true
+		a.initErr = a.init()
+	}
+	if a.initErr != nil {
+		return nil, a.initErr
+	}
+
+	var count int32
+	if err := a.call("ADL2_Adapter_NumberOfAdapters_Get", uintptr(unsafe.Pointer(&count))); err != nil {
+		return nil, err
+	}
+	if count <= 0 {
+		return nil, nil
+	}
+	infos := make([]adlAdapterInfo, count)
+	size := int32(len(infos)) * int32(unsafe.Sizeof(infos[0]))
+	if err := a.call("ADL2_Adapter_AdapterInfo_Get", uintptr(unsafe.Pointer(&infos[0])), uintptr(size)); err != nil {
+		return nil, err
+	}
+
+	var devices []GPUDevice
+	seen := make(map[int32]bool)
+	for _, info := range infos {
+		if info.vendorID != amdVendorID || seen[info.busNumber] {
+			continue
+		}
+		seen[info.busNumber] = true
+		d, err := a.device(info.adapterIndex)
+		if err != nil {
+			return nil, fmt.Errorf("adapter %d: %w", info.adapterIndex, err)
+		}
+		d.Index = len(devices)
+		d.Name = windows.ByteSliceToString(info.adapterName[:])
+		devices = append(devices, d)
+	}
+	return devices, nil
+}
+
+// device reads one card, from the PM log where the card has one and the
+// Overdrive 5 calls otherwise. Power is only available from the PM log.
+func (a *amdGPU) device(adapter int32) (GPUDevice, error) {
+	d := GPUDevice{Vendor: "amd"}
+	index := uintptr(adapter)
+
+	var mem adlMemoryInfo
+	if err := a.call("ADL2_Adapter_MemoryInfo_Get", index, uintptr(unsafe.Pointer(&mem))); err != nil {
+		return d, fmt.Errorf("memory: %w", err)
+	}
+	d.MemTotal = uint64(max(mem.memorySize, 0))
+	var usedMB int32
+	if err := a.call("ADL2_Adapter_VRAMUsage_Get", index, uintptr(unsafe.Pointer(&usedMB))); err == nil {
+		d.MemUsed = min(uint64(max(usedMB, 0))<<20, d.MemTotal)
+	}
+
+	var log adlPMLogData
+	if err := a.call("ADL2_New_QueryPMLogData_Get", index, uintptr(unsafe.Pointer(&log))); err == nil {
+		sensor := func(i int) (int32, bool) {
+			return log.sensors[i].value, log.sensors[i].supported != 0
+		}
+		if v, ok := sensor(pmlogActivityGFX); ok {
+			d.Usage = clampPercent(float64(v))
+			if t, ok := sensor(pmlogTemperatureGPU); ok {
+				d.TempC = float64(t)
+			}
+			if p, ok := sensor(pmlogASICPower); ok {
+				d.PowerW = float64(p)
+			}
+			return d, nil
+		}
+	}
+
+	activity := adlPMActivity{size: int32(unsafe.Sizeof(adlPMActivity{}))}
+	if err := a.call("ADL2_Overdrive5_CurrentActivity_Get", index, uintptr(unsafe.Pointer(&activity))); err != nil {
+		return d, fmt.Errorf("activity: %w", err)
+	}
+	d.Usage = clampPercent(float64(activity.activityPercent))
+	temp := adlTemperature{size: int32(unsafe.Sizeof(adlTemperature{}))}
+	if err := a.call("ADL2_Overdrive5_Temperature_Get", index, 0, uintptr(unsafe.Pointer(&temp))); err == nil {
+		d.TempC = float64(temp.temperature) / 1000
+	}
+	return d, nil
+}
+
+// init loads ADL and creates a context. No library (no Radeon driver) is
+// unsupported.
+func (a *amdGPU) init() error {
+	name := "atiadlxx.dll"
+	if unsafe.Sizeof(uintptr(0)) == 4 {
+		name = "atiadlxy.dll" // 32-bit build on 64-bit Windows
+	}
+	lib, err := windows.LoadLibraryEx(name, 0, windows.LOAD_LIBRARY_SEARCH_SYSTEM32)
+	if err != nil {
+		return fmt.Errorf("%s: %w: %w", name, err, errors.ErrUnsupported)
+	}
+	a.procs = make(map[string]uintptr, len(adlProcNames))
+	for _, proc := range adlProcNames {
+		addr, err := windows.GetProcAddress(lib, proc)
+		if err != nil {
+			return fmt.Errorf("%s %s: %w: %w", name, proc, err, errors.ErrUnsupported)
+		}
+		a.procs[proc] = addr
+	}
+
+	// ADL allocates its results through a caller-supplied malloc. Callbacks
+	// can't be freed, so there is only ever one.
+	adlMallocOnce.Do(func() {
+		adlMalloc = windows.NewCallback(func(size int32) uintptr {
+			ptr, _ := windows.LocalAlloc(windows.LMEM_FIXED|windows.LMEM_ZEROINIT, uint32(max(size, 0)))
+			return ptr
+		})
+	})
+	r, _, _ := syscall.SyscallN(a.procs["ADL2_Main_Control_Create"], adlMalloc, 1, uintptr(unsafe.Pointer(&a.context)))
+	if int32(r) != adlOK {
+		return fmt.Errorf("ADL2_Main_Control_Create: %d: %w", int32(r), errors.ErrUnsupported)
+	}
+	return nil
+}
+
+// call invokes an ADL2 function with the context as the first argument,
+// turning its return code into an error
+func (a *amdGPU) call(name string, args ...uintptr) error {
+	r, _, _ := syscall.SyscallN(a.procs[name], append([]uintptr{a.context}, args...)...)
+	switch int32(r) {
+	case adlOK:
+		return nil
+	case adlErrNotSupported:
+		return fmt.Errorf("%s: %w", name, errors.ErrUnsupported)
+	default:
+		return fmt.Errorf("%s: ADL error %d", name, int32(r))
+	}
+}
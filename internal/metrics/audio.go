@@ -0,0 +1,14 @@
+package metrics
+
+// AudioStats reports the default output device and whether anything is
+// playing, for telling when a media PC is in use
+type AudioStats struct {
+	Device   string `json:"device,omitempty"` // Default output device's friendly name
+	Playing  bool   `json:"playing"`          // At least one session is active
+	Sessions int    `json:"sessions"`         // Active audio sessions on the default device
+}
+
+// EnableAudio adds audio device reporting to real samples. Must be called before Start.
+func (c *Collector) EnableAudio() {
+	c.audio = true
+}
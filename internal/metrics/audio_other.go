@@ -0,0 +1,10 @@
+//go:build !windows
+
+package metrics
+
+import "errors"
+
+// collectAudio is not implemented outside Windows
+func collectAudio() (*AudioStats, error) {
+	return nil, errors.ErrUnsupported
+}
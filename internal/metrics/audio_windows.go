@@ -0,0 +1,167 @@
+//go:build windows
+
+package metrics
+
+import (
+	"errors"
+	"fmt"
+	"runtime"
+	"syscall"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+)
+
+var (
+	ole32                = windows.NewLazySystemDLL("ole32.dll")
+	procCoCreateInstance = ole32.NewProc("CoCreateInstance")
+	procPropVariantClear = ole32.NewProc("PropVariantClear")
+
+	clsidMMDeviceEnumerator  = windows.GUID{Data1: 0xBCDE0395, Data2: 0xE52F, Data3: 0x467C, Data4: [8]byte{0x8E, 0x3D, 0xC4, 0x57, 0x92, 0x91, 0x69, 0x2E}}
+	iidIMMDeviceEnumerator   = windows.GUID{Data1: 0xA95664D2, Data2: 0x9614, Data3: 0x4F35, Data4: [8]byte{0xA7, 0x46, 0xDE, 0x8D, 0xB6, 0x36, 0x17, 0xE6}}
+	iidIAudioSessionManager2 = windows.GUID{Data1: 0x77AA99A0, Data2: 0x1BD6, Data3: 0x484F, Data4: [8]byte{0x8B, 0xC7, 0x2C, 0x65, 0x4C, 0x9A, 0x9B, 0x6F}}
+	pkeyDeviceFriendlyName   = propertyKey{
+		fmtid: windows.GUID{Data1: 0xA45C254E, Data2: 0xDF1C, Data3: 0x4EFD, Data4: [8]byte{0x80, 0x20, 0x67, 0xD1, 0x46, 0xA8, 0x50, 0xE0}},
+		pid:   14,
+	}
+)
+
+const (
+	clsctxAll          = 0x17
+	eRender            = 0
+	eMultimedia        = 1
+	stgmRead           = 0
+	vtLPWStr           = 31
+	audioSessionActive = 1
+	hresultNotFound    = 0x80070490 // No default output device
+
+	// Vtable slots (after IUnknown's QueryInterface, AddRef, Release)
+	slotRelease                 = 2
+	slotGetDefaultAudioEndpoint = 4 // IMMDeviceEnumerator
+	slotActivate                = 3 // IMMDevice
+	slotOpenPropertyStore       = 4 // IMMDevice
+	slotGetValue                = 5 // IPropertyStore
+	slotGetSessionEnumerator    = 5 // IAudioSessionManager2
+	slotGetCount                = 3 // IAudioSessionEnumerator
+	slotGetSession              = 4 // IAudioSessionEnumerator
+	slotGetState                = 3 // IAudioSessionControl
+)
+
+type propertyKey struct {
+	fmtid windows.GUID
+	pid   uint32
+}
+
+// propVariant is the PROPVARIANT layout for pointer values
+type propVariant struct {
+	vt  uint16
+	_   [3]uint16
+	val unsafe.Pointer
+	_   uintptr
+}
+
+// comObject is a COM interface pointer
+type comObject struct {
+	vtbl *[16]uintptr
+}
+
+// call invokes a method by vtable slot
+func (o *comObject) call(slot int, args ...uintptr) error {
+	hr, _, _ := syscall.SyscallN(o.vtbl[slot], append([]uintptr{uintptr(unsafe.Pointer(o))}, args...)...)
+	if int32(hr) < 0 {
+		return hresultError(uint32(hr))
+	}
+	return nil
+}
+
+func (o *comObject) release() {
+	syscall.SyscallN(o.vtbl[slotRelease], uintptr(unsafe.Pointer(o)))
+}
+
+type hresultError uint32
+
+func (e hresultError) Error() string {
+	return fmt.Sprintf("HRESULT 0x%08X", uint32(e))
+}
+
+// collectAudio reads the default output device and counts its active
+// sessions through the Core Audio API
+func collectAudio() (*AudioStats, error) {
+	// COM state is per thread
+	runtime.LockOSThread()
+	defer runtime.UnlockOSThread()
+	if err := windows.CoInitializeEx(0, windows.COINIT_MULTITHREADED); err != nil && err != syscall.Errno(windows.S_FALSE) {
+		return nil, fmt.Errorf("CoInitializeEx: %w", err)
+	}
+	defer windows.CoUninitialize()
+
+	var enumerator *comObject
+	hr, _, _ := procCoCreateInstance.Call(
+		uintptr(unsafe.Pointer(&clsidMMDeviceEnumerator)), 0, clsctxAll,
+		uintptr(unsafe.Pointer(&iidIMMDeviceEnumerator)), uintptr(unsafe.Pointer(&enumerator)))
+	if int32(hr) < 0 {
+		return nil, fmt.Errorf("create device enumerator: %w", hresultError(uint32(hr)))
+	}
+	defer enumerator.release()
+
+	var device *comObject
+	err := enumerator.call(slotGetDefaultAudioEndpoint, eRender, eMultimedia, uintptr(unsafe.Pointer(&device)))
+	if errors.Is(err, hresultError(hresultNotFound)) {
+		return &AudioStats{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("default audio endpoint: %w", err)
+	}
+	defer device.release()
+
+	stats := &AudioStats{Device: friendlyName(device)}
+
+	var manager *comObject
+	if err := device.call(slotActivate, uintptr(unsafe.Pointer(&iidIAudioSessionManager2)), clsctxAll, 0, uintptr(unsafe.Pointer(&manager))); err != nil {
+		return nil, fmt.Errorf("activate session manager: %w", err)
+	}
+	defer manager.release()
+
+	var sessions *comObject
+	if err := manager.call(slotGetSessionEnumerator, uintptr(unsafe.Pointer(&sessions))); err != nil {
+		return nil, fmt.Errorf("enumerate sessions: %w", err)
+	}
+	defer sessions.release()
+
+	var count int32
+	if err := sessions.call(slotGetCount, uintptr(unsafe.Pointer(&count))); err != nil {
+		return nil, fmt.Errorf("count sessions: %w", err)
+	}
+	for i := int32(0); i < count; i++ {
+		var control *comObject
+		if err := sessions.call(slotGetSession, uintptr(i), uintptr(unsafe.Pointer(&control))); err != nil {
+			continue
+		}
+		var state int32
+		if control.call(slotGetState, uintptr(unsafe.Pointer(&state))) == nil && state == audioSessionActive {
+			stats.Sessions++
+		}
+		control.release()
+	}
+	stats.Playing = stats.Sessions > 0
+	return stats, nil
+}
+
+// friendlyName reads a device's display name, or "" if unavailable
+func friendlyName(device *comObject) string {
+	var store *comObject
+	if device.call(slotOpenPropertyStore, stgmRead, uintptr(unsafe.Pointer(&store))) != nil {
+		return ""
+	}
+	defer store.release()
+
+	var pv propVariant
+	if store.call(slotGetValue, uintptr(unsafe.Pointer(&pkeyDeviceFriendlyName)), uintptr(unsafe.Pointer(&pv))) != nil {
+		return ""
+	}
+	defer procPropVariantClear.Call(uintptr(unsafe.Pointer(&pv)))
+	if pv.vt != vtLPWStr || pv.val == nil {
+		return ""
+	}
+	return windows.UTF16PtrToString((*uint16)(pv.val))
+}
@@ -2,9 +2,15 @@ package metrics
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"sync/atomic"
 	"time"
 
-	"github.com/shirou/gopsutil/v4/cpu"
+	"github.com/jcdorr003/windash-agent/internal/alerts"
+	"github.com/jcdorr003/windash-agent/internal/config"
+	"github.com/jcdorr003/windash-agent/internal/netprobe"
+	"github.com/jcdorr003/windash-agent/internal/outbound"
 	"github.com/shirou/gopsutil/v4/disk"
 	"github.com/shirou/gopsutil/v4/host"
 	"github.com/shirou/gopsutil/v4/mem"
@@ -13,36 +19,17 @@ import (
 	"go.uber.org/zap"
 )
 
-// SampleV1 represents a versioned metrics sample
-type SampleV1 struct {
-	V      int       `json:"v"`  // Schema version (always 1)
-	TS     time.Time `json:"ts"` // Timestamp
-	HostID string    `json:"hostId"`
+const (
+	// Bounds for runtime interval changes (e.g. setRate from the server)
+	MinInterval = 1 * time.Second
+	MaxInterval = 1 * time.Hour
 
-	CPU struct {
-		Total   float64   `json:"total"`             // Total CPU usage %
-		PerCore []float64 `json:"perCore,omitempty"` // Per-core usage %
-	} `json:"cpu"`
+	// HighResMinInterval is the lower bound in high-resolution mode
+	HighResMinInterval = 100 * time.Millisecond
 
-	Mem struct {
-		Used  uint64 `json:"used"`  // Used memory in bytes
-		Total uint64 `json:"total"` // Total memory in bytes
-	} `json:"mem"`
-
-	Disks []struct {
-		Name  string `json:"name"`  // Mount point or drive letter
-		Used  uint64 `json:"used"`  // Used space in bytes
-		Total uint64 `json:"total"` // Total space in bytes
-	} `json:"disk"`
-
-	Net struct {
-		TxBps uint64 `json:"txBps"` // Transmit bytes per second
-		RxBps uint64 `json:"rxBps"` // Receive bytes per second
-	} `json:"net"`
-
-	UptimeSec uint64 `json:"uptimeSec"` // System uptime in seconds
-	ProcCount uint64 `json:"procCount"` // Number of running processes
-}
+	// warmupDelay separates the discarded baseline pass from the first real sample
+	warmupDelay = 1 * time.Second
+)
 
 // Collector periodically collects system metrics
 type Collector struct {
@@ -50,7 +37,78 @@ type Collector struct {
 	hostID   string
 	interval time.Duration
 
-	// For network rate calculations
+	// Lower interval bound: MinInterval, or HighResMinInterval in high-resolution mode
+	minInterval time.Duration
+
+	// Runtime control (setRate/pause/resume)
+	intervalCh chan time.Duration
+	paused     atomic.Bool
+
+	// When set, samples are generated instead of read from the system
+	synthetic *SyntheticSource
+
+	// Per-core trimming on many-core machines
+	perCore *perCoreTrimmer
+
+	// Optional sources
+	audio      bool
+	temps      bool
+	gpu        *gpuSampler
+	gpuDevices *gpuDevices
+	topProcs   *topProcSampler
+	dpc        *dpcSampler
+	tcpStates  bool
+	hyperv     *hypervSampler
+	wifi       bool
+	links      bool
+
+	// Leave process names out of samples (privacy.hideProcessNames)
+	hideNames bool
+
+	// Hash Wi-Fi network names and BSSIDs (privacy.hashSsids)
+	hashSSIDs bool
+
+	// Network probes whose grade samples carry (nil = none)
+	netQuality *netprobe.Prober
+
+	// Interval ramp after boot or login (nil = not slowing down)
+	slowStart *slowStart
+
+	// Idle-send suppression (nil = send every sample)
+	suppress *idleSuppressor
+
+	// File the validate stage writes rejected samples to ("" = log only)
+	quarantinePath string
+
+	// Processing stages between collection and transport, built at Start
+	stages   []string // Stage order (nil = DefaultPipeline)
+	pipeline Pipeline
+
+	// Health score inputs
+	healthWeights config.HealthConfig
+	openAlerts    *alerts.OpenSet
+
+	// Error budget per metrics subsystem (collector goroutine only)
+	subsystems map[string]*subsystemState
+
+	// Most recent sample(s), for reports built outside the sampling loop
+	latest    atomic.Pointer[SampleV1]
+	history   *sampleHistory    // nil unless KeepHistory was called
+	observers []func(*SampleV1) // Called with every kept sample
+
+	// Reused process list buffer (Windows)
+	procBuf []byte
+
+	// Background disk usage queries
+	diskUsage *diskUsageCache
+
+	// Mounted volumes at the last collection, for attach/detach events
+	volumes      map[string]Volume
+	volumeEvents outbound.Sender
+
+	// For CPU and network rate calculations
+	lastCPU      cpuTimes
+	cpuFreq      cpuFreqSampler
 	lastNetStats net.IOCountersStat
 	lastNetTime  time.Time
 }
@@ -58,21 +116,183 @@ type Collector struct {
 // NewCollector creates a new metrics collector
 func NewCollector(logger *zap.SugaredLogger, hostID string, interval time.Duration) *Collector {
 	return &Collector{
-		logger:   logger,
-		hostID:   hostID,
-		interval: interval,
+		logger:      logger,
+		hostID:      hostID,
+		interval:    interval,
+		minInterval: MinInterval,
+		intervalCh:  make(chan time.Duration, 1),
+		perCore:     newPerCoreTrimmer(config.CPUConfig{}),
+		diskUsage:   newDiskUsageCache(),
+	}
+}
+
+// EnableHighResolution allows intervals down to HighResMinInterval for
+// near-real-time gauges. Must be called before Start.
+func (c *Collector) EnableHighResolution() {
+	c.minInterval = HighResMinInterval
+	c.logger.Warn("⚡ High-resolution mode enabled - expect much higher bandwidth and CPU use",
+		"interval", c.interval, "samplesPerMinute", int(time.Minute/max(c.interval, HighResMinInterval)))
+}
+
+// SetSources applies the collectors.enable allow/deny list: disabled sources
+// are not collected and report "disabled", and optional sources (gpu,
+// gpuDevices, audio, temps, topProcs, dpc, tcp, hyperv, wifi, links) are turned on unless off. Must be called before Start.
+func (c *Collector) SetSources(cfg config.CollectorsConfig) {
+	modes := cfg.SourceModes()
+	c.setSourceModes(modes)
+	if modes["gpu"] != config.SourceOff {
+		c.EnableGPU(cfg.GPU)
+	}
+	if modes["gpuDevices"] != config.SourceOff {
+		c.EnableGPUDevices()
+	}
+	if modes["audio"] != config.SourceOff {
+		c.EnableAudio()
+	}
+	if modes["temps"] != config.SourceOff {
+		c.EnableTemps()
+	}
+	if modes["topProcs"] != config.SourceOff {
+		c.EnableTopProcesses(cfg.TopProcs)
+	}
+	if modes["dpc"] != config.SourceOff {
+		c.EnableDPC(cfg.DPC)
+	}
+	if modes["tcp"] != config.SourceOff {
+		c.EnableTCPStates()
+	}
+	if modes["hyperv"] != config.SourceOff {
+		c.EnableHyperV()
+	}
+	if modes["wifi"] != config.SourceOff {
+		c.EnableWiFi()
+	}
+	if modes["links"] != config.SourceOff {
+		c.EnableLinks()
+	}
+}
+
+// HideProcessNames leaves process names out of samples, reporting PIDs
+// only. Must be called before Start.
+func (c *Collector) HideProcessNames() {
+	c.hideNames = true
+}
+
+// SetNetQuality adds the network probes' latest connectivity grade to real
+// samples. Must be called before Start.
+func (c *Collector) SetNetQuality(prober *netprobe.Prober) {
+	c.netQuality = prober
+}
+
+// SetCPUOptions configures per-core trimming. Must be called before Start.
+func (c *Collector) SetCPUOptions(cfg config.CPUConfig) {
+	c.perCore = newPerCoreTrimmer(cfg)
+}
+
+// SetPerCoreTrimming turns per-core trimming on while the server accepts
+// the trimmedCores feature. Safe to call while running.
+func (c *Collector) SetPerCoreTrimming(accepted bool) {
+	c.perCore.accepted.Store(accepted)
+}
+
+// SetSuppression enables idle-send suppression. Must be called before Start.
+func (c *Collector) SetSuppression(cfg config.SuppressConfig) {
+	c.suppress = newIdleSuppressor(cfg)
+}
+
+// UseSynthetic switches the collector to generated metrics. Must be called before Start.
+func (c *Collector) UseSynthetic(cfg config.SyntheticConfig) {
+	c.synthetic = NewSyntheticSource(c.hostID, cfg)
+	c.logger.Warn("🧪 Synthetic metrics enabled - samples are fake!")
+}
+
+// SetHealth configures the health score weights and the open-alert state it
+// considers. Must be called before Start.
+func (c *Collector) SetHealth(weights config.HealthConfig, open *alerts.OpenSet) {
+	c.healthWeights = weights
+	c.openAlerts = open
+}
+
+// SetInterval changes the collection interval while running
+func (c *Collector) SetInterval(interval time.Duration) error {
+	if interval < c.minInterval || interval > MaxInterval {
+		return fmt.Errorf("interval %s out of range (%s-%s)", interval, c.minInterval, MaxInterval)
+	}
+
+	// Replace any pending change that hasn't been picked up yet
+	select {
+	case <-c.intervalCh:
+	default:
+	}
+	c.intervalCh <- interval
+	return nil
+}
+
+// Pause stops emitting samples until Resume is called
+func (c *Collector) Pause() {
+	if !c.paused.Swap(true) {
+		c.logger.Info("⏸️  Metrics collection paused")
+	}
+}
+
+// Resume restarts sample emission after Pause
+func (c *Collector) Resume() {
+	if c.paused.Swap(false) {
+		c.logger.Info("▶️  Metrics collection resumed")
+	}
+}
+
+// Paused reports whether collection is currently paused
+func (c *Collector) Paused() bool {
+	return c.paused.Load()
+}
+
+// Latest returns the most recently collected sample, or nil before the first.
+// The sample must not be modified.
+func (c *Collector) Latest() *SampleV1 {
+	return c.latest.Load()
+}
+
+// KeepHistory retains the last n samples for Recent. Must be called before Start.
+func (c *Collector) KeepHistory(n int) {
+	if n > 0 {
+		c.history = &sampleHistory{size: n}
 	}
 }
 
+// Recent returns up to n of the most recently collected samples, oldest
+// first. The samples must not be modified.
+func (c *Collector) Recent(n int) []*SampleV1 {
+	return c.history.recent(n)
+}
+
+// Observe calls fn with every collected sample as kept after the pipeline
+// (including samples suppressed from sending), on the collector goroutine.
+// fn must not block or modify the sample. Must be called before Start.
+func (c *Collector) Observe(fn func(*SampleV1)) {
+	c.observers = append(c.observers, fn)
+}
+
 // Start begins collecting metrics and sending them to the channel
 func (c *Collector) Start(ctx context.Context, sampleChan chan<- *SampleV1) {
-	c.logger.Info("📊 Metrics collector started", "interval", c.interval)
+	if c.interval < c.minInterval {
+		c.logger.Warn("Metrics interval below minimum, raising it (enable highResolution for sub-second intervals)",
+			"interval", c.interval, "min", c.minInterval)
+		c.interval = c.minInterval
+	}
+	c.pipeline = c.buildPipeline()
+	c.logger.Info("📊 Metrics collector started", "interval", c.interval, "pipeline", c.pipeline.Names())
 
-	ticker := time.NewTicker(c.interval)
+	ticker := time.NewTicker(c.beginSlowStart())
 	defer ticker.Stop()
 
+	// Prime CPU and network baselines so the first sample has real rates
+	if !c.warmUp(ctx) {
+		return
+	}
+
 	// Collect initial sample immediately
-	if sample := c.collect(); sample != nil {
+	if sample := c.next(); sample != nil {
 		select {
 		case sampleChan <- sample:
 		case <-ctx.Done():
@@ -82,8 +302,20 @@ func (c *Collector) Start(ctx context.Context, sampleChan chan<- *SampleV1) {
 
 	for {
 		select {
-		case <-ticker.C:
-			if sample := c.collect(); sample != nil {
+		case interval := <-c.intervalCh:
+			c.interval = interval
+			c.slowStart = nil // An explicit rate wins over the ramp
+			ticker.Reset(interval)
+			c.logger.Info("🔧 Metrics interval changed", "interval", interval)
+		case now := <-ticker.C:
+			if c.slowStart != nil {
+				ticker.Reset(c.rampInterval(now))
+			}
+			if c.paused.Load() {
+				c.suppress.reset() // Send the first sample after resuming
+				continue
+			}
+			if sample := c.next(); sample != nil {
 				select {
 				case sampleChan <- sample:
 				case <-ctx.Done():
@@ -99,53 +331,114 @@ func (c *Collector) Start(ctx context.Context, sampleChan chan<- *SampleV1) {
 	}
 }
 
-// collect gathers all system metrics
-func (c *Collector) collect() *SampleV1 {
-	sample := &SampleV1{
-		V:      1,
-		TS:     time.Now(),
-		HostID: c.hostID,
+// warmUp runs a discarded collection pass: the CPU time deltas and network
+// rates both compare against the previous call, so without a baseline the
+// first sample would have no CPU usage and zero network rates.
+// Returns false if ctx was cancelled while waiting.
+func (c *Collector) warmUp(ctx context.Context) bool {
+	if c.synthetic != nil {
+		return true
 	}
 
-	// CPU metrics
-	if cpuPercent, err := cpu.Percent(0, false); err == nil && len(cpuPercent) > 0 {
-		sample.CPU.Total = cpuPercent[0]
+	c.collect()
+	c.logger.Debug("Collector warm-up pass complete")
+
+	select {
+	case <-ctx.Done():
+		return false
+	case <-time.After(warmupDelay):
+		return true
 	}
+}
 
-	if cpuPerCore, err := cpu.Percent(0, true); err == nil {
-		sample.CPU.PerCore = cpuPerCore
+// next produces the next sample from the active source and runs it through
+// the pipeline. Returns nil if there is nothing to send.
+func (c *Collector) next() *SampleV1 {
+	var sample *SampleV1
+	if c.synthetic != nil {
+		sample = c.synthetic.Generate()
+	} else {
+		sample = c.collect()
+	}
+	if sample == nil {
+		return nil
+	}
+	out, kept := c.pipeline.Run(sample)
+	c.latest.Store(kept)
+	c.history.add(kept)
+	for _, fn := range c.observers {
+		fn(kept)
+	}
+	return out
+}
+
+// collect gathers all system metrics. Each subsystem runs independently
+// and its outcome is recorded in the sample, so a failure shows up as
+// "no data" rather than as zero values.
+func (c *Collector) collect() *SampleV1 {
+	sample := NewSample(c.hostID, time.Now())
+
+	// CPU metrics
+	c.runSubsystem(sample, "cpu", func(ctx context.Context) error {
+		return c.collectCPU(ctx, sample)
+	})
+
+	// CPU clock speeds and throttling, on and off with cpu
+	if st := c.subsystems["cpu"]; !st.disabled {
+		c.runSubsystem(sample, "cpuFreq", func(ctx context.Context) error {
+			return c.cpuFreq.collect(ctx, &sample.CPU)
+		})
 	}
 
 	// Memory metrics
-	if memInfo, err := mem.VirtualMemory(); err == nil {
+	c.runSubsystem(sample, "mem", func(ctx context.Context) error {
+		memInfo, err := mem.VirtualMemoryWithContext(ctx)
+		if err != nil {
+			return err
+		}
 		sample.Mem.Used = memInfo.Used
 		sample.Mem.Total = memInfo.Total
-	}
+		return nil
+	})
 
 	// Disk metrics
-	if partitions, err := disk.Partitions(false); err == nil {
-		sample.Disks = make([]struct {
-			Name  string `json:"name"`
-			Used  uint64 `json:"used"`
-			Total uint64 `json:"total"`
-		}, 0, len(partitions))
+	c.runSubsystem(sample, "disk", func(ctx context.Context) error {
+		partitions, err := disk.PartitionsWithContext(ctx, false)
+		if err != nil {
+			return err
+		}
+		c.trackVolumes(partitions)
+		sample.Disks = make([]DiskUsage, 0, len(partitions))
+		mounts := make(map[string]bool, len(partitions))
+		var lastErr error
 		for _, partition := range partitions {
-			if usage, err := disk.Usage(partition.Mountpoint); err == nil {
-				sample.Disks = append(sample.Disks, struct {
-					Name  string `json:"name"`
-					Used  uint64 `json:"used"`
-					Total uint64 `json:"total"`
-				}{
-					Name:  partition.Mountpoint,
-					Used:  usage.Used,
-					Total: usage.Total,
-				})
+			mounts[partition.Mountpoint] = true
+			usage, err := c.diskUsage.usage(partition)
+			if errors.Is(err, errUsagePending) {
+				continue // Slow volume; it appears once its first query finishes
 			}
+			if err != nil {
+				lastErr = err
+				continue
+			}
+			sample.Disks = append(sample.Disks, usage)
 		}
-	}
+		c.diskUsage.retain(mounts)
+		if len(sample.Disks) == 0 && lastErr != nil {
+			return lastErr
+		}
+		return nil
+	})
 
 	// Network metrics (calculate rates)
-	if netStats, err := net.IOCounters(false); err == nil && len(netStats) > 0 {
+	c.runSubsystem(sample, "net", func(ctx context.Context) error {
+		netStats, err := net.IOCountersWithContext(ctx, false)
+		if err != nil {
+			return err
+		}
+		if len(netStats) == 0 {
+			return nil
+		}
 		now := time.Now()
 		if !c.lastNetTime.IsZero() {
 			elapsed := now.Sub(c.lastNetTime).Seconds()
@@ -156,16 +449,168 @@ func (c *Collector) collect() *SampleV1 {
 		}
 		c.lastNetStats = netStats[0]
 		c.lastNetTime = now
-	}
+		return nil
+	})
 
 	// Uptime
-	if uptime, err := host.Uptime(); err == nil {
+	c.runSubsystem(sample, "uptime", func(ctx context.Context) error {
+		uptime, err := host.UptimeWithContext(ctx)
+		if err != nil {
+			return err
+		}
 		sample.UptimeSec = uptime
-	}
+		return nil
+	})
 
-	// Process count
-	if procs, err := process.Pids(); err == nil {
+	// Process count, with a breakdown by state where supported
+	c.runSubsystem(sample, "procs", func(ctx context.Context) error {
+		total, stats, err := collectProcStats(&c.procBuf)
+		if err == nil {
+			sample.ProcCount = total
+			sample.Procs = stats
+			return nil
+		}
+		if !errors.Is(err, errors.ErrUnsupported) {
+			return err
+		}
+		procs, err := process.PidsWithContext(ctx)
+		if err != nil {
+			return err
+		}
 		sample.ProcCount = uint64(len(procs))
+		return nil
+	})
+
+	// Top processes by CPU and memory, on their own slower interval (optional)
+	if c.topProcs != nil && c.topProcs.due(time.Now()) {
+		c.runSubsystem(sample, "topProcs", func(ctx context.Context) error {
+			top, err := c.topProcs.collect(ctx, !c.hideNames)
+			if err != nil {
+				return err
+			}
+			sample.TopProcs = top
+			return nil
+		})
+	}
+
+	// Top GPU processes and engine utilization (optional)
+	if c.gpu != nil {
+		c.runSubsystem(sample, "gpu", func(ctx context.Context) error {
+			procs, engines, err := c.gpu.collect(!c.hideNames)
+			if err != nil {
+				return err
+			}
+			sample.GPUProcesses = procs
+			sample.GPUEngines = engines
+			return nil
+		})
+	}
+
+	// Per-card GPU stats from the vendor libraries (optional)
+	if c.gpuDevices != nil {
+		c.runSubsystem(sample, "gpuDevices", func(ctx context.Context) error {
+			devices, err := c.gpuDevices.collect()
+			if err != nil {
+				return err
+			}
+			sample.GPUs = devices
+			return nil
+		})
+	}
+
+	// Default audio device and playback (optional)
+	if c.audio {
+		c.runSubsystem(sample, "audio", func(ctx context.Context) error {
+			audio, err := collectAudio()
+			if err != nil {
+				return err
+			}
+			sample.Audio = audio
+			return nil
+		})
+	}
+
+	// CPU and motherboard sensors (optional)
+	if c.temps {
+		c.runSubsystem(sample, "temps", func(ctx context.Context) error {
+			temps, err := collectTemps(ctx)
+			if err != nil {
+				return err
+			}
+			sample.Temps = temps
+			return nil
+		})
+	}
+
+	// DPC and interrupt time (optional)
+	if c.dpc != nil {
+		c.runSubsystem(sample, "dpc", func(ctx context.Context) error {
+			dpc, err := c.dpc.collect()
+			if err != nil {
+				return err
+			}
+			sample.DPC = dpc
+			return nil
+		})
+	}
+
+	// Sockets by TCP state (optional)
+	if c.tcpStates {
+		c.runSubsystem(sample, "tcp", func(ctx context.Context) error {
+			tcp, err := collectTCPStates(ctx)
+			if err != nil {
+				return err
+			}
+			sample.TCP = tcp
+			return nil
+		})
+	}
+
+	// Hyper-V guests (optional)
+	if c.hyperv != nil {
+		c.runSubsystem(sample, "hyperv", func(ctx context.Context) error {
+			vms, err := c.hyperv.collect()
+			if err != nil {
+				return err
+			}
+			sample.VMs = vms
+			return nil
+		})
+	}
+
+	// Wireless links (optional)
+	if c.wifi {
+		c.runSubsystem(sample, "wifi", func(ctx context.Context) error {
+			links, err := readWiFi(ctx)
+			if err != nil {
+				return err
+			}
+			if c.hashSSIDs {
+				for i := range links {
+					links[i].SSID = c.hashNetworkName(links[i].SSID)
+					links[i].BSSID = c.hashNetworkName(links[i].BSSID)
+				}
+			}
+			sample.WiFi = links
+			return nil
+		})
+	}
+
+	// Adapter link speeds (optional)
+	if c.links {
+		c.runSubsystem(sample, "links", func(ctx context.Context) error {
+			links, err := readLinks(ctx)
+			if err != nil {
+				return err
+			}
+			sample.Links = links
+			return nil
+		})
+	}
+
+	// Latest connectivity grade from the network probes (no collection cost)
+	if c.netQuality != nil {
+		sample.NetQuality = c.netQuality.Quality()
 	}
 
 	c.logger.Debug("📈 Collected metrics",
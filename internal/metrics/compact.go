@@ -0,0 +1,91 @@
+package metrics
+
+import (
+	"bytes"
+	"encoding/json"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// compactScale rounds fractional numbers in compact samples to two
+// decimals, finer than any source measures
+const compactScale = 100
+
+// compactKept are the top-level fields compact samples carry even when zero
+var compactKept = map[string]bool{"v": true, "ts": true, "hostId": true, "health": true}
+
+// MarshalCompact encodes the sample for servers that accept compact samples:
+// top-level sections without data (an empty disk list, all-zero net rates,
+// a zero uptime) are left out and fractional numbers are rounded to two
+// decimals. A missing section reads as zero.
+func (s *SampleV1) MarshalCompact() ([]byte, error) {
+	full, err := json.Marshal(s)
+	if err != nil {
+		return nil, err
+	}
+	dec := json.NewDecoder(bytes.NewReader(full))
+	dec.UseNumber() // Keep byte counters exact
+	var fields map[string]any
+	if err := dec.Decode(&fields); err != nil {
+		return nil, err
+	}
+	for name, value := range fields {
+		if !compactKept[name] && zeroValue(value) {
+			delete(fields, name)
+			continue
+		}
+		fields[name] = compactValue(value)
+	}
+	return json.Marshal(fields)
+}
+
+// compactValue rounds every fractional number in a decoded JSON value
+func compactValue(value any) any {
+	switch v := value.(type) {
+	case json.Number:
+		if !strings.ContainsAny(string(v), ".eE") {
+			return v // Integers, including counters beyond float64 precision
+		}
+		f, err := v.Float64()
+		if err != nil {
+			return v
+		}
+		return json.Number(strconv.FormatFloat(math.Round(f*compactScale)/compactScale, 'f', -1, 64))
+	case map[string]any:
+		for k, e := range v {
+			v[k] = compactValue(e)
+		}
+	case []any:
+		for i, e := range v {
+			v[i] = compactValue(e)
+		}
+	}
+	return value
+}
+
+// zeroValue reports whether a decoded JSON value carries no data: null,
+// false, zero, an empty string or list, or an object of such values
+func zeroValue(value any) bool {
+	switch v := value.(type) {
+	case nil:
+		return true
+	case bool:
+		return !v
+	case string:
+		return v == ""
+	case json.Number:
+		f, err := v.Float64()
+		return err == nil && f == 0
+	case []any:
+		return len(v) == 0
+	case map[string]any:
+		for _, e := range v {
+			if !zeroValue(e) {
+				return false
+			}
+		}
+		return true
+	}
+	return false
+}
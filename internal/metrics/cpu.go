@@ -0,0 +1,67 @@
+package metrics
+
+import (
+	"context"
+
+	"github.com/shirou/gopsutil/v4/cpu"
+)
+
+// cpuTimes holds the previous CPU time counters for delta computation
+type cpuTimes struct {
+	total   cpu.TimesStat
+	perCore []cpu.TimesStat
+}
+
+// collectCPU computes utilization from the change in CPU time counters
+// since the previous call. Unlike cpu.Percent(0), which keeps one global
+// baseline per process, the baseline lives in the collector, so results are
+// exact at any interval (including sub-second high-resolution mode) and
+// unaffected by other callers. The first call only records a baseline.
+func (c *Collector) collectCPU(ctx context.Context, sample *SampleV1) error {
+	total, err := cpu.TimesWithContext(ctx, false)
+	if err != nil {
+		return err
+	}
+	perCore, err := cpu.TimesWithContext(ctx, true)
+	if err != nil {
+		return err
+	}
+
+	prev := c.lastCPU
+	if len(total) > 0 {
+		c.lastCPU.total = total[0]
+		if prev.total.CPU != "" {
+			sample.CPU.Total = busyPercent(prev.total, total[0])
+		}
+	}
+	c.lastCPU.perCore = perCore
+	if len(prev.perCore) == len(perCore) {
+		sample.CPU.PerCore = make([]float64, len(perCore))
+		for i := range perCore {
+			sample.CPU.PerCore[i] = busyPercent(prev.perCore[i], perCore[i])
+		}
+	}
+	return nil
+}
+
+// busyPercent returns the share of non-idle time between two samples of
+// the same CPU's counters
+func busyPercent(prev, cur cpu.TimesStat) float64 {
+	prevTotal, curTotal := timesTotal(prev), timesTotal(cur)
+	prevBusy := prevTotal - prev.Idle - prev.Iowait
+	curBusy := curTotal - cur.Idle - cur.Iowait
+
+	if curTotal <= prevTotal {
+		return 0
+	}
+	if curBusy <= prevBusy {
+		return 0
+	}
+	return clampPercent((curBusy - prevBusy) / (curTotal - prevTotal) * 100)
+}
+
+// timesTotal sums all CPU time counters (guest time is already included
+// in user time on Linux, so it is not added again)
+func timesTotal(t cpu.TimesStat) float64 {
+	return t.User + t.System + t.Idle + t.Nice + t.Iowait + t.Irq + t.Softirq + t.Steal
+}
@@ -0,0 +1,60 @@
+package metrics
+
+import (
+	"context"
+	"math"
+
+	"github.com/shirou/gopsutil/v4/cpu"
+)
+
+// throttleLimitPct is the Windows "% Performance Limit" below which the CPU
+// counts as throttled: held under its rated clock by a thermal, power or
+// firmware limit
+const throttleLimitPct = 95
+
+// cpuClocks is one reading of the CPU clocks
+type cpuClocks struct {
+	perCore   []float64 // Current MHz per logical processor
+	baseMHz   float64   // Rated base clock, where the platform reports it
+	throttled bool
+}
+
+// cpuFreqSampler adds clock speeds to the CPU block. The platform reader
+// keeps whatever state its counters need between samples; the base clock
+// falls back to cpu.Info, read once.
+type cpuFreqSampler struct {
+	reader   cpuFreqReader
+	infoMHz  float64
+	infoRead bool
+}
+
+// collect sets the frequency fields of stats. Until the reader has a
+// baseline (rate counters need two readings) nothing is set.
+func (f *cpuFreqSampler) collect(ctx context.Context, stats *CPUStats) error {
+	if !f.infoRead {
+		f.infoRead = true
+		if infos, err := cpu.InfoWithContext(ctx); err == nil && len(infos) > 0 {
+			f.infoMHz = infos[0].Mhz
+		}
+	}
+	clocks, err := f.reader.read(ctx)
+	if err != nil || clocks == nil {
+		return err
+	}
+
+	stats.BaseMHz = clocks.baseMHz
+	if stats.BaseMHz == 0 {
+		stats.BaseMHz = f.infoMHz
+	}
+	stats.PerCoreMHz = make([]float64, len(clocks.perCore))
+	var sum float64
+	for i, mhz := range clocks.perCore {
+		stats.PerCoreMHz[i] = math.Round(mhz)
+		sum += mhz
+	}
+	if len(clocks.perCore) > 0 {
+		stats.FreqMHz = math.Round(sum / float64(len(clocks.perCore)))
+	}
+	stats.Throttled = clocks.throttled
+	return nil
+}
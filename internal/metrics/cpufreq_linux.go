@@ -0,0 +1,74 @@
+//go:build linux
+
+package metrics
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const sysCPUDir = "/sys/devices/system/cpu"
+
+// cpuFreqReader reads cpufreq's current clocks and the thermal_throttle
+// event counters, which only grow
+type cpuFreqReader struct {
+	throttles uint64
+	primed    bool
+}
+
+// read returns every CPU's current clock. Throttled means a core or package
+// throttle event since the previous reading (Intel only). Machines without
+// cpufreq, such as most VMs, are unsupported.
+func (r *cpuFreqReader) read(ctx context.Context) (*cpuClocks, error) {
+	dirs, _ := filepath.Glob(filepath.Join(sysCPUDir, "cpu[0-9]*"))
+	sort.Slice(dirs, func(i, j int) bool { return cpuIndex(dirs[i]) < cpuIndex(dirs[j]) })
+
+	clocks := &cpuClocks{}
+	var throttles uint64
+	for _, dir := range dirs {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		khz, err := readSysUint(filepath.Join(dir, "cpufreq", "scaling_cur_freq"))
+		if err != nil {
+			continue // Offline CPU
+		}
+		clocks.perCore = append(clocks.perCore, float64(khz)/1000)
+		for _, name := range []string{"core_throttle_count", "package_throttle_count"} {
+			if n, err := readSysUint(filepath.Join(dir, "thermal_throttle", name)); err == nil {
+				throttles += n
+			}
+		}
+	}
+	if len(clocks.perCore) == 0 {
+		return nil, fmt.Errorf("no cpufreq in %s: %w", sysCPUDir, errors.ErrUnsupported)
+	}
+	if khz, err := readSysUint(filepath.Join(sysCPUDir, "cpu0", "cpufreq", "base_frequency")); err == nil {
+		clocks.baseMHz = float64(khz) / 1000 // intel_pstate only
+	}
+
+	clocks.throttled = r.primed && throttles > r.throttles
+	r.throttles, r.primed = throttles, true
+	return clocks, nil
+}
+
+// cpuIndex returns N for a .../cpuN directory
+func cpuIndex(dir string) int {
+	n, _ := strconv.Atoi(strings.TrimPrefix(filepath.Base(dir), "cpu"))
+	return n
+}
+
+// readSysUint reads a sysfs file holding one unsigned number
+func readSysUint(path string) (uint64, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return 0, err
+	}
+	return strconv.ParseUint(strings.TrimSpace(string(data)), 10, 64)
+}
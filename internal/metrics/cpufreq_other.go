@@ -0,0 +1,15 @@
+//go:build !windows && !linux
+
+package metrics
+
+import (
+	"context"
+	"errors"
+)
+
+// cpuFreqReader is not implemented outside Windows and Linux
+type cpuFreqReader struct{}
+
+func (r *cpuFreqReader) read(ctx context.Context) (*cpuClocks, error) {
+	return nil, errors.ErrUnsupported
+}
@@ -0,0 +1,125 @@
+//go:build windows
+
+package metrics
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+)
+
+// Processor Information counters, one instance per logical processor
+// ("group,number") plus per-group and overall totals
+var cpuFreqCounters = [...]string{
+	`\Processor Information(*)\Processor Frequency`,     // Rated base clock, MHz
+	`\Processor Information(*)\% Processor Performance`, // Current clock as % of base, over 100 when boosting
+	`\Processor Information(*)\% Performance Limit`,     // Share of base the firmware currently allows
+}
+
+// cpuFreqReader keeps a PDH query open because % Processor Performance is
+// a rate, like gpuSampler
+type cpuFreqReader struct {
+	query    windows.Handle
+	counters [len(cpuFreqCounters)]windows.Handle
+	primed   bool
+}
+
+// read returns every logical processor's current clock. Throttled means
+// the overall performance limit is below throttleLimitPct.
+func (r *cpuFreqReader) read(ctx context.Context) (*cpuClocks, error) {
+	if r.query == 0 {
+		if err := r.open(); err != nil {
+			return nil, err
+		}
+	}
+	if ret, _, _ := procPdhCollectQueryData.Call(uintptr(r.query)); ret != 0 {
+		return nil, fmt.Errorf("PdhCollectQueryData: 0x%08X", uint32(ret))
+	}
+	if !r.primed {
+		r.primed = true // Rates need two collections
+		return nil, nil
+	}
+
+	var values [len(cpuFreqCounters)]map[string]float64
+	for i, counter := range r.counters {
+		v, err := pdhCounterValues(counter)
+		if err != nil {
+			return nil, err
+		}
+		values[i] = v
+	}
+	base, perf, limit := values[0], values[1], values[2]
+
+	var cores []string
+	for name := range perf {
+		if !strings.Contains(name, "_Total") {
+			cores = append(cores, name)
+		}
+	}
+	sort.Slice(cores, func(i, j int) bool { return processorOrder(cores[i]) < processorOrder(cores[j]) })
+
+	clocks := &cpuClocks{baseMHz: base["_Total"], perCore: make([]float64, len(cores))}
+	for i, name := range cores {
+		clocks.perCore[i] = base[name] * perf[name] / 100
+	}
+	if l, ok := limit["_Total"]; ok && l > 0 {
+		clocks.throttled = l < throttleLimitPct
+	}
+	return clocks, nil
+}
+
+// open creates the PDH query. Processor Information exists from Windows 7
+// on, so a failure here means the counters are disabled or corrupt.
+func (r *cpuFreqReader) open() error {
+	if ret, _, _ := procPdhOpenQueryW.Call(0, 0, uintptr(unsafe.Pointer(&r.query))); ret != 0 {
+		return fmt.Errorf("PdhOpenQuery: 0x%08X", uint32(ret))
+	}
+	for i, path := range cpuFreqCounters {
+		p, _ := windows.UTF16PtrFromString(path)
+		if ret, _, _ := procPdhAddEnglishCounterW.Call(uintptr(r.query), uintptr(unsafe.Pointer(p)), 0, uintptr(unsafe.Pointer(&r.counters[i]))); ret != 0 {
+			return fmt.Errorf("%s: 0x%08X: %w", path, uint32(ret), errors.ErrUnsupported)
+		}
+	}
+	return nil
+}
+
+// pdhCounterValues returns a wildcard counter's value by instance name
+func pdhCounterValues(counter windows.Handle) (map[string]float64, error) {
+	var size, count uint32
+	ret, _, _ := procPdhGetFormattedCounterArrayW.Call(uintptr(counter), pdhFmtDouble, uintptr(unsafe.Pointer(&size)), uintptr(unsafe.Pointer(&count)), 0)
+	if ret == pdhNoData || (ret == 0 && count == 0) {
+		return nil, nil
+	}
+	if ret != pdhMoreData {
+		return nil, fmt.Errorf("PdhGetFormattedCounterArray: 0x%08X", uint32(ret))
+	}
+
+	buf := make([]byte, size)
+	ret, _, _ = procPdhGetFormattedCounterArrayW.Call(uintptr(counter), pdhFmtDouble, uintptr(unsafe.Pointer(&size)), uintptr(unsafe.Pointer(&count)), uintptr(unsafe.Pointer(&buf[0])))
+	if ret != 0 {
+		return nil, fmt.Errorf("PdhGetFormattedCounterArray: 0x%08X", uint32(ret))
+	}
+
+	values := make(map[string]float64, count)
+	for _, item := range unsafe.Slice((*pdhCounterItem)(unsafe.Pointer(&buf[0])), count) {
+		if item.status == pdhCStatusOK || item.status == pdhCStatusNew {
+			values[windows.UTF16PtrToString(item.name)] = item.value
+		}
+	}
+	return values, nil
+}
+
+// processorOrder sorts "group,number" instance names into logical
+// processor order
+func processorOrder(name string) int {
+	group, number, _ := strings.Cut(name, ",")
+	g, _ := strconv.Atoi(group)
+	n, _ := strconv.Atoi(number)
+	return g<<16 | n
+}
@@ -0,0 +1,143 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/shirou/gopsutil/v4/disk"
+)
+
+const (
+	// Capacity changes slowly, so usage is re-read at most this often
+	diskUsageTTL = 10 * time.Second
+
+	// Volumes whose query took longer than slowDiskThreshold (network
+	// shares, optical and sleeping USB drives) are re-read less often
+	slowDiskThreshold = 500 * time.Millisecond
+	slowDiskUsageTTL  = 2 * time.Minute
+
+	// diskUsageWait is how long a collection waits for a fresh value before
+	// reusing the last known one
+	diskUsageWait = 250 * time.Millisecond
+
+	// diskUsageTimeout bounds a single background query
+	diskUsageTimeout = 30 * time.Second
+)
+
+// errUsagePending means the first query for a volume is still running
+var errUsagePending = errors.New("disk usage query in progress")
+
+// diskUsageCache reads volume usage in the background with a per-volume TTL,
+// so one hanging network or optical drive can't stall sample collection
+type diskUsageCache struct {
+	mu      sync.Mutex
+	entries map[string]*diskUsageEntry
+}
+
+// diskUsageEntry is the cached usage and volume info of one mountpoint
+type diskUsageEntry struct {
+	used, total uint64
+	info        volumeInfo
+	ok          bool // A value has been read at least once
+	fetched     time.Time
+	ttl         time.Duration
+	err         error         // Outcome of the last query
+	pending     chan struct{} // Closed when the in-flight query finishes; nil when idle
+}
+
+func newDiskUsageCache() *diskUsageCache {
+	return &diskUsageCache{entries: make(map[string]*diskUsageEntry)}
+}
+
+// usage returns the usage of partition p, starting a background query
+// when the cached value has expired. If the query doesn't finish within
+// diskUsageWait, the last known value is returned with Stale set.
+func (d *diskUsageCache) usage(p disk.PartitionStat) (DiskUsage, error) {
+	d.mu.Lock()
+	e := d.entries[p.Mountpoint]
+	if e == nil {
+		e = &diskUsageEntry{}
+		d.entries[p.Mountpoint] = e
+	}
+	if e.ok && time.Since(e.fetched) < e.ttl {
+		defer d.mu.Unlock()
+		return e.diskUsage(p, false), nil
+	}
+	if e.pending == nil {
+		e.pending = make(chan struct{})
+		go d.query(p, e, e.pending)
+	}
+	pending := e.pending
+	d.mu.Unlock()
+
+	select {
+	case <-pending:
+	case <-time.After(diskUsageWait):
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	switch {
+	case e.ok:
+		return e.diskUsage(p, time.Since(e.fetched) >= e.ttl), nil
+	case e.err != nil:
+		return DiskUsage{}, e.err
+	default:
+		return DiskUsage{}, errUsagePending
+	}
+}
+
+// diskUsage builds the sample entry for p from the cached values
+func (e *diskUsageEntry) diskUsage(p disk.PartitionStat, stale bool) DiskUsage {
+	return DiskUsage{
+		Name:   p.Mountpoint,
+		Used:   e.used,
+		Total:  e.total,
+		Stale:  stale,
+		Label:  e.info.label,
+		FSType: p.Fstype,
+		Type:   e.info.driveType,
+		Serial: e.info.serial,
+	}
+}
+
+// query reads the usage and volume info of p into e
+func (d *diskUsageCache) query(p disk.PartitionStat, e *diskUsageEntry, done chan struct{}) {
+	ctx, cancel := context.WithTimeout(context.Background(), diskUsageTimeout)
+	defer cancel()
+
+	start := time.Now()
+	u, err := disk.UsageWithContext(ctx, p.Mountpoint)
+	var info volumeInfo
+	if err == nil {
+		info = readVolumeInfo(ctx, p)
+	}
+	took := time.Since(start)
+
+	d.mu.Lock()
+	e.err = err
+	if err == nil {
+		e.used, e.total, e.info, e.ok = u.Used, u.Total, info, true
+		e.fetched = time.Now()
+		e.ttl = diskUsageTTL
+		if took > slowDiskThreshold {
+			e.ttl = slowDiskUsageTTL
+		}
+	}
+	e.pending = nil
+	d.mu.Unlock()
+	close(done)
+}
+
+// retain forgets volumes that are no longer mounted
+func (d *diskUsageCache) retain(mounts map[string]bool) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	for mount := range d.entries {
+		if !mounts[mount] {
+			delete(d.entries, mount)
+		}
+	}
+}
@@ -0,0 +1,93 @@
+package metrics
+
+import (
+	"time"
+
+	"github.com/jcdorr003/windash-agent/internal/config"
+)
+
+const defaultDPCSpikePct = 15
+
+// DPCStats is the CPU time spent in deferred procedure calls and interrupt
+// handlers. High values on one core are the usual cause of audio crackling
+// and input lag.
+type DPCStats struct {
+	DPCPct           float64 `json:"dpcPct"`                     // Share of CPU time in DPCs (softirqs on Linux), all cores
+	InterruptPct     float64 `json:"interruptPct"`               // Share of CPU time in interrupt handlers, all cores
+	InterruptsPerSec float64 `json:"interruptsPerSec,omitempty"` // Hardware interrupts per second (Windows)
+	MaxCorePct       float64 `json:"maxCorePct"`                 // Highest DPC + interrupt share on a single core
+	MaxCore          int     `json:"maxCore"`                    // That core's index
+	Spike            bool    `json:"spike,omitempty"`            // maxCorePct reached collectors.dpc.spikePct
+}
+
+// dpcCore holds one core's cumulative counters
+type dpcCore struct {
+	dpc        float64 // Seconds in DPCs
+	interrupt  float64 // Seconds in interrupt handlers
+	total      float64 // Seconds of all CPU time, idle included
+	interrupts uint64  // Interrupt count (0 where unknown)
+}
+
+// dpcSampler computes DPC and interrupt shares from the change in the
+// per-core counters since the previous call, like collectCPU
+type dpcSampler struct {
+	spikePct float64
+	last     []dpcCore
+	lastTime time.Time
+}
+
+// EnableDPC adds DPC and interrupt time to real samples. Must be called
+// before Start.
+func (c *Collector) EnableDPC(cfg config.DPCConfig) {
+	spike := cfg.SpikePct
+	if spike <= 0 {
+		spike = defaultDPCSpikePct
+	}
+	c.dpc = &dpcSampler{spikePct: spike}
+}
+
+// collect returns the shares since the previous call. The first call only
+// records a baseline and returns nil.
+func (d *dpcSampler) collect() (*DPCStats, error) {
+	cores, err := readDPCCounters()
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	prev, elapsed := d.last, now.Sub(d.lastTime).Seconds()
+	d.last, d.lastTime = cores, now
+	if len(prev) != len(cores) {
+		return nil, nil
+	}
+
+	stats := &DPCStats{}
+	var dpc, interrupt, total float64
+	var interrupts uint64
+	for i, cur := range cores {
+		dt := cur.total - prev[i].total
+		if dt <= 0 {
+			continue
+		}
+		coreDPC := cur.dpc - prev[i].dpc
+		coreInterrupt := cur.interrupt - prev[i].interrupt
+		if share := clampPercent((coreDPC + coreInterrupt) / dt * 100); share > stats.MaxCorePct {
+			stats.MaxCorePct = share
+			stats.MaxCore = i
+		}
+		dpc += coreDPC
+		interrupt += coreInterrupt
+		total += dt
+		if cur.interrupts >= prev[i].interrupts {
+			interrupts += cur.interrupts - prev[i].interrupts
+		}
+	}
+	if total > 0 {
+		stats.DPCPct = clampPercent(dpc / total * 100)
+		stats.InterruptPct = clampPercent(interrupt / total * 100)
+	}
+	if elapsed > 0 {
+		stats.InterruptsPerSec = float64(interrupts) / elapsed
+	}
+	stats.Spike = stats.MaxCorePct >= d.spikePct
+	return stats, nil
+}
@@ -0,0 +1,19 @@
+//go:build linux
+
+package metrics
+
+import "github.com/shirou/gopsutil/v4/cpu"
+
+// readDPCCounters reads every core's softirq and irq time from /proc/stat.
+// Softirqs are the Linux counterpart of DPCs; interrupt counts are not read.
+func readDPCCounters() ([]dpcCore, error) {
+	times, err := cpu.Times(true)
+	if err != nil {
+		return nil, err
+	}
+	cores := make([]dpcCore, len(times))
+	for i, t := range times {
+		cores[i] = dpcCore{dpc: t.Softirq, interrupt: t.Irq, total: timesTotal(t)}
+	}
+	return cores, nil
+}
@@ -0,0 +1,10 @@
+//go:build !windows && !linux
+
+package metrics
+
+import "errors"
+
+// readDPCCounters is not implemented outside Windows and Linux
+func readDPCCounters() ([]dpcCore, error) {
+	return nil, errors.ErrUnsupported
+}
@@ -0,0 +1,47 @@
+//go:build windows
+
+package metrics
+
+import (
+	"fmt"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+)
+
+// processorPerformance is SYSTEM_PROCESSOR_PERFORMANCE_INFORMATION. Times
+// are in 100ns units; KernelTime includes IdleTime.
+type processorPerformance struct {
+	IdleTime       int64
+	KernelTime     int64
+	UserTime       int64
+	DpcTime        int64
+	InterruptTime  int64
+	InterruptCount uint32
+	_              uint32
+}
+
+// readDPCCounters reads every core's DPC and interrupt time. Machines with
+// more than 64 logical processors report the current processor group only.
+func readDPCCounters() ([]dpcCore, error) {
+	buf := make([]processorPerformance, 256)
+	var size uint32
+	err := windows.NtQuerySystemInformation(windows.SystemProcessorPerformanceInformation,
+		unsafe.Pointer(&buf[0]), uint32(len(buf))*uint32(unsafe.Sizeof(buf[0])), &size)
+	if err != nil {
+		return nil, fmt.Errorf("NtQuerySystemInformation: %w", err)
+	}
+
+	const tick = 1e-7 // 100ns in seconds
+	cores := make([]dpcCore, size/uint32(unsafe.Sizeof(buf[0])))
+	for i := range cores {
+		p := buf[i]
+		cores[i] = dpcCore{
+			dpc:        float64(p.DpcTime) * tick,
+			interrupt:  float64(p.InterruptTime) * tick,
+			total:      float64(p.KernelTime+p.UserTime) * tick,
+			interrupts: uint64(p.InterruptCount),
+		}
+	}
+	return cores, nil
+}
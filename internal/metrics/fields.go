@@ -0,0 +1,275 @@
+package metrics
+
+// FieldLanguages are the languages every field description is available in
+var FieldLanguages = []string{"en", "de", "fr", "es"}
+
+// FieldInfo describes one field of SampleV1 for dashboard tooltips and axes
+type FieldInfo struct {
+	Path        string            `json:"path"`             // JSON path in the sample; [] marks array elements
+	Type        string            `json:"type"`             // number, integer, string, bool, time, object or map
+	Unit        string            `json:"unit,omitempty"`   // %, bytes, bytes/s, s, °C, W, 1/s, MHz, dBm, Mbit/s
+	Min         *float64          `json:"min,omitempty"`    // Lowest possible value
+	Max         *float64          `json:"max,omitempty"`    // Highest possible value
+	Source      string            `json:"source,omitempty"` // Subsystem (collectors.enable key) that fills it
+	Method      string            `json:"method"`           // How the value is collected
+	Description map[string]string `json:"description"`      // By language (FieldLanguages)
+}
+
+// FieldsMessage is sent as a "fields" message on every connection so the
+// dashboard can describe this agent version's samples without hardcoding them
+type FieldsMessage struct {
+	Type          string      `json:"type"` // always "fields"
+	SchemaVersion int         `json:"schemaVersion"`
+	AgentVersion  string      `json:"agentVersion"`
+	Languages     []string    `json:"languages"`
+	Fields        []FieldInfo `json:"fields"`
+}
+
+// NewFieldsMessage returns the field metadata message for agentVersion
+func NewFieldsMessage(agentVersion string) *FieldsMessage {
+	return &FieldsMessage{
+		Type:          "fields",
+		SchemaVersion: SchemaVersion,
+		AgentVersion:  agentVersion,
+		Languages:     FieldLanguages,
+		Fields:        Fields,
+	}
+}
+
+var (
+	fieldZero    = 0.0
+	fieldHundred = 100.0
+)
+
+// desc builds a description map with one entry per FieldLanguages language
+func desc(en, de, fr, es string) map[string]string {
+	return map[string]string{"en": en, "de": de, "fr": fr, "es": es}
+}
+
+// Fields describes every field SampleV1 can carry. Keep it in step with
+// the sample types when adding a field.
+var Fields = []FieldInfo{
+	{Path: "v", Type: "integer", Method: "constant",
+		Description: desc("Sample schema version", "Schemaversion der Messung", "Version du schéma de l'échantillon", "Versión del esquema de la muestra")},
+	{Path: "ts", Type: "time", Method: "agent clock (RFC 3339)",
+		Description: desc("Time the sample was taken", "Zeitpunkt der Messung", "Heure de la mesure", "Hora de la muestra")},
+	{Path: "hostId", Type: "string", Method: "hostId providers",
+		Description: desc("Host the sample belongs to", "Host, zu dem die Messung gehört", "Hôte auquel appartient l'échantillon", "Equipo al que pertenece la muestra")},
+
+	{Path: "cpu.total", Type: "number", Unit: "%", Min: &fieldZero, Max: &fieldHundred, Source: "cpu", Method: "CPU time counters, change since the previous sample",
+		Description: desc("CPU usage across all cores", "CPU-Auslastung über alle Kerne", "Utilisation du processeur, tous cœurs", "Uso de CPU en todos los núcleos")},
+	{Path: "cpu.perCore[]", Type: "number", Unit: "%", Min: &fieldZero, Max: &fieldHundred, Source: "cpu", Method: "CPU time counters per core",
+		Description: desc("Usage of each core, by core number", "Auslastung je Kern, nach Kernnummer", "Utilisation de chaque cœur, par numéro", "Uso de cada núcleo, por número")},
+	{Path: "cpu.cores", Type: "integer", Source: "cpu", Method: "set when perCore is trimmed",
+		Description: desc("Number of cores (when perCore is left out)", "Anzahl der Kerne (wenn perCore fehlt)", "Nombre de cœurs (quand perCore est omis)", "Número de núcleos (cuando se omite perCore)")},
+	{Path: "cpu.topCores[].core", Type: "integer", Source: "cpu", Method: "busiest cores when perCore is trimmed",
+		Description: desc("Core number of one of the busiest cores", "Nummer eines der meistausgelasteten Kerne", "Numéro d'un des cœurs les plus chargés", "Número de uno de los núcleos más cargados")},
+	{Path: "cpu.topCores[].usage", Type: "number", Unit: "%", Min: &fieldZero, Max: &fieldHundred, Source: "cpu", Method: "CPU time counters per core",
+		Description: desc("Usage of that core", "Auslastung dieses Kerns", "Utilisation de ce cœur", "Uso de ese núcleo")},
+	{Path: "cpu.coreHistogram[]", Type: "integer", Source: "cpu", Method: "cores per 10% usage band, when perCore is trimmed",
+		Description: desc("Number of cores in each 10% usage band (0-10%, 10-20%, ...)", "Anzahl Kerne je 10-%-Auslastungsband (0-10 %, 10-20 %, ...)", "Nombre de cœurs par tranche de 10 % (0-10 %, 10-20 %, ...)", "Número de núcleos por franja del 10 % (0-10 %, 10-20 %, ...)")},
+	{Path: "cpu.freqMhz", Type: "number", Unit: "MHz", Min: &fieldZero, Source: "cpuFreq", Method: "Processor Information counters (Windows), cpufreq (Linux)",
+		Description: desc("Current clock speed, average of all cores", "Aktueller Takt, Mittel über alle Kerne", "Fréquence actuelle, moyenne de tous les cœurs", "Frecuencia actual, media de todos los núcleos")},
+	{Path: "cpu.baseMhz", Type: "number", Unit: "MHz", Min: &fieldZero, Source: "cpuFreq", Method: "Processor Frequency counter (Windows), base_frequency or CPU info (Linux)",
+		Description: desc("Rated base clock; a current clock above it is boost", "Nenntakt; ein höherer aktueller Takt ist Boost", "Fréquence de base nominale ; au-delà, c'est le boost", "Frecuencia base nominal; por encima es boost")},
+	{Path: "cpu.perCoreMhz[]", Type: "number", Unit: "MHz", Min: &fieldZero, Source: "cpuFreq", Method: "Processor Information counters (Windows), cpufreq (Linux); trimmed with perCore",
+		Description: desc("Current clock of each core, by core number", "Aktueller Takt je Kern, nach Kernnummer", "Fréquence actuelle de chaque cœur, par numéro", "Frecuencia actual de cada núcleo, por número")},
+	{Path: "cpu.throttled", Type: "bool", Source: "cpuFreq", Method: "% Performance Limit below 95 (Windows), new thermal_throttle events (Linux, Intel)",
+		Description: desc("The CPU is held below its rated clock by a thermal or power limit", "Die CPU wird durch ein Temperatur- oder Leistungslimit unter ihrem Nenntakt gehalten", "Le processeur est bridé sous sa fréquence nominale par une limite thermique ou de puissance", "La CPU está limitada por debajo de su frecuencia nominal por un límite térmico o de potencia")},
+
+	{Path: "mem.used", Type: "integer", Unit: "bytes", Min: &fieldZero, Source: "mem", Method: "OS memory status",
+		Description: desc("Physical memory in use", "Belegter Arbeitsspeicher", "Mémoire physique utilisée", "Memoria física en uso")},
+	{Path: "mem.total", Type: "integer", Unit: "bytes", Min: &fieldZero, Source: "mem", Method: "OS memory status",
+		Description: desc("Installed physical memory", "Installierter Arbeitsspeicher", "Mémoire physique installée", "Memoria física instalada")},
+
+	{Path: "disk[].name", Type: "string", Source: "disk", Method: "fixed partitions",
+		Description: desc("Mount point or drive letter", "Einhängepunkt oder Laufwerksbuchstabe", "Point de montage ou lettre de lecteur", "Punto de montaje o letra de unidad")},
+	{Path: "disk[].used", Type: "integer", Unit: "bytes", Min: &fieldZero, Source: "disk", Method: "volume free space query",
+		Description: desc("Space used on the volume", "Belegter Speicherplatz auf dem Volume", "Espace utilisé sur le volume", "Espacio usado en el volumen")},
+	{Path: "disk[].total", Type: "integer", Unit: "bytes", Min: &fieldZero, Source: "disk", Method: "volume free space query",
+		Description: desc("Size of the volume", "Größe des Volumes", "Taille du volume", "Tamaño del volumen")},
+	{Path: "disk[].stale", Type: "bool", Source: "disk", Method: "set while a slow volume query is still running",
+		Description: desc("Last known value; a fresh reading is pending", "Letzter bekannter Wert; neue Messung läuft noch", "Dernière valeur connue ; nouvelle mesure en cours", "Último valor conocido; lectura nueva pendiente")},
+	{Path: "disk[].label", Type: "string", Source: "disk", Method: "GetVolumeInformation (Windows), /dev/disk/by-label (Linux)",
+		Description: desc("Volume label, e.g. Games", "Volumebezeichnung, z. B. Spiele", "Nom de volume, par ex. Jeux", "Etiqueta del volumen, p. ej. Juegos")},
+	{Path: "disk[].fsType", Type: "string", Source: "disk", Method: "partition list",
+		Description: desc("File system, e.g. NTFS, exFAT, ext4", "Dateisystem, z. B. NTFS, exFAT, ext4", "Système de fichiers, par ex. NTFS, exFAT, ext4", "Sistema de archivos, p. ej. NTFS, exFAT, ext4")},
+	{Path: "disk[].type", Type: "string", Source: "disk", Method: "GetDriveType (Windows), sysfs removable flag and USB path (Linux), file system type (others)",
+		Description: desc("Drive type: fixed, removable, network, cdrom or ram", "Laufwerkstyp: fixed, removable, network, cdrom oder ram", "Type de lecteur : fixed, removable, network, cdrom ou ram", "Tipo de unidad: fixed, removable, network, cdrom o ram")},
+	{Path: "disk[].serial", Type: "string", Source: "disk", Method: "GetVolumeInformation (Windows), /dev/disk/by-uuid (Linux)",
+		Description: desc("Volume serial number or file system UUID, to recognize a drive under another letter", "Seriennummer des Volumes oder UUID des Dateisystems, um ein Laufwerk unter anderem Buchstaben wiederzuerkennen", "Numéro de série du volume ou UUID du système de fichiers, pour reconnaître un lecteur sous une autre lettre", "Número de serie del volumen o UUID del sistema de archivos, para reconocer una unidad con otra letra")},
+
+	{Path: "net.txBps", Type: "integer", Unit: "bytes/s", Min: &fieldZero, Source: "net", Method: "interface byte counters, change since the previous sample",
+		Description: desc("Data sent per second, all interfaces", "Gesendete Daten pro Sekunde, alle Schnittstellen", "Données envoyées par seconde, toutes interfaces", "Datos enviados por segundo, todas las interfaces")},
+	{Path: "net.rxBps", Type: "integer", Unit: "bytes/s", Min: &fieldZero, Source: "net", Method: "interface byte counters, change since the previous sample",
+		Description: desc("Data received per second, all interfaces", "Empfangene Daten pro Sekunde, alle Schnittstellen", "Données reçues par seconde, toutes interfaces", "Datos recibidos por segundo, todas las interfaces")},
+
+	{Path: "uptimeSec", Type: "integer", Unit: "s", Min: &fieldZero, Source: "uptime", Method: "OS boot time",
+		Description: desc("Time since the system started", "Zeit seit dem Systemstart", "Temps depuis le démarrage du système", "Tiempo desde el inicio del sistema")},
+	{Path: "procCount", Type: "integer", Min: &fieldZero, Source: "procs", Method: "process list",
+		Description: desc("Number of processes", "Anzahl der Prozesse", "Nombre de processus", "Número de procesos")},
+	{Path: "procs.running", Type: "integer", Min: &fieldZero, Source: "procs", Method: "process list (Windows)",
+		Description: desc("Processes with at least one running thread", "Prozesse mit mindestens einem laufenden Thread", "Processus avec au moins un thread actif", "Procesos con al menos un hilo activo")},
+	{Path: "procs.suspended", Type: "integer", Min: &fieldZero, Source: "procs", Method: "process list (Windows)",
+		Description: desc("Processes whose threads are all suspended", "Prozesse, deren Threads alle angehalten sind", "Processus dont tous les threads sont suspendus", "Procesos con todos sus hilos suspendidos")},
+	{Path: "procs.zombie", Type: "integer", Min: &fieldZero, Source: "procs", Method: "process list (Windows)",
+		Description: desc("Exited processes kept alive by open handles", "Beendete Prozesse, die durch offene Handles bestehen bleiben", "Processus terminés maintenus par des handles ouverts", "Procesos terminados que siguen vivos por handles abiertos")},
+	{Path: "procs.threads", Type: "integer", Min: &fieldZero, Source: "procs", Method: "process list (Windows)",
+		Description: desc("Threads across all processes", "Threads aller Prozesse", "Threads de tous les processus", "Hilos de todos los procesos")},
+	{Path: "procs.handles", Type: "integer", Min: &fieldZero, Source: "procs", Method: "process list (Windows)",
+		Description: desc("Open handles across all processes; steady growth hints at a leak", "Offene Handles aller Prozesse; stetiges Wachstum deutet auf ein Leck hin", "Handles ouverts de tous les processus ; une hausse continue signale une fuite", "Handles abiertos de todos los procesos; un aumento constante indica una fuga")},
+
+	{Path: "topProcs.byCpu[].pid", Type: "integer", Source: "topProcs", Method: "process list, every collectors.topProcs.intervalSec",
+		Description: desc("Process ID of one of the busiest processes", "Prozess-ID eines der auslastungsstärksten Prozesse", "PID d'un des processus les plus gourmands", "PID de uno de los procesos más activos")},
+	{Path: "topProcs.byCpu[].name", Type: "string", Source: "topProcs", Method: "process image name (omitted with privacy.hideProcessNames)",
+		Description: desc("Process name", "Prozessname", "Nom du processus", "Nombre del proceso")},
+	{Path: "topProcs.byCpu[].cpu", Type: "number", Unit: "%", Min: &fieldZero, Max: &fieldHundred, Source: "topProcs", Method: "process CPU time since the previous report",
+		Description: desc("Share of total CPU capacity used by the process", "Anteil der gesamten CPU-Kapazität, den der Prozess nutzt", "Part de la capacité processeur totale utilisée", "Parte de la capacidad total de CPU usada")},
+	{Path: "topProcs.byCpu[].rss", Type: "integer", Unit: "bytes", Min: &fieldZero, Source: "topProcs", Method: "process working set",
+		Description: desc("Memory held in RAM by the process", "Vom Prozess belegter Arbeitsspeicher", "Mémoire résidente du processus", "Memoria residente del proceso")},
+	{Path: "topProcs.byMem[]", Type: "object", Source: "topProcs", Method: "process list, every collectors.topProcs.intervalSec",
+		Description: desc("Processes using the most memory, with the same fields as byCpu", "Prozesse mit dem höchsten Speicherverbrauch, gleiche Felder wie byCpu", "Processus utilisant le plus de mémoire, mêmes champs que byCpu", "Procesos que más memoria usan, con los mismos campos que byCpu")},
+
+	{Path: "health", Type: "integer", Min: &fieldZero, Max: &fieldHundred, Method: "weighted score (health config)",
+		Description: desc("Overall health score from CPU, memory, disk, temperatures and open alerts", "Gesamtbewertung aus CPU, Speicher, Datenträger, Temperaturen und offenen Warnungen", "Score global basé sur CPU, mémoire, disque, températures et alertes ouvertes", "Puntuación global según CPU, memoria, disco, temperaturas y alertas abiertas")},
+
+	{Path: "audio.device", Type: "string", Source: "audio", Method: "Core Audio default endpoint (Windows)",
+		Description: desc("Default output device", "Standard-Ausgabegerät", "Périphérique de sortie par défaut", "Dispositivo de salida predeterminado")},
+	{Path: "audio.playing", Type: "bool", Source: "audio", Method: "Core Audio sessions (Windows)",
+		Description: desc("Whether anything is playing", "Ob gerade etwas abgespielt wird", "Lecture audio en cours", "Si se está reproduciendo algo")},
+	{Path: "audio.sessions", Type: "integer", Min: &fieldZero, Source: "audio", Method: "Core Audio sessions (Windows)",
+		Description: desc("Active audio sessions on the default device", "Aktive Audiositzungen auf dem Standardgerät", "Sessions audio actives sur le périphérique par défaut", "Sesiones de audio activas en el dispositivo predeterminado")},
+
+	{Path: "temps.cpu", Type: "number", Unit: "°C", Source: "temps", Method: "LibreHardwareMonitor/OpenHardwareMonitor WMI, ACPI or hwmon",
+		Description: desc("CPU package temperature", "Temperatur des CPU-Gehäuses", "Température du processeur", "Temperatura del encapsulado de la CPU")},
+	{Path: "temps.cores[]", Type: "number", Unit: "°C", Source: "temps", Method: "hardware monitor sensors",
+		Description: desc("Temperature of each core, by core number", "Temperatur je Kern, nach Kernnummer", "Température de chaque cœur, par numéro", "Temperatura de cada núcleo, por número")},
+	{Path: "temps.sensors[].name", Type: "string", Source: "temps", Method: "hardware monitor sensors",
+		Description: desc("Sensor name", "Sensorname", "Nom du capteur", "Nombre del sensor")},
+	{Path: "temps.sensors[].source", Type: "string", Source: "temps", Method: "hardware monitor sensors",
+		Description: desc("Chip or device the sensor belongs to", "Chip oder Gerät des Sensors", "Puce ou appareil du capteur", "Chip o dispositivo del sensor")},
+	{Path: "temps.sensors[].type", Type: "string", Source: "temps", Method: "hardware monitor sensors",
+		Description: desc("temperature (°C), fan (RPM) or voltage (V)", "temperature (°C), fan (U/min) oder voltage (V)", "temperature (°C), fan (tr/min) ou voltage (V)", "temperature (°C), fan (RPM) o voltage (V)")},
+	{Path: "temps.sensors[].value", Type: "number", Source: "temps", Method: "hardware monitor sensors",
+		Description: desc("Reading, in the unit given by type", "Messwert in der Einheit laut type", "Valeur, dans l'unité indiquée par type", "Lectura, en la unidad indicada por type")},
+
+	{Path: "dpc.dpcPct", Type: "number", Unit: "%", Min: &fieldZero, Max: &fieldHundred, Source: "dpc", Method: "per-core DPC time (softirq on Linux), change since the previous sample",
+		Description: desc("CPU time spent in deferred procedure calls", "CPU-Zeit in verzögerten Prozeduraufrufen (DPC)", "Temps processeur passé en appels de procédure différés (DPC)", "Tiempo de CPU en llamadas a procedimientos diferidos (DPC)")},
+	{Path: "dpc.interruptPct", Type: "number", Unit: "%", Min: &fieldZero, Max: &fieldHundred, Source: "dpc", Method: "per-core interrupt time",
+		Description: desc("CPU time spent in interrupt handlers", "CPU-Zeit in Interrupt-Handlern", "Temps processeur passé dans les gestionnaires d'interruptions", "Tiempo de CPU en manejadores de interrupciones")},
+	{Path: "dpc.interruptsPerSec", Type: "number", Unit: "1/s", Min: &fieldZero, Source: "dpc", Method: "per-core interrupt counts (Windows)",
+		Description: desc("Hardware interrupts per second", "Hardware-Interrupts pro Sekunde", "Interruptions matérielles par seconde", "Interrupciones de hardware por segundo")},
+	{Path: "dpc.maxCorePct", Type: "number", Unit: "%", Min: &fieldZero, Max: &fieldHundred, Source: "dpc", Method: "per-core DPC and interrupt time",
+		Description: desc("Highest DPC + interrupt share on a single core; high values cause audio crackling and input lag", "Höchster DPC- und Interrupt-Anteil eines Kerns; hohe Werte verursachen Audioknacksen und Eingabeverzögerung", "Part DPC + interruptions la plus élevée sur un cœur ; des valeurs hautes causent craquements audio et latence", "Mayor proporción DPC + interrupciones en un núcleo; valores altos causan chasquidos de audio y retraso de entrada")},
+	{Path: "dpc.maxCore", Type: "integer", Min: &fieldZero, Source: "dpc", Method: "per-core DPC and interrupt time",
+		Description: desc("Core with the highest DPC + interrupt share", "Kern mit dem höchsten DPC- und Interrupt-Anteil", "Cœur avec la plus forte part DPC + interruptions", "Núcleo con la mayor proporción DPC + interrupciones")},
+	{Path: "dpc.spike", Type: "bool", Source: "dpc", Method: "maxCorePct compared with collectors.dpc.spikePct",
+		Description: desc("A core spent too much time in DPCs and interrupts", "Ein Kern verbrachte zu viel Zeit mit DPCs und Interrupts", "Un cœur a passé trop de temps en DPC et interruptions", "Un núcleo pasó demasiado tiempo en DPC e interrupciones")},
+
+	{Path: "tcp.states", Type: "map", Source: "tcp", Method: "kernel socket tables (/proc/net on Linux, GetExtendedTcpTable on Windows)",
+		Description: desc("TCP sockets by state, e.g. ESTABLISHED, TIME_WAIT, LISTEN, CLOSE_WAIT; a count that keeps growing points to a connection leak", "TCP-Sockets nach Zustand, z. B. ESTABLISHED, TIME_WAIT, LISTEN, CLOSE_WAIT; ein stetig wachsender Wert deutet auf ein Verbindungsleck hin", "Sockets TCP par état, par ex. ESTABLISHED, TIME_WAIT, LISTEN, CLOSE_WAIT ; un nombre qui ne cesse de croître signale une fuite de connexions", "Sockets TCP por estado, p. ej. ESTABLISHED, TIME_WAIT, LISTEN, CLOSE_WAIT; un número que no deja de crecer indica una fuga de conexiones")},
+	{Path: "tcp.tcp", Type: "integer", Min: &fieldZero, Source: "tcp", Method: "kernel socket tables",
+		Description: desc("TCP sockets, listeners included", "TCP-Sockets einschließlich lauschender", "Sockets TCP, écoutes comprises", "Sockets TCP, incluidos los de escucha")},
+	{Path: "tcp.udp", Type: "integer", Min: &fieldZero, Source: "tcp", Method: "kernel socket tables",
+		Description: desc("UDP sockets", "UDP-Sockets", "Sockets UDP", "Sockets UDP")},
+	{Path: "tcp.sockets", Type: "integer", Min: &fieldZero, Source: "tcp", Method: "kernel socket tables",
+		Description: desc("All TCP and UDP sockets, IPv4 and IPv6", "Alle TCP- und UDP-Sockets, IPv4 und IPv6", "Tous les sockets TCP et UDP, IPv4 et IPv6", "Todos los sockets TCP y UDP, IPv4 e IPv6")},
+
+	{Path: "vms[].name", Type: "string", Source: "hyperv", Method: "Msvm_ComputerSystem (Windows, Hyper-V)",
+		Description: desc("Virtual machine name, as in Hyper-V Manager", "Name der virtuellen Maschine wie im Hyper-V-Manager", "Nom de la machine virtuelle, comme dans le Gestionnaire Hyper-V", "Nombre de la máquina virtual, como en el Administrador de Hyper-V")},
+	{Path: "vms[].id", Type: "string", Source: "hyperv", Method: "Msvm_ComputerSystem (Windows, Hyper-V)",
+		Description: desc("Virtual machine GUID", "GUID der virtuellen Maschine", "GUID de la machine virtuelle", "GUID de la máquina virtual")},
+	{Path: "vms[].state", Type: "string", Source: "hyperv", Method: "Msvm_ComputerSystem (Windows, Hyper-V)",
+		Description: desc("running, off, saved, paused, starting, stopping, saving, pausing, resuming or other", "running, off, saved, paused, starting, stopping, saving, pausing, resuming oder other", "running, off, saved, paused, starting, stopping, saving, pausing, resuming ou other", "running, off, saved, paused, starting, stopping, saving, pausing, resuming u other")},
+	{Path: "vms[].vcpus", Type: "integer", Min: &fieldZero, Source: "hyperv", Method: "Hyper-V Hypervisor Virtual Processor counters",
+		Description: desc("Virtual processors of a running VM", "Virtuelle Prozessoren einer laufenden VM", "Processeurs virtuels d'une VM en cours d'exécution", "Procesadores virtuales de una VM en ejecución")},
+	{Path: "vms[].cpu", Type: "number", Unit: "%", Min: &fieldZero, Max: &fieldHundred, Source: "hyperv", Method: "Hyper-V Hypervisor Virtual Processor counters",
+		Description: desc("Share of the host's CPU capacity used by the VM since the previous sample", "Anteil der CPU-Kapazität des Hosts, den die VM seit dem vorigen Sample genutzt hat", "Part de la capacité CPU de l'hôte utilisée par la VM depuis l'échantillon précédent", "Parte de la capacidad de CPU del host usada por la VM desde la muestra anterior")},
+	{Path: "vms[].memAssigned", Type: "integer", Unit: "bytes", Min: &fieldZero, Source: "hyperv", Method: "Hyper-V Dynamic Memory VM counters, else the configured memory",
+		Description: desc("Host memory assigned to a running VM", "Einer laufenden VM zugewiesener Hostspeicher", "Mémoire de l'hôte attribuée à une VM en cours d'exécution", "Memoria del host asignada a una VM en ejecución")},
+	{Path: "vms[].uptimeSec", Type: "integer", Unit: "s", Min: &fieldZero, Source: "hyperv", Method: "Msvm_ComputerSystem (Windows, Hyper-V)",
+		Description: desc("Time since the VM was started", "Zeit seit dem Start der VM", "Temps écoulé depuis le démarrage de la VM", "Tiempo desde que se inició la VM")},
+
+	{Path: "wifi[].interface", Type: "string", Source: "wifi", Method: "WLAN API (Windows)",
+		Description: desc("Wireless adapter description", "Beschreibung des WLAN-Adapters", "Description de l'adaptateur sans fil", "Descripción del adaptador inalámbrico")},
+	{Path: "wifi[].ssid", Type: "string", Source: "wifi", Method: "WLAN API (Windows); a host-salted hash with privacy.hashSsids",
+		Description: desc("Name of the connected network", "Name des verbundenen Netzwerks", "Nom du réseau connecté", "Nombre de la red conectada")},
+	{Path: "wifi[].bssid", Type: "string", Source: "wifi", Method: "WLAN API (Windows); a host-salted hash with privacy.hashSsids",
+		Description: desc("MAC address of the access point; changes when the adapter roams", "MAC-Adresse des Access Points; ändert sich beim Roaming", "Adresse MAC du point d'accès ; change lors de l'itinérance", "Dirección MAC del punto de acceso; cambia al hacer roaming")},
+	{Path: "wifi[].signal", Type: "integer", Unit: "%", Min: &fieldZero, Max: &fieldHundred, Source: "wifi", Method: "WLAN API (Windows)",
+		Description: desc("Signal quality as shown by the Windows Wi-Fi icon", "Signalqualität wie im WLAN-Symbol von Windows", "Qualité du signal, comme l'icône Wi-Fi de Windows", "Calidad de la señal, como el icono de Wi-Fi de Windows")},
+	{Path: "wifi[].rssi", Type: "integer", Unit: "dBm", Source: "wifi", Method: "WLAN API (Windows)",
+		Description: desc("Received signal strength; below about -70 dBm the link is weak", "Empfangene Signalstärke; unter etwa -70 dBm ist die Verbindung schwach", "Puissance du signal reçu ; en dessous d'environ -70 dBm la liaison est faible", "Intensidad de la señal recibida; por debajo de unos -70 dBm el enlace es débil")},
+	{Path: "wifi[].channel", Type: "integer", Min: &fieldZero, Source: "wifi", Method: "WLAN API (Windows)",
+		Description: desc("Radio channel", "Funkkanal", "Canal radio", "Canal de radio")},
+	{Path: "wifi[].phy", Type: "string", Source: "wifi", Method: "WLAN API (Windows)",
+		Description: desc("802.11 standard of the connection, e.g. 802.11ax", "802.11-Standard der Verbindung, z. B. 802.11ax", "Norme 802.11 de la connexion, par ex. 802.11ax", "Estándar 802.11 de la conexión, p. ej. 802.11ax")},
+	{Path: "wifi[].rxMbps", Type: "number", Unit: "Mbit/s", Min: &fieldZero, Source: "wifi", Method: "WLAN API (Windows)",
+		Description: desc("Current receive PHY rate", "Aktuelle PHY-Empfangsrate", "Débit PHY de réception actuel", "Velocidad PHY de recepción actual")},
+	{Path: "wifi[].txMbps", Type: "number", Unit: "Mbit/s", Min: &fieldZero, Source: "wifi", Method: "WLAN API (Windows)",
+		Description: desc("Current transmit PHY rate", "Aktuelle PHY-Senderate", "Débit PHY d'émission actuel", "Velocidad PHY de transmisión actual")},
+
+	{Path: "links[].name", Type: "string", Source: "links", Method: "GetAdaptersAddresses (Windows), /sys/class/net (Linux)",
+		Description: desc("Network adapter name", "Name des Netzwerkadapters", "Nom de la carte réseau", "Nombre del adaptador de red")},
+	{Path: "links[].type", Type: "string", Source: "links", Method: "GetAdaptersAddresses (Windows), /sys/class/net (Linux)",
+		Description: desc("Adapter type: ethernet, wifi or other", "Adaptertyp: ethernet, wifi oder other", "Type de carte : ethernet, wifi ou other", "Tipo de adaptador: ethernet, wifi u other")},
+	{Path: "links[].speedMbps", Type: "number", Unit: "Mbit/s", Min: &fieldZero, Source: "links", Method: "GetAdaptersAddresses transmit link speed (Windows), /sys/class/net/*/speed (Linux)",
+		Description: desc("Negotiated link speed, e.g. 1000 for gigabit Ethernet", "Ausgehandelte Verbindungsgeschwindigkeit, z. B. 1000 bei Gigabit-Ethernet", "Vitesse de liaison négociée, par ex. 1000 en Ethernet gigabit", "Velocidad de enlace negociada, p. ej. 1000 en Ethernet gigabit")},
+
+	{Path: "netQuality.grade", Type: "string", Method: "netProbe round: gateway, target loss and latency, DNS timing, captive portal check",
+		Description: desc("Connectivity grade: good, fair, poor or offline", "Verbindungsnote: good, fair, poor oder offline", "Note de connectivité : good, fair, poor ou offline", "Nota de conectividad: good, fair, poor u offline")},
+	{Path: "netQuality.ts", Type: "time", Method: "netProbe round",
+		Description: desc("When the probe round ran", "Zeitpunkt der Messrunde", "Heure de la série de sondes", "Momento de la ronda de sondeo")},
+	{Path: "netQuality.gateway", Type: "string", Method: "default route (GetAdaptersAddresses on Windows, /proc/net/route on Linux)",
+		Description: desc("Detected default gateway", "Erkanntes Standard-Gateway", "Passerelle par défaut détectée", "Puerta de enlace predeterminada detectada")},
+	{Path: "netQuality.gatewayUp", Type: "bool", Method: "ICMP echo to the gateway",
+		Description: desc("The gateway answered", "Das Gateway hat geantwortet", "La passerelle a répondu", "La puerta de enlace respondió")},
+	{Path: "netQuality.dnsMs", Type: "number", Unit: "ms", Min: &fieldZero, Method: "lookup of netProbe.dnsName through the system resolver",
+		Description: desc("DNS resolution time", "Dauer der DNS-Auflösung", "Temps de résolution DNS", "Tiempo de resolución DNS")},
+	{Path: "netQuality.dnsError", Type: "string", Method: "lookup of netProbe.dnsName through the system resolver",
+		Description: desc("Why DNS resolution failed", "Warum die DNS-Auflösung fehlschlug", "Pourquoi la résolution DNS a échoué", "Por qué falló la resolución DNS")},
+	{Path: "netQuality.internet", Type: "bool", Method: "connectivity check (msftconnecttest.com) or a public target answering",
+		Description: desc("The internet is reachable", "Das Internet ist erreichbar", "Internet est joignable", "Internet es accesible")},
+	{Path: "netQuality.captivePortal", Type: "bool", Method: "connectivity check redirected or answered with other content",
+		Description: desc("A captive portal (hotel or guest Wi-Fi login) intercepts traffic", "Ein Captive Portal (Hotel- oder Gast-WLAN-Anmeldung) fängt den Verkehr ab", "Un portail captif (connexion Wi-Fi d'hôtel ou invité) intercepte le trafic", "Un portal cautivo (inicio de sesión de Wi-Fi de hotel o invitados) intercepta el tráfico")},
+
+	{Path: "gpu[].index", Type: "integer", Min: &fieldZero, Source: "gpuDevices", Method: "vendor library order",
+		Description: desc("Position among the vendor's cards", "Position unter den Karten des Herstellers", "Position parmi les cartes du fabricant", "Posición entre las tarjetas del fabricante")},
+	{Path: "gpu[].vendor", Type: "string", Source: "gpuDevices", Method: "NVML or ADL/amdgpu",
+		Description: desc("Card vendor: nvidia or amd", "Kartenhersteller: nvidia oder amd", "Fabricant de la carte : nvidia ou amd", "Fabricante de la tarjeta: nvidia o amd")},
+	{Path: "gpu[].name", Type: "string", Source: "gpuDevices", Method: "NVML or ADL/amdgpu",
+		Description: desc("Card model", "Kartenmodell", "Modèle de la carte", "Modelo de la tarjeta")},
+	{Path: "gpu[].usage", Type: "number", Unit: "%", Min: &fieldZero, Max: &fieldHundred, Source: "gpuDevices", Method: "NVML or ADL/amdgpu",
+		Description: desc("GPU utilization", "GPU-Auslastung", "Utilisation du GPU", "Uso de la GPU")},
+	{Path: "gpu[].memUsed", Type: "integer", Unit: "bytes", Min: &fieldZero, Source: "gpuDevices", Method: "NVML or ADL/amdgpu",
+		Description: desc("Video memory in use", "Belegter Grafikspeicher", "Mémoire vidéo utilisée", "Memoria de vídeo en uso")},
+	{Path: "gpu[].memTotal", Type: "integer", Unit: "bytes", Min: &fieldZero, Source: "gpuDevices", Method: "NVML or ADL/amdgpu",
+		Description: desc("Video memory on the card", "Grafikspeicher der Karte", "Mémoire vidéo de la carte", "Memoria de vídeo de la tarjeta")},
+	{Path: "gpu[].tempC", Type: "number", Unit: "°C", Source: "gpuDevices", Method: "NVML or ADL/amdgpu",
+		Description: desc("GPU core temperature", "Temperatur des GPU-Kerns", "Température du cœur GPU", "Temperatura del núcleo de la GPU")},
+	{Path: "gpu[].powerW", Type: "number", Unit: "W", Min: &fieldZero, Source: "gpuDevices", Method: "NVML or ADL/amdgpu",
+		Description: desc("Board power draw", "Leistungsaufnahme der Karte", "Consommation de la carte", "Consumo de la tarjeta")},
+
+	{Path: "gpuProcesses[].pid", Type: "integer", Source: "gpu", Method: "GPU Engine performance counters (Windows)",
+		Description: desc("Process ID of one of the top GPU users", "Prozess-ID eines der größten GPU-Nutzer", "PID d'un des plus gros utilisateurs du GPU", "PID de uno de los mayores usuarios de la GPU")},
+	{Path: "gpuProcesses[].name", Type: "string", Source: "gpu", Method: "process image name (omitted with privacy.hideProcessNames)",
+		Description: desc("Process name", "Prozessname", "Nom du processus", "Nombre del proceso")},
+	{Path: "gpuProcesses[].usage", Type: "number", Unit: "%", Min: &fieldZero, Max: &fieldHundred, Source: "gpu", Method: "GPU Engine performance counters (Windows)",
+		Description: desc("Utilization of the process's busiest engine type, as in Task Manager", "Auslastung des meistgenutzten Engine-Typs, wie im Task-Manager", "Utilisation du moteur le plus sollicité, comme dans le Gestionnaire des tâches", "Uso del motor más cargado, como en el Administrador de tareas")},
+	{Path: "gpuProcesses[].engine", Type: "string", Source: "gpu", Method: "GPU Engine performance counters (Windows)",
+		Description: desc("That engine type, e.g. 3D, Copy, VideoDecode", "Dieser Engine-Typ, z. B. 3D, Copy, VideoDecode", "Ce type de moteur, par ex. 3D, Copy, VideoDecode", "Ese tipo de motor, p. ej. 3D, Copy, VideoDecode")},
+	{Path: "gpuProcesses[].memUsed", Type: "integer", Unit: "bytes", Min: &fieldZero, Source: "gpu", Method: "GPU Process Memory performance counters (Windows), dedicated usage summed over adapters",
+		Description: desc("Dedicated GPU memory (VRAM) the process uses", "Vom Prozess belegter dedizierter GPU-Speicher (VRAM)", "Mémoire GPU dédiée (VRAM) utilisée par le processus", "Memoria de GPU dedicada (VRAM) que usa el proceso")},
+
+	{Path: "gpuEngines.3d", Type: "number", Unit: "%", Min: &fieldZero, Max: &fieldHundred, Source: "gpu", Method: "GPU Engine performance counters (Windows), busiest 3D engine",
+		Description: desc("3D (graphics) engine utilization", "Auslastung der 3D-Engine (Grafik)", "Utilisation du moteur 3D (graphique)", "Uso del motor 3D (gráficos)")},
+	{Path: "gpuEngines.videoDecode", Type: "number", Unit: "%", Min: &fieldZero, Max: &fieldHundred, Source: "gpu", Method: "GPU Engine performance counters (Windows), busiest VideoDecode engine; AMD VideoCodec counts here too",
+		Description: desc("Video decode engine utilization, e.g. while a media server transcodes", "Auslastung der Video-Decode-Engine, z. B. beim Transkodieren eines Medienservers", "Utilisation du moteur de décodage vidéo, par ex. pendant un transcodage", "Uso del motor de decodificación de vídeo, p. ej. al transcodificar")},
+	{Path: "gpuEngines.videoEncode", Type: "number", Unit: "%", Min: &fieldZero, Max: &fieldHundred, Source: "gpu", Method: "GPU Engine performance counters (Windows), busiest VideoEncode engine; AMD VideoCodec counts here too",
+		Description: desc("Video encode engine utilization, e.g. while streaming or transcoding", "Auslastung der Video-Encode-Engine, z. B. beim Streamen oder Transkodieren", "Utilisation du moteur d'encodage vidéo, par ex. en streaming ou transcodage", "Uso del motor de codificación de vídeo, p. ej. al emitir o transcodificar")},
+	{Path: "gpuEngines.types", Type: "map", Unit: "%", Source: "gpu", Method: "GPU Engine performance counters (Windows), busiest engine of each type",
+		Description: desc("Utilization of every engine type by name, e.g. Copy, Compute_0", "Auslastung jedes Engine-Typs nach Name, z. B. Copy, Compute_0", "Utilisation de chaque type de moteur par nom, par ex. Copy, Compute_0", "Uso de cada tipo de motor por nombre, p. ej. Copy, Compute_0")},
+
+	{Path: "subsystems", Type: "map", Method: "collector bookkeeping",
+		Description: desc("Outcome of each source this cycle: ok, error, timeout, unsupported, skipped, disabled or deferred (slow start)", "Ergebnis jeder Quelle in diesem Zyklus: ok, error, timeout, unsupported, skipped, disabled oder deferred (langsamer Start)", "Résultat de chaque source pour ce cycle : ok, error, timeout, unsupported, skipped, disabled ou deferred (démarrage progressif)", "Resultado de cada fuente en este ciclo: ok, error, timeout, unsupported, skipped, disabled o deferred (arranque gradual)")},
+}
@@ -0,0 +1,168 @@
+package metrics
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/jcdorr003/windash-agent/internal/config"
+	"github.com/shirou/gopsutil/v4/process"
+)
+
+const defaultGPUTopProcesses = 5
+
+// GPUProcess is one of the processes using the GPU the most
+type GPUProcess struct {
+	PID     int32   `json:"pid"`
+	Name    string  `json:"name,omitempty"`    // Omitted with privacy.hideProcessNames
+	Usage   float64 `json:"usage"`             // % of the process's busiest engine type, as in Task Manager
+	Engine  string  `json:"engine"`            // That engine type, e.g. 3D, Copy, VideoDecode
+	MemUsed uint64  `json:"memUsed,omitempty"` // Dedicated GPU memory (VRAM) in bytes, all adapters
+}
+
+// GPUEngines is the utilization of each GPU engine type, so a media server's
+// video transcoding shows separately from 3D load. Each figure is the
+// busiest engine of that type across all adapters.
+type GPUEngines struct {
+	ThreeD      float64            `json:"3d"`          // 3D (graphics) engine %
+	VideoDecode float64            `json:"videoDecode"` // Video decode engine %
+	VideoEncode float64            `json:"videoEncode"` // Video encode engine %
+	Types       map[string]float64 `json:"types"`       // Every engine type by name, e.g. Copy, Compute_0, VideoProcessing
+}
+
+// EnableGPU adds GPU collection to real samples. Must be called before Start.
+func (c *Collector) EnableGPU(cfg config.GPUConfig) {
+	top := cfg.TopProcesses
+	if top <= 0 {
+		top = defaultGPUTopProcesses
+	}
+	c.gpu = &gpuSampler{top: top, names: make(map[int32]string)}
+}
+
+// engineUsage is one GPU engine's utilization attributed to a process
+type engineUsage struct {
+	pid      int32
+	instance string // Physical engine, e.g. luid_0x0_0xD1A5_phys_0_eng_0
+	engine   string
+	usage    float64
+}
+
+// parseEngineInstance parses a "GPU Engine" counter instance name such as
+// pid_1234_luid_0x0_0xD1A5_phys_0_eng_0_engtype_3D
+func parseEngineInstance(name string) (pid int32, instance, engine string, ok bool) {
+	pid, rest, ok := parseProcessInstance(name)
+	if !ok {
+		return 0, "", "", false
+	}
+	instance, engine, found := strings.Cut(rest, "_engtype_")
+	if !found {
+		return 0, "", "", false
+	}
+	return pid, instance, engine, true
+}
+
+// parseProcessInstance splits the PID off a per-process GPU counter instance
+// name such as pid_1234_luid_0x0_0xD1A5_phys_0 ("GPU Process Memory")
+func parseProcessInstance(name string) (pid int32, rest string, ok bool) {
+	rest, found := strings.CutPrefix(name, "pid_")
+	if !found {
+		return 0, "", false
+	}
+	pidStr, rest, _ := strings.Cut(rest, "_")
+	n, err := strconv.ParseInt(pidStr, 10, 32)
+	if err != nil {
+		return 0, "", false
+	}
+	return int32(n), rest, true
+}
+
+// gpuEngineTotals sums every process's share of each physical engine and
+// reports the busiest engine of each type. AMD cards have a single
+// VideoCodec engine for both directions, which counts as decode and encode.
+func gpuEngineTotals(usages []engineUsage) *GPUEngines {
+	type key struct {
+		instance string
+		engine   string
+	}
+	sums := make(map[key]float64)
+	for _, u := range usages {
+		sums[key{u.instance, u.engine}] += u.usage
+	}
+
+	engines := &GPUEngines{Types: make(map[string]float64)}
+	for k, usage := range sums {
+		usage = clampPercent(usage)
+		engines.Types[k.engine] = max(engines.Types[k.engine], usage)
+
+		switch name := strings.ToLower(strings.ReplaceAll(k.engine, " ", "")); {
+		case name == "3d":
+			engines.ThreeD = max(engines.ThreeD, usage)
+		case strings.HasPrefix(name, "videodecode"):
+			engines.VideoDecode = max(engines.VideoDecode, usage)
+		case strings.HasPrefix(name, "videoencode"):
+			engines.VideoEncode = max(engines.VideoEncode, usage)
+		case strings.HasPrefix(name, "videocodec"):
+			engines.VideoDecode = max(engines.VideoDecode, usage)
+			engines.VideoEncode = max(engines.VideoEncode, usage)
+		}
+	}
+	return engines
+}
+
+// topGPUProcesses sums utilization per process and engine type, scores
+// each process by its busiest engine type and returns the top n with their
+// dedicated memory from memUsed, looking up their names if withNames is set
+func (g *gpuSampler) topGPUProcesses(usages []engineUsage, memUsed map[int32]uint64, withNames bool) []GPUProcess {
+	type key struct {
+		pid    int32
+		engine string
+	}
+	sums := make(map[key]float64)
+	for _, u := range usages {
+		if u.pid > 0 {
+			sums[key{u.pid, u.engine}] += u.usage
+		}
+	}
+
+	best := make(map[int32]GPUProcess)
+	for k, usage := range sums {
+		if p, ok := best[k.pid]; !ok || usage > p.Usage {
+			best[k.pid] = GPUProcess{PID: k.pid, Usage: clampPercent(usage), Engine: k.engine}
+		}
+	}
+
+	procs := make([]GPUProcess, 0, len(best))
+	for _, p := range best {
+		if p.Usage > 0 {
+			procs = append(procs, p)
+		}
+	}
+	sort.Slice(procs, func(i, j int) bool { return procs[i].Usage > procs[j].Usage })
+	procs = procs[:min(g.top, len(procs))]
+	for i := range procs {
+		procs[i].MemUsed = memUsed[procs[i].PID]
+	}
+
+	if withNames {
+		for i := range procs {
+			procs[i].Name = g.processName(procs[i].PID)
+		}
+	}
+	return procs
+}
+
+// processName looks up a process name, caching it per PID
+func (g *gpuSampler) processName(pid int32) string {
+	if name, ok := g.names[pid]; ok {
+		return name
+	}
+	name := ""
+	if p, err := process.NewProcess(pid); err == nil {
+		name, _ = p.Name()
+	}
+	if len(g.names) > 1024 {
+		clear(g.names) // PIDs get reused; don't let the cache grow forever
+	}
+	g.names[pid] = name
+	return name
+}
@@ -0,0 +1,16 @@
+//go:build !windows
+
+package metrics
+
+import "errors"
+
+// gpuSampler reads GPU usage (Windows only)
+type gpuSampler struct {
+	top   int
+	names map[int32]string
+}
+
+// collect is not implemented outside Windows
+func (g *gpuSampler) collect(withNames bool) ([]GPUProcess, *GPUEngines, error) {
+	return nil, nil, errors.ErrUnsupported
+}
@@ -0,0 +1,166 @@
+//go:build windows
+
+package metrics
+
+import (
+	"errors"
+	"fmt"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+)
+
+var (
+	modpdh                           = windows.NewLazySystemDLL("pdh.dll")
+	procPdhOpenQueryW                = modpdh.NewProc("PdhOpenQueryW")
+	procPdhAddEnglishCounterW        = modpdh.NewProc("PdhAddEnglishCounterW")
+	procPdhCollectQueryData          = modpdh.NewProc("PdhCollectQueryData")
+	procPdhGetFormattedCounterArrayW = modpdh.NewProc("PdhGetFormattedCounterArrayW")
+)
+
+const (
+	pdhFmtDouble   = 0x00000200
+	pdhMoreData    = 0x800007D2
+	pdhNoData      = 0x800007D5
+	pdhCStatusOK   = 0x0
+	pdhCStatusNew  = 0x1
+	gpuEngineQuery = `\GPU Engine(*)\Utilization Percentage`
+	gpuMemoryQuery = `\GPU Process Memory(*)\Dedicated Usage`
+)
+
+// pdhCounterItem is PDH_FMT_COUNTERVALUE_ITEM_W with a double value (64-bit layout)
+type pdhCounterItem struct {
+	name   *uint16
+	status uint32
+	_      uint32
+	value  float64
+}
+
+// gpuSampler reads per-process GPU engine utilization from the Windows GPU
+// performance counters, which work for NVIDIA, AMD and Intel alike (WDDM 2.0+).
+// The query stays open between samples because utilization is a rate.
+type gpuSampler struct {
+	top   int
+	names map[int32]string
+
+	query      windows.Handle
+	counter    windows.Handle
+	memCounter windows.Handle // 0 if the driver has no GPU Process Memory counters
+	primed     bool
+}
+
+// collect returns the top GPU processes and the utilization per engine type
+// since the previous call
+func (g *gpuSampler) collect(withNames bool) ([]GPUProcess, *GPUEngines, error) {
+	if g.query == 0 {
+		if err := g.open(); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	if r, _, _ := procPdhCollectQueryData.Call(uintptr(g.query)); r != 0 {
+		return nil, nil, fmt.Errorf("PdhCollectQueryData: 0x%08X", uint32(r))
+	}
+	if !g.primed {
+		g.primed = true // Rates need two collections; the warm-up pass provides the first
+		return nil, nil, nil
+	}
+
+	usages, err := g.read()
+	if err != nil {
+		return nil, nil, err
+	}
+	memUsed, err := g.readMemory()
+	if err != nil {
+		return nil, nil, err
+	}
+	return g.topGPUProcesses(usages, memUsed, withNames), gpuEngineTotals(usages), nil
+}
+
+// open creates the PDH query. A machine without a WDDM 2.0 GPU has no
+// GPU Engine counters, which is reported as unsupported.
+func (g *gpuSampler) open() error {
+	if r, _, _ := procPdhOpenQueryW.Call(0, 0, uintptr(unsafe.Pointer(&g.query))); r != 0 {
+		return fmt.Errorf("PdhOpenQuery: 0x%08X", uint32(r))
+	}
+	path, _ := windows.UTF16PtrFromString(gpuEngineQuery)
+	if r, _, _ := procPdhAddEnglishCounterW.Call(uintptr(g.query), uintptr(unsafe.Pointer(path)), 0, uintptr(unsafe.Pointer(&g.counter))); r != 0 {
+		return fmt.Errorf("GPU Engine counters: 0x%08X: %w", uint32(r), errors.ErrUnsupported)
+	}
+	// Older drivers have engine counters but no per-process memory
+	path, _ = windows.UTF16PtrFromString(gpuMemoryQuery)
+	if r, _, _ := procPdhAddEnglishCounterW.Call(uintptr(g.query), uintptr(unsafe.Pointer(path)), 0, uintptr(unsafe.Pointer(&g.memCounter))); r != 0 {
+		g.memCounter = 0
+	}
+	return nil
+}
+
+// read returns the utilization of every GPU engine instance
+func (g *gpuSampler) read() ([]engineUsage, error) {
+	items, err := formattedArray(g.counter)
+	if err != nil {
+		return nil, err
+	}
+	usages := make([]engineUsage, 0, len(items))
+	for _, item := range items {
+		pid, instance, engine, ok := parseEngineInstance(item.name)
+		if !ok {
+			continue
+		}
+		usages = append(usages, engineUsage{pid: pid, instance: instance, engine: engine, usage: item.value})
+	}
+	return usages, nil
+}
+
+// readMemory returns each process's dedicated GPU memory in bytes, summed
+// over adapters
+func (g *gpuSampler) readMemory() (map[int32]uint64, error) {
+	if g.memCounter == 0 {
+		return nil, nil
+	}
+	items, err := formattedArray(g.memCounter)
+	if err != nil {
+		return nil, err
+	}
+	memUsed := make(map[int32]uint64, len(items))
+	for _, item := range items {
+		if pid, _, ok := parseProcessInstance(item.name); ok && item.value > 0 {
+			memUsed[pid] += uint64(item.value)
+		}
+	}
+	return memUsed, nil
+}
+
+// counterValue is one instance of a wildcard counter
+type counterValue struct {
+	name  string
+	value float64
+}
+
+// formattedArray returns every valid instance of a wildcard counter
+func formattedArray(counter windows.Handle) ([]counterValue, error) {
+	var size, count uint32
+	r, _, _ := procPdhGetFormattedCounterArrayW.Call(uintptr(counter), pdhFmtDouble, uintptr(unsafe.Pointer(&size)), uintptr(unsafe.Pointer(&count)), 0)
+	if r == pdhNoData || (r == 0 && count == 0) {
+		return nil, nil
+	}
+	if r != pdhMoreData {
+		return nil, fmt.Errorf("PdhGetFormattedCounterArray: 0x%08X", uint32(r))
+	}
+
+	buf := make([]byte, size)
+	r, _, _ = procPdhGetFormattedCounterArrayW.Call(uintptr(counter), pdhFmtDouble, uintptr(unsafe.Pointer(&size)), uintptr(unsafe.Pointer(&count)), uintptr(unsafe.Pointer(&buf[0])))
+	if r != 0 {
+		return nil, fmt.Errorf("PdhGetFormattedCounterArray: 0x%08X", uint32(r))
+	}
+
+	items := unsafe.Slice((*pdhCounterItem)(unsafe.Pointer(&buf[0])), count)
+	values := make([]counterValue, 0, count)
+	for _, item := range items {
+		if item.status != pdhCStatusOK && item.status != pdhCStatusNew {
+			continue
+		}
+		values = append(values, counterValue{name: windows.UTF16PtrToString(item.name), value: item.value})
+	}
+	return values, nil
+}
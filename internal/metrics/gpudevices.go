@@ -0,0 +1,77 @@
+package metrics
+
+import (
+	"errors"
+	"fmt"
+)
+
+// GPUDevice reports one graphics card's load, memory, temperature and power
+type GPUDevice struct {
+	Index    int     `json:"index"`            // Position among the vendor's cards
+	Vendor   string  `json:"vendor"`           // nvidia or amd
+	Name     string  `json:"name,omitempty"`   // Model name
+	Usage    float64 `json:"usage"`            // GPU utilization %
+	MemUsed  uint64  `json:"memUsed"`          // Dedicated memory (VRAM) used in bytes
+	MemTotal uint64  `json:"memTotal"`         // Dedicated memory (VRAM) in bytes
+	TempC    float64 `json:"tempC,omitempty"`  // Core temperature, when the card reports it
+	PowerW   float64 `json:"powerW,omitempty"` // Board power draw, when the card reports it
+}
+
+// Validate checks the utilization and that used memory does not exceed total
+func (d GPUDevice) Validate() error {
+	if !validPercent(d.Usage) {
+		return fmt.Errorf("gpu %d usage out of range: %v", d.Index, d.Usage)
+	}
+	if d.MemUsed > d.MemTotal {
+		return fmt.Errorf("gpu %d: memUsed (%d) exceeds memTotal (%d)", d.Index, d.MemUsed, d.MemTotal)
+	}
+	return nil
+}
+
+// EnableGPUDevices adds per-card GPU stats from the vendor libraries to
+// real samples. Must be called before Start.
+func (c *Collector) EnableGPUDevices() {
+	c.gpuDevices = &gpuDevices{}
+}
+
+// gpuDevices reads card stats from every vendor library present, so a
+// machine with both an NVIDIA and an AMD card reports both
+type gpuDevices struct {
+	nvidia nvml
+	amd    amdGPU
+}
+
+// collect returns the stats of every card found. It is unsupported when no
+// vendor library is installed, and fails only when no card could be read.
+func (g *gpuDevices) collect() ([]GPUDevice, error) {
+	vendors := []struct {
+		name string
+		read func() ([]GPUDevice, error)
+	}{
+		{"nvml", g.nvidia.devices},
+		{"amd", g.amd.devices},
+	}
+
+	var devices []GPUDevice
+	var errs []error
+	supported := false
+	for _, v := range vendors {
+		found, err := v.read()
+		if errors.Is(err, errors.ErrUnsupported) {
+			continue
+		}
+		supported = true
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", v.name, err))
+			continue
+		}
+		devices = append(devices, found...)
+	}
+	switch {
+	case !supported:
+		return nil, errors.ErrUnsupported
+	case len(devices) == 0 && len(errs) > 0:
+		return nil, errors.Join(errs...)
+	}
+	return devices, nil
+}
@@ -0,0 +1,83 @@
+package metrics
+
+import (
+	"math"
+
+	"github.com/jcdorr003/windash-agent/internal/config"
+)
+
+// DefaultHealthWeights are used when no weights are configured
+var DefaultHealthWeights = config.HealthConfig{
+	CPU:    0.25,
+	Memory: 0.25,
+	Disk:   0.25,
+	Temps:  0.10,
+	Alerts: 0.15,
+}
+
+const (
+	healthDiskFreeTarget = 20.0 // Free space (%) at or above which the disk factor is perfect
+	healthAlertPenalty   = 25.0 // Points lost per open alert
+	healthTempCool       = 60.0 // Hottest sensor (°C) at or below which the temps factor is perfect
+	healthTempHot        = 95.0 // Hottest sensor (°C) at or above which the temps factor is zero
+)
+
+// ComputeHealth scores a sample from 0 (critical) to 100 (healthy) as the
+// weighted average of per-factor scores. Factors the sample has no data for
+// are skipped and the remaining weights rescaled.
+func ComputeHealth(s *SampleV1, weights config.HealthConfig, openAlerts int) int {
+	if weights == (config.HealthConfig{}) {
+		weights = DefaultHealthWeights
+	}
+
+	var total, weightSum float64
+	add := func(weight, score float64) {
+		if weight <= 0 {
+			return
+		}
+		total += weight * clampPercent(score)
+		weightSum += weight
+	}
+
+	// CPU headroom
+	if s.HasData("cpu") {
+		add(weights.CPU, 100-s.CPU.Total)
+	}
+
+	// Memory pressure
+	if s.HasData("mem") && s.Mem.Total > 0 {
+		add(weights.Memory, 100-float64(s.Mem.Used)/float64(s.Mem.Total)*100)
+	}
+
+	// Free space on the fullest volume, perfect above the target
+	if s.HasData("disk") {
+		minFree := -1.0
+		for _, d := range s.Disks {
+			if d.Total == 0 {
+				continue
+			}
+			free := float64(d.Total-d.Used) / float64(d.Total) * 100
+			if minFree < 0 || free < minFree {
+				minFree = free
+			}
+		}
+		if minFree >= 0 {
+			add(weights.Disk, minFree/healthDiskFreeTarget*100)
+		}
+	}
+
+	// Hottest temperature sensor, scaled between cool and hot
+	if s.HasData("temps") && s.Temps != nil {
+		if hottest, ok := s.Temps.Hottest(); ok {
+			add(weights.Temps, (healthTempHot-hottest)/(healthTempHot-healthTempCool)*100)
+		}
+	}
+
+	// Open alerts
+	add(weights.Alerts, 100-float64(openAlerts)*healthAlertPenalty)
+
+	if weightSum == 0 {
+		return 100
+	}
+	return int(math.Round(total / weightSum))
+}
@@ -0,0 +1,38 @@
+package metrics
+
+import "sync"
+
+// sampleHistory keeps the most recent samples in memory so events can be
+// reported with the samples that led up to them
+type sampleHistory struct {
+	mu      sync.Mutex
+	size    int
+	samples []*SampleV1 // Oldest first
+}
+
+// add records s, evicting the oldest sample beyond size
+func (h *sampleHistory) add(s *SampleV1) {
+	if h == nil {
+		return
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if len(h.samples) == h.size {
+		copy(h.samples, h.samples[1:])
+		h.samples = h.samples[:h.size-1]
+	}
+	h.samples = append(h.samples, s)
+}
+
+// recent returns up to n of the newest samples, oldest first
+func (h *sampleHistory) recent(n int) []*SampleV1 {
+	if h == nil {
+		return nil
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	n = min(n, len(h.samples))
+	out := make([]*SampleV1, n)
+	copy(out, h.samples[len(h.samples)-n:])
+	return out
+}
@@ -0,0 +1,10 @@
+//go:build !windows
+
+package metrics
+
+import "errors"
+
+// readHyperV is only implemented on Windows
+func readHyperV() ([]hypervGuest, error) {
+	return nil, errors.ErrUnsupported
+}
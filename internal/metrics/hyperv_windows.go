@@ -0,0 +1,147 @@
+//go:build windows
+
+package metrics
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/yusufpapurcu/wmi"
+	"golang.org/x/sys/windows"
+)
+
+// virtualizationNamespace holds the Hyper-V management classes. It exists
+// only with the Hyper-V role installed.
+const virtualizationNamespace = `root\virtualization\v2`
+
+// msvmComputerSystem is the Hyper-V class for the host and each VM
+type msvmComputerSystem struct {
+	Name                 string // VM GUID
+	ElementName          string // Display name
+	EnabledState         uint16
+	OnTimeInMilliseconds uint64
+}
+
+// msvmMemorySettingData is a VM's memory configuration
+type msvmMemorySettingData struct {
+	InstanceID      string // Microsoft:<VM GUID>\<setting GUID>
+	VirtualQuantity uint64 // MB
+}
+
+// hvVirtualProcessor is the Hyper-V Hypervisor Virtual Processor counter set
+type hvVirtualProcessor struct {
+	Name                string // <VM name>:Hv VP <n>
+	PercentTotalRunTime uint64 // 100ns units
+}
+
+// dynamicMemoryVM is the Hyper-V Dynamic Memory VM counter set
+type dynamicMemoryVM struct {
+	Name           string // VM name
+	PhysicalMemory uint64 // MB
+}
+
+// readHyperV reads every VM on the host. Without the Hyper-V role this is
+// unsupported; the management classes also need administrator rights or
+// membership of Hyper-V Administrators.
+func readHyperV() ([]hypervGuest, error) {
+	var systems []msvmComputerSystem
+	q := "SELECT Name, ElementName, EnabledState, OnTimeInMilliseconds FROM Msvm_ComputerSystem WHERE Caption = 'Virtual Machine'"
+	if err := wmi.QueryNamespace(q, &systems, virtualizationNamespace); err != nil {
+		if !windows.GetCurrentProcessToken().IsElevated() {
+			return nil, fmt.Errorf("Msvm_ComputerSystem: %w (needs administrator or Hyper-V Administrators)", err)
+		}
+		return nil, fmt.Errorf("Hyper-V not installed: %w: %w", err, errors.ErrUnsupported)
+	}
+
+	// Counters are keyed by VM name. They are missing while no VM runs, and
+	// the dynamic memory set for VMs with static memory on older hosts.
+	runTimes := make(map[string]float64)
+	vcpus := make(map[string]int)
+	var vps []hvVirtualProcessor
+	if err := wmi.Query("SELECT Name, PercentTotalRunTime FROM Win32_PerfRawData_HvStats_HyperVHypervisorVirtualProcessor", &vps); err == nil {
+		for _, vp := range vps {
+			name, _, found := strings.Cut(vp.Name, ":Hv VP ")
+			if !found {
+				continue // _Total
+			}
+			runTimes[name] += float64(vp.PercentTotalRunTime) * 1e-7
+			vcpus[name]++
+		}
+	}
+	assigned := make(map[string]uint64)
+	var dm []dynamicMemoryVM
+	if err := wmi.Query("SELECT Name, PhysicalMemory FROM Win32_PerfRawData_BalancerStats_HyperVDynamicMemoryVM", &dm); err == nil {
+		for _, m := range dm {
+			assigned[m.Name] = m.PhysicalMemory << 20
+		}
+	}
+
+	guests := make([]hypervGuest, 0, len(systems))
+	var static map[string]uint64
+	for _, s := range systems {
+		g := hypervGuest{VirtualMachine: VirtualMachine{
+			Name:  s.ElementName,
+			ID:    strings.ToLower(s.Name),
+			State: vmState(s.EnabledState),
+		}}
+		if g.State == VMRunning {
+			g.VCPUs = vcpus[s.ElementName]
+			g.runTime = runTimes[s.ElementName]
+			g.UptimeSec = s.OnTimeInMilliseconds / 1000
+			g.MemAssigned = assigned[s.ElementName]
+			if g.MemAssigned == 0 {
+				if static == nil {
+					static = staticMemory()
+				}
+				g.MemAssigned = static[g.ID]
+			}
+		}
+		guests = append(guests, g)
+	}
+	return guests, nil
+}
+
+// staticMemory reads each VM's configured memory in bytes by VM GUID, for
+// VMs without dynamic memory counters. Snapshots have settings of their
+// own, under the snapshot's GUID, so they don't match a VM.
+func staticMemory() map[string]uint64 {
+	var settings []msvmMemorySettingData
+	memory := make(map[string]uint64)
+	if err := wmi.QueryNamespace("SELECT InstanceID, VirtualQuantity FROM Msvm_MemorySettingData", &settings, virtualizationNamespace); err != nil {
+		return memory
+	}
+	for _, s := range settings {
+		id, _, found := strings.Cut(strings.TrimPrefix(s.InstanceID, "Microsoft:"), `\`)
+		if found {
+			memory[strings.ToLower(id)] = s.VirtualQuantity << 20
+		}
+	}
+	return memory
+}
+
+// vmState maps Msvm_ComputerSystem.EnabledState to a VirtualMachine state
+func vmState(enabledState uint16) string {
+	switch enabledState {
+	case 2:
+		return VMRunning
+	case 3:
+		return VMOff
+	case 6, 32769:
+		return VMSaved
+	case 9, 32768:
+		return VMPaused
+	case 10, 32770:
+		return VMStarting
+	case 4, 32774:
+		return VMStopping
+	case 32773:
+		return VMSaving
+	case 32776:
+		return VMPausing
+	case 32777:
+		return VMResuming
+	default:
+		return VMOther
+	}
+}
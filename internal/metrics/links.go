@@ -0,0 +1,15 @@
+package metrics
+
+// NetLink is a connected network adapter and its negotiated speed, to tell
+// a slow network from a slow link
+type NetLink struct {
+	Name      string  `json:"name"`                // Adapter name, e.g. Ethernet, eth0
+	Type      string  `json:"type"`                // ethernet, wifi or other
+	SpeedMbps float64 `json:"speedMbps,omitempty"` // Negotiated link speed; omitted when the driver doesn't report it
+}
+
+// EnableLinks adds each connected adapter's link speed to real samples.
+// Must be called before Start.
+func (c *Collector) EnableLinks() {
+	c.links = true
+}
@@ -0,0 +1,45 @@
+//go:build linux
+
+package metrics
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+const sysClassNet = "/sys/class/net"
+
+// readLinks lists the physical interfaces that are up with their speed from
+// sysfs. Virtual ones (bridges, veth, docker) have no device link and are
+// skipped; Wi-Fi drivers usually report no speed.
+func readLinks(ctx context.Context) ([]NetLink, error) {
+	entries, err := os.ReadDir(sysClassNet)
+	if err != nil {
+		return nil, err
+	}
+	var links []NetLink
+	for _, e := range entries {
+		dir := filepath.Join(sysClassNet, e.Name())
+		if _, err := os.Stat(filepath.Join(dir, "device")); err != nil {
+			continue
+		}
+		if state, _ := os.ReadFile(filepath.Join(dir, "operstate")); strings.TrimSpace(string(state)) != "up" {
+			continue
+		}
+		link := NetLink{Name: e.Name(), Type: "ethernet"}
+		if _, err := os.Stat(filepath.Join(dir, "wireless")); err == nil {
+			link.Type = "wifi"
+		}
+		// Reading speed fails on some drivers and gives -1 when unknown
+		if data, err := os.ReadFile(filepath.Join(dir, "speed")); err == nil {
+			if mbps, err := strconv.Atoi(strings.TrimSpace(string(data))); err == nil && mbps > 0 {
+				link.SpeedMbps = float64(mbps)
+			}
+		}
+		links = append(links, link)
+	}
+	return links, nil
+}
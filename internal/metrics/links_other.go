@@ -0,0 +1,13 @@
+//go:build !windows && !linux
+
+package metrics
+
+import (
+	"context"
+	"errors"
+)
+
+// readLinks is only implemented on Windows and Linux
+func readLinks(ctx context.Context) ([]NetLink, error) {
+	return nil, errors.ErrUnsupported
+}
@@ -0,0 +1,58 @@
+//go:build windows
+
+package metrics
+
+import (
+	"context"
+	"fmt"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+)
+
+// IANA interface types (IFTYPE) reported by GetAdaptersAddresses
+const (
+	ifTypeEthernet  = 6
+	ifTypeLoopback  = 24
+	ifTypeIEEE80211 = 71
+	ifTypeTunnel    = 131
+)
+
+// readLinks lists the adapters that are up with their transmit link speed,
+// the figure shown as "Speed" in the adapter status dialog
+func readLinks(ctx context.Context) ([]NetLink, error) {
+	size := uint32(15 * 1024)
+	var buf []byte
+	for {
+		buf = make([]byte, size)
+		err := windows.GetAdaptersAddresses(windows.AF_UNSPEC,
+			windows.GAA_FLAG_SKIP_ANYCAST|windows.GAA_FLAG_SKIP_MULTICAST|windows.GAA_FLAG_SKIP_DNS_SERVER,
+			0, (*windows.IpAdapterAddresses)(unsafe.Pointer(&buf[0])), &size)
+		if err == nil {
+			break
+		}
+		if err != windows.ERROR_BUFFER_OVERFLOW {
+			return nil, fmt.Errorf("GetAdaptersAddresses: %w", err)
+		}
+	}
+
+	var links []NetLink
+	for a := (*windows.IpAdapterAddresses)(unsafe.Pointer(&buf[0])); a != nil; a = a.Next {
+		if a.OperStatus != windows.IfOperStatusUp || a.IfType == ifTypeLoopback || a.IfType == ifTypeTunnel {
+			continue
+		}
+		link := NetLink{Name: windows.UTF16PtrToString(a.FriendlyName), Type: "other"}
+		switch a.IfType {
+		case ifTypeEthernet:
+			link.Type = "ethernet"
+		case ifTypeIEEE80211:
+			link.Type = "wifi"
+		}
+		// ^0 means unknown
+		if a.TransmitLinkSpeed != 0 && a.TransmitLinkSpeed != ^uint64(0) {
+			link.SpeedMbps = float64(a.TransmitLinkSpeed) / 1e6
+		}
+		links = append(links, link)
+	}
+	return links, nil
+}
@@ -0,0 +1,13 @@
+//go:build !windows
+
+package metrics
+
+import "errors"
+
+// nvml reads NVIDIA card stats (Windows only; elsewhere NVML needs cgo)
+type nvml struct{}
+
+// devices is not implemented outside Windows
+func (n *nvml) devices() ([]GPUDevice, error) {
+	return nil, errors.ErrUnsupported
+}
@@ -0,0 +1,165 @@
+//go:build windows
+
+package metrics
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"syscall"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+)
+
+const (
+	nvmlSuccess         = 0
+	nvmlErrNotSupported = 3
+	nvmlTemperatureGPU  = 0
+	nvmlNameBufferSize  = 96 // NVML_DEVICE_NAME_V2_BUFFER_SIZE
+)
+
+// nvmlUtilization is nvmlUtilization_t
+type nvmlUtilization struct {
+	gpu    uint32
+	memory uint32
+}
+
+// nvmlMemory is nvmlMemory_t
+type nvmlMemory struct {
+	total uint64
+	free  uint64
+	used  uint64
+}
+
+// nvml reads NVIDIA card stats through nvml.dll, which ships with the
+// driver. The library is loaded and initialized on first use and stays
+// loaded for the life of the process.
+type nvml struct {
+	loaded  bool
+	initErr error
+	procs   map[string]uintptr
+}
+
+var nvmlProcNames = []string{
+	"nvmlInit_v2",
+	"nvmlDeviceGetCount_v2",
+	"nvmlDeviceGetHandleByIndex_v2",
+	"nvmlDeviceGetName",
+	"nvmlDeviceGetUtilizationRates",
+	"nvmlDeviceGetMemoryInfo",
+	"nvmlDeviceGetTemperature",
+	"nvmlDeviceGetPowerUsage",
+}
+
+// devices returns the stats of every NVIDIA card
+func (n *nvml) devices() ([]GPUDevice, error) {
+	if !n.loaded {
+		n.loaded = true
+		n.initErr = n.init()
+	}
+	if n.initErr != nil {
+		return nil, n.initErr
+	}
+
+	var count uint32
+	if err := n.call("nvmlDeviceGetCount_v2", uintptr(unsafe.Pointer(&count))); err != nil {
+		return nil, err
+	}
+	devices := make([]GPUDevice, 0, count)
+	for i := range count {
+		var device uintptr
+		if err := n.call("nvmlDeviceGetHandleByIndex_v2", uintptr(i), uintptr(unsafe.Pointer(&device))); err != nil {
+			return nil, fmt.Errorf("device %d: %w", i, err)
+		}
+		d, err := n.device(device)
+		if err != nil {
+			return nil, fmt.Errorf("device %d: %w", i, err)
+		}
+		d.Index = int(i)
+		devices = append(devices, d)
+	}
+	return devices, nil
+}
+
+// device reads one card. Utilization and memory are required; temperature
+// and power are left out on cards that don't report them.
+func (n *nvml) device(device uintptr) (GPUDevice, error) {
+	d := GPUDevice{Vendor: "nvidia"}
+
+	name := make([]byte, nvmlNameBufferSize)
+	if err := n.call("nvmlDeviceGetName", device, uintptr(unsafe.Pointer(&name[0])), uintptr(len(name))); err == nil {
+		d.Name = windows.ByteSliceToString(name)
+	}
+
+	var util nvmlUtilization
+	if err := n.call("nvmlDeviceGetUtilizationRates", device, uintptr(unsafe.Pointer(&util))); err != nil {
+		return d, fmt.Errorf("utilization: %w", err)
+	}
+	d.Usage = clampPercent(float64(util.gpu))
+
+	var mem nvmlMemory
+	if err := n.call("nvmlDeviceGetMemoryInfo", device, uintptr(unsafe.Pointer(&mem))); err != nil {
+		return d, fmt.Errorf("memory: %w", err)
+	}
+	d.MemUsed, d.MemTotal = mem.used, mem.total
+
+	var temp uint32
+	if err := n.call("nvmlDeviceGetTemperature", device, nvmlTemperatureGPU, uintptr(unsafe.Pointer(&temp))); err == nil {
+		d.TempC = float64(temp)
+	}
+	var milliwatts uint32
+	if err := n.call("nvmlDeviceGetPowerUsage", device, uintptr(unsafe.Pointer(&milliwatts))); err == nil {
+		d.PowerW = float64(milliwatts) / 1000
+	}
+	return d, nil
+}
+
+// init loads nvml.dll and initializes the library. No library (no NVIDIA
+// driver) or a failed initialization (no NVIDIA card) is unsupported.
+func (n *nvml) init() error {
+	lib, err := loadNVML()
+	if err != nil {
+		return fmt.Errorf("nvml.dll: %w: %w", err, errors.ErrUnsupported)
+	}
+	n.procs = make(map[string]uintptr, len(nvmlProcNames))
+	for _, name := range nvmlProcNames {
+		proc, err := windows.GetProcAddress(lib, name)
+		if err != nil {
+			return fmt.Errorf("nvml.dll %s: %w: %w", name, err, errors.ErrUnsupported)
+		}
+		n.procs[name] = proc
+	}
+	if err := n.call("nvmlInit_v2"); err != nil {
+		return fmt.Errorf("nvmlInit: %w: %w", err, errors.ErrUnsupported)
+	}
+	return nil
+}
+
+// loadNVML loads nvml.dll from System32, where current drivers put it,
+// falling back to the NVSMI folder used by older drivers
+func loadNVML() (windows.Handle, error) {
+	lib, err := windows.LoadLibraryEx("nvml.dll", 0, windows.LOAD_LIBRARY_SEARCH_SYSTEM32)
+	if err == nil {
+		return lib, nil
+	}
+	programFiles := os.Getenv("ProgramFiles")
+	if programFiles == "" {
+		return 0, err
+	}
+	return windows.LoadLibraryEx(filepath.Join(programFiles, "NVIDIA Corporation", "NVSMI", "nvml.dll"), 0, windows.LOAD_WITH_ALTERED_SEARCH_PATH)
+}
+
+// call invokes an NVML function, turning its nvmlReturn_t into an error
+func (n *nvml) call(name string, args ...uintptr) error {
+	r, _, _ := syscall.SyscallN(n.procs[name], args...)
+	switch r {
+	case nvmlSuccess:
+		return nil
+	case nvmlErrNotSupported:
+		return fmt.Errorf("%s: %w", name, errors.ErrUnsupported)
+	default:
+		return fmt.Errorf("%s: nvmlReturn %d", name, r)
+	}
+}
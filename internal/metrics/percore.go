@@ -0,0 +1,76 @@
+package metrics
+
+import (
+	"sort"
+	"sync/atomic"
+
+	"github.com/jcdorr003/windash-agent/internal/config"
+)
+
+// Defaults for per-core trimming on many-core machines
+const (
+	defaultPerCoreLimit = 32
+	defaultTopCores     = 8
+	coreHistogramBins   = 10 // 0-10%, 10-20%, ... 90-100%
+)
+
+// CoreUsage is one core's utilization, identified by its index
+type CoreUsage struct {
+	Core  int     `json:"core"`
+	Usage float64 `json:"usage"` // %
+}
+
+// perCoreTrimmer replaces the full per-core array with the busiest cores and
+// a utilization histogram once the core count exceeds a limit, since on
+// 64-128 core workstations perCore dominates the payload. Servers that
+// don't read the trimmed fields would lose per-core data, so it only trims
+// while the server has accepted the trimmedCores feature.
+type perCoreTrimmer struct {
+	limit int // Trim when there are more cores than this (<0 disables)
+	topK  int
+	every int // Still send the full array every N samples (0 = never)
+	count int
+
+	accepted atomic.Bool // The server accepted trimmedCores (set by the WebSocket client)
+}
+
+// newPerCoreTrimmer applies defaults to cfg
+func newPerCoreTrimmer(cfg config.CPUConfig) *perCoreTrimmer {
+	t := &perCoreTrimmer{limit: cfg.PerCoreLimit, topK: cfg.TopCores, every: cfg.PerCoreEvery}
+	if t.limit == 0 {
+		t.limit = defaultPerCoreLimit
+	}
+	if t.topK <= 0 {
+		t.topK = defaultTopCores
+	}
+	return t
+}
+
+func (t *perCoreTrimmer) Name() string { return "perCore" }
+
+// Process trims s.CPU in place if it has too many cores
+func (t *perCoreTrimmer) Process(s *SampleV1) *SampleV1 {
+	n := len(s.CPU.PerCore)
+	if !t.accepted.Load() || t.limit < 0 || n <= t.limit {
+		return s
+	}
+
+	s.CPU.Cores = n
+	s.CPU.Histogram = make([]int, coreHistogramBins)
+	cores := make([]CoreUsage, n)
+	for i, v := range s.CPU.PerCore {
+		cores[i] = CoreUsage{Core: i, Usage: v}
+		bin := min(int(v/(100/coreHistogramBins)), coreHistogramBins-1)
+		s.CPU.Histogram[max(bin, 0)]++
+	}
+	sort.SliceStable(cores, func(i, j int) bool { return cores[i].Usage > cores[j].Usage })
+	s.CPU.TopCores = cores[:min(t.topK, n)]
+
+	t.count++
+	if t.every > 0 && (t.count-1)%t.every == 0 {
+		return s // Keep the full array on this sample
+	}
+	s.CPU.PerCore = nil
+	s.CPU.PerCoreMHz = nil
+	return s
+}
@@ -0,0 +1,104 @@
+package metrics
+
+import (
+	"fmt"
+
+	"github.com/jcdorr003/windash-agent/internal/alerts"
+	"github.com/jcdorr003/windash-agent/internal/config"
+)
+
+// DefaultPipeline is the stage order used when none is configured
+var DefaultPipeline = []string{"perCore", "health", "validate", "suppress"}
+
+// Stage is one step between collection and transport. Process may modify
+// the sample in place or return a replacement; returning nil drops it.
+type Stage interface {
+	Name() string
+	Process(s *SampleV1) *SampleV1
+}
+
+// Pipeline runs stages in order
+type Pipeline []Stage
+
+// Run passes s through every stage. It returns the sample to send (nil if a
+// stage dropped it) and the last version before any drop, which is still
+// worth keeping locally (see Collector.Latest).
+func (p Pipeline) Run(s *SampleV1) (out, kept *SampleV1) {
+	out = s
+	for _, stage := range p {
+		kept = out
+		if out = stage.Process(out); out == nil {
+			return nil, kept
+		}
+	}
+	return out, out
+}
+
+// Names returns the stage names in order
+func (p Pipeline) Names() []string {
+	names := make([]string, len(p))
+	for i, stage := range p {
+		names[i] = stage.Name()
+	}
+	return names
+}
+
+// SetPipeline sets the order of the sample processing stages (see
+// DefaultPipeline). Every name must be a known stage and appear at most
+// once; stages left out are skipped. Must be called before Start.
+func (c *Collector) SetPipeline(names []string) error {
+	seen := make(map[string]bool, len(names))
+	for _, name := range names {
+		if seen[name] {
+			return fmt.Errorf("pipeline stage %q listed twice", name)
+		}
+		seen[name] = true
+		if c.stage(name) == nil {
+			return fmt.Errorf("unknown pipeline stage %q", name)
+		}
+	}
+	c.stages = names
+	return nil
+}
+
+// buildPipeline creates the configured stages from the collector's settings
+func (c *Collector) buildPipeline() Pipeline {
+	names := c.stages
+	if names == nil {
+		names = DefaultPipeline
+	}
+	p := make(Pipeline, 0, len(names))
+	for _, name := range names {
+		p = append(p, c.stage(name))
+	}
+	return p
+}
+
+// stage returns the named stage, or nil if there is none
+func (c *Collector) stage(name string) Stage {
+	switch name {
+	case "perCore":
+		return c.perCore
+	case "health":
+		return &healthStage{weights: c.healthWeights, open: c.openAlerts}
+	case "validate":
+		return &validateStage{logger: c.logger, path: c.quarantinePath}
+	case "suppress":
+		return c.suppress
+	default:
+		return nil
+	}
+}
+
+// healthStage sets the composite health score
+type healthStage struct {
+	weights config.HealthConfig
+	open    *alerts.OpenSet
+}
+
+func (h *healthStage) Name() string { return "health" }
+
+func (h *healthStage) Process(s *SampleV1) *SampleV1 {
+	s.Health = ComputeHealth(s, h.weights, h.open.Count())
+	return s
+}
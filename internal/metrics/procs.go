@@ -0,0 +1,19 @@
+package metrics
+
+// ProcStats breaks the process count down by state and adds system-wide
+// handle and thread totals. Steady handle growth is a common leak symptom.
+type ProcStats struct {
+	Running   int    `json:"running"`   // Processes with at least one thread not suspended
+	Suspended int    `json:"suspended"` // Processes whose threads are all suspended (e.g. suspended UWP apps)
+	Zombie    int    `json:"zombie"`    // Exited processes kept alive by open handles (no threads)
+	Threads   uint64 `json:"threads"`
+	Handles   uint64 `json:"handles"`
+}
+
+// ProcessHandles is one process's handle count (see ListProcessHandles)
+type ProcessHandles struct {
+	PID     int32
+	Name    string
+	Handles uint32
+	Created int64 // Creation time (FILETIME), to tell reused PIDs apart
+}
@@ -0,0 +1,15 @@
+//go:build !windows
+
+package metrics
+
+import "errors"
+
+// collectProcStats is not implemented outside Windows
+func collectProcStats(buf *[]byte) (total uint64, stats *ProcStats, err error) {
+	return 0, nil, errors.ErrUnsupported
+}
+
+// ListProcessHandles is not implemented outside Windows
+func ListProcessHandles() ([]ProcessHandles, error) {
+	return nil, errors.ErrUnsupported
+}
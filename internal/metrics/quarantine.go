@@ -0,0 +1,96 @@
+package metrics
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+const (
+	// quarantineMaxBytes caps the quarantine file; a full file is moved to
+	// <name>.1, replacing the previous one
+	quarantineMaxBytes = 4 << 20
+
+	// quarantineWarnEvery limits warnings when a collector keeps producing
+	// bad samples
+	quarantineWarnEvery = time.Minute
+)
+
+// quarantineEntry is one line of the quarantine file
+type quarantineEntry struct {
+	TS     time.Time `json:"ts"`
+	Reason string    `json:"reason"`
+	Sample *SampleV1 `json:"sample"`
+}
+
+// validateStage drops samples that are malformed (SampleV1.Validate) or
+// whose timestamp doesn't move forward, so one buggy collector can't put
+// impossible values into the dashboard's history. Dropped samples are
+// appended to the quarantine file with the reason.
+type validateStage struct {
+	logger *zap.SugaredLogger
+	path   string // Quarantine file (JSON lines); "" only logs
+
+	lastTS      time.Time
+	quarantined int
+	lastWarn    time.Time
+}
+
+// SetQuarantine sets the file the validate stage writes rejected samples
+// to. Must be called before Start.
+func (c *Collector) SetQuarantine(path string) {
+	c.quarantinePath = path
+}
+
+func (v *validateStage) Name() string { return "validate" }
+
+// Process passes valid samples on and quarantines the rest. Timestamps are
+// compared on the monotonic clock, so a wall clock step doesn't reject
+// good samples.
+func (v *validateStage) Process(s *SampleV1) *SampleV1 {
+	err := s.Validate()
+	if err == nil && !v.lastTS.IsZero() && !s.TS.After(v.lastTS) {
+		err = fmt.Errorf("timestamp %s not after the previous sample's %s", s.TS.Format(time.RFC3339Nano), v.lastTS.Format(time.RFC3339Nano))
+	}
+	if err == nil {
+		v.lastTS = s.TS
+		return s
+	}
+
+	v.quarantined++
+	if v.path != "" {
+		if werr := v.write(quarantineEntry{TS: time.Now(), Reason: err.Error(), Sample: s}); werr != nil {
+			v.logger.Debug("Failed to write quarantined sample", "path", v.path, "error", werr)
+		}
+	}
+	if now := time.Now(); now.Sub(v.lastWarn) >= quarantineWarnEvery {
+		v.lastWarn = now
+		v.logger.Warn("🧫 Invalid sample quarantined, not sent", "reason", err, "quarantined", v.quarantined, "file", v.path)
+	}
+	return nil
+}
+
+// write appends entry to the quarantine file, rotating it when full
+func (v *validateStage) write(entry quarantineEntry) error {
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	if info, err := os.Stat(v.path); err == nil && info.Size()+int64(len(data)) > quarantineMaxBytes {
+		if err := os.Rename(v.path, v.path+".1"); err != nil {
+			return err
+		}
+	}
+	f, err := os.OpenFile(v.path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0600)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(append(data, '\n')); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
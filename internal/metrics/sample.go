@@ -0,0 +1,292 @@
+package metrics
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/jcdorr003/windash-agent/internal/netprobe"
+)
+
+// SchemaVersion is the value of SampleV1.V
+const SchemaVersion = 1
+
+// SampleV1 represents a versioned metrics sample
+type SampleV1 struct {
+	V      int       `json:"v"`  // Schema version (always 1)
+	TS     time.Time `json:"ts"` // Timestamp
+	HostID string    `json:"hostId"`
+
+	CPU   CPUStats    `json:"cpu"`
+	Mem   MemStats    `json:"mem"`
+	Disks []DiskUsage `json:"disk"`
+	Net   NetStats    `json:"net"`
+
+	UptimeSec uint64     `json:"uptimeSec"`       // System uptime in seconds
+	ProcCount uint64     `json:"procCount"`       // Number of running processes
+	Procs     *ProcStats `json:"procs,omitempty"` // Breakdown by state, threads and handles (Windows)
+
+	TopProcs *TopProcesses `json:"topProcs,omitempty"` // Heaviest processes, every collectors.topProcs.intervalSec (collectors.enable.topProcs)
+
+	Health int `json:"health"` // Composite 0-100 health score (see ComputeHealth)
+
+	Audio *AudioStats `json:"audio,omitempty"` // Default output device and playback (collectors.audio)
+	Temps *TempStats  `json:"temps,omitempty"` // CPU and motherboard sensors (collectors.enable.temps)
+	DPC   *DPCStats   `json:"dpc,omitempty"`   // DPC and interrupt time (collectors.enable.dpc)
+	TCP   *TCPStats   `json:"tcp,omitempty"`   // Sockets by TCP state (collectors.enable.tcp)
+
+	VMs   []VirtualMachine `json:"vms,omitempty"`   // Hyper-V guests (collectors.enable.hyperv)
+	WiFi  []WiFiLink       `json:"wifi,omitempty"`  // Connected wireless interfaces (collectors.enable.wifi)
+	Links []NetLink        `json:"links,omitempty"` // Connected adapters and their link speed (collectors.enable.links)
+
+	NetQuality *netprobe.Quality `json:"netQuality,omitempty"` // Connectivity grade from the last probe round (netProbe.enabled)
+
+	GPUs         []GPUDevice  `json:"gpu,omitempty"`          // Per-card load, VRAM, temperature and power (NVIDIA, AMD)
+	GPUProcesses []GPUProcess `json:"gpuProcesses,omitempty"` // Top GPU consumers (collectors.gpu)
+	GPUEngines   *GPUEngines  `json:"gpuEngines,omitempty"`   // Utilization per engine type, e.g. video decode (collectors.gpu)
+
+	// Subsystems records each collection subsystem's outcome this cycle
+	// (ok, error, timeout, unsupported, skipped, disabled, deferred) so
+	// missing data can be told apart from zero values
+	Subsystems map[string]string `json:"subsystems,omitempty"`
+}
+
+// CPUStats holds CPU utilization. On many-core machines PerCore may be
+// omitted in favor of TopCores and Histogram (see collectors.cpu).
+type CPUStats struct {
+	Total     float64     `json:"total"`                   // Total CPU usage %
+	PerCore   []float64   `json:"perCore,omitempty"`       // Per-core usage %
+	Cores     int         `json:"cores,omitempty"`         // Core count, set when perCore is trimmed
+	TopCores  []CoreUsage `json:"topCores,omitempty"`      // Busiest cores, set when perCore is trimmed
+	Histogram []int       `json:"coreHistogram,omitempty"` // Cores per 10% utilization band, set when perCore is trimmed
+
+	FreqMHz    float64   `json:"freqMhz,omitempty"`    // Current clock, average of all cores
+	BaseMHz    float64   `json:"baseMhz,omitempty"`    // Rated base clock
+	PerCoreMHz []float64 `json:"perCoreMhz,omitempty"` // Current clock per core, trimmed with perCore
+	Throttled  bool      `json:"throttled,omitempty"`  // Held below its rated clock by a thermal or power limit
+}
+
+// MemStats holds physical memory usage
+type MemStats struct {
+	Used  uint64 `json:"used"`  // Used memory in bytes
+	Total uint64 `json:"total"` // Total memory in bytes
+}
+
+// DiskUsage holds space usage for one volume
+type DiskUsage struct {
+	Name  string `json:"name"`            // Mount point or drive letter
+	Used  uint64 `json:"used"`            // Used space in bytes
+	Total uint64 `json:"total"`           // Total space in bytes
+	Stale bool   `json:"stale,omitempty"` // Last known value; a fresh query is still running
+
+	Label  string `json:"label,omitempty"`  // Volume label, e.g. Games
+	FSType string `json:"fsType,omitempty"` // File system, e.g. NTFS, exFAT, ext4
+	Type   string `json:"type,omitempty"`   // fixed, removable, network, cdrom or ram
+	Serial string `json:"serial,omitempty"` // Volume serial number (Windows) or file system UUID (Linux)
+}
+
+// NetStats holds aggregate network throughput
+type NetStats struct {
+	TxBps uint64 `json:"txBps"` // Transmit bytes per second
+	RxBps uint64 `json:"rxBps"` // Receive bytes per second
+}
+
+// NewSample creates an empty sample with the schema version set
+func NewSample(hostID string, ts time.Time) *SampleV1 {
+	return &SampleV1{
+		V:      SchemaVersion,
+		TS:     ts,
+		HostID: hostID,
+	}
+}
+
+// AddDisk appends a volume's usage to the sample
+func (s *SampleV1) AddDisk(name string, used, total uint64) {
+	s.Disks = append(s.Disks, DiskUsage{Name: name, Used: used, Total: total})
+}
+
+// ApproxSize estimates the in-memory footprint of the sample in bytes,
+// used for memory budgeting without the cost of marshaling
+func (s *SampleV1) ApproxSize() int64 {
+	size := int64(256 + len(s.HostID)) // Struct, timestamp and scalar fields
+	size += int64(8*len(s.CPU.PerCore) + 16*len(s.CPU.TopCores) + 8*len(s.CPU.Histogram) + 8*len(s.CPU.PerCoreMHz))
+	for _, d := range s.Disks {
+		size += int64(64 + len(d.Name) + len(d.Label) + len(d.FSType) + len(d.Type) + len(d.Serial))
+	}
+	size += int64(48 * len(s.Subsystems))
+	for _, g := range s.GPUs {
+		size += int64(72 + len(g.Vendor) + len(g.Name))
+	}
+	for _, p := range s.GPUProcesses {
+		size += int64(48 + len(p.Name) + len(p.Engine))
+	}
+	if s.GPUEngines != nil {
+		size += 64
+		for engine := range s.GPUEngines.Types {
+			size += int64(16 + len(engine))
+		}
+	}
+	if s.Procs != nil {
+		size += 40
+	}
+	if s.TopProcs != nil {
+		for _, list := range [][]TopProcess{s.TopProcs.ByCPU, s.TopProcs.ByMem} {
+			for _, p := range list {
+				size += int64(32 + len(p.Name))
+			}
+		}
+	}
+	if s.Audio != nil {
+		size += int64(32 + len(s.Audio.Device))
+	}
+	if s.DPC != nil {
+		size += 48
+	}
+	if s.TCP != nil {
+		size += int64(48 + 24*len(s.TCP.States))
+	}
+	for _, vm := range s.VMs {
+		size += int64(120 + len(vm.Name) + len(vm.ID))
+	}
+	for _, w := range s.WiFi {
+		size += int64(140 + len(w.Interface) + len(w.SSID))
+	}
+	for _, l := range s.Links {
+		size += int64(56 + len(l.Name) + len(l.Type))
+	}
+	if q := s.NetQuality; q != nil {
+		size += int64(96 + len(q.Grade) + len(q.Gateway) + len(q.DNSError))
+	}
+	if s.Temps != nil {
+		size += int64(32 + 8*len(s.Temps.Cores))
+		for _, t := range s.Temps.Sensors {
+			size += int64(48 + len(t.Name) + len(t.Source) + len(t.Type))
+		}
+	}
+	return size
+}
+
+// Validate checks that the sample is well-formed: required fields present
+// and values within physically possible ranges
+func (s *SampleV1) Validate() error {
+	var errs []error
+
+	if s.V != SchemaVersion {
+		errs = append(errs, fmt.Errorf("unsupported schema version %d", s.V))
+	}
+	if s.TS.IsZero() {
+		errs = append(errs, errors.New("missing timestamp"))
+	}
+	if s.HostID == "" {
+		errs = append(errs, errors.New("missing hostId"))
+	}
+	if err := s.CPU.Validate(); err != nil {
+		errs = append(errs, err)
+	}
+	if err := s.Mem.Validate(); err != nil {
+		errs = append(errs, err)
+	}
+	for _, d := range s.Disks {
+		if err := d.Validate(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	for _, g := range s.GPUs {
+		if err := g.Validate(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if e := s.GPUEngines; e != nil {
+		if !validPercent(e.ThreeD) || !validPercent(e.VideoDecode) || !validPercent(e.VideoEncode) {
+			errs = append(errs, fmt.Errorf("gpuEngines out of range: 3d %v, videoDecode %v, videoEncode %v", e.ThreeD, e.VideoDecode, e.VideoEncode))
+		}
+		for engine, v := range e.Types {
+			if !validPercent(v) {
+				errs = append(errs, fmt.Errorf("gpuEngines.types %q out of range: %v", engine, v))
+			}
+		}
+	}
+	if s.DPC != nil {
+		for _, f := range []struct {
+			name  string
+			value float64
+		}{{"dpcPct", s.DPC.DPCPct}, {"interruptPct", s.DPC.InterruptPct}, {"maxCorePct", s.DPC.MaxCorePct}} {
+			if !validPercent(f.value) {
+				errs = append(errs, fmt.Errorf("dpc.%s out of range: %v", f.name, f.value))
+			}
+		}
+	}
+	for _, vm := range s.VMs {
+		if !validPercent(vm.CPU) {
+			errs = append(errs, fmt.Errorf("vm %q cpu out of range: %v", vm.Name, vm.CPU))
+		}
+	}
+	for _, l := range s.Links {
+		if math.IsNaN(l.SpeedMbps) || l.SpeedMbps < 0 {
+			errs = append(errs, fmt.Errorf("link %q speed out of range: %v", l.Name, l.SpeedMbps))
+		}
+	}
+	for _, w := range s.WiFi {
+		if w.Signal < 0 || w.Signal > 100 {
+			errs = append(errs, fmt.Errorf("wifi %q signal out of range: %d", w.SSID, w.Signal))
+		}
+	}
+	if s.TopProcs != nil {
+		for _, p := range s.TopProcs.ByCPU {
+			if !validPercent(p.CPU) {
+				errs = append(errs, fmt.Errorf("topProcs pid %d cpu out of range: %v", p.PID, p.CPU))
+			}
+		}
+	}
+	if s.Health < 0 || s.Health > 100 {
+		errs = append(errs, fmt.Errorf("health out of range: %d", s.Health))
+	}
+
+	return errors.Join(errs...)
+}
+
+// Validate checks that all percentages are within [0, 100]
+func (c CPUStats) Validate() error {
+	if !validPercent(c.Total) {
+		return fmt.Errorf("cpu.total out of range: %v", c.Total)
+	}
+	for i, v := range c.PerCore {
+		if !validPercent(v) {
+			return fmt.Errorf("cpu.perCore[%d] out of range: %v", i, v)
+		}
+	}
+	for _, core := range c.TopCores {
+		if !validPercent(core.Usage) {
+			return fmt.Errorf("cpu.topCores core %d out of range: %v", core.Core, core.Usage)
+		}
+	}
+	if c.FreqMHz < 0 || c.BaseMHz < 0 {
+		return fmt.Errorf("cpu frequency negative: freqMhz %v, baseMhz %v", c.FreqMHz, c.BaseMHz)
+	}
+	return nil
+}
+
+// Validate checks that used memory does not exceed total
+func (m MemStats) Validate() error {
+	if m.Used > m.Total {
+		return fmt.Errorf("mem.used (%d) exceeds mem.total (%d)", m.Used, m.Total)
+	}
+	return nil
+}
+
+// Validate checks that the volume is named and used space does not exceed total
+func (d DiskUsage) Validate() error {
+	if d.Name == "" {
+		return errors.New("disk entry missing name")
+	}
+	if d.Used > d.Total {
+		return fmt.Errorf("disk %s: used (%d) exceeds total (%d)", d.Name, d.Used, d.Total)
+	}
+	return nil
+}
+
+// validPercent reports whether v is a finite percentage
+func validPercent(v float64) bool {
+	return !math.IsNaN(v) && v >= 0 && v <= 100
+}
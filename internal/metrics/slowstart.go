@@ -0,0 +1,89 @@
+package metrics
+
+import (
+	"time"
+
+	"github.com/jcdorr003/windash-agent/internal/config"
+	"github.com/shirou/gopsutil/v4/host"
+)
+
+const (
+	defaultSlowStartWindow   = 10 * time.Minute
+	defaultSlowStartRamp     = 5 * time.Minute
+	defaultSlowStartInterval = 30 * time.Second
+)
+
+// coreSubsystems keep running during slow start; the rest report "deferred"
+var coreSubsystems = map[string]bool{
+	"cpu": true, "mem": true, "disk": true, "net": true, "uptime": true, "procs": true,
+}
+
+// slowStart stretches the interval from from down to the configured one
+// over ramp, starting at begin (set by Start)
+type slowStart struct {
+	begin time.Time
+	ramp  time.Duration
+	from  time.Duration
+}
+
+// SlowStart makes the collector begin at a long interval with only the core
+// sources when boot or lastLogon (zero if unknown) was within
+// slowStart.windowSec, ramping to the configured settings over
+// slowStart.rampSec. Must be called before Start.
+func (c *Collector) SlowStart(cfg config.SlowStartConfig, lastLogon time.Time) {
+	if !cfg.Enabled {
+		return
+	}
+	window := time.Duration(cfg.WindowSec) * time.Second
+	if window <= 0 {
+		window = defaultSlowStartWindow
+	}
+	ramp := time.Duration(cfg.RampSec) * time.Second
+	if ramp <= 0 {
+		ramp = defaultSlowStartRamp
+	}
+	from := time.Duration(cfg.IntervalMs) * time.Millisecond
+	if from <= 0 {
+		from = defaultSlowStartInterval
+	}
+
+	since := lastLogon
+	if boot, err := host.BootTime(); err == nil && time.Unix(int64(boot), 0).After(since) {
+		since = time.Unix(int64(boot), 0)
+	}
+	if since.IsZero() || time.Since(since) > window {
+		return
+	}
+	c.slowStart = &slowStart{ramp: ramp, from: min(from, MaxInterval)}
+}
+
+// beginSlowStart starts the ramp and returns the first interval, or drops
+// slow start when it wouldn't slow anything down
+func (c *Collector) beginSlowStart() time.Duration {
+	if c.slowStart == nil {
+		return c.interval
+	}
+	if c.synthetic != nil || c.slowStart.from <= c.interval {
+		c.slowStart = nil
+		return c.interval
+	}
+	c.slowStart.begin = time.Now()
+	c.logger.Info("🐢 Started shortly after boot or login, easing into sampling",
+		"interval", c.slowStart.from, "target", c.interval, "ramp", c.slowStart.ramp)
+	return c.slowStart.from
+}
+
+// rampInterval returns the interval for the next tick, ending slow start
+// once the ramp is over
+func (c *Collector) rampInterval(now time.Time) time.Duration {
+	s := c.slowStart
+	elapsed := now.Sub(s.begin)
+	if elapsed >= s.ramp {
+		c.slowStart = nil
+		c.logger.Info("🐇 Slow start finished, sampling at the configured settings", "interval", c.interval)
+		return c.interval
+	}
+	left := float64(s.ramp-elapsed) / float64(s.ramp)
+	next := c.interval + time.Duration(float64(s.from-c.interval)*left)
+	return max(next.Round(time.Second), c.interval)
+}
@@ -0,0 +1,142 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/jcdorr003/windash-agent/internal/chaos"
+	"github.com/jcdorr003/windash-agent/internal/config"
+)
+
+// Per-subsystem outcomes reported in SampleV1.Subsystems
+const (
+	SubsystemOK          = "ok"
+	SubsystemError       = "error"
+	SubsystemTimeout     = "timeout"
+	SubsystemUnsupported = "unsupported"
+	SubsystemSkipped     = "skipped"  // Failing repeatedly; retried after a cool-down
+	SubsystemDisabled    = "disabled" // Turned off in collectors.enable
+	SubsystemDeferred    = "deferred" // Held back until slow start ends
+)
+
+const (
+	subsystemTimeout = 2 * time.Second
+	errorBudget      = 3  // Consecutive failures before a subsystem is skipped
+	skipCycles       = 10 // Collection cycles skipped once the budget is spent
+)
+
+// subsystemState tracks the error budget of one subsystem
+type subsystemState struct {
+	failures    int
+	skipLeft    int
+	unsupported bool
+	disabled    bool // Off in collectors.enable
+	quiet       bool // "auto": being unsupported is expected, not worth an Info log
+}
+
+// runSubsystem calls fn under a timeout and records its outcome in sample.
+// A subsystem that fails errorBudget times in a row is skipped for
+// skipCycles cycles, and one that is unsupported on this platform is never
+// retried, so a broken source doesn't slow down every collection.
+func (c *Collector) runSubsystem(sample *SampleV1, name string, fn func(ctx context.Context) error) {
+	if c.subsystems == nil {
+		c.subsystems = make(map[string]*subsystemState)
+	}
+	st := c.subsystems[name]
+	if st == nil {
+		st = &subsystemState{}
+		c.subsystems[name] = st
+	}
+
+	switch {
+	case st.disabled:
+		sample.setSubsystem(name, SubsystemDisabled)
+		return
+	case st.unsupported:
+		sample.setSubsystem(name, SubsystemUnsupported)
+		return
+	case c.slowStart != nil && !coreSubsystems[name]:
+		sample.setSubsystem(name, SubsystemDeferred)
+		return
+	case st.skipLeft > 0:
+		st.skipLeft--
+		sample.setSubsystem(name, SubsystemSkipped)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), subsystemTimeout)
+	defer cancel()
+
+	err := chaos.Delay(ctx)
+	if err == nil {
+		err = fn(ctx)
+	}
+	status := subsystemStatus(err)
+	sample.setSubsystem(name, status)
+
+	switch status {
+	case SubsystemOK:
+		st.failures = 0
+	case SubsystemUnsupported:
+		st.unsupported = true
+		if st.quiet {
+			c.logger.Debug("Metrics subsystem unsupported on this machine", "subsystem", name)
+		} else {
+			c.logger.Info("Metrics subsystem unsupported on this machine", "subsystem", name)
+		}
+	default:
+		st.failures++
+		c.logger.Debug("Metrics subsystem failed", "subsystem", name, "status", status, "error", err)
+		if st.failures >= errorBudget {
+			st.failures = 0
+			st.skipLeft = skipCycles
+			c.logger.Warn("⚠️  Metrics subsystem keeps failing, skipping it for a while",
+				"subsystem", name, "cycles", skipCycles, "error", err)
+		}
+	}
+}
+
+// setSourceModes applies collectors.enable modes to the subsystem states
+func (c *Collector) setSourceModes(modes map[string]string) {
+	if c.subsystems == nil {
+		c.subsystems = make(map[string]*subsystemState)
+	}
+	for name, mode := range modes {
+		c.subsystems[name] = &subsystemState{
+			disabled: mode == config.SourceOff,
+			quiet:    mode == config.SourceAuto,
+		}
+	}
+}
+
+// subsystemStatus classifies a subsystem error
+func subsystemStatus(err error) string {
+	switch {
+	case err == nil:
+		return SubsystemOK
+	case errors.Is(err, context.DeadlineExceeded):
+		return SubsystemTimeout
+	case errors.Is(err, errors.ErrUnsupported), err.Error() == "not implemented yet": // gopsutil's ErrNotImplementedError
+		return SubsystemUnsupported
+	default:
+		return SubsystemError
+	}
+}
+
+// setSubsystem records a subsystem outcome
+func (s *SampleV1) setSubsystem(name, status string) {
+	if s.Subsystems == nil {
+		s.Subsystems = make(map[string]string)
+	}
+	s.Subsystems[name] = status
+}
+
+// HasData reports whether the named subsystem produced data for this
+// sample. Samples without subsystem outcomes (e.g. synthetic) always do.
+func (s *SampleV1) HasData(name string) bool {
+	if s.Subsystems == nil {
+		return true
+	}
+	return s.Subsystems[name] == SubsystemOK
+}
@@ -0,0 +1,132 @@
+package metrics
+
+import (
+	"maps"
+
+	"github.com/jcdorr003/windash-agent/internal/config"
+)
+
+// Defaults for idle-send suppression
+const (
+	defaultSuppressCPU       = 2.0   // percentage points
+	defaultSuppressMemory    = 1.0   // % of total
+	defaultSuppressDisk      = 0.1   // % of total
+	defaultSuppressNetBps    = 10240 // bytes/sec
+	defaultSuppressKeepalive = 30    // intervals
+	suppressHealth           = 5     // health score points
+)
+
+// idleSuppressor drops samples that are within tolerance of the last sent
+// one, so idle always-on machines don't upload near-identical samples every
+// interval. A sample is still sent every keepalive intervals so the server
+// can tell a quiet host from a disconnected one.
+type idleSuppressor struct {
+	cpu       float64
+	memory    float64
+	disk      float64
+	netBps    uint64
+	keepalive int
+
+	last    *SampleV1 // Last sample sent
+	skipped int       // Samples suppressed since last
+}
+
+// newIdleSuppressor returns nil (never suppress) unless cfg is enabled
+func newIdleSuppressor(cfg config.SuppressConfig) *idleSuppressor {
+	if !cfg.Enabled {
+		return nil
+	}
+	s := &idleSuppressor{
+		cpu:       cfg.CPU,
+		memory:    cfg.Memory,
+		disk:      cfg.Disk,
+		netBps:    cfg.NetBps,
+		keepalive: cfg.KeepaliveEvery,
+	}
+	if s.cpu <= 0 {
+		s.cpu = defaultSuppressCPU
+	}
+	if s.memory <= 0 {
+		s.memory = defaultSuppressMemory
+	}
+	if s.disk <= 0 {
+		s.disk = defaultSuppressDisk
+	}
+	if s.netBps == 0 {
+		s.netBps = defaultSuppressNetBps
+	}
+	if s.keepalive <= 0 {
+		s.keepalive = defaultSuppressKeepalive
+	}
+	return s
+}
+
+func (s *idleSuppressor) Name() string { return "suppress" }
+
+// Process drops sample if it is within tolerance of the last one sent, and
+// otherwise records it as the new reference
+func (s *idleSuppressor) Process(sample *SampleV1) *SampleV1 {
+	if s == nil {
+		return sample
+	}
+	if s.last != nil && s.skipped+1 < s.keepalive && !s.changed(sample) {
+		s.skipped++
+		return nil
+	}
+	s.last = sample
+	s.skipped = 0
+	return sample
+}
+
+// reset forces the next sample to be sent (e.g. after a resume)
+func (s *idleSuppressor) reset() {
+	if s != nil {
+		s.last = nil
+	}
+}
+
+// changed reports whether any monitored value moved beyond its tolerance
+func (s *idleSuppressor) changed(cur *SampleV1) bool {
+	prev := s.last
+	if absDiff(cur.CPU.Total, prev.CPU.Total) > s.cpu {
+		return true
+	}
+	if beyond(cur.Mem.Used, prev.Mem.Used, cur.Mem.Total, s.memory) {
+		return true
+	}
+	if absDiff(float64(cur.Net.TxBps), float64(prev.Net.TxBps)) > float64(s.netBps) ||
+		absDiff(float64(cur.Net.RxBps), float64(prev.Net.RxBps)) > float64(s.netBps) {
+		return true
+	}
+	if absDiff(float64(cur.Health), float64(prev.Health)) >= suppressHealth {
+		return true
+	}
+	if (cur.Audio == nil) != (prev.Audio == nil) || cur.Audio != nil && *cur.Audio != *prev.Audio {
+		return true
+	}
+	if len(cur.Disks) != len(prev.Disks) || !maps.Equal(cur.Subsystems, prev.Subsystems) {
+		return true
+	}
+	for i, d := range cur.Disks {
+		p := prev.Disks[i]
+		if d.Name != p.Name || beyond(d.Used, p.Used, d.Total, s.disk) {
+			return true
+		}
+	}
+	return false
+}
+
+// beyond reports whether a and b differ by more than pct percent of total
+func beyond(a, b, total uint64, pct float64) bool {
+	if total == 0 {
+		return a != b
+	}
+	return absDiff(float64(a), float64(b))/float64(total)*100 > pct
+}
+
+func absDiff(a, b float64) float64 {
+	if a > b {
+		return a - b
+	}
+	return b - a
+}
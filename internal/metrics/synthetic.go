@@ -0,0 +1,117 @@
+package metrics
+
+import (
+	"math"
+	"math/rand/v2"
+	"time"
+
+	"github.com/jcdorr003/windash-agent/internal/config"
+)
+
+const (
+	syntheticMemTotal  = 16 << 30  // 16 GiB
+	syntheticDiskTotal = 512 << 30 // 512 GiB
+)
+
+// SyntheticSource generates realistic fake metrics (sine-wave CPU with random
+// spikes, drifting memory, steadily filling disk) so dashboard charts and
+// alerts can be exercised without a real noisy machine
+type SyntheticSource struct {
+	cfg     config.SyntheticConfig
+	hostID  string
+	started time.Time
+
+	spikeLeft int     // Samples remaining in the current CPU spike
+	diskUsed  uint64  // Grows by DiskGrowthBytesPerSec, wraps near full
+	netRx     float64 // Random walk, bytes/sec
+	netTx     float64
+	lastTS    time.Time
+}
+
+// NewSyntheticSource creates a synthetic metrics generator
+func NewSyntheticSource(hostID string, cfg config.SyntheticConfig) *SyntheticSource {
+	if cfg.Cores <= 0 {
+		cfg.Cores = 8
+	}
+	if cfg.CPUBase == 0 {
+		cfg.CPUBase = 35
+	}
+	if cfg.CPUAmplitude == 0 {
+		cfg.CPUAmplitude = 25
+	}
+	if cfg.CPUPeriodSec <= 0 {
+		cfg.CPUPeriodSec = 120
+	}
+	if cfg.SpikeChance == 0 {
+		cfg.SpikeChance = 0.02
+	}
+	if cfg.DiskGrowthBytesPerSec == 0 {
+		cfg.DiskGrowthBytesPerSec = 1 << 20 // 1 MiB/s
+	}
+
+	now := time.Now()
+	return &SyntheticSource{
+		cfg:      cfg,
+		hostID:   hostID,
+		started:  now,
+		diskUsed: syntheticDiskTotal * 6 / 10,
+		netRx:    200_000,
+		netTx:    50_000,
+		lastTS:   now,
+	}
+}
+
+// Generate produces the next synthetic sample
+func (s *SyntheticSource) Generate() *SampleV1 {
+	now := time.Now()
+	elapsed := now.Sub(s.started).Seconds()
+	step := now.Sub(s.lastTS).Seconds()
+	s.lastTS = now
+
+	sample := NewSample(s.hostID, now)
+
+	// CPU: sine wave plus noise, with occasional multi-sample spikes
+	phase := 2 * math.Pi * elapsed / float64(s.cfg.CPUPeriodSec)
+	total := s.cfg.CPUBase + s.cfg.CPUAmplitude*math.Sin(phase) + rand.NormFloat64()*3
+	if s.spikeLeft == 0 && rand.Float64() < s.cfg.SpikeChance {
+		s.spikeLeft = 2 + rand.IntN(4)
+	}
+	if s.spikeLeft > 0 {
+		s.spikeLeft--
+		total = 92 + rand.Float64()*8
+	}
+	sample.CPU.Total = clampPercent(total)
+
+	sample.CPU.PerCore = make([]float64, s.cfg.Cores)
+	for i := range sample.CPU.PerCore {
+		sample.CPU.PerCore[i] = clampPercent(total + rand.NormFloat64()*10)
+	}
+
+	// Memory: slow drift between roughly 40% and 70%
+	memFrac := 0.55 + 0.15*math.Sin(elapsed/600) + rand.NormFloat64()*0.01
+	sample.Mem.Total = syntheticMemTotal
+	sample.Mem.Used = uint64(float64(syntheticMemTotal) * math.Min(math.Max(memFrac, 0), 1))
+
+	// Disk: fills steadily, then "cleans up" back to 60% when nearly full
+	s.diskUsed += uint64(step * float64(s.cfg.DiskGrowthBytesPerSec))
+	if s.diskUsed > syntheticDiskTotal*95/100 {
+		s.diskUsed = syntheticDiskTotal * 6 / 10
+	}
+	sample.AddDisk("C:", s.diskUsed, syntheticDiskTotal)
+
+	// Network: bounded random walk
+	s.netRx = math.Min(math.Max(s.netRx*(1+rand.NormFloat64()*0.2), 1_000), 50_000_000)
+	s.netTx = math.Min(math.Max(s.netTx*(1+rand.NormFloat64()*0.2), 1_000), 10_000_000)
+	sample.Net.RxBps = uint64(s.netRx)
+	sample.Net.TxBps = uint64(s.netTx)
+
+	sample.UptimeSec = uint64(elapsed) + 3600
+	sample.ProcCount = uint64(240 + rand.IntN(30))
+
+	return sample
+}
+
+// clampPercent bounds a percentage to [0, 100]
+func clampPercent(v float64) float64 {
+	return math.Min(math.Max(v, 0), 100)
+}
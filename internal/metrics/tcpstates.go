@@ -0,0 +1,33 @@
+package metrics
+
+import "context"
+
+// TCPStats counts the machine's sockets, with TCP connections broken down by
+// state. A steadily growing ESTABLISHED or CLOSE_WAIT count usually means a
+// program is leaking connections.
+type TCPStats struct {
+	States  map[string]int `json:"states"`  // TCP sockets by state: ESTABLISHED, TIME_WAIT, LISTEN, CLOSE_WAIT, ...
+	TCP     int            `json:"tcp"`     // TCP sockets, listeners included
+	UDP     int            `json:"udp"`     // UDP sockets
+	Sockets int            `json:"sockets"` // All TCP and UDP sockets, IPv4 and IPv6
+}
+
+// EnableTCPStates adds socket counts by TCP state to real samples. Must be
+// called before Start.
+func (c *Collector) EnableTCPStates() {
+	c.tcpStates = true
+}
+
+// collectTCPStates counts the IPv4 and IPv6 sockets of every process
+func collectTCPStates(ctx context.Context) (*TCPStats, error) {
+	states, udp, err := readSocketStates(ctx)
+	if err != nil {
+		return nil, err
+	}
+	stats := &TCPStats{States: states, UDP: udp}
+	for _, n := range states {
+		stats.TCP += n
+	}
+	stats.Sockets = stats.TCP + stats.UDP
+	return stats, nil
+}
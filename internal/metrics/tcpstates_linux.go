@@ -0,0 +1,80 @@
+//go:build linux
+
+package metrics
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"os"
+	"strings"
+)
+
+// procTCPStates maps the hex state column of /proc/net/tcp to its name
+var procTCPStates = map[string]string{
+	"01": "ESTABLISHED",
+	"02": "SYN_SENT",
+	"03": "SYN_RECV",
+	"04": "FIN_WAIT1",
+	"05": "FIN_WAIT2",
+	"06": "TIME_WAIT",
+	"07": "CLOSE",
+	"08": "CLOSE_WAIT",
+	"09": "LAST_ACK",
+	"0A": "LISTEN",
+	"0B": "CLOSING",
+}
+
+// readSocketStates reads the kernel socket tables in /proc/net directly.
+// Going through gopsutil would also walk every process's file descriptors
+// to find socket owners, which we don't need.
+func readSocketStates(ctx context.Context) (map[string]int, int, error) {
+	states := make(map[string]int)
+	for _, name := range []string{"tcp", "tcp6"} {
+		err := scanProcNet(name, func(state string) {
+			if s, ok := procTCPStates[state]; ok {
+				states[s]++
+			} else {
+				states["UNKNOWN"]++
+			}
+		})
+		if err != nil {
+			return nil, 0, err
+		}
+		if err := ctx.Err(); err != nil {
+			return nil, 0, err
+		}
+	}
+	udp := 0
+	for _, name := range []string{"udp", "udp6"} {
+		if err := scanProcNet(name, func(string) { udp++ }); err != nil {
+			return nil, 0, err
+		}
+	}
+	return states, udp, nil
+}
+
+// scanProcNet calls fn with the state column of every socket in
+// /proc/net/<name>. A missing table (IPv6 disabled) has no sockets.
+func scanProcNet(name string, fn func(state string)) error {
+	f, err := os.Open("/proc/net/" + name)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	scanner.Scan() // Header
+	for scanner.Scan() {
+		// sl local_address rem_address st ...
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 4 {
+			continue
+		}
+		fn(fields[3])
+	}
+	return scanner.Err()
+}
@@ -0,0 +1,45 @@
+//go:build !linux
+
+package metrics
+
+import (
+	"context"
+	"syscall"
+
+	"github.com/shirou/gopsutil/v4/net"
+)
+
+// tcpStateNames maps gopsutil's state names to the Linux kernel's, so the
+// dashboard sees the same keys from every platform
+var tcpStateNames = map[string]string{
+	"SYN_RECEIVED": "SYN_RECV",
+	"FIN_WAIT_1":   "FIN_WAIT1",
+	"FIN_WAIT_2":   "FIN_WAIT2",
+	"CLOSED":       "CLOSE",
+}
+
+// readSocketStates reads the socket tables through gopsutil
+// (GetExtendedTcpTable/GetExtendedUdpTable on Windows)
+func readSocketStates(ctx context.Context) (map[string]int, int, error) {
+	conns, err := net.ConnectionsWithContext(ctx, "inet")
+	if err != nil {
+		return nil, 0, err
+	}
+	states := make(map[string]int)
+	udp := 0
+	for _, c := range conns {
+		if c.Type != syscall.SOCK_STREAM {
+			udp++
+			continue
+		}
+		state := c.Status
+		if name, ok := tcpStateNames[state]; ok {
+			state = name
+		}
+		if state == "" {
+			state = "UNKNOWN"
+		}
+		states[state]++
+	}
+	return states, udp, nil
+}
@@ -0,0 +1,131 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/shirou/gopsutil/v4/sensors"
+)
+
+const maxTempSensors = 32 // Cap on sensors per sample; boards with many probes repeat themselves
+
+// Sensor types in TempSensor.Type
+const (
+	SensorTemperature = "temperature" // °C
+	SensorFan         = "fan"         // RPM
+	SensorVoltage     = "voltage"     // V
+)
+
+// TempStats holds hardware sensor readings
+type TempStats struct {
+	CPU     float64      `json:"cpu,omitempty"`     // CPU package temperature °C
+	Cores   []float64    `json:"cores,omitempty"`   // Per-core temperatures °C, by core number
+	Sensors []TempSensor `json:"sensors,omitempty"` // Motherboard, drive and other sensors
+}
+
+// TempSensor is one motherboard or device sensor reading
+type TempSensor struct {
+	Name   string  `json:"name"`
+	Source string  `json:"source,omitempty"` // Chip or device the sensor belongs to
+	Type   string  `json:"type"`             // temperature, fan or voltage
+	Value  float64 `json:"value"`
+}
+
+// Hottest returns the highest temperature reading, or false if there is none
+func (t *TempStats) Hottest() (float64, bool) {
+	hottest, found := t.CPU, t.CPU > 0
+	for _, c := range t.Cores {
+		if c > hottest {
+			hottest, found = c, true
+		}
+	}
+	for _, s := range t.Sensors {
+		if s.Type == SensorTemperature && s.Value > 0 && (!found || s.Value > hottest) {
+			hottest, found = s.Value, true
+		}
+	}
+	return hottest, found
+}
+
+// EnableTemps adds hardware sensor readings to real samples. Must be called
+// before Start.
+func (c *Collector) EnableTemps() {
+	c.temps = true
+}
+
+// collectTemps reads the sensors, preferring a hardware monitor that knows
+// the board's sensor chip and falling back to the OS thermal sensors
+func collectTemps(ctx context.Context) (*TempStats, error) {
+	temps, err := readHardwareMonitor()
+	if err == nil {
+		return temps, nil
+	}
+	if !errors.Is(err, errors.ErrUnsupported) {
+		return nil, err
+	}
+	return readThermalSensors(ctx)
+}
+
+// readThermalSensors reads the temperatures the OS exposes (hwmon on Linux,
+// ACPI thermal zones on Windows, which need administrator rights). Linux
+// coretemp and k10temp readings are recognized as CPU temperatures.
+func readThermalSensors(ctx context.Context) (*TempStats, error) {
+	stats, err := sensors.TemperaturesWithContext(ctx)
+	if len(stats) == 0 {
+		if err == nil {
+			err = errors.ErrUnsupported
+		}
+		return nil, err
+	}
+
+	temps := &TempStats{}
+	cores := make(map[int]float64)
+	for _, s := range stats {
+		if s.Temperature <= 0 {
+			continue
+		}
+		key := strings.ToLower(s.SensorKey)
+		switch {
+		case strings.HasPrefix(key, "coretemp_package_id_"), key == "k10temp_tctl", key == "k10temp_tdie":
+			temps.CPU = max(temps.CPU, s.Temperature)
+		case strings.HasPrefix(key, "coretemp_core_"):
+			if n, err := strconv.Atoi(strings.TrimPrefix(key, "coretemp_core_")); err == nil {
+				cores[n] = s.Temperature
+			}
+		default:
+			source, name, _ := strings.Cut(s.SensorKey, "_")
+			if name == "" {
+				source, name = "", s.SensorKey
+			}
+			temps.addSensor(TempSensor{Name: name, Source: source, Type: SensorTemperature, Value: s.Temperature})
+		}
+	}
+	temps.setCores(cores)
+	return temps, nil
+}
+
+// addSensor appends a sensor reading unless the cap is reached
+func (t *TempStats) addSensor(s TempSensor) {
+	if len(t.Sensors) < maxTempSensors {
+		t.Sensors = append(t.Sensors, s)
+	}
+}
+
+// setCores stores per-core temperatures in core order
+func (t *TempStats) setCores(cores map[int]float64) {
+	if len(cores) == 0 {
+		return
+	}
+	nums := make([]int, 0, len(cores))
+	for n := range cores {
+		nums = append(nums, n)
+	}
+	sort.Ints(nums)
+	t.Cores = make([]float64, len(nums))
+	for i, n := range nums {
+		t.Cores[i] = cores[n]
+	}
+}
@@ -0,0 +1,10 @@
+//go:build !windows
+
+package metrics
+
+import "errors"
+
+// readHardwareMonitor is Windows only; elsewhere the OS sensors are complete
+func readHardwareMonitor() (*TempStats, error) {
+	return nil, errors.ErrUnsupported
+}
@@ -0,0 +1,86 @@
+//go:build windows
+
+package metrics
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/yusufpapurcu/wmi"
+)
+
+// Hardware monitors that publish their sensors over WMI while running.
+// OpenHardwareMonitor uses the same schema as its successor.
+var hardwareMonitorNamespaces = []string{`root\LibreHardwareMonitor`, `root\OpenHardwareMonitor`}
+
+// hwmonSensor is the Sensor class of the hardware monitor WMI namespaces
+type hwmonSensor struct {
+	Name       string
+	SensorType string // Temperature, Fan, Voltage, Load, Clock, ...
+	Parent     string // Hardware identifier, e.g. /intelcpu/0, /lpc/nct6798d, /nvme/0
+	Value      float32
+}
+
+// readHardwareMonitor reads CPU package, per-core and motherboard sensors
+// from LibreHardwareMonitor (or OpenHardwareMonitor) when it is running.
+// Windows itself has no API for them, so without one this is unsupported.
+func readHardwareMonitor() (*TempStats, error) {
+	var sensors []hwmonSensor
+	var err error
+	for _, ns := range hardwareMonitorNamespaces {
+		err = wmi.QueryNamespace("SELECT Name, SensorType, Parent, Value FROM Sensor", &sensors, ns)
+		if err == nil {
+			break
+		}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("no hardware monitor running: %w: %w", err, errors.ErrUnsupported)
+	}
+
+	temps := &TempStats{}
+	cores := make(map[int]float64)
+	for _, s := range sensors {
+		value := float64(s.Value)
+		parent := strings.ToLower(s.Parent)
+		switch {
+		case strings.Contains(parent, "cpu"):
+			if s.SensorType != "Temperature" || value <= 0 {
+				continue
+			}
+			if n, ok := cpuCoreNumber(s.Name); ok {
+				cores[n] = value
+			} else if strings.Contains(s.Name, "Package") || strings.HasPrefix(s.Name, "Core (Tctl") {
+				temps.CPU = value
+			}
+		case strings.HasPrefix(parent, "/lpc/"), strings.HasPrefix(parent, "/motherboard"):
+			kind := map[string]string{"Temperature": SensorTemperature, "Fan": SensorFan, "Voltage": SensorVoltage}[s.SensorType]
+			if kind != "" {
+				temps.addSensor(TempSensor{Name: s.Name, Source: s.Parent, Type: kind, Value: value})
+			}
+		case s.SensorType == "Temperature" && value > 0:
+			temps.addSensor(TempSensor{Name: s.Name, Source: s.Parent, Type: SensorTemperature, Value: value}) // Drives, GPUs
+		}
+	}
+	temps.setCores(cores)
+	return temps, nil
+}
+
+// cpuCoreNumber parses per-core sensor names such as "CPU Core #3" (Intel)
+// or "Core #3", returning the zero-based core number
+func cpuCoreNumber(name string) (int, bool) {
+	rest, found := strings.CutPrefix(name, "CPU ")
+	if !found {
+		rest = name
+	}
+	rest, found = strings.CutPrefix(rest, "Core #")
+	if !found {
+		return 0, false
+	}
+	n, err := strconv.Atoi(rest)
+	if err != nil || n < 1 {
+		return 0, false
+	}
+	return n - 1, true
+}
@@ -0,0 +1,145 @@
+package metrics
+
+import (
+	"context"
+	"runtime"
+	"slices"
+	"sort"
+	"time"
+
+	"github.com/jcdorr003/windash-agent/internal/config"
+	"github.com/shirou/gopsutil/v4/process"
+)
+
+const (
+	defaultTopProcsCount    = 5
+	defaultTopProcsInterval = 30 * time.Second
+)
+
+// TopProcess is one of the busiest processes
+type TopProcess struct {
+	PID  int32   `json:"pid"`
+	Name string  `json:"name,omitempty"` // Omitted with privacy.hideProcessNames
+	CPU  float64 `json:"cpu"`            // % of total CPU capacity since the previous report, as in Task Manager
+	RSS  uint64  `json:"rss"`            // Resident (working set) memory in bytes
+}
+
+// TopProcesses lists the heaviest processes by CPU and by memory. A process
+// can appear in both lists.
+type TopProcesses struct {
+	ByCPU []TopProcess `json:"byCpu"`
+	ByMem []TopProcess `json:"byMem"`
+}
+
+// topProcSampler walks the process list every interval, keeping each
+// process's CPU time so usage can be computed from the difference
+type topProcSampler struct {
+	count    int
+	interval time.Duration
+	next     time.Time
+
+	lastCPU  map[int32]float64 // Total CPU seconds per PID at the last walk
+	lastTime time.Time
+	names    map[int32]string
+}
+
+// EnableTopProcesses adds the top processes by CPU and memory to real
+// samples, every collectors.topProcs.intervalSec rather than every sample.
+// Must be called before Start.
+func (c *Collector) EnableTopProcesses(cfg config.TopProcsConfig) {
+	count := cfg.Count
+	if count <= 0 {
+		count = defaultTopProcsCount
+	}
+	interval := time.Duration(cfg.IntervalSec) * time.Second
+	if interval <= 0 {
+		interval = defaultTopProcsInterval
+	}
+	c.topProcs = &topProcSampler{count: count, interval: interval, names: make(map[int32]string)}
+}
+
+// due reports whether the process list should be walked at now
+func (t *topProcSampler) due(now time.Time) bool {
+	return !now.Before(t.next)
+}
+
+// collect walks the process list. The first walk only records CPU times and
+// returns nil; later walks return the top processes since the previous one.
+func (t *topProcSampler) collect(ctx context.Context, withNames bool) (*TopProcesses, error) {
+	procs, err := process.ProcessesWithContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	t.next = now.Add(t.interval)
+
+	elapsed := now.Sub(t.lastTime).Seconds()
+	capacity := elapsed * float64(runtime.NumCPU())
+	cpuTimes := make(map[int32]float64, len(procs))
+	all := make([]TopProcess, 0, len(procs))
+	for _, p := range procs {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		if p.Pid == 0 {
+			continue // System Idle Process
+		}
+		entry := TopProcess{PID: p.Pid}
+		if times, err := p.TimesWithContext(ctx); err == nil {
+			total := times.User + times.System
+			cpuTimes[p.Pid] = total
+			// A PID whose CPU time went down was reused by a new process
+			if last, ok := t.lastCPU[p.Pid]; ok && total >= last && capacity > 0 {
+				entry.CPU = clampPercent((total - last) / capacity * 100)
+			}
+		}
+		if m, err := p.MemoryInfoWithContext(ctx); err == nil {
+			entry.RSS = m.RSS
+		}
+		all = append(all, entry)
+	}
+
+	first := t.lastCPU == nil
+	t.lastCPU = cpuTimes
+	t.lastTime = now
+	if first {
+		return nil, nil
+	}
+
+	top := &TopProcesses{
+		ByCPU: t.top(all, func(a, b TopProcess) bool { return a.CPU > b.CPU }),
+		ByMem: t.top(all, func(a, b TopProcess) bool { return a.RSS > b.RSS }),
+	}
+	if withNames {
+		for _, list := range [][]TopProcess{top.ByCPU, top.ByMem} {
+			for i := range list {
+				list[i].Name = t.processName(list[i].PID)
+			}
+		}
+	}
+	if len(t.names) > 1024 {
+		clear(t.names) // PIDs get reused; don't let the cache grow forever
+	}
+	return top, nil
+}
+
+// top returns the first count processes of all ordered by less
+func (t *topProcSampler) top(all []TopProcess, less func(a, b TopProcess) bool) []TopProcess {
+	sorted := make([]TopProcess, len(all))
+	copy(sorted, all)
+	sort.Slice(sorted, func(i, j int) bool { return less(sorted[i], sorted[j]) })
+	return slices.Clone(sorted[:min(t.count, len(sorted))]) // Don't keep the whole list alive
+}
+
+// processName looks up a process name, caching it per PID
+func (t *topProcSampler) processName(pid int32) string {
+	if name, ok := t.names[pid]; ok {
+		return name
+	}
+	name := ""
+	if p, err := process.NewProcess(pid); err == nil {
+		name, _ = p.Name()
+	}
+	t.names[pid] = name
+	return name
+}
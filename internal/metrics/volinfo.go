@@ -0,0 +1,40 @@
+package metrics
+
+import "strings"
+
+// Drive types in DiskUsage.Type
+const (
+	DriveFixed     = "fixed"
+	DriveRemovable = "removable" // USB sticks and card readers
+	DriveNetwork   = "network"
+	DriveCDROM     = "cdrom"
+	DriveRAM       = "ram"
+)
+
+// volumeInfo describes a volume beyond its usage. Reading it can be as slow
+// as reading usage on network drives, so it is cached with it.
+type volumeInfo struct {
+	label     string
+	serial    string
+	driveType string
+}
+
+// networkFSTypes are file systems served by another machine
+var networkFSTypes = map[string]bool{
+	"nfs": true, "nfs4": true, "cifs": true, "smbfs": true, "smb3": true,
+	"afpfs": true, "webdav": true, "davfs": true, "fuse.sshfs": true, "9p": true,
+}
+
+// driveTypeOf guesses the drive type from the file system alone
+func driveTypeOf(fsType string) string {
+	fsType = strings.ToLower(fsType)
+	switch {
+	case networkFSTypes[fsType]:
+		return DriveNetwork
+	case fsType == "tmpfs" || fsType == "ramfs":
+		return DriveRAM
+	case fsType == "iso9660" || fsType == "udf" || fsType == "cd9660":
+		return DriveCDROM
+	}
+	return DriveFixed
+}
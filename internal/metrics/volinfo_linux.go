@@ -0,0 +1,66 @@
+//go:build linux
+
+package metrics
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/shirou/gopsutil/v4/disk"
+)
+
+// readVolumeInfo finds the label and UUID through the /dev/disk/by-label
+// and by-uuid links udev keeps, and whether the disk is removable (or on
+// USB) from sysfs
+func readVolumeInfo(ctx context.Context, p disk.PartitionStat) volumeInfo {
+	info := volumeInfo{driveType: driveTypeOf(p.Fstype)}
+	if !strings.HasPrefix(p.Device, "/dev/") {
+		return info
+	}
+	device, err := filepath.EvalSymlinks(p.Device)
+	if err != nil {
+		return info
+	}
+	info.label = diskLinkName("/dev/disk/by-label", device)
+	info.serial = diskLinkName("/dev/disk/by-uuid", device)
+
+	name := filepath.Base(device)
+	if strings.HasPrefix(name, "sr") {
+		info.driveType = DriveCDROM
+		return info
+	}
+	// /sys/class/block/sdb1 links to .../usb2/.../block/sdb/sdb1
+	sysPath, err := filepath.EvalSymlinks(filepath.Join("/sys/class/block", name))
+	if err != nil {
+		return info
+	}
+	if _, err := os.Stat(filepath.Join(sysPath, "partition")); err == nil {
+		sysPath = filepath.Dir(sysPath)
+	}
+	removable, _ := os.ReadFile(filepath.Join(sysPath, "removable"))
+	if strings.TrimSpace(string(removable)) == "1" || strings.Contains(sysPath, "/usb") {
+		info.driveType = DriveRemovable
+	}
+	return info
+}
+
+// diskLinkName returns the name of the link in dir that points to device,
+// with udev's \x escapes undone
+func diskLinkName(dir, device string) string {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return ""
+	}
+	for _, e := range entries {
+		if target, err := filepath.EvalSymlinks(filepath.Join(dir, e.Name())); err == nil && target == device {
+			if name, err := strconv.Unquote(`"` + e.Name() + `"`); err == nil {
+				return name
+			}
+			return e.Name()
+		}
+	}
+	return ""
+}
@@ -0,0 +1,14 @@
+//go:build !windows && !linux
+
+package metrics
+
+import (
+	"context"
+
+	"github.com/shirou/gopsutil/v4/disk"
+)
+
+// readVolumeInfo only knows the drive type, from the file system
+func readVolumeInfo(ctx context.Context, p disk.PartitionStat) volumeInfo {
+	return volumeInfo{driveType: driveTypeOf(p.Fstype)}
+}
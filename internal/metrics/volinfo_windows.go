@@ -0,0 +1,42 @@
+//go:build windows
+
+package metrics
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/shirou/gopsutil/v4/disk"
+	"golang.org/x/sys/windows"
+)
+
+// readVolumeInfo reads the label and serial number (as shown by vol) with
+// GetVolumeInformation and the drive type with GetDriveType
+func readVolumeInfo(ctx context.Context, p disk.PartitionStat) volumeInfo {
+	root, err := windows.UTF16PtrFromString(p.Mountpoint + `\`)
+	if err != nil {
+		return volumeInfo{}
+	}
+
+	var info volumeInfo
+	switch windows.GetDriveType(root) {
+	case windows.DRIVE_REMOVABLE:
+		info.driveType = DriveRemovable
+	case windows.DRIVE_REMOTE:
+		info.driveType = DriveNetwork
+	case windows.DRIVE_CDROM:
+		info.driveType = DriveCDROM
+	case windows.DRIVE_RAMDISK:
+		info.driveType = DriveRAM
+	default:
+		info.driveType = DriveFixed
+	}
+
+	label := make([]uint16, windows.MAX_PATH+1)
+	var serial uint32
+	if err := windows.GetVolumeInformation(root, &label[0], uint32(len(label)), &serial, nil, nil, nil, 0); err == nil {
+		info.label = windows.UTF16ToString(label)
+		info.serial = fmt.Sprintf("%04X-%04X", serial>>16, serial&0xFFFF)
+	}
+	return info
+}
@@ -0,0 +1,58 @@
+package metrics
+
+import (
+	"time"
+
+	"github.com/jcdorr003/windash-agent/internal/outbound"
+	"github.com/shirou/gopsutil/v4/disk"
+)
+
+// Volume identifies a mounted volume in volume events
+type Volume struct {
+	Mount  string `json:"mount"`            // Mount point or drive letter
+	Device string `json:"device,omitempty"` // e.g. \\?\Volume{...} or /dev/sdb1
+	FSType string `json:"fsType,omitempty"` // e.g. NTFS, exFAT
+}
+
+// VolumeEvent is an "event" message for a volume appearing or disappearing
+// (USB drives, mounted VHDs, network drives)
+type VolumeEvent struct {
+	Type   string    `json:"type"` // always "event"
+	Kind   string    `json:"kind"` // volumeAttached or volumeDetached
+	TS     time.Time `json:"ts"`
+	HostID string    `json:"hostId"`
+	Volume Volume    `json:"volume"`
+}
+
+// SendVolumeEvents sends an event to sender whenever the set of mounted
+// volumes changes between collections. Must be called before Start.
+func (c *Collector) SendVolumeEvents(sender outbound.Sender) {
+	c.volumeEvents = sender
+}
+
+// trackVolumes compares the partitions of this collection with the previous
+// one and sends an event for each change. The first call only records the
+// baseline.
+func (c *Collector) trackVolumes(partitions []disk.PartitionStat) {
+	current := make(map[string]Volume, len(partitions))
+	for _, p := range partitions {
+		current[p.Mountpoint] = Volume{Mount: p.Mountpoint, Device: p.Device, FSType: p.Fstype}
+	}
+
+	if c.volumes != nil && c.volumeEvents != nil {
+		now := time.Now()
+		for mount, v := range current {
+			if _, ok := c.volumes[mount]; !ok {
+				c.logger.Info("💽 Volume attached", "mount", mount, "fsType", v.FSType)
+				c.volumeEvents.Send("event", &VolumeEvent{Type: "event", Kind: "volumeAttached", TS: now, HostID: c.hostID, Volume: v})
+			}
+		}
+		for mount, v := range c.volumes {
+			if _, ok := current[mount]; !ok {
+				c.logger.Info("💽 Volume detached", "mount", mount)
+				c.volumeEvents.Send("event", &VolumeEvent{Type: "event", Kind: "volumeDetached", TS: now, HostID: c.hostID, Volume: v})
+			}
+		}
+	}
+	c.volumes = current
+}
@@ -0,0 +1,54 @@
+package metrics
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+)
+
+// WiFiLink is a connected wireless interface. Comparing signal and rates
+// with dashboard latency shows whether spikes come from a weak link.
+type WiFiLink struct {
+	Interface string  `json:"interface"`       // Adapter description
+	SSID      string  `json:"ssid"`            // Network name
+	BSSID     string  `json:"bssid,omitempty"` // Access point MAC; changes when roaming
+	Signal    int     `json:"signal"`          // Signal quality 0-100 (as in the Windows Wi-Fi icon)
+	RSSI      int     `json:"rssi,omitempty"`  // Received signal strength in dBm
+	Channel   int     `json:"channel,omitempty"`
+	PHY       string  `json:"phy,omitempty"` // 802.11 standard, e.g. 802.11ax
+	RxMbps    float64 `json:"rxMbps"`        // Current receive PHY rate
+	TxMbps    float64 `json:"txMbps"`        // Current transmit PHY rate
+}
+
+// EnableWiFi adds the connected wireless interfaces to real samples. Must
+// be called before Start.
+func (c *Collector) EnableWiFi() {
+	c.wifi = true
+}
+
+// HashSSIDs replaces Wi-Fi network names and access point MACs with
+// host-salted hashes, so roaming and network changes still show without
+// revealing where the machine is. Must be called before Start.
+func (c *Collector) HashSSIDs() {
+	c.hashSSIDs = true
+}
+
+// hashNetworkName returns a short, host-salted hash of name ("" stays "")
+func (c *Collector) hashNetworkName(name string) string {
+	if name == "" {
+		return ""
+	}
+	sum := sha256.Sum256([]byte(c.hostID + "|" + name))
+	return hex.EncodeToString(sum[:8])
+}
+
+// phyTypes names DOT11_PHY_TYPE values by their 802.11 standard
+var phyTypes = map[uint32]string{
+	4:  "802.11a",
+	5:  "802.11b",
+	6:  "802.11g",
+	7:  "802.11n",
+	8:  "802.11ac",
+	9:  "802.11ad",
+	10: "802.11ax",
+	11: "802.11be",
+}
@@ -0,0 +1,13 @@
+//go:build !windows
+
+package metrics
+
+import (
+	"context"
+	"errors"
+)
+
+// readWiFi is only implemented on Windows
+func readWiFi(ctx context.Context) ([]WiFiLink, error) {
+	return nil, errors.ErrUnsupported
+}
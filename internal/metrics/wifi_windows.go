@@ -0,0 +1,141 @@
+//go:build windows
+
+package metrics
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+)
+
+var (
+	modwlanapi             = windows.NewLazySystemDLL("wlanapi.dll")
+	procWlanOpenHandle     = modwlanapi.NewProc("WlanOpenHandle")
+	procWlanCloseHandle    = modwlanapi.NewProc("WlanCloseHandle")
+	procWlanEnumInterfaces = modwlanapi.NewProc("WlanEnumInterfaces")
+	procWlanQueryInterface = modwlanapi.NewProc("WlanQueryInterface")
+	procWlanFreeMemory     = modwlanapi.NewProc("WlanFreeMemory")
+)
+
+// WLAN_INTF_OPCODE values queried here
+const (
+	wlanIntfOpcodeCurrentConnection = 7
+	wlanIntfOpcodeChannelNumber     = 8
+	wlanIntfOpcodeRSSI              = 0x10000102
+)
+
+const wlanInterfaceStateConnected = 1
+
+// wlanInterfaceInfo is WLAN_INTERFACE_INFO
+type wlanInterfaceInfo struct {
+	InterfaceGUID windows.GUID
+	Description   [256]uint16
+	State         uint32
+}
+
+// wlanConnectionAttributes is WLAN_CONNECTION_ATTRIBUTES, up to the end of
+// its association attributes
+type wlanConnectionAttributes struct {
+	State          uint32
+	ConnectionMode uint32
+	ProfileName    [256]uint16
+	SSIDLength     uint32
+	SSID           [32]byte
+	BSSType        uint32
+	BSSID          [6]byte
+	PHYType        uint32
+	PHYIndex       uint32
+	SignalQuality  uint32
+	RxRate         uint32 // kbps
+	TxRate         uint32 // kbps
+}
+
+// readWiFi reports every connected interface through the WLAN API. Without
+// the WLAN AutoConfig service (servers, machines without Wi-Fi) the source
+// is unsupported.
+func readWiFi(ctx context.Context) ([]WiFiLink, error) {
+	if err := modwlanapi.Load(); err != nil {
+		return nil, errors.ErrUnsupported
+	}
+	var version uint32
+	var handle windows.Handle
+	if r, _, _ := procWlanOpenHandle.Call(2, 0, uintptr(unsafe.Pointer(&version)), uintptr(unsafe.Pointer(&handle))); r != 0 {
+		if windows.Errno(r) == windows.ERROR_SERVICE_NOT_ACTIVE {
+			return nil, errors.ErrUnsupported
+		}
+		return nil, fmt.Errorf("WlanOpenHandle: %w", windows.Errno(r))
+	}
+	defer procWlanCloseHandle.Call(uintptr(handle), 0)
+
+	var list *struct {
+		Count uint32
+		Index uint32
+	}
+	if r, _, _ := procWlanEnumInterfaces.Call(uintptr(handle), 0, uintptr(unsafe.Pointer(&list))); r != 0 {
+		return nil, fmt.Errorf("WlanEnumInterfaces: %w", windows.Errno(r))
+	}
+	defer procWlanFreeMemory.Call(uintptr(unsafe.Pointer(list)))
+	infos := unsafe.Slice((*wlanInterfaceInfo)(unsafe.Add(unsafe.Pointer(list), 8)), list.Count)
+
+	var links []WiFiLink
+	for i := range infos {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		if infos[i].State != wlanInterfaceStateConnected {
+			continue
+		}
+		link, err := queryWiFiLink(handle, &infos[i])
+		if err != nil {
+			return nil, err
+		}
+		links = append(links, link)
+	}
+	return links, nil
+}
+
+// queryWiFiLink reads the connection, channel and RSSI of one interface. The
+// channel and RSSI are left out if the driver doesn't report them.
+func queryWiFiLink(handle windows.Handle, info *wlanInterfaceInfo) (WiFiLink, error) {
+	var attrs wlanConnectionAttributes
+	if err := wlanQuery(handle, info, wlanIntfOpcodeCurrentConnection, unsafe.Pointer(&attrs), unsafe.Sizeof(attrs)); err != nil {
+		return WiFiLink{}, err
+	}
+	link := WiFiLink{
+		Interface: windows.UTF16ToString(info.Description[:]),
+		SSID:      string(attrs.SSID[:min(attrs.SSIDLength, uint32(len(attrs.SSID)))]),
+		BSSID:     net.HardwareAddr(attrs.BSSID[:]).String(),
+		Signal:    int(attrs.SignalQuality),
+		PHY:       phyTypes[attrs.PHYType],
+		RxMbps:    float64(attrs.RxRate) / 1000,
+		TxMbps:    float64(attrs.TxRate) / 1000,
+	}
+	var channel uint32
+	if wlanQuery(handle, info, wlanIntfOpcodeChannelNumber, unsafe.Pointer(&channel), unsafe.Sizeof(channel)) == nil {
+		link.Channel = int(channel)
+	}
+	var rssi int32
+	if wlanQuery(handle, info, wlanIntfOpcodeRSSI, unsafe.Pointer(&rssi), unsafe.Sizeof(rssi)) == nil {
+		link.RSSI = int(rssi)
+	}
+	return link, nil
+}
+
+// wlanQuery runs WlanQueryInterface and copies up to size bytes of the
+// result to dst
+func wlanQuery(handle windows.Handle, info *wlanInterfaceInfo, opcode uint32, dst unsafe.Pointer, size uintptr) error {
+	var dataSize uint32
+	var data unsafe.Pointer
+	r, _, _ := procWlanQueryInterface.Call(uintptr(handle), uintptr(unsafe.Pointer(&info.InterfaceGUID)), uintptr(opcode), 0,
+		uintptr(unsafe.Pointer(&dataSize)), uintptr(unsafe.Pointer(&data)), 0)
+	if r != 0 {
+		return fmt.Errorf("WlanQueryInterface(%d): %w", opcode, windows.Errno(r))
+	}
+	defer procWlanFreeMemory.Call(uintptr(data))
+	copy(unsafe.Slice((*byte)(dst), size), unsafe.Slice((*byte)(data), min(uintptr(dataSize), size)))
+	return nil
+}
@@ -0,0 +1,302 @@
+// Package netprobe measures round-trip time and packet loss to a configured
+// list of targets - the default gateway, public resolvers, custom hosts -
+// and reports them in a "netprobe" message every interval. Hosts are pinged
+// over ICMP; host:port targets are timed with TCP connects instead, for
+// networks that drop pings. Each round also times DNS, looks for a captive
+// portal and sums everything up as a connectivity grade.
+package netprobe
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"net"
+	"net/http"
+	"net/netip"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/jcdorr003/windash-agent/internal/config"
+	"github.com/jcdorr003/windash-agent/internal/outbound"
+	"go.uber.org/zap"
+)
+
+const (
+	defaultInterval = time.Minute
+	defaultCount    = 4
+	maxTargets      = 16
+
+	probeSpacing = 250 * time.Millisecond
+	probeTimeout = 2 * time.Second
+
+	// GatewayTarget stands for the default gateway in netProbe.targets
+	GatewayTarget = "gateway"
+)
+
+// Probe methods in Result.Method
+const (
+	MethodICMP = "icmp"
+	MethodTCP  = "tcp"
+)
+
+var defaultTargets = []string{GatewayTarget, "8.8.8.8"}
+
+// Report is the periodic "netprobe" message
+type Report struct {
+	Type    string    `json:"type"` // always "netprobe"
+	TS      time.Time `json:"ts"`
+	HostID  string    `json:"hostId"`
+	Targets []Result  `json:"targets"`
+	Quality *Quality  `json:"quality"`
+}
+
+// Result is the latency to one target over the last round
+type Result struct {
+	Target   string  `json:"target"`            // As configured, e.g. gateway, 8.8.8.8, nas.local:445
+	Address  string  `json:"address,omitempty"` // What was probed
+	Method   string  `json:"method"`            // icmp or tcp
+	Sent     int     `json:"sent"`
+	Loss     float64 `json:"loss"`            // % of probes unanswered
+	RTTMs    float64 `json:"rttMs,omitempty"` // Average of answered probes
+	MinMs    float64 `json:"minMs,omitempty"`
+	MaxMs    float64 `json:"maxMs,omitempty"`
+	JitterMs float64 `json:"jitterMs,omitempty"` // Mean difference between consecutive answered probes
+	Error    string  `json:"error,omitempty"`    // Why the target couldn't be probed at all
+}
+
+// Prober pings the configured targets every interval
+type Prober struct {
+	logger   *zap.SugaredLogger
+	hostID   string
+	targets  []string
+	interval time.Duration
+	count    int
+	dnsName  string
+	http     *http.Client
+
+	mu      sync.Mutex
+	quality *Quality // Last round's grade
+}
+
+// New creates a prober. Targets beyond maxTargets are dropped.
+func New(logger *zap.SugaredLogger, hostID string, cfg config.NetProbeConfig) *Prober {
+	targets := cfg.Targets
+	if len(targets) == 0 {
+		targets = defaultTargets
+	}
+	if len(targets) > maxTargets {
+		logger.Warn("Too many netProbe targets, probing the first ones", "targets", len(targets), "max", maxTargets)
+		targets = targets[:maxTargets]
+	}
+	interval := time.Duration(cfg.IntervalSec) * time.Second
+	if interval <= 0 {
+		interval = defaultInterval
+	}
+	count := cfg.Count
+	if count <= 0 {
+		count = defaultCount
+	}
+	dnsName := cfg.DNSName
+	if dnsName == "" {
+		dnsName = defaultDNSName
+	}
+	return &Prober{
+		logger:   logger,
+		hostID:   hostID,
+		targets:  slices.Clone(targets),
+		interval: interval,
+		count:    count,
+		dnsName:  dnsName,
+		http: &http.Client{
+			Timeout: portalTimeout,
+			// A redirect is the portal's answer; don't follow it
+			CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse },
+		},
+	}
+}
+
+// Run probes the targets every interval until ctx is cancelled
+func (p *Prober) Run(ctx context.Context, sender outbound.Sender) {
+	p.logger.Info("📶 Network probes started", "targets", p.targets, "dnsName", p.dnsName, "interval", p.interval)
+	ticker := time.NewTicker(p.interval)
+	defer ticker.Stop()
+
+	for {
+		report := p.Round(ctx)
+		if ctx.Err() != nil {
+			return
+		}
+		sender.Send("netprobe", report)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Round probes every target once and runs the quality checks, all at the
+// same time
+func (p *Prober) Round(ctx context.Context) *Report {
+	results := make([]Result, len(p.targets))
+	gatewayIndex := slices.Index(p.targets, GatewayTarget)
+	var checks qualityChecks
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		checks = p.runQualityChecks(ctx, gatewayIndex < 0)
+	}()
+	for i, target := range p.targets {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[i] = p.probeTarget(ctx, target)
+		}()
+	}
+	wg.Wait()
+
+	gateway := checks.gateway
+	if gatewayIndex >= 0 {
+		gateway = results[gatewayIndex]
+	}
+	quality := checks.summarize(gateway, results)
+	p.mu.Lock()
+	p.quality = quality
+	p.mu.Unlock()
+	return &Report{Type: "netprobe", TS: time.Now(), HostID: p.hostID, Targets: results, Quality: quality}
+}
+
+// probeTarget sends count probes to one target, spaced out, and summarizes
+// them
+func (p *Prober) probeTarget(ctx context.Context, target string) Result {
+	result := Result{Target: target, Method: MethodICMP}
+	probe, err := p.resolve(ctx, target, &result)
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+
+	var rtts []time.Duration
+	var lastErr error
+	for i := range p.count {
+		if i > 0 && sleep(ctx, probeSpacing) != nil {
+			break
+		}
+		result.Sent++
+		rtt, err := probe(ctx, uint16(i))
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		rtts = append(rtts, max(rtt, time.Microsecond))
+	}
+	if result.Sent == 0 {
+		result.Error = ctx.Err().Error()
+		return result
+	}
+	result.Loss = float64(result.Sent-len(rtts)) / float64(result.Sent) * 100
+	if len(rtts) == 0 {
+		// Errors other than timeouts (no ICMP permission, no route) mean
+		// the target was never really probed
+		if lastErr != nil && !isTimeout(lastErr) {
+			result.Error = lastErr.Error()
+		}
+		return result
+	}
+
+	var total, jitter time.Duration
+	for i, rtt := range rtts {
+		total += rtt
+		if i > 0 {
+			jitter += (rtt - rtts[i-1]).Abs()
+		}
+	}
+	result.RTTMs = ms(total / time.Duration(len(rtts)))
+	result.MinMs = ms(slices.Min(rtts))
+	result.MaxMs = ms(slices.Max(rtts))
+	if len(rtts) > 1 {
+		jitter /= time.Duration(len(rtts) - 1)
+		result.JitterMs = ms(jitter)
+	}
+	return result
+}
+
+// resolve turns a target into a probe function, filling in the result's
+// address and method
+func (p *Prober) resolve(ctx context.Context, target string, result *Result) (func(ctx context.Context, seq uint16) (time.Duration, error), error) {
+	if host, port, err := net.SplitHostPort(target); err == nil {
+		addr := net.JoinHostPort(host, port)
+		result.Method, result.Address = MethodTCP, addr
+		return func(ctx context.Context, _ uint16) (time.Duration, error) {
+			return dialTCP(ctx, addr)
+		}, nil
+	}
+
+	var addr netip.Addr
+	if target == GatewayTarget {
+		gw, err := defaultGateway()
+		if err != nil {
+			return nil, fmt.Errorf("default gateway: %w", err)
+		}
+		addr = gw
+	} else if ip, err := netip.ParseAddr(target); err == nil {
+		addr = ip
+	} else {
+		ips, err := net.DefaultResolver.LookupNetIP(ctx, "ip4", target)
+		if err != nil {
+			return nil, err
+		}
+		addr = ips[0]
+	}
+	addr = addr.Unmap()
+	if !addr.Is4() {
+		return nil, fmt.Errorf("%s: only IPv4 can be pinged", addr)
+	}
+	result.Address = addr.String()
+	return func(ctx context.Context, seq uint16) (time.Duration, error) {
+		return pingICMP(ctx, addr, seq, probeTimeout)
+	}, nil
+}
+
+// dialTCP times a TCP connect
+func dialTCP(ctx context.Context, addr string) (time.Duration, error) {
+	dialer := net.Dialer{Timeout: probeTimeout}
+	start := time.Now()
+	conn, err := dialer.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return 0, err
+	}
+	rtt := time.Since(start)
+	conn.Close()
+	return rtt, nil
+}
+
+// errTimeout is returned by pingICMP when no reply arrived in time
+var errTimeout = errors.New("request timed out")
+
+// isTimeout reports whether err is a probe that went unanswered
+func isTimeout(err error) bool {
+	var netErr net.Error
+	return errors.Is(err, errTimeout) || (errors.As(err, &netErr) && netErr.Timeout())
+}
+
+// ms converts a duration to milliseconds, rounded to the microsecond
+func ms(d time.Duration) float64 {
+	return math.Round(float64(d)/float64(time.Microsecond)) / 1000
+}
+
+// sleep waits for d or until ctx is cancelled
+func sleep(ctx context.Context, d time.Duration) error {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
+	}
+}
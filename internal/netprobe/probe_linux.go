@@ -0,0 +1,142 @@
+//go:build linux
+
+package netprobe
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net"
+	"net/netip"
+	"os"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// pingICMP sends one echo request over an unprivileged ICMP socket, which
+// the kernel allows for groups in net.ipv4.ping_group_range (all users on
+// most distributions), and there it sets the identifier and checksum and
+// filters replies to the socket. Where that is off, a raw socket works for
+// root or with CAP_NET_RAW.
+func pingICMP(ctx context.Context, addr netip.Addr, seq uint16, timeout time.Duration) (time.Duration, error) {
+	conn, raw, err := openICMP()
+	if err != nil {
+		return 0, err
+	}
+	defer conn.Close()
+
+	deadline := time.Now().Add(timeout)
+	if d, ok := ctx.Deadline(); ok && d.Before(deadline) {
+		deadline = d
+	}
+	conn.SetDeadline(deadline)
+	stop := context.AfterFunc(ctx, func() { conn.SetDeadline(time.Now()) })
+	defer stop()
+
+	// Echo request: type 8, code 0, checksum, identifier, sequence, data
+	id := uint16(os.Getpid())
+	request := make([]byte, 16)
+	request[0] = 8
+	binary.BigEndian.PutUint16(request[4:], id)
+	binary.BigEndian.PutUint16(request[6:], seq)
+	copy(request[8:], "windash!")
+	binary.BigEndian.PutUint16(request[2:], checksum(request))
+
+	var to net.Addr = &net.UDPAddr{IP: addr.AsSlice()}
+	if raw {
+		to = &net.IPAddr{IP: addr.AsSlice()}
+	}
+	start := time.Now()
+	if _, err := conn.WriteTo(request, to); err != nil {
+		return 0, err
+	}
+	reply := make([]byte, 512)
+	for {
+		n, from, err := conn.ReadFrom(reply)
+		if err != nil {
+			if ctx.Err() != nil {
+				return 0, ctx.Err()
+			}
+			return 0, err
+		}
+		// Echo reply: type 0 with our sequence number, and on a raw socket,
+		// which sees every reply, our identifier and target
+		if n >= 8 && reply[0] == 0 && binary.BigEndian.Uint16(reply[6:]) == seq &&
+			(!raw || binary.BigEndian.Uint16(reply[4:]) == id && from.String() == addr.String()) {
+			return time.Since(start), nil
+		}
+	}
+}
+
+// openICMP opens an unprivileged ICMP socket, or a raw one if that isn't
+// allowed
+func openICMP() (conn net.PacketConn, raw bool, err error) {
+	fd, err := syscall.Socket(syscall.AF_INET, syscall.SOCK_DGRAM|syscall.SOCK_CLOEXEC, syscall.IPPROTO_ICMP)
+	if err == nil {
+		f := os.NewFile(uintptr(fd), "icmp")
+		defer f.Close()
+		conn, err := net.FilePacketConn(f)
+		return conn, false, err
+	}
+	if conn, rawErr := net.ListenPacket("ip4:icmp", "0.0.0.0"); rawErr == nil {
+		return conn, true, nil
+	}
+	return nil, false, fmt.Errorf("ICMP socket (check net.ipv4.ping_group_range): %w", err)
+}
+
+// checksum is the Internet checksum of an ICMP message
+func checksum(b []byte) uint16 {
+	var sum uint32
+	for i := 0; i+1 < len(b); i += 2 {
+		sum += uint32(binary.BigEndian.Uint16(b[i:]))
+	}
+	if len(b)%2 == 1 {
+		sum += uint32(b[len(b)-1]) << 8
+	}
+	for sum>>16 != 0 {
+		sum = sum&0xffff + sum>>16
+	}
+	return ^uint16(sum)
+}
+
+// defaultGateway reads the IPv4 default route with the lowest metric from
+// /proc/net/route
+func defaultGateway() (netip.Addr, error) {
+	f, err := os.Open("/proc/net/route")
+	if err != nil {
+		return netip.Addr{}, err
+	}
+	defer f.Close()
+
+	const rtfGateway = 0x2
+	var best netip.Addr
+	bestMetric := -1
+	scanner := bufio.NewScanner(f)
+	scanner.Scan() // Header
+	for scanner.Scan() {
+		// Iface Destination Gateway Flags RefCnt Use Metric Mask ...
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 8 || fields[1] != "00000000" || fields[7] != "00000000" {
+			continue
+		}
+		flags, _ := strconv.ParseUint(fields[3], 16, 32)
+		gw, err := strconv.ParseUint(fields[2], 16, 32)
+		if err != nil || flags&rtfGateway == 0 {
+			continue
+		}
+		metric, _ := strconv.Atoi(fields[6])
+		if bestMetric < 0 || metric < bestMetric {
+			var ip [4]byte
+			binary.LittleEndian.PutUint32(ip[:], uint32(gw)) // Stored in network order
+			best, bestMetric = netip.AddrFrom4(ip), metric
+		}
+	}
+	if bestMetric < 0 {
+		return netip.Addr{}, errors.New("no default route")
+	}
+	return best, scanner.Err()
+}
@@ -0,0 +1,21 @@
+//go:build !windows && !linux
+
+package netprobe
+
+import (
+	"context"
+	"errors"
+	"net/netip"
+	"time"
+)
+
+// pingICMP is not implemented outside Windows and Linux; host:port targets
+// still work
+func pingICMP(ctx context.Context, addr netip.Addr, seq uint16, timeout time.Duration) (time.Duration, error) {
+	return 0, errors.ErrUnsupported
+}
+
+// defaultGateway is not implemented outside Windows and Linux
+func defaultGateway() (netip.Addr, error) {
+	return netip.Addr{}, errors.ErrUnsupported
+}
@@ -0,0 +1,120 @@
+//go:build windows
+
+package netprobe
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net/netip"
+	"syscall"
+	"time"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+)
+
+var (
+	modiphlpapi         = windows.NewLazySystemDLL("iphlpapi.dll")
+	procIcmpCreateFile  = modiphlpapi.NewProc("IcmpCreateFile")
+	procIcmpCloseHandle = modiphlpapi.NewProc("IcmpCloseHandle")
+	procIcmpSendEcho    = modiphlpapi.NewProc("IcmpSendEcho")
+)
+
+// ipOptionInformation is IP_OPTION_INFORMATION (64-bit layout)
+type ipOptionInformation struct {
+	TTL         uint8
+	TOS         uint8
+	Flags       uint8
+	OptionsSize uint8
+	OptionsData uintptr
+}
+
+// icmpEchoReply is ICMP_ECHO_REPLY (64-bit layout)
+type icmpEchoReply struct {
+	Address       uint32
+	Status        uint32 // 0 = IP_SUCCESS
+	RoundTripTime uint32 // Milliseconds
+	DataSize      uint16
+	Reserved      uint16
+	Data          uintptr
+	Options       ipOptionInformation
+}
+
+var echoData = []byte("windash!")
+
+// pingICMP sends one echo request with IcmpSendEcho, which needs no
+// administrator rights. The call blocks until the reply or the timeout; the
+// round trip is timed here because the API reports whole milliseconds,
+// which hides LAN latencies.
+func pingICMP(ctx context.Context, addr netip.Addr, seq uint16, timeout time.Duration) (time.Duration, error) {
+	if d, ok := ctx.Deadline(); ok {
+		timeout = min(timeout, time.Until(d))
+	}
+	h, _, err := procIcmpCreateFile.Call()
+	if windows.Handle(h) == windows.InvalidHandle {
+		return 0, fmt.Errorf("IcmpCreateFile: %w", err)
+	}
+	defer procIcmpCloseHandle.Call(h)
+
+	ip := addr.As4()
+	reply := make([]byte, unsafe.Sizeof(icmpEchoReply{})+uintptr(len(echoData))+8)
+	start := time.Now()
+	n, _, err := procIcmpSendEcho.Call(h,
+		uintptr(binary.LittleEndian.Uint32(ip[:])), // IPAddr: the address bytes in network order
+		uintptr(unsafe.Pointer(&echoData[0])), uintptr(len(echoData)), 0,
+		uintptr(unsafe.Pointer(&reply[0])), uintptr(len(reply)), uintptr(timeout.Milliseconds()))
+	rtt := time.Since(start)
+	if n == 0 {
+		const ipReqTimedOut = 11010
+		if errors.Is(err, windows.Errno(ipReqTimedOut)) {
+			return 0, errTimeout
+		}
+		return 0, fmt.Errorf("IcmpSendEcho: %w", err)
+	}
+	if r := (*icmpEchoReply)(unsafe.Pointer(&reply[0])); r.Status != 0 {
+		return 0, errTimeout // Unreachable and TTL-expired replies count as lost
+	}
+	return rtt, nil
+}
+
+// defaultGateway returns the IPv4 gateway of the connected adapter with the
+// lowest interface metric
+func defaultGateway() (netip.Addr, error) {
+	size := uint32(15 * 1024)
+	var buf []byte
+	for range 3 {
+		buf = make([]byte, size)
+		err := windows.GetAdaptersAddresses(windows.AF_INET,
+			windows.GAA_FLAG_INCLUDE_GATEWAYS|windows.GAA_FLAG_SKIP_ANYCAST|windows.GAA_FLAG_SKIP_MULTICAST|windows.GAA_FLAG_SKIP_DNS_SERVER,
+			0, (*windows.IpAdapterAddresses)(unsafe.Pointer(&buf[0])), &size)
+		if err == nil {
+			break
+		}
+		if !errors.Is(err, windows.ERROR_BUFFER_OVERFLOW) {
+			return netip.Addr{}, fmt.Errorf("GetAdaptersAddresses: %w", err)
+		}
+	}
+
+	var best netip.Addr
+	var bestMetric uint32
+	for a := (*windows.IpAdapterAddresses)(unsafe.Pointer(&buf[0])); a != nil; a = a.Next {
+		if a.OperStatus != windows.IfOperStatusUp {
+			continue
+		}
+		for g := a.FirstGatewayAddress; g != nil; g = g.Next {
+			sa, err := g.Address.Sockaddr.Sockaddr()
+			if err != nil {
+				continue
+			}
+			if in4, ok := sa.(*syscall.SockaddrInet4); ok && (!best.IsValid() || a.Ipv4Metric < bestMetric) {
+				best, bestMetric = netip.AddrFrom4(in4.Addr), a.Ipv4Metric
+			}
+		}
+	}
+	if !best.IsValid() {
+		return netip.Addr{}, errors.New("no default gateway")
+	}
+	return best, nil
+}
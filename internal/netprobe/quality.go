@@ -0,0 +1,206 @@
+package netprobe
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// defaultDNSName is resolved every round to time the system resolver
+	defaultDNSName = "www.msftconnecttest.com"
+
+	// portalURL is the Windows network connectivity check (NCSI). A network
+	// behind a captive portal redirects it or answers with the portal page.
+	portalURL     = "http://www.msftconnecttest.com/connecttest.txt"
+	portalContent = "Microsoft Connect Test"
+
+	dnsTimeout    = 3 * time.Second
+	portalTimeout = 5 * time.Second
+)
+
+// Connectivity grades in Quality.Grade
+const (
+	GradeGood    = "good"
+	GradeFair    = "fair"    // Some loss, high latency or a slow resolver
+	GradePoor    = "poor"    // Heavy loss, DNS failing or a captive portal
+	GradeOffline = "offline" // Nothing beyond the local network answers
+)
+
+// Quality summarizes one round as a single connectivity grade, sent in the
+// netprobe message and added to samples
+type Quality struct {
+	TS            time.Time `json:"ts"` // When the round ran
+	Grade         string    `json:"grade"`
+	Gateway       string    `json:"gateway,omitempty"` // Detected default gateway
+	GatewayUp     bool      `json:"gatewayUp"`
+	DNSMs         float64   `json:"dnsMs,omitempty"`    // Time to resolve dnsName through the system resolver
+	DNSError      string    `json:"dnsError,omitempty"` // Why resolving failed
+	Internet      bool      `json:"internet"`           // The connectivity check or a public target answered
+	CaptivePortal bool      `json:"captivePortal,omitempty"`
+}
+
+// portalResult is the outcome of the connectivity check
+type portalResult int
+
+const (
+	portalFailed portalResult = iota // No answer at all
+	portalOK
+	portalDetected
+)
+
+// qualityChecks holds the outcome of the checks run beside the targets
+type qualityChecks struct {
+	dnsMs   float64
+	dnsErr  error
+	portal  portalResult
+	gateway Result // Probed here only when it isn't one of the targets
+}
+
+// runQualityChecks times DNS and runs the captive portal check, probing
+// the gateway too if probeGateway is set, all at once
+func (p *Prober) runQualityChecks(ctx context.Context, probeGateway bool) qualityChecks {
+	var c qualityChecks
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		c.dnsMs, c.dnsErr = p.resolveTime(ctx)
+	}()
+	go func() {
+		defer wg.Done()
+		c.portal = p.checkPortal(ctx)
+	}()
+	if probeGateway {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			c.gateway = p.probeTarget(ctx, GatewayTarget)
+		}()
+	}
+	wg.Wait()
+	return c
+}
+
+// summarize grades the round from the checks, the gateway's result and
+// the targets' results
+func (c *qualityChecks) summarize(gateway Result, results []Result) *Quality {
+	q := &Quality{
+		TS:            time.Now(),
+		Gateway:       gateway.Address,
+		GatewayUp:     gateway.Error == "" && gateway.Sent > 0 && gateway.Loss < 100,
+		DNSMs:         c.dnsMs,
+		CaptivePortal: c.portal == portalDetected,
+	}
+	if c.dnsErr != nil {
+		q.DNSMs, q.DNSError = 0, c.dnsErr.Error()
+	}
+	public := publicResults(results)
+	q.Internet = c.portal == portalOK || len(public) > 0
+	q.Grade = grade(q, gateway, public)
+	return q
+}
+
+// resolveTime times a lookup of dnsName through the system resolver
+func (p *Prober) resolveTime(ctx context.Context) (float64, error) {
+	ctx, cancel := context.WithTimeout(ctx, dnsTimeout)
+	defer cancel()
+	start := time.Now()
+	if _, err := net.DefaultResolver.LookupHost(ctx, p.dnsName); err != nil {
+		return 0, err
+	}
+	return ms(time.Since(start)), nil
+}
+
+// checkPortal fetches the connectivity check page. Any other answer, such
+// as a redirect to a login page, means a captive portal.
+func (p *Prober) checkPortal(ctx context.Context) portalResult {
+	ctx, cancel := context.WithTimeout(ctx, portalTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, portalURL, nil)
+	if err != nil {
+		return portalFailed
+	}
+	resp, err := p.http.Do(req)
+	if err != nil {
+		return portalFailed
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1024))
+	if err != nil {
+		return portalFailed
+	}
+	if resp.StatusCode == http.StatusOK && strings.HasPrefix(string(body), portalContent) {
+		return portalOK
+	}
+	return portalDetected
+}
+
+// publicResults returns the answered targets outside the local network
+func publicResults(results []Result) []Result {
+	var public []Result
+	for _, r := range results {
+		if r.Target == GatewayTarget || r.Error != "" || r.Sent == 0 || r.Loss >= 100 {
+			continue
+		}
+		host := r.Address
+		if h, _, err := net.SplitHostPort(host); err == nil {
+			host = h
+		}
+		// Unresolved host:port targets count; their name was public enough
+		// to look up
+		if addr, err := netip.ParseAddr(host); err == nil {
+			if addr.IsPrivate() || addr.IsLoopback() || addr.IsLinkLocalUnicast() {
+				continue
+			}
+		}
+		public = append(public, r)
+	}
+	return public
+}
+
+// grade turns a round into a connectivity grade
+func grade(q *Quality, gateway Result, public []Result) string {
+	if q.CaptivePortal {
+		return GradePoor
+	}
+	if !q.Internet {
+		return GradeOffline
+	}
+	if q.DNSError != "" {
+		return GradePoor
+	}
+
+	var loss, rtt, jitter float64
+	for _, r := range public {
+		loss = max(loss, r.Loss)
+		rtt = max(rtt, r.RTTMs)
+		jitter = max(jitter, r.JitterMs)
+	}
+	if q.GatewayUp {
+		loss = max(loss, gateway.Loss)
+	}
+	switch {
+	case loss >= 10 || rtt >= 200:
+		return GradePoor
+	case loss > 0 || rtt >= 80 || jitter >= 30 || q.DNSMs >= 200:
+		return GradeFair
+	}
+	return GradeGood
+}
+
+// Quality returns the last round's grade, nil before the first round
+func (p *Prober) Quality() *Quality {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.quality == nil {
+		return nil
+	}
+	q := *p.quality
+	return &q
+}
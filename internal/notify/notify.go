@@ -0,0 +1,40 @@
+package notify
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// toastTimeout bounds how long showing a notification may take
+const toastTimeout = 15 * time.Second
+
+// errUnsupported is returned by showToast where desktop notifications aren't implemented
+var errUnsupported = errors.New("desktop notifications are only supported on Windows")
+
+// Notifier shows desktop notifications (Windows toasts) to the logged-in user
+type Notifier struct {
+	logger *zap.SugaredLogger
+}
+
+// New creates a notifier
+func New(logger *zap.SugaredLogger) *Notifier {
+	return &Notifier{logger: logger}
+}
+
+// Notify shows a notification. link, if set, must be an http(s) URL and is
+// opened when the notification is clicked. severity is currently only
+// informational.
+func (n *Notifier) Notify(title, body, severity, link string) error {
+	if link != "" {
+		u, err := url.Parse(link)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+			return fmt.Errorf("refusing to open non-http URL %q", link)
+		}
+	}
+	n.logger.Debug("Showing notification", "title", title, "severity", severity)
+	return showToast(title, body, link)
+}
@@ -0,0 +1,8 @@
+//go:build !windows
+
+package notify
+
+// showToast is not implemented outside Windows
+func showToast(title, body, link string) error {
+	return errUnsupported
+}
@@ -0,0 +1,47 @@
+//go:build windows
+
+package notify
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"syscall"
+)
+
+// toastScript shows a toast through the WinRT API via PowerShell. Text is
+// passed in environment variables, never spliced into the script, so notice
+// content can't inject commands. PowerShell's own AppUserModelID is used
+// because the agent isn't registered with a Start menu shortcut.
+const toastScript = `
+[Windows.UI.Notifications.ToastNotificationManager, Windows.UI.Notifications, ContentType = WindowsRuntime] | Out-Null
+[Windows.Data.Xml.Dom.XmlDocument, Windows.Data.Xml.Dom.XmlDocument, ContentType = WindowsRuntime] | Out-Null
+$e = [System.Security.SecurityElement]
+$launch = ''
+if ($env:WINDASH_NOTICE_URL) { $launch = ' activationType="protocol" launch="' + $e::Escape($env:WINDASH_NOTICE_URL) + '"' }
+$xml = New-Object Windows.Data.Xml.Dom.XmlDocument
+$xml.LoadXml('<toast' + $launch + '><visual><binding template="ToastGeneric"><text>' + $e::Escape($env:WINDASH_NOTICE_TITLE) + '</text><text>' + $e::Escape($env:WINDASH_NOTICE_BODY) + '</text></binding></visual></toast>')
+$appId = '{1AC14E77-02E7-4E5D-B744-2EB1AE5198B7}\WindowsPowerShell\v1.0\powershell.exe'
+[Windows.UI.Notifications.ToastNotificationManager]::CreateToastNotifier($appId).Show([Windows.UI.Notifications.ToastNotification]::new($xml))
+`
+
+// showToast displays a Windows toast notification
+func showToast(title, body, link string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), toastTimeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, "powershell.exe", "-NoProfile", "-NonInteractive", "-Command", toastScript)
+	cmd.Env = append(os.Environ(),
+		"WINDASH_NOTICE_TITLE="+title,
+		"WINDASH_NOTICE_BODY="+body,
+		"WINDASH_NOTICE_URL="+link,
+	)
+	cmd.SysProcAttr = &syscall.SysProcAttr{HideWindow: true}
+
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("show toast: %w: %s", err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
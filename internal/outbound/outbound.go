@@ -0,0 +1,9 @@
+// Package outbound declares how collectors and monitors hand messages to
+// the backend connection, so they don't each need their own interface or
+// an import of ws.
+package outbound
+
+// Sender queues a typed message for the backend (implemented by ws.Client)
+type Sender interface {
+	Send(msgType string, payload any)
+}
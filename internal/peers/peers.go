@@ -0,0 +1,319 @@
+// Package peers measures round-trip time and packet loss to other agents on
+// the same LAN. The server tells each agent who its peers are (setPeers);
+// every agent answers UDP probes on peers.port and reports its row of the
+// latency matrix in a "peers" message.
+package peers
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"net"
+	"net/netip"
+	"sync"
+	"time"
+
+	"github.com/jcdorr003/windash-agent/internal/config"
+	"github.com/jcdorr003/windash-agent/internal/outbound"
+	"go.uber.org/zap"
+)
+
+const (
+	defaultPort     = 47810
+	defaultInterval = time.Minute
+	maxPeers        = 64
+
+	probesPerRound = 5
+	probeSpacing   = 100 * time.Millisecond
+	replyTimeout   = time.Second // After the last probe of a round
+
+	packetPing = 0
+	packetPong = 1
+	packetSize = 4 + 1 + 8 // magic, kind, sequence number
+)
+
+var magic = []byte("WDP1")
+
+// Peer is another agent to measure, as sent by the server
+type Peer struct {
+	HostID  string `json:"hostId"`
+	Address string `json:"address"` // IP, or IP:port when the peer uses another port
+}
+
+// Report is the periodic "peers" message: this host's row of the matrix
+type Report struct {
+	Type   string    `json:"type"` // always "peers"
+	TS     time.Time `json:"ts"`
+	HostID string    `json:"hostId"`
+	Peers  []Result  `json:"peers"`
+}
+
+// Result is the latency to one peer over the last round
+type Result struct {
+	HostID  string  `json:"hostId"`
+	Address string  `json:"address"`
+	Sent    int     `json:"sent"`
+	Loss    float64 `json:"loss"`            // % of probes unanswered
+	RTTMs   float64 `json:"rttMs,omitempty"` // Average of answered probes
+	MinMs   float64 `json:"minMs,omitempty"`
+	MaxMs   float64 `json:"maxMs,omitempty"`
+}
+
+// target is a validated peer
+type target struct {
+	peer Peer
+	addr netip.AddrPort
+}
+
+// probe is one ping waiting for its pong
+type probe struct {
+	target int
+	sent   time.Time
+	rtt    time.Duration // 0 until answered
+}
+
+// Mesh answers probes from peers and probes them in turn
+type Mesh struct {
+	logger   *zap.SugaredLogger
+	hostID   string
+	port     int
+	interval time.Duration
+
+	mu      sync.Mutex
+	targets []target
+	pending map[uint64]*probe
+	seq     uint64
+}
+
+// NewMesh creates a peer latency mesh
+func NewMesh(logger *zap.SugaredLogger, hostID string, cfg config.PeersConfig) *Mesh {
+	port := cfg.Port
+	if port <= 0 {
+		port = defaultPort
+	}
+	interval := time.Duration(cfg.IntervalSec) * time.Second
+	if interval <= 0 {
+		interval = defaultInterval
+	}
+	return &Mesh{logger: logger, hostID: hostID, port: port, interval: interval}
+}
+
+// SetPeers replaces the peer list. It is rejected as a whole if any address
+// is invalid or outside the local network, so the server can't point agents
+// at hosts on the internet.
+func (m *Mesh) SetPeers(peers []Peer) error {
+	targets, err := m.resolve(peers)
+	if err != nil {
+		return err
+	}
+	m.mu.Lock()
+	m.targets = targets
+	m.mu.Unlock()
+	m.logger.Info("🕸️  Peer list updated", "peers", len(targets))
+	return nil
+}
+
+// Validate checks a peer list without applying it
+func (m *Mesh) Validate(peers []Peer) error {
+	_, err := m.resolve(peers)
+	return err
+}
+
+// resolve validates peers, leaving out this host
+func (m *Mesh) resolve(peers []Peer) ([]target, error) {
+	if len(peers) > maxPeers {
+		return nil, fmt.Errorf("too many peers (%d, max %d)", len(peers), maxPeers)
+	}
+	targets := make([]target, 0, len(peers))
+	for _, p := range peers {
+		if p.HostID == m.hostID {
+			continue
+		}
+		addr, err := m.parseAddress(p.Address)
+		if err != nil {
+			return nil, fmt.Errorf("peer %s: %w", p.HostID, err)
+		}
+		targets = append(targets, target{peer: p, addr: addr})
+	}
+	return targets, nil
+}
+
+// parseAddress parses "ip" or "ip:port" and checks it is on the LAN
+func (m *Mesh) parseAddress(s string) (netip.AddrPort, error) {
+	addr, err := netip.ParseAddrPort(s)
+	if err != nil {
+		ip, ipErr := netip.ParseAddr(s)
+		if ipErr != nil {
+			return netip.AddrPort{}, fmt.Errorf("invalid address %q", s)
+		}
+		addr = netip.AddrPortFrom(ip, uint16(m.port))
+	}
+	if !localAddr(addr.Addr()) {
+		return netip.AddrPort{}, fmt.Errorf("address %s is not on the local network", addr.Addr())
+	}
+	return addr, nil
+}
+
+// localAddr reports whether ip is a private, link-local or loopback address
+func localAddr(ip netip.Addr) bool {
+	ip = ip.Unmap()
+	return ip.IsPrivate() || ip.IsLinkLocalUnicast() || ip.IsLoopback()
+}
+
+// Run answers probes and measures the peers every interval until ctx is
+// cancelled. Rounds are skipped while the server hasn't sent any peers.
+func (m *Mesh) Run(ctx context.Context, sender outbound.Sender) {
+	conn, err := net.ListenUDP("udp", &net.UDPAddr{Port: m.port})
+	if err != nil {
+		m.logger.Warn("Peer latency unavailable: can't listen for probes", "port", m.port, "error", err)
+		return
+	}
+	defer conn.Close()
+	go func() {
+		<-ctx.Done()
+		conn.Close()
+	}()
+	go m.listen(conn)
+
+	m.logger.Info("🕸️  Peer latency started", "port", m.port, "interval", m.interval)
+	ticker := time.NewTicker(m.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		m.mu.Lock()
+		targets := m.targets
+		m.mu.Unlock()
+		if len(targets) == 0 {
+			continue
+		}
+		results, err := m.round(ctx, conn, targets)
+		if err != nil {
+			if ctx.Err() == nil {
+				m.logger.Warn("Peer probe round failed", "error", err)
+			}
+			continue
+		}
+		sender.Send("peers", &Report{Type: "peers", TS: time.Now(), HostID: m.hostID, Peers: results})
+	}
+}
+
+// listen answers pings and records pongs until conn is closed. Only
+// well-formed packets from local addresses are answered, and replies are no
+// larger than requests, so the responder can't be used for amplification.
+func (m *Mesh) listen(conn *net.UDPConn) {
+	buf := make([]byte, 64)
+	for {
+		n, from, err := conn.ReadFromUDPAddrPort(buf)
+		if err != nil {
+			if errors.Is(err, net.ErrClosed) {
+				return
+			}
+			continue
+		}
+		if n != packetSize || !bytes.Equal(buf[:4], magic) || !localAddr(from.Addr()) {
+			continue
+		}
+		seq := binary.BigEndian.Uint64(buf[5:packetSize])
+		switch buf[4] {
+		case packetPing:
+			buf[4] = packetPong
+			conn.WriteToUDPAddrPort(buf[:packetSize], from)
+		case packetPong:
+			m.mu.Lock()
+			if p := m.pending[seq]; p != nil && p.rtt == 0 {
+				p.rtt = max(time.Since(p.sent), time.Microsecond)
+			}
+			m.mu.Unlock()
+		}
+	}
+}
+
+// round sends probesPerRound pings to every target, spaced out, waits for
+// the replies and summarizes them per target
+func (m *Mesh) round(ctx context.Context, conn *net.UDPConn, targets []target) ([]Result, error) {
+	m.mu.Lock()
+	m.pending = make(map[uint64]*probe, len(targets)*probesPerRound)
+	m.mu.Unlock()
+
+	packet := make([]byte, packetSize)
+	copy(packet, magic)
+	packet[4] = packetPing
+	for range probesPerRound {
+		for i, t := range targets {
+			m.mu.Lock()
+			m.seq++
+			seq := m.seq
+			m.pending[seq] = &probe{target: i, sent: time.Now()}
+			m.mu.Unlock()
+
+			binary.BigEndian.PutUint64(packet[5:], seq)
+			conn.WriteToUDPAddrPort(packet, t.addr) // A failed send counts as a lost probe
+		}
+		if err := sleep(ctx, probeSpacing); err != nil {
+			return nil, err
+		}
+	}
+	if err := sleep(ctx, replyTimeout); err != nil {
+		return nil, err
+	}
+
+	m.mu.Lock()
+	pending := m.pending
+	m.pending = nil
+	m.mu.Unlock()
+
+	results := make([]Result, len(targets))
+	total := make([]time.Duration, len(targets))
+	answered := make([]int, len(targets))
+	for i, t := range targets {
+		results[i] = Result{HostID: t.peer.HostID, Address: t.addr.String(), Sent: probesPerRound}
+	}
+	for _, p := range pending {
+		if p.rtt == 0 {
+			continue
+		}
+		r := &results[p.target]
+		ms := float64(p.rtt) / float64(time.Millisecond)
+		if answered[p.target] == 0 || ms < r.MinMs {
+			r.MinMs = ms
+		}
+		r.MaxMs = max(r.MaxMs, ms)
+		total[p.target] += p.rtt
+		answered[p.target]++
+	}
+	for i := range results {
+		r := &results[i]
+		r.Loss = float64(r.Sent-answered[i]) / float64(r.Sent) * 100
+		if answered[i] > 0 {
+			r.RTTMs = roundMs(float64(total[i]) / float64(answered[i]) / float64(time.Millisecond))
+			r.MinMs, r.MaxMs = roundMs(r.MinMs), roundMs(r.MaxMs)
+		}
+	}
+	return results, nil
+}
+
+// roundMs rounds a millisecond value to the microsecond
+func roundMs(ms float64) float64 {
+	return math.Round(ms*1000) / 1000
+}
+
+// sleep waits for d or until ctx is cancelled
+func sleep(ctx context.Context, d time.Duration) error {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
+	}
+}
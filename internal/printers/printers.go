@@ -0,0 +1,164 @@
+package printers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jcdorr003/windash-agent/internal/alerts"
+	"github.com/jcdorr003/windash-agent/internal/config"
+	"github.com/jcdorr003/windash-agent/internal/outbound"
+	"go.uber.org/zap"
+)
+
+const (
+	defaultInterval = time.Minute
+	defaultStuck    = 10 * time.Minute
+)
+
+// errUnsupported is returned by listQueues off Windows
+var errUnsupported = errors.New("printer monitoring is only supported on Windows")
+
+// Report is the periodic "printers" message
+type Report struct {
+	Type     string    `json:"type"` // always "printers"
+	TS       time.Time `json:"ts"`
+	HostID   string    `json:"hostId"`
+	Printers []Printer `json:"printers"`
+}
+
+// Printer is one print queue. Document names and owners are never sent.
+type Printer struct {
+	Name    string     `json:"name"`
+	Status  string     `json:"status,omitempty"` // e.g. idle, printing, error
+	Offline bool       `json:"offline,omitempty"`
+	Queued  int        `json:"queued"`          // Jobs in the queue
+	Stuck   []StuckJob `json:"stuck,omitempty"` // Jobs older than the threshold
+}
+
+// StuckJob is a job that has been queued longer than the threshold
+type StuckJob struct {
+	ID     uint32 `json:"id"`
+	AgeSec uint64 `json:"ageSec"`
+	Status string `json:"status,omitempty"`
+	Pages  uint32 `json:"pages,omitempty"`
+}
+
+// queue is what the platform query returns for a printer
+type queue struct {
+	Name    string
+	Status  string
+	Offline bool
+	Jobs    []job
+}
+
+type job struct {
+	ID        uint32
+	Submitted time.Time
+	Status    string
+	Pages     uint32
+}
+
+// Monitor periodically reports print queues and alerts on stuck jobs
+type Monitor struct {
+	logger   *zap.SugaredLogger
+	hostID   string
+	interval time.Duration
+	stuck    time.Duration
+	alert    bool
+	alerted  map[string]bool // Printers with an open stuck-job alert
+	open     *alerts.OpenSet // Shared open-alert state (may be nil)
+}
+
+// NewMonitor creates a printer queue monitor
+func NewMonitor(logger *zap.SugaredLogger, hostID string, cfg config.PrintersConfig) *Monitor {
+	interval := time.Duration(cfg.IntervalSec) * time.Second
+	if interval <= 0 {
+		interval = defaultInterval
+	}
+	stuck := time.Duration(cfg.StuckMinutes) * time.Minute
+	if stuck <= 0 {
+		stuck = defaultStuck
+	}
+	return &Monitor{
+		logger:   logger,
+		hostID:   hostID,
+		interval: interval,
+		stuck:    stuck,
+		alert:    cfg.Alert,
+		alerted:  make(map[string]bool),
+	}
+}
+
+// SetOpenAlerts shares open-alert state with other subsystems. Must be called before Run.
+func (m *Monitor) SetOpenAlerts(open *alerts.OpenSet) {
+	m.open = open
+}
+
+// Run reports on every interval until ctx is cancelled, stopping early if
+// print queues can't be read on this machine
+func (m *Monitor) Run(ctx context.Context, sender outbound.Sender) {
+	m.logger.Info("🖨️  Printer monitor started", "interval", m.interval, "stuckAfter", m.stuck)
+
+	ticker := time.NewTicker(m.interval)
+	defer ticker.Stop()
+
+	for {
+		queues, err := listQueues()
+		switch {
+		case errors.Is(err, errUnsupported):
+			m.logger.Info("Printer monitoring unavailable, stopping", "error", err)
+			return
+		case err != nil:
+			m.logger.Warn("Printer scan failed", "error", err)
+		default:
+			sender.Send("printers", m.report(queues, time.Now(), sender))
+		}
+
+		select {
+		case <-ctx.Done():
+			m.logger.Info("🖨️  Printer monitor stopped")
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// report builds the report, alerting on printers that newly have stuck jobs
+func (m *Monitor) report(queues []queue, now time.Time, sender outbound.Sender) *Report {
+	report := &Report{Type: "printers", TS: now, HostID: m.hostID, Printers: []Printer{}}
+	for _, q := range queues {
+		p := Printer{Name: q.Name, Status: q.Status, Offline: q.Offline, Queued: len(q.Jobs)}
+		for _, j := range q.Jobs {
+			if age := now.Sub(j.Submitted); !j.Submitted.IsZero() && age >= m.stuck {
+				p.Stuck = append(p.Stuck, StuckJob{ID: j.ID, AgeSec: uint64(age.Seconds()), Status: j.Status, Pages: j.Pages})
+			}
+		}
+		report.Printers = append(report.Printers, p)
+		m.checkStuck(p, sender)
+	}
+	return report
+}
+
+// checkStuck raises an alert when a printer first has stuck jobs and clears
+// it once the queue drains
+func (m *Monitor) checkStuck(p Printer, sender outbound.Sender) {
+	stuck := len(p.Stuck) > 0
+	if !m.alert || stuck == m.alerted[p.Name] {
+		return
+	}
+	m.alerted[p.Name] = stuck
+	m.open.Set("printer:"+p.Name, stuck)
+	if !stuck {
+		m.logger.Info("🖨️  Printer queue cleared", "printer", p.Name)
+		return
+	}
+
+	m.logger.Warn("🖨️  Print jobs stuck", "printer", p.Name, "jobs", len(p.Stuck))
+	alert := alerts.New(m.hostID, "printers", alerts.SeverityWarning,
+		fmt.Sprintf("Print jobs stuck on %s", p.Name),
+		fmt.Sprintf("%d job(s) queued for more than %s", len(p.Stuck), m.stuck))
+	alert.Labels = map[string]string{"printer": p.Name}
+	sender.Send("alert", alert)
+}
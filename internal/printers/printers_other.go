@@ -0,0 +1,8 @@
+//go:build !windows
+
+package printers
+
+// listQueues is not implemented outside Windows
+func listQueues() ([]queue, error) {
+	return nil, errUnsupported
+}
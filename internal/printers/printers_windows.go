@@ -0,0 +1,75 @@
+//go:build windows
+
+package printers
+
+import (
+	"strings"
+	"time"
+
+	"github.com/yusufpapurcu/wmi"
+)
+
+type win32Printer struct {
+	Name          string
+	PrinterStatus uint16
+	WorkOffline   bool
+}
+
+type win32PrintJob struct {
+	Name          string // "<printer>, <job id>"
+	JobId         uint32
+	JobStatus     *string
+	TotalPages    uint32
+	TimeSubmitted time.Time
+}
+
+// listQueues reads printers and their jobs through WMI
+func listQueues() ([]queue, error) {
+	var printers []win32Printer
+	if err := wmi.Query("SELECT Name, PrinterStatus, WorkOffline FROM Win32_Printer", &printers); err != nil {
+		return nil, err
+	}
+	var jobs []win32PrintJob
+	if err := wmi.Query("SELECT Name, JobId, JobStatus, TotalPages, TimeSubmitted FROM Win32_PrintJob", &jobs); err != nil {
+		return nil, err
+	}
+
+	byPrinter := make(map[string][]job)
+	for _, j := range jobs {
+		printer, _, _ := strings.Cut(j.Name, ",")
+		entry := job{ID: j.JobId, Submitted: j.TimeSubmitted, Pages: j.TotalPages}
+		if j.JobStatus != nil {
+			entry.Status = strings.ToLower(*j.JobStatus)
+		}
+		byPrinter[printer] = append(byPrinter[printer], entry)
+	}
+
+	queues := make([]queue, 0, len(printers))
+	for _, p := range printers {
+		queues = append(queues, queue{
+			Name:    p.Name,
+			Status:  printerStatus(p.PrinterStatus),
+			Offline: p.WorkOffline,
+			Jobs:    byPrinter[p.Name],
+		})
+	}
+	return queues, nil
+}
+
+// printerStatus maps Win32_Printer.PrinterStatus
+func printerStatus(v uint16) string {
+	switch v {
+	case 3:
+		return "idle"
+	case 4:
+		return "printing"
+	case 5:
+		return "warmingUp"
+	case 6:
+		return "stopped"
+	case 7:
+		return "offline"
+	default:
+		return ""
+	}
+}
@@ -0,0 +1,56 @@
+package security
+
+import (
+	"errors"
+	"time"
+)
+
+// antivirusInterval is how often the protection status is sent; it changes
+// far less often than sessions do
+const antivirusInterval = 15 * time.Minute
+
+// errAntivirusUnsupported is returned by readAntivirus off Windows
+var errAntivirusUnsupported = errors.New("antivirus status is only supported on Windows")
+
+// StatusReport is the periodic "securityStatus" message saying whether the
+// machine is protected by an antivirus product
+type StatusReport struct {
+	Type      string          `json:"type"` // always "securityStatus"
+	TS        time.Time       `json:"ts"`
+	HostID    string          `json:"hostId"`
+	Protected bool            `json:"protected"` // Some product has real-time protection on
+	Defender  *DefenderStatus `json:"defender,omitempty"`
+	Products  []AVProduct     `json:"products,omitempty"` // Registered with Security Center (client Windows only)
+}
+
+// DefenderStatus is Microsoft Defender Antivirus's own view of its state
+type DefenderStatus struct {
+	Enabled          bool      `json:"enabled"` // Antivirus engine on (off when a third-party product took over)
+	RealTime         bool      `json:"realTime"`
+	SignatureVersion string    `json:"signatureVersion,omitempty"`
+	SignatureUpdated time.Time `json:"signatureUpdated,omitzero"`
+	SignatureAgeDays int       `json:"signatureAgeDays"`
+	LastQuickScan    time.Time `json:"lastQuickScan,omitzero"` // Zero if never run
+	LastFullScan     time.Time `json:"lastFullScan,omitzero"`
+}
+
+// AVProduct is an antivirus product registered with Windows Security Center
+type AVProduct struct {
+	Name     string `json:"name"`
+	Enabled  bool   `json:"enabled"`  // Real-time scanning on
+	UpToDate bool   `json:"upToDate"` // Signatures current
+}
+
+// scanAntivirus reads the protection status
+func (m *Monitor) scanAntivirus(now time.Time) (*StatusReport, error) {
+	defender, products, err := readAntivirus()
+	if err != nil {
+		return nil, err
+	}
+	report := &StatusReport{Type: "securityStatus", TS: now, HostID: m.hostID, Defender: defender, Products: products}
+	report.Protected = defender != nil && defender.Enabled && defender.RealTime
+	for _, p := range products {
+		report.Protected = report.Protected || p.Enabled
+	}
+	return report, nil
+}
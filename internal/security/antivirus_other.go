@@ -0,0 +1,8 @@
+//go:build !windows
+
+package security
+
+// readAntivirus is not implemented outside Windows
+func readAntivirus() (*DefenderStatus, []AVProduct, error) {
+	return nil, nil, errAntivirusUnsupported
+}
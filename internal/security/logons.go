@@ -0,0 +1,74 @@
+package security
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jcdorr003/windash-agent/internal/alerts"
+	"github.com/jcdorr003/windash-agent/internal/outbound"
+)
+
+const defaultLogonBurst = 10
+
+var (
+	// errLogonsUnsupported is returned by countFailedLogons off Windows
+	errLogonsUnsupported = errors.New("failed logon counting is only supported on Windows")
+
+	// errLogonsDenied is returned when the Security log can't be read
+	errLogonsDenied = errors.New("reading the Security event log requires running elevated (or membership in Event Log Readers)")
+)
+
+// LogonReport is the per-interval "logons" message
+type LogonReport struct {
+	Type        string    `json:"type"` // always "logons"
+	TS          time.Time `json:"ts"`
+	HostID      string    `json:"hostId"`
+	WindowStart time.Time `json:"windowStart"`
+	Failed      int       `json:"failed"` // Event 4625 occurrences in [windowStart, ts)
+}
+
+// isFatal reports whether err means a check can never succeed on this machine
+func isFatal(err error) bool {
+	return errors.Is(err, errSessionsUnsupported) ||
+		errors.Is(err, errUsersUnsupported) ||
+		errors.Is(err, errAntivirusUnsupported) ||
+		errors.Is(err, errLogonsUnsupported) ||
+		errors.Is(err, errLogonsDenied)
+}
+
+// scanLogons counts failed logons since the previous scan and alerts when
+// the count reaches the burst threshold. The first scan only sets the
+// window start and returns no report.
+func (m *Monitor) scanLogons(now time.Time, sender outbound.Sender) (*LogonReport, error) {
+	if m.lastLogonTS.IsZero() {
+		// Probe once so a missing privilege is reported at startup
+		if _, err := countFailedLogons(now, now); err != nil {
+			return nil, err
+		}
+		m.lastLogonTS = now
+		return nil, nil
+	}
+
+	count, err := countFailedLogons(m.lastLogonTS, now)
+	if err != nil {
+		return nil, err
+	}
+	report := &LogonReport{Type: "logons", TS: now, HostID: m.hostID, WindowStart: m.lastLogonTS, Failed: count}
+	m.lastLogonTS = now
+
+	switch {
+	case count >= m.logonBurst && !m.logonAlerted:
+		m.logger.Warn("⚠️  Burst of failed logons", "count", count, "window", m.interval)
+		sender.Send("alert", alerts.New(m.hostID, "logons", alerts.SeverityWarning,
+			"Burst of failed logons",
+			fmt.Sprintf("%d failed logon attempts in the last %s", count, m.interval)))
+		m.logonAlerted = true
+		m.open.Set("logons", true)
+	case count < m.logonBurst:
+		m.logonAlerted = false
+		m.open.Set("logons", false)
+	}
+
+	return report, nil
+}
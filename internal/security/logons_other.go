@@ -0,0 +1,10 @@
+//go:build !windows
+
+package security
+
+import "time"
+
+// countFailedLogons is not implemented outside Windows
+func countFailedLogons(from, to time.Time) (int, error) {
+	return 0, errLogonsUnsupported
+}
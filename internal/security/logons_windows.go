@@ -0,0 +1,75 @@
+//go:build windows
+
+package security
+
+import (
+	"errors"
+	"fmt"
+	"time"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+)
+
+var (
+	modwevtapi   = windows.NewLazySystemDLL("wevtapi.dll")
+	procEvtQuery = modwevtapi.NewProc("EvtQuery")
+	procEvtNext  = modwevtapi.NewProc("EvtNext")
+	procEvtClose = modwevtapi.NewProc("EvtClose")
+)
+
+const (
+	evtQueryChannelPath      = 0x1
+	evtQueryForwardDirection = 0x100
+
+	evtBatchSize   = 64
+	evtNextTimeout = 1000 // ms
+
+	eventFailedLogon = 4625
+)
+
+// countFailedLogons counts Security log event 4625 entries in [from, to)
+func countFailedLogons(from, to time.Time) (int, error) {
+	const layout = "2006-01-02T15:04:05.000Z"
+	query := fmt.Sprintf("*[System[EventID=%d and TimeCreated[@SystemTime>='%s' and @SystemTime<'%s']]]",
+		eventFailedLogon, from.UTC().Format(layout), to.UTC().Format(layout))
+
+	channel, err := windows.UTF16PtrFromString("Security")
+	if err != nil {
+		return 0, err
+	}
+	xpath, err := windows.UTF16PtrFromString(query)
+	if err != nil {
+		return 0, err
+	}
+
+	h, _, err := procEvtQuery.Call(0,
+		uintptr(unsafe.Pointer(channel)), uintptr(unsafe.Pointer(xpath)),
+		evtQueryChannelPath|evtQueryForwardDirection)
+	if h == 0 {
+		if errors.Is(err, windows.ERROR_ACCESS_DENIED) {
+			return 0, errLogonsDenied
+		}
+		return 0, fmt.Errorf("query Security log: %w", err)
+	}
+	defer procEvtClose.Call(h)
+
+	count := 0
+	events := make([]uintptr, evtBatchSize)
+	for {
+		var returned uint32
+		ok, _, err := procEvtNext.Call(h, evtBatchSize,
+			uintptr(unsafe.Pointer(&events[0])), evtNextTimeout, 0,
+			uintptr(unsafe.Pointer(&returned)))
+		if ok == 0 {
+			if errors.Is(err, windows.ERROR_NO_MORE_ITEMS) {
+				return count, nil
+			}
+			return count, fmt.Errorf("read Security log: %w", err)
+		}
+		for _, e := range events[:returned] {
+			procEvtClose.Call(e)
+		}
+		count += int(returned)
+	}
+}
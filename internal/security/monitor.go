@@ -0,0 +1,154 @@
+package security
+
+import (
+	"context"
+	"time"
+
+	"github.com/jcdorr003/windash-agent/internal/alerts"
+	"github.com/jcdorr003/windash-agent/internal/config"
+	"github.com/jcdorr003/windash-agent/internal/outbound"
+	"go.uber.org/zap"
+)
+
+const defaultInterval = time.Minute
+
+// Monitor periodically runs the enabled security checks and reports them
+type Monitor struct {
+	logger   *zap.SugaredLogger
+	hostID   string
+	interval time.Duration
+
+	// Remote sessions
+	sessions       bool
+	known          map[sessionKey]struct{}
+	sessionsPrimed bool // First scan establishes the baseline and raises no alerts
+
+	// Signed-in users
+	users bool
+
+	// Antivirus status
+	antivirus     bool
+	nextAntivirus time.Time
+
+	// Failed logons
+	logons       bool
+	logonBurst   int
+	lastLogonTS  time.Time // End of the previous counting window
+	logonAlerted bool      // A burst alert is open until a quiet interval
+
+	open *alerts.OpenSet // Shared open-alert state (may be nil)
+}
+
+// NewMonitor creates a monitor for the checks enabled in cfg
+func NewMonitor(logger *zap.SugaredLogger, hostID string, cfg config.SecurityConfig) *Monitor {
+	interval := time.Duration(cfg.IntervalSec) * time.Second
+	if interval <= 0 {
+		interval = defaultInterval
+	}
+	burst := cfg.FailedLogonBurst
+	if burst <= 0 {
+		burst = defaultLogonBurst
+	}
+	return &Monitor{
+		logger:     logger,
+		hostID:     hostID,
+		interval:   interval,
+		sessions:   cfg.RemoteSessions,
+		users:      cfg.UserSessions,
+		antivirus:  cfg.Antivirus,
+		known:      make(map[sessionKey]struct{}),
+		logons:     cfg.FailedLogons,
+		logonBurst: burst,
+	}
+}
+
+// SetOpenAlerts shares open-alert state with other subsystems. Must be called before Run.
+func (m *Monitor) SetOpenAlerts(open *alerts.OpenSet) {
+	m.open = open
+}
+
+// Run scans on every interval and sends reports until ctx is cancelled.
+// Checks that are unsupported or not permitted on this machine are
+// disabled after the first failure.
+func (m *Monitor) Run(ctx context.Context, sender outbound.Sender) {
+	m.logger.Info("🛡️  Security monitor started", "remoteSessions", m.sessions, "userSessions", m.users, "antivirus", m.antivirus, "failedLogons", m.logons, "interval", m.interval)
+
+	ticker := time.NewTicker(m.interval)
+	defer ticker.Stop()
+
+	for {
+		m.scan(sender)
+		if !m.sessions && !m.users && !m.antivirus && !m.logons {
+			m.logger.Warn("Security monitor has no usable checks, stopping")
+			return
+		}
+
+		select {
+		case <-ctx.Done():
+			m.logger.Info("🛡️  Security monitor stopped")
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// scan runs each enabled check once
+func (m *Monitor) scan(sender outbound.Sender) {
+	now := time.Now()
+
+	if m.sessions {
+		report, err := m.scanSessions(now, sender)
+		switch {
+		case isFatal(err):
+			m.logger.Warn("Remote session reporting disabled", "error", err)
+			m.sessions = false
+		case err != nil:
+			m.logger.Warn("Remote session scan failed", "error", err)
+		default:
+			sender.Send("sessions", report)
+		}
+	}
+
+	if m.users {
+		report, err := m.scanUsers(now)
+		switch {
+		case isFatal(err):
+			m.logger.Warn("User session reporting disabled", "error", err)
+			m.users = false
+		case err != nil:
+			m.logger.Warn("User session scan failed", "error", err)
+		default:
+			sender.Send("users", report)
+		}
+	}
+
+	if m.antivirus && !now.Before(m.nextAntivirus) {
+		m.nextAntivirus = now.Add(antivirusInterval)
+		report, err := m.scanAntivirus(now)
+		switch {
+		case isFatal(err):
+			m.logger.Warn("Antivirus status reporting disabled", "error", err)
+			m.antivirus = false
+		case err != nil:
+			m.logger.Warn("Antivirus status scan failed", "error", err)
+		default:
+			if !report.Protected {
+				m.logger.Warn("⚠️  No antivirus real-time protection is on")
+			}
+			sender.Send("securityStatus", report)
+		}
+	}
+
+	if m.logons {
+		report, err := m.scanLogons(now, sender)
+		switch {
+		case isFatal(err):
+			m.logger.Warn("Failed logon counting disabled", "error", err)
+			m.logons = false
+		case err != nil:
+			m.logger.Warn("Failed logon scan failed", "error", err)
+		case report != nil:
+			sender.Send("logons", report)
+		}
+	}
+}
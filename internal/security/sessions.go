@@ -0,0 +1,95 @@
+package security
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jcdorr003/windash-agent/internal/alerts"
+	"github.com/jcdorr003/windash-agent/internal/outbound"
+)
+
+// errSessionsUnsupported is returned by listRemoteSessions off Windows
+var errSessionsUnsupported = errors.New("remote session reporting is only supported on Windows")
+
+// SessionReport is the periodic "sessions" message listing remote sessions
+type SessionReport struct {
+	Type     string          `json:"type"` // always "sessions"
+	TS       time.Time       `json:"ts"`
+	HostID   string          `json:"hostId"`
+	Count    int             `json:"count"`
+	Sessions []RemoteSession `json:"sessions,omitempty"`
+}
+
+// RemoteSession describes one RDP session. The client address is never
+// sent in the clear; ClientHash lets the dashboard tell clients apart.
+type RemoteSession struct {
+	ID          uint32    `json:"id"`
+	State       string    `json:"state"` // active or disconnected
+	ClientHash  string    `json:"clientHash,omitempty"`
+	LogonTime   time.Time `json:"logonTime,omitzero"`
+	DurationSec uint64    `json:"durationSec"`
+}
+
+// sessionInfo is what the platform query returns for a session
+type sessionInfo struct {
+	ID         uint32
+	State      string
+	ClientAddr string
+	LogonTime  time.Time
+}
+
+// sessionKey identifies a session across scans (session IDs get reused)
+type sessionKey struct {
+	id    uint32
+	logon int64
+}
+
+// scanSessions lists remote sessions, alerting on any not seen before
+func (m *Monitor) scanSessions(now time.Time, sender outbound.Sender) (*SessionReport, error) {
+	infos, err := listRemoteSessions()
+	if err != nil {
+		return nil, err
+	}
+
+	report := &SessionReport{Type: "sessions", TS: now, HostID: m.hostID, Count: len(infos)}
+
+	current := make(map[sessionKey]struct{}, len(infos))
+	for _, info := range infos {
+		session := RemoteSession{
+			ID:        info.ID,
+			State:     info.State,
+			LogonTime: info.LogonTime,
+		}
+		if info.ClientAddr != "" {
+			session.ClientHash = m.hashAddress(info.ClientAddr)
+		}
+		if !info.LogonTime.IsZero() && now.After(info.LogonTime) {
+			session.DurationSec = uint64(now.Sub(info.LogonTime).Seconds())
+		}
+		report.Sessions = append(report.Sessions, session)
+
+		key := sessionKey{id: info.ID, logon: info.LogonTime.UnixMilli()}
+		current[key] = struct{}{}
+		if _, seen := m.known[key]; !seen && m.sessionsPrimed {
+			m.logger.Info("🛡️  New remote login", "session", info.ID, "client", session.ClientHash)
+			alert := alerts.New(m.hostID, "sessions", alerts.SeverityInfo,
+				"New remote login", fmt.Sprintf("RDP session %d started", info.ID))
+			alert.Labels = map[string]string{"clientHash": session.ClientHash}
+			sender.Send("alert", alert)
+		}
+	}
+	m.known = current
+	m.sessionsPrimed = true
+
+	return report, nil
+}
+
+// hashAddress returns a short, host-salted hash of a client address so the
+// same client is recognizable on this host without revealing its IP
+func (m *Monitor) hashAddress(addr string) string {
+	sum := sha256.Sum256([]byte(m.hostID + "|" + addr))
+	return hex.EncodeToString(sum[:8])
+}
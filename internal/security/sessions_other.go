@@ -0,0 +1,8 @@
+//go:build !windows
+
+package security
+
+// listRemoteSessions is not implemented outside Windows
+func listRemoteSessions() ([]sessionInfo, error) {
+	return nil, errSessionsUnsupported
+}
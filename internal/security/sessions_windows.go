@@ -0,0 +1,133 @@
+//go:build windows
+
+package security
+
+import (
+	"fmt"
+	"net"
+	"time"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+)
+
+var (
+	modwtsapi32                     = windows.NewLazySystemDLL("wtsapi32.dll")
+	procWTSQuerySessionInformationW = modwtsapi32.NewProc("WTSQuerySessionInformationW")
+)
+
+// WTS_INFO_CLASS values used below
+const (
+	wtsClientAddress      = 14
+	wtsClientProtocolType = 16
+	wtsSessionInfo        = 24
+
+	wtsProtocolRDP = 2
+)
+
+// wtsClientAddr mirrors WTS_CLIENT_ADDRESS
+type wtsClientAddr struct {
+	AddressFamily uint32
+	Address       [20]byte
+}
+
+// wtsInfo mirrors WTSINFOW. The explicit padding keeps the LARGE_INTEGER
+// fields 8-byte aligned on 386 as well as amd64.
+type wtsInfo struct {
+	State                   uint32
+	SessionID               uint32
+	IncomingBytes           uint32
+	OutgoingBytes           uint32
+	IncomingFrames          uint32
+	OutgoingFrames          uint32
+	IncomingCompressedBytes uint32
+	OutgoingCompressedBytes uint32
+	WinStationName          [32]uint16
+	Domain                  [17]uint16
+	UserName                [21]uint16
+	_                       uint32
+	ConnectTime             int64
+	DisconnectTime          int64
+	LastInputTime           int64
+	LogonTime               int64
+	CurrentTime             int64
+}
+
+// listRemoteSessions enumerates RDP sessions on the local server
+func listRemoteSessions() ([]sessionInfo, error) {
+	var sessions *windows.WTS_SESSION_INFO
+	var count uint32
+	if err := windows.WTSEnumerateSessions(0, 0, 1, &sessions, &count); err != nil {
+		return nil, fmt.Errorf("enumerate sessions: %w", err)
+	}
+	defer windows.WTSFreeMemory(uintptr(unsafe.Pointer(sessions)))
+
+	var out []sessionInfo
+	for _, s := range unsafe.Slice(sessions, count) {
+		if s.State != windows.WTSActive && s.State != windows.WTSDisconnected {
+			continue
+		}
+		if !isRDP(s.SessionID) {
+			continue
+		}
+
+		info := sessionInfo{ID: s.SessionID, State: "active"}
+		if s.State == windows.WTSDisconnected {
+			info.State = "disconnected"
+		}
+		info.ClientAddr = clientAddress(s.SessionID)
+		if buf, err := querySession(s.SessionID, wtsSessionInfo); err == nil {
+			wi := (*wtsInfo)(buf)
+			if wi.LogonTime != 0 {
+				ft := windows.Filetime{LowDateTime: uint32(wi.LogonTime), HighDateTime: uint32(wi.LogonTime >> 32)}
+				info.LogonTime = time.Unix(0, ft.Nanoseconds())
+			}
+			windows.WTSFreeMemory(uintptr(buf))
+		}
+		out = append(out, info)
+	}
+	return out, nil
+}
+
+// isRDP reports whether a session was opened over RDP (not the console)
+func isRDP(id uint32) bool {
+	buf, err := querySession(id, wtsClientProtocolType)
+	if err != nil {
+		return false
+	}
+	defer windows.WTSFreeMemory(uintptr(buf))
+	return *(*uint16)(buf) == wtsProtocolRDP
+}
+
+// clientAddress returns the session's client IP, or "" if unknown
+func clientAddress(id uint32) string {
+	buf, err := querySession(id, wtsClientAddress)
+	if err != nil {
+		return ""
+	}
+	defer windows.WTSFreeMemory(uintptr(buf))
+
+	addr := (*wtsClientAddr)(buf)
+	switch addr.AddressFamily {
+	case windows.AF_INET:
+		// IPv4 bytes start at offset 2 of Address
+		return net.IP(addr.Address[2:6]).String()
+	case windows.AF_INET6:
+		return net.IP(addr.Address[:16]).String()
+	}
+	return ""
+}
+
+// querySession calls WTSQuerySessionInformationW; the caller frees the buffer
+func querySession(id uint32, class uint32) (unsafe.Pointer, error) {
+	var buf *byte
+	var size uint32
+	r, _, err := procWTSQuerySessionInformationW.Call(
+		0, uintptr(id), uintptr(class),
+		uintptr(unsafe.Pointer(&buf)), uintptr(unsafe.Pointer(&size)),
+	)
+	if r == 0 {
+		return nil, err
+	}
+	return unsafe.Pointer(buf), nil
+}
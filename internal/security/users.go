@@ -0,0 +1,57 @@
+package security
+
+import (
+	"errors"
+	"time"
+)
+
+// errUsersUnsupported is returned by listUserSessions where the sessions
+// can never be listed: no login records on this platform, or no permission
+// to read them
+var errUsersUnsupported = errors.New("user session reporting is not supported here")
+
+// UserReport is the periodic "users" message listing who is signed in
+type UserReport struct {
+	Type   string        `json:"type"` // always "users"
+	TS     time.Time     `json:"ts"`
+	HostID string        `json:"hostId"`
+	Count  int           `json:"count"`
+	Users  []UserSession `json:"users,omitempty"`
+}
+
+// UserSession is one interactive session: a user signed in at the console,
+// over RDP or (outside Windows) on a terminal
+type UserSession struct {
+	ID        uint32    `json:"id,omitempty"` // Windows session ID
+	User      string    `json:"user"`         // DOMAIN\name on Windows
+	Type      string    `json:"type"`         // console or rdp; tty or remote outside Windows
+	State     string    `json:"state"`        // active or disconnected
+	Locked    bool      `json:"locked,omitempty"`
+	LogonTime time.Time `json:"logonTime,omitzero"`
+	IdleSec   uint64    `json:"idleSec,omitempty"` // Time since the last keyboard or mouse input, when known
+}
+
+// scanUsers lists the interactive sessions
+func (m *Monitor) scanUsers(now time.Time) (*UserReport, error) {
+	users, err := listUserSessions()
+	if err != nil {
+		return nil, err
+	}
+	return &UserReport{Type: "users", TS: now, HostID: m.hostID, Count: len(users), Users: users}, nil
+}
+
+// LatestLogon returns when the most recent interactive session began, or
+// the zero time if nobody is signed in or it isn't known
+func LatestLogon() (time.Time, error) {
+	users, err := listUserSessions()
+	if err != nil {
+		return time.Time{}, err
+	}
+	var latest time.Time
+	for _, u := range users {
+		if u.LogonTime.After(latest) {
+			latest = u.LogonTime
+		}
+	}
+	return latest, nil
+}
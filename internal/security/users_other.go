@@ -0,0 +1,42 @@
+//go:build !windows
+
+package security
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/shirou/gopsutil/v4/host"
+)
+
+// listUserSessions reads the login records (utmp). Idle time and lock state
+// aren't known here.
+func listUserSessions() ([]UserSession, error) {
+	stats, err := host.Users()
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil // No utmp (containers): nobody has logged in
+	}
+	// gopsutil's "not implemented yet" sentinel is internal, so match its text
+	if errors.Is(err, os.ErrPermission) || (err != nil && err.Error() == "not implemented yet") {
+		return nil, fmt.Errorf("%w: %v", errUsersUnsupported, err)
+	}
+	if err != nil {
+		return nil, err
+	}
+	users := make([]UserSession, 0, len(stats))
+	for _, s := range stats {
+		session := UserSession{User: s.User, Type: "tty", State: "active"}
+		// X displays (":0") are local; any other host is a remote login
+		if s.Host != "" && !strings.HasPrefix(s.Host, ":") {
+			session.Type = "remote"
+		}
+		if s.Started > 0 {
+			session.LogonTime = time.Unix(int64(s.Started), 0)
+		}
+		users = append(users, session)
+	}
+	return users, nil
+}
@@ -0,0 +1,117 @@
+//go:build windows
+
+package security
+
+import (
+	"errors"
+	"fmt"
+	"time"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+)
+
+var (
+	moduser32            = windows.NewLazySystemDLL("user32.dll")
+	procGetLastInputInfo = moduser32.NewProc("GetLastInputInfo")
+)
+
+// More WTS_INFO_CLASS values
+const (
+	wtsUserName      = 5
+	wtsDomainName    = 7
+	wtsSessionInfoEx = 25
+
+	wtsSessionStateLock = 0 // WTSINFOEX_LEVEL1 SessionFlags
+)
+
+// wtsInfoEx mirrors WTSINFOEXW up to the session flags of its level 1 data
+type wtsInfoEx struct {
+	Level        uint32
+	SessionID    uint32
+	SessionState uint32
+	SessionFlags int32
+}
+
+// listUserSessions lists the console and RDP sessions a user is signed in to
+func listUserSessions() ([]UserSession, error) {
+	var sessions *windows.WTS_SESSION_INFO
+	var count uint32
+	if err := windows.WTSEnumerateSessions(0, 0, 1, &sessions, &count); err != nil {
+		if errors.Is(err, windows.ERROR_ACCESS_DENIED) {
+			return nil, fmt.Errorf("%w: enumerate sessions: %v", errUsersUnsupported, err)
+		}
+		return nil, fmt.Errorf("enumerate sessions: %w", err)
+	}
+	defer windows.WTSFreeMemory(uintptr(unsafe.Pointer(sessions)))
+
+	var own uint32
+	_ = windows.ProcessIdToSessionId(windows.GetCurrentProcessId(), &own)
+
+	var users []UserSession
+	for _, s := range unsafe.Slice(sessions, count) {
+		if s.State != windows.WTSActive && s.State != windows.WTSDisconnected {
+			continue
+		}
+		name := sessionString(s.SessionID, wtsUserName)
+		if name == "" {
+			continue // Services and the RDP listener
+		}
+		if domain := sessionString(s.SessionID, wtsDomainName); domain != "" {
+			name = domain + `\` + name
+		}
+
+		session := UserSession{ID: s.SessionID, User: name, Type: "console", State: "active"}
+		if s.State == windows.WTSDisconnected {
+			session.State = "disconnected"
+		}
+		if isRDP(s.SessionID) {
+			session.Type = "rdp"
+		}
+		if buf, err := querySession(s.SessionID, wtsSessionInfoEx); err == nil {
+			ex := (*wtsInfoEx)(buf)
+			session.Locked = ex.Level == 1 && ex.SessionFlags == wtsSessionStateLock
+			windows.WTSFreeMemory(uintptr(buf))
+		}
+		if buf, err := querySession(s.SessionID, wtsSessionInfo); err == nil {
+			wi := (*wtsInfo)(buf)
+			if wi.LogonTime != 0 {
+				ft := windows.Filetime{LowDateTime: uint32(wi.LogonTime), HighDateTime: uint32(wi.LogonTime >> 32)}
+				session.LogonTime = time.Unix(0, ft.Nanoseconds())
+			}
+			// LastInputTime is only filled in for remote sessions
+			if wi.LastInputTime != 0 && wi.CurrentTime > wi.LastInputTime {
+				session.IdleSec = uint64((wi.CurrentTime - wi.LastInputTime) / 1e7)
+			}
+			windows.WTSFreeMemory(uintptr(buf))
+		}
+		if session.IdleSec == 0 && s.SessionID == own {
+			session.IdleSec = ownIdleSec()
+		}
+		users = append(users, session)
+	}
+	return users, nil
+}
+
+// sessionString queries a string WTS_INFO_CLASS, returning "" on failure
+func sessionString(id uint32, class uint32) string {
+	buf, err := querySession(id, class)
+	if err != nil {
+		return ""
+	}
+	defer windows.WTSFreeMemory(uintptr(buf))
+	return windows.UTF16PtrToString((*uint16)(buf))
+}
+
+// ownIdleSec returns the time since the last input in the agent's own
+// session (GetLastInputInfo only sees the caller's session)
+func ownIdleSec() uint64 {
+	info := struct {
+		Size uint32
+		Time uint32 // Tick count of the last input
+	}{Size: 8}
+	if r, _, _ := procGetLastInputInfo.Call(uintptr(unsafe.Pointer(&info))); r == 0 {
+		return 0
+	}
+	return uint64(uint32(windows.DurationSinceBoot().Milliseconds())-info.Time) / 1000
+}
@@ -0,0 +1,287 @@
+package selftest
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"slices"
+	"time"
+
+	"github.com/jcdorr003/windash-agent/internal/config"
+	"github.com/jcdorr003/windash-agent/internal/outbound"
+	"go.uber.org/zap"
+)
+
+const (
+	defaultInterval = 7 * 24 * time.Hour
+	defaultDiskMB   = 64
+
+	// startDelay keeps an overdue run clear of the agent's own startup work
+	startDelay = 10 * time.Minute
+
+	// maxResults is how many runs the history file keeps (a year, weekly)
+	maxResults = 52
+
+	// baselineRuns is how many previous runs a trend compares against
+	baselineRuns = 8
+
+	// slowerThreshold is the change from the baseline, in percent, at which
+	// a trend is flagged as slower
+	slowerThreshold = 20
+)
+
+// Result is one run of the self-tests. A test that failed has no value and
+// its error in Errors.
+type Result struct {
+	TS            time.Time         `json:"ts"`
+	DiskWriteMBps float64           `json:"diskWriteMBps,omitempty"` // Sequential write, flushed to disk (MB = 10^6 bytes)
+	DiskReadMBps  float64           `json:"diskReadMBps,omitempty"`  // Sequential read, bypassing the cache where the OS allows
+	DiskSyncMs    float64           `json:"diskSyncMs,omitempty"`    // Median time to write and flush 4 KiB
+	MemGBps       float64           `json:"memGBps,omitempty"`       // Memory copy bandwidth
+	BackendRTTMs  float64           `json:"backendRttMs,omitempty"`  // Median TCP connect time to the API host
+	Errors        map[string]string `json:"errors,omitempty"`        // Test name → why it failed
+}
+
+// Trend compares one measurement with the median of the previous runs
+type Trend struct {
+	Metric    string  `json:"metric"` // Result field, e.g. diskWriteMBps
+	Value     float64 `json:"value"`
+	Baseline  float64 `json:"baseline"`  // Median of up to 8 previous runs
+	ChangePct float64 `json:"changePct"` // Signed change from the baseline
+	Slower    bool    `json:"slower"`    // Worse than the baseline by 20% or more
+}
+
+// Report is sent as a "selftest" message after every run
+type Report struct {
+	Type   string  `json:"type"` // always "selftest"
+	HostID string  `json:"hostId"`
+	Result Result  `json:"result"`
+	Runs   int     `json:"runs"` // Runs in the local history, including this one
+	Trends []Trend `json:"trends,omitempty"`
+}
+
+// metric describes how a Result field is compared over time
+type metric struct {
+	name         string
+	value        func(r *Result) float64
+	higherBetter bool
+}
+
+var metrics = []metric{
+	{"diskWriteMBps", func(r *Result) float64 { return r.DiskWriteMBps }, true},
+	{"diskReadMBps", func(r *Result) float64 { return r.DiskReadMBps }, true},
+	{"diskSyncMs", func(r *Result) float64 { return r.DiskSyncMs }, false},
+	{"memGBps", func(r *Result) float64 { return r.MemGBps }, true},
+	{"backendRttMs", func(r *Result) float64 { return r.BackendRTTMs }, false},
+}
+
+// Runner runs the self-tests on a schedule and keeps their history
+type Runner struct {
+	logger   *zap.SugaredLogger
+	hostID   string
+	apiURL   string
+	path     string // History file
+	interval time.Duration
+	diskMB   int
+	tempDir  string
+}
+
+// NewRunner creates a runner that keeps its history in path and measures
+// backend latency against apiURL
+func NewRunner(logger *zap.SugaredLogger, hostID, apiURL, path string, cfg config.SelfTestConfig) *Runner {
+	interval := time.Duration(cfg.IntervalHours) * time.Hour
+	if interval <= 0 {
+		interval = defaultInterval
+	}
+	diskMB := cfg.DiskMB
+	if diskMB <= 0 {
+		diskMB = defaultDiskMB
+	}
+	return &Runner{
+		logger:   logger,
+		hostID:   hostID,
+		apiURL:   apiURL,
+		path:     path,
+		interval: interval,
+		diskMB:   diskMB,
+		tempDir:  cfg.Dir,
+	}
+}
+
+// Run runs the tests every interval until ctx is cancelled. The schedule
+// follows the last stored run, so restarts don't cause extra runs.
+func (r *Runner) Run(ctx context.Context, sender outbound.Sender) {
+	next := time.Now().Add(startDelay)
+	if history, err := r.History(); err != nil {
+		r.logger.Warn("Failed to read self-test history", "path", r.path, "error", err)
+	} else if len(history) > 0 {
+		next = history[len(history)-1].TS.Add(r.interval)
+		if earliest := time.Now().Add(startDelay); next.Before(earliest) {
+			next = earliest
+		}
+	}
+
+	for {
+		r.logger.Info("🧪 Next self-test scheduled", "at", next)
+		timer := time.NewTimer(time.Until(next))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+
+		report, err := r.RunOnce(ctx)
+		if err != nil {
+			r.logger.Warn("Self-test failed", "error", err)
+		} else {
+			sender.Send("selftest", report)
+		}
+		next = time.Now().Add(r.interval)
+	}
+}
+
+// RunOnce runs every test now, stores the result and returns it with its
+// trends
+func (r *Runner) RunOnce(ctx context.Context) (*Report, error) {
+	r.logger.Info("🧪 Running self-tests")
+	result := Result{TS: time.Now(), Errors: map[string]string{}}
+
+	if disk, err := diskBenchmark(ctx, r.tempDir, r.diskMB); err != nil {
+		result.Errors["disk"] = err.Error()
+	} else {
+		result.DiskWriteMBps = round(disk.writeMBps)
+		result.DiskReadMBps = round(disk.readMBps)
+		result.DiskSyncMs = round(disk.syncMs)
+	}
+	if gbps, err := memoryBandwidth(ctx); err != nil {
+		result.Errors["memory"] = err.Error()
+	} else {
+		result.MemGBps = round(gbps)
+	}
+	if rtt, err := backendLatency(ctx, r.apiURL); err != nil {
+		result.Errors["backend"] = err.Error()
+	} else {
+		result.BackendRTTMs = round(rtt)
+	}
+	if len(result.Errors) == 0 {
+		result.Errors = nil
+	}
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+
+	history, err := r.History()
+	if err != nil {
+		r.logger.Warn("Self-test history unreadable, starting a new one", "path", r.path, "error", err)
+		history = nil
+	}
+	report := &Report{
+		Type:   "selftest",
+		HostID: r.hostID,
+		Result: result,
+		Runs:   len(history) + 1,
+		Trends: Trends(history, &result),
+	}
+
+	history = append(history, result)
+	if len(history) > maxResults {
+		history = history[len(history)-maxResults:]
+	}
+	if err := r.save(history); err != nil {
+		r.logger.Warn("Failed to save self-test history", "path", r.path, "error", err)
+	}
+
+	for _, t := range report.Trends {
+		if t.Slower {
+			r.logger.Warn("🐢 Self-test slower than usual", "metric", t.Metric, "value", t.Value, "baseline", t.Baseline, "changePct", t.ChangePct)
+		}
+	}
+	r.logger.Info("🧪 Self-tests finished", "failed", len(result.Errors))
+	return report, nil
+}
+
+// History returns the stored results, oldest first
+func (r *Runner) History() ([]Result, error) {
+	data, err := os.ReadFile(r.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var history []Result
+	if err := json.Unmarshal(data, &history); err != nil {
+		return nil, fmt.Errorf("parse %s: %w", r.path, err)
+	}
+	return history, nil
+}
+
+// save replaces the history file
+func (r *Runner) save(history []Result) error {
+	data, err := json.MarshalIndent(history, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := r.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, r.path)
+}
+
+// Trends compares latest with the median of up to baselineRuns previous
+// results, for every measurement both have
+func Trends(previous []Result, latest *Result) []Trend {
+	previous = previous[max(len(previous)-baselineRuns, 0):]
+
+	var trends []Trend
+	for _, m := range metrics {
+		value := m.value(latest)
+		if value == 0 {
+			continue
+		}
+		var values []float64
+		for i := range previous {
+			if v := m.value(&previous[i]); v > 0 {
+				values = append(values, v)
+			}
+		}
+		if len(values) == 0 {
+			continue
+		}
+		baseline := median(values)
+		change := (value - baseline) / baseline * 100
+		slower := change <= -slowerThreshold
+		if !m.higherBetter {
+			slower = change >= slowerThreshold
+		}
+		trends = append(trends, Trend{
+			Metric:    m.name,
+			Value:     value,
+			Baseline:  round(baseline),
+			ChangePct: math.Round(change*10) / 10,
+			Slower:    slower,
+		})
+	}
+	return trends
+}
+
+// median returns the median of values, which must not be empty
+func median(values []float64) float64 {
+	sorted := slices.Clone(values)
+	slices.Sort(sorted)
+	mid := len(sorted) / 2
+	if len(sorted)%2 == 0 {
+		return (sorted[mid-1] + sorted[mid]) / 2
+	}
+	return sorted[mid]
+}
+
+// round keeps two decimals, plenty for benchmark figures
+func round(v float64) float64 {
+	return math.Round(v*100) / 100
+}
@@ -0,0 +1,192 @@
+package selftest
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"os"
+	"time"
+	"unsafe"
+)
+
+const (
+	chunkSize   = 1 << 20 // Disk I/O unit; a multiple of any sector size
+	sectorAlign = 4096    // Buffer alignment for unbuffered reads on Windows
+
+	syncWrites = 32 // Small flushed writes for the sync latency figure
+
+	memBufferSize = 64 << 20 // Larger than any CPU cache
+	memDuration   = 500 * time.Millisecond
+
+	latencyDials   = 5
+	latencyTimeout = 5 * time.Second
+)
+
+// diskResult holds the figures of one disk benchmark
+type diskResult struct {
+	writeMBps float64
+	readMBps  float64
+	syncMs    float64
+}
+
+// diskBenchmark writes sizeMB to a temp file in dir (the system temp
+// directory if empty), flushes it, reads it back and times small flushed
+// writes. The file is removed afterwards.
+func diskBenchmark(ctx context.Context, dir string, sizeMB int) (*diskResult, error) {
+	if dir == "" {
+		dir = os.TempDir()
+	}
+	f, err := os.CreateTemp(dir, "windash-selftest-*.tmp")
+	if err != nil {
+		return nil, err
+	}
+	path := f.Name()
+	defer os.Remove(path)
+	defer f.Close()
+
+	// Random data so compressing or deduplicating storage can't shortcut it
+	buf := alignedBuffer(chunkSize)
+	if _, err := rand.Read(buf); err != nil {
+		return nil, err
+	}
+
+	var res diskResult
+	start := time.Now()
+	for range sizeMB {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		if _, err := f.Write(buf); err != nil {
+			return nil, fmt.Errorf("write: %w", err)
+		}
+	}
+	if err := f.Sync(); err != nil {
+		return nil, fmt.Errorf("flush: %w", err)
+	}
+	res.writeMBps = float64(sizeMB*chunkSize) / 1e6 / time.Since(start).Seconds()
+
+	syncTimes := make([]float64, 0, syncWrites)
+	for range syncWrites {
+		start := time.Now()
+		if _, err := f.WriteAt(buf[:4096], 0); err != nil {
+			return nil, fmt.Errorf("write: %w", err)
+		}
+		if err := f.Sync(); err != nil {
+			return nil, fmt.Errorf("flush: %w", err)
+		}
+		syncTimes = append(syncTimes, float64(time.Since(start).Microseconds())/1000)
+	}
+	res.syncMs = median(syncTimes)
+	if err := f.Close(); err != nil {
+		return nil, err
+	}
+
+	start = time.Now()
+	n, err := readUncached(ctx, path, buf)
+	if err != nil {
+		return nil, fmt.Errorf("read: %w", err)
+	}
+	res.readMBps = float64(n) / 1e6 / time.Since(start).Seconds()
+	return &res, nil
+}
+
+// readUncached reads the file at path through openUncached and returns the
+// bytes read. buf must come from alignedBuffer.
+func readUncached(ctx context.Context, path string, buf []byte) (int64, error) {
+	f, err := openUncached(path)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+
+	var total int64
+	for {
+		if err := ctx.Err(); err != nil {
+			return total, err
+		}
+		n, err := f.Read(buf)
+		total += int64(n)
+		if errors.Is(err, io.EOF) {
+			return total, nil
+		}
+		if err != nil {
+			return total, err
+		}
+	}
+}
+
+// alignedBuffer returns a size-byte slice starting on a sectorAlign boundary
+func alignedBuffer(size int) []byte {
+	raw := make([]byte, size+sectorAlign)
+	off := 0
+	if rem := int(uintptr(unsafe.Pointer(&raw[0])) % sectorAlign); rem != 0 {
+		off = sectorAlign - rem
+	}
+	return raw[off : off+size]
+}
+
+// memoryBandwidth copies between two buffers larger than the CPU caches for
+// a fixed time and returns the copy rate in GB/s
+func memoryBandwidth(ctx context.Context) (float64, error) {
+	src := make([]byte, memBufferSize)
+	dst := make([]byte, memBufferSize)
+	for i := range src {
+		src[i] = byte(i)
+	}
+	copy(dst, src) // Fault in the pages before timing
+
+	var copied int64
+	start := time.Now()
+	for time.Since(start) < memDuration {
+		if err := ctx.Err(); err != nil {
+			return 0, err
+		}
+		copy(dst, src)
+		copied += memBufferSize
+	}
+	return float64(copied) / time.Since(start).Seconds() / 1e9, nil
+}
+
+// backendLatency returns the median TCP connect time in milliseconds to the
+// host of apiURL. Connecting needs no credentials and, unlike an HTTP
+// request, isn't skewed by server-side work.
+func backendLatency(ctx context.Context, apiURL string) (float64, error) {
+	u, err := url.Parse(apiURL)
+	if err != nil {
+		return 0, err
+	}
+	port := u.Port()
+	if port == "" {
+		switch u.Scheme {
+		case "wss", "https":
+			port = "443"
+		case "ws", "http":
+			port = "80"
+		default:
+			return 0, fmt.Errorf("unsupported scheme %q", u.Scheme)
+		}
+	}
+	addr := net.JoinHostPort(u.Hostname(), port)
+
+	dialer := net.Dialer{Timeout: latencyTimeout}
+	var times []float64
+	var lastErr error
+	for range latencyDials {
+		start := time.Now()
+		conn, err := dialer.DialContext(ctx, "tcp", addr)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		times = append(times, float64(time.Since(start).Microseconds())/1000)
+		conn.Close()
+	}
+	if len(times) == 0 {
+		return 0, errors.Join(fmt.Errorf("could not connect to %s", addr), lastErr)
+	}
+	return median(times), nil
+}
@@ -0,0 +1,21 @@
+//go:build linux
+
+package selftest
+
+import (
+	"os"
+
+	"golang.org/x/sys/unix"
+)
+
+// openUncached opens path and drops it from the page cache, so reads come
+// from the disk
+func openUncached(path string) (*os.File, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	// Best effort: the pages were flushed, so they can be evicted
+	_ = unix.Fadvise(int(f.Fd()), 0, 0, unix.FADV_DONTNEED)
+	return f, nil
+}
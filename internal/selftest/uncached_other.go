@@ -0,0 +1,17 @@
+//go:build !windows && !linux
+
+package selftest
+
+import (
+	"os"
+)
+
+// openUncached opens path. There is no portable way to bypass the file
+// cache here, so read figures mostly reflect memory speed.
+func openUncached(path string) (*os.File, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	return f, nil
+}
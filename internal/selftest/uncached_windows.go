@@ -0,0 +1,25 @@
+//go:build windows
+
+package selftest
+
+import (
+	"os"
+
+	"golang.org/x/sys/windows"
+)
+
+// openUncached opens path with FILE_FLAG_NO_BUFFERING so reads come from
+// the disk and not the file cache. Reads must use sector-aligned buffers
+// that are a multiple of the sector size.
+func openUncached(path string) (*os.File, error) {
+	name, err := windows.UTF16PtrFromString(path)
+	if err != nil {
+		return nil, err
+	}
+	h, err := windows.CreateFile(name, windows.GENERIC_READ, windows.FILE_SHARE_READ, nil,
+		windows.OPEN_EXISTING, windows.FILE_FLAG_NO_BUFFERING|windows.FILE_FLAG_SEQUENTIAL_SCAN, 0)
+	if err != nil {
+		return nil, err
+	}
+	return os.NewFile(uintptr(h), path), nil
+}
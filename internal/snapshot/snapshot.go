@@ -0,0 +1,150 @@
+package snapshot
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/jcdorr003/windash-agent/internal/inventory"
+	"github.com/jcdorr003/windash-agent/internal/metrics"
+	"github.com/jcdorr003/windash-agent/internal/outbound"
+	"github.com/jcdorr003/windash-agent/internal/storage"
+	"github.com/shirou/gopsutil/v4/process"
+	"go.uber.org/zap"
+)
+
+// topProcesses is how many processes (by memory) a report includes
+const topProcesses = 25
+
+// Report is a detailed point-in-time snapshot sent as a "report" message
+type Report struct {
+	Type      string               `json:"type"` // always "report"
+	Kind      string               `json:"kind"` // "daily"
+	TS        time.Time            `json:"ts"`
+	HostID    string               `json:"hostId"`
+	Inventory *inventory.Inventory `json:"inventory"`
+	Sample    *metrics.SampleV1    `json:"sample,omitempty"` // Most recent live sample
+	Processes []ProcessInfo        `json:"processes,omitempty"`
+
+	// Storage and drive health (Windows), as in the diskHealth message
+	DiskHealth *storage.Report `json:"diskHealth,omitempty"`
+}
+
+// ProcessInfo is one entry in a report's process list
+type ProcessInfo struct {
+	PID        int32   `json:"pid"`
+	Name       string  `json:"name,omitempty"` // Omitted with privacy.hideProcessNames
+	RSS        uint64  `json:"rss"`            // Resident memory in bytes
+	CPUPercent float64 `json:"cpuPercent"`     // Average since process start
+}
+
+// Scheduler sends a daily report at a fixed local time of day, independent
+// of the live sampling interval (it keeps running while sampling is paused)
+type Scheduler struct {
+	logger *zap.SugaredLogger
+	hostID string
+	hour   int
+	minute int
+	latest func() *metrics.SampleV1
+
+	hideNames bool
+}
+
+// NewScheduler creates a scheduler firing daily at dailyAt ("HH:MM", local
+// time). latest supplies the most recent live sample and may be nil.
+func NewScheduler(logger *zap.SugaredLogger, hostID, dailyAt string, latest func() *metrics.SampleV1) (*Scheduler, error) {
+	t, err := time.Parse("15:04", dailyAt)
+	if err != nil {
+		return nil, fmt.Errorf("invalid daily report time %q (want HH:MM): %w", dailyAt, err)
+	}
+	return &Scheduler{
+		logger: logger,
+		hostID: hostID,
+		hour:   t.Hour(),
+		minute: t.Minute(),
+		latest: latest,
+	}, nil
+}
+
+// HideProcessNames leaves process names out of reports. Must be called before Run.
+func (s *Scheduler) HideProcessNames() {
+	s.hideNames = true
+}
+
+// Run sends a report at every scheduled time until ctx is cancelled
+func (s *Scheduler) Run(ctx context.Context, sender outbound.Sender) {
+	for {
+		next := s.nextRun(time.Now())
+		s.logger.Info("🗓️  Next daily report scheduled", "at", next)
+
+		timer := time.NewTimer(time.Until(next))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+
+		sender.Send("report", s.Build("daily"))
+		s.logger.Info("🗓️  Daily report sent")
+	}
+}
+
+// nextRun returns the first scheduled time strictly after now
+func (s *Scheduler) nextRun(now time.Time) time.Time {
+	next := time.Date(now.Year(), now.Month(), now.Day(), s.hour, s.minute, 0, 0, now.Location())
+	if !next.After(now) {
+		next = next.AddDate(0, 0, 1)
+	}
+	return next
+}
+
+// Build gathers a full report of the given kind
+func (s *Scheduler) Build(kind string) *Report {
+	report := &Report{
+		Type:      "report",
+		Kind:      kind,
+		TS:        time.Now(),
+		HostID:    s.hostID,
+		Inventory: inventory.Collect(),
+		Processes: listProcesses(!s.hideNames),
+	}
+	if s.latest != nil {
+		report.Sample = s.latest()
+	}
+	if health, err := storage.Query(s.hostID); err != nil {
+		s.logger.Debug("Leaving disk health out of the report", "error", err)
+	} else {
+		report.DiskHealth = health
+	}
+	return report
+}
+
+// listProcesses returns the processes using the most memory
+func listProcesses(withNames bool) []ProcessInfo {
+	procs, err := process.Processes()
+	if err != nil {
+		return nil
+	}
+
+	var out []ProcessInfo
+	for _, p := range procs {
+		memInfo, err := p.MemoryInfo()
+		if err != nil {
+			continue
+		}
+		info := ProcessInfo{PID: p.Pid, RSS: memInfo.RSS}
+		if withNames {
+			info.Name, _ = p.Name()
+		}
+		info.CPUPercent, _ = p.CPUPercent()
+		out = append(out, info)
+	}
+
+	sort.Slice(out, func(i, j int) bool { return out[i].RSS > out[j].RSS })
+	if len(out) > topProcesses {
+		out = out[:topProcesses]
+	}
+	return out
+}
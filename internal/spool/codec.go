@@ -0,0 +1,578 @@
+package spool
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"math/bits"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jcdorr003/windash-agent/internal/metrics"
+)
+
+// Segment format. Samples are flattened to their JSON leaves and stored by
+// column, so values that barely change from one sample to the next cost a
+// bit or two each:
+//
+//	magic "WDS1"
+//	uvarint sample count
+//	timestamps: first in Unix ns, then delta-of-deltas (zigzag varints)
+//	uvarint column count, then per column:
+//	  uvarint path length, path
+//	  kind byte (kindAllPresent set when every sample has the leaf)
+//	  presence bitmap, one bit per sample (omitted when all present)
+//	  uvarint data length, data:
+//	    kindFloat: gorilla XOR-compressed float64 values
+//	    kindRaw:   runs of (uvarint repeat count, uvarint length, JSON value)
+const segmentMagic = "WDS1"
+
+const (
+	kindFloat      byte = 1
+	kindRaw        byte = 2
+	kindAllPresent byte = 0x80
+)
+
+var errCorrupt = errors.New("corrupt spool segment")
+
+// column holds one leaf path's values across a segment's samples
+type column struct {
+	path    string
+	kind    byte
+	present []bool
+	floats  []float64
+	raws    [][]byte
+}
+
+// encodeSegment compresses samples into a segment
+func encodeSegment(samples []*metrics.SampleV1) ([]byte, error) {
+	n := len(samples)
+	var columns []*column
+	index := make(map[string]*column)
+
+	for i, s := range samples {
+		data, err := json.Marshal(s)
+		if err != nil {
+			return nil, err
+		}
+		dec := json.NewDecoder(bytes.NewReader(data))
+		dec.UseNumber()
+		var tree map[string]any
+		if err := dec.Decode(&tree); err != nil {
+			return nil, err
+		}
+		delete(tree, "ts") // Stored separately, delta-encoded
+
+		flatten("", tree, func(path string, kind byte, f float64, raw []byte) {
+			key := string(kind) + path
+			col := index[key]
+			if col == nil {
+				col = &column{path: path, kind: kind, present: make([]bool, n)}
+				index[key] = col
+				columns = append(columns, col)
+			}
+			col.present[i] = true
+			if kind == kindFloat {
+				col.floats = append(col.floats, f)
+			} else {
+				col.raws = append(col.raws, raw)
+			}
+		})
+	}
+
+	out := []byte(segmentMagic)
+	out = binary.AppendUvarint(out, uint64(n))
+
+	var prevTS, prevDelta int64
+	for i, s := range samples {
+		ts := s.TS.UnixNano()
+		if i == 0 {
+			out = binary.AppendVarint(out, ts)
+		} else {
+			delta := ts - prevTS
+			out = binary.AppendVarint(out, delta-prevDelta)
+			prevDelta = delta
+		}
+		prevTS = ts
+	}
+
+	out = binary.AppendUvarint(out, uint64(len(columns)))
+	for _, col := range columns {
+		out = binary.AppendUvarint(out, uint64(len(col.path)))
+		out = append(out, col.path...)
+
+		count := len(col.floats) + len(col.raws)
+		kind := col.kind
+		if count == n {
+			kind |= kindAllPresent
+		}
+		out = append(out, kind)
+		if count != n {
+			out = append(out, packBits(col.present)...)
+		}
+
+		var data []byte
+		if col.kind == kindFloat {
+			data = encodeFloats(col.floats)
+		} else {
+			data = encodeRuns(col.raws)
+		}
+		out = binary.AppendUvarint(out, uint64(len(data)))
+		out = append(out, data...)
+	}
+	return out, nil
+}
+
+// decodeSegment reconstitutes the samples in a segment
+func decodeSegment(data []byte) ([]*metrics.SampleV1, error) {
+	r := &byteReader{data: data}
+	if string(r.next(len(segmentMagic))) != segmentMagic {
+		return nil, fmt.Errorf("%w: bad magic", errCorrupt)
+	}
+	n := int(r.uvarint())
+	if r.err != nil || n > len(data)*8 {
+		return nil, fmt.Errorf("%w: bad sample count", errCorrupt)
+	}
+
+	timestamps := make([]int64, n)
+	var delta int64
+	for i := range timestamps {
+		if i == 0 {
+			timestamps[0] = r.varint()
+			continue
+		}
+		delta += r.varint()
+		timestamps[i] = timestamps[i-1] + delta
+	}
+
+	trees := make([]*node, n)
+	for i := range trees {
+		trees[i] = &node{}
+	}
+
+	columns := int(r.uvarint())
+	for c := 0; c < columns && r.err == nil; c++ {
+		path := string(r.next(int(r.uvarint())))
+		kind := r.byte()
+		present := make([]bool, n)
+		if kind&kindAllPresent != 0 {
+			for i := range present {
+				present[i] = true
+			}
+		} else {
+			present = unpackBits(r.next((n+7)/8), n)
+		}
+		count := 0
+		for _, p := range present {
+			if p {
+				count++
+			}
+		}
+		payload := r.next(int(r.uvarint()))
+		if r.err != nil {
+			break
+		}
+
+		values, err := decodeColumn(kind&^kindAllPresent, payload, count)
+		if err != nil {
+			return nil, fmt.Errorf("%w: column %q: %v", errCorrupt, path, err)
+		}
+		for i, v := 0, 0; i < n; i++ {
+			if present[i] {
+				if err := trees[i].set(path, values[v]); err != nil {
+					return nil, fmt.Errorf("%w: %v", errCorrupt, err)
+				}
+				v++
+			}
+		}
+	}
+	if r.err != nil {
+		return nil, fmt.Errorf("%w: %v", errCorrupt, r.err)
+	}
+
+	samples := make([]*metrics.SampleV1, n)
+	for i, tree := range trees {
+		data, err := json.Marshal(tree)
+		if err != nil {
+			return nil, err
+		}
+		var s metrics.SampleV1
+		if err := json.Unmarshal(data, &s); err != nil {
+			return nil, fmt.Errorf("%w: %v", errCorrupt, err)
+		}
+		s.TS = time.Unix(0, timestamps[i])
+		samples[i] = &s
+	}
+	return samples, nil
+}
+
+// decodeColumn returns a column's count values as JSON
+func decodeColumn(kind byte, payload []byte, count int) ([]json.RawMessage, error) {
+	values := make([]json.RawMessage, 0, count)
+	switch kind {
+	case kindFloat:
+		floats, err := decodeFloats(payload, count)
+		if err != nil {
+			return nil, err
+		}
+		for _, f := range floats {
+			data, err := json.Marshal(f)
+			if err != nil {
+				return nil, err
+			}
+			values = append(values, data)
+		}
+	case kindRaw:
+		r := &byteReader{data: payload}
+		for len(values) < count && r.err == nil {
+			repeat := int(r.uvarint())
+			value := r.next(int(r.uvarint()))
+			for range min(repeat, count-len(values)) {
+				values = append(values, json.RawMessage(value))
+			}
+		}
+		if r.err != nil {
+			return nil, r.err
+		}
+	default:
+		return nil, fmt.Errorf("unknown column kind %d", kind)
+	}
+	if len(values) != count {
+		return nil, fmt.Errorf("expected %d values, got %d", count, len(values))
+	}
+	return values, nil
+}
+
+// flatten calls leaf for every leaf of a decoded JSON value. Numbers that
+// survive a float64 round trip are stored as floats, everything else
+// (strings, bools, null, empty objects and arrays, huge integers) as raw JSON.
+func flatten(path string, v any, leaf func(path string, kind byte, f float64, raw []byte)) {
+	switch v := v.(type) {
+	case map[string]any:
+		if len(v) == 0 {
+			leaf(path, kindRaw, 0, []byte("{}"))
+			return
+		}
+		keys := make([]string, 0, len(v))
+		for k := range v {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			flatten(path+"."+escapeKey(k), v[k], leaf)
+		}
+	case []any:
+		if len(v) == 0 {
+			leaf(path, kindRaw, 0, []byte("[]"))
+			return
+		}
+		for i, item := range v {
+			flatten(path+"["+strconv.Itoa(i)+"]", item, leaf)
+		}
+	case json.Number:
+		if f, err := v.Float64(); err == nil {
+			if data, err := json.Marshal(f); err == nil && string(data) == v.String() {
+				leaf(path, kindFloat, f, nil)
+				return
+			}
+		}
+		leaf(path, kindRaw, 0, []byte(v))
+	default:
+		data, _ := json.Marshal(v)
+		leaf(path, kindRaw, 0, data)
+	}
+}
+
+// escapeKey escapes the path separators in an object key
+func escapeKey(k string) string {
+	if !strings.ContainsAny(k, `.[\`) {
+		return k
+	}
+	var b strings.Builder
+	for _, r := range k {
+		if r == '.' || r == '[' || r == '\\' {
+			b.WriteByte('\\')
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// node rebuilds a JSON value from flattened leaves
+type node struct {
+	obj  map[string]*node
+	arr  []*node
+	leaf json.RawMessage
+}
+
+// set stores value at path, creating intermediate objects and arrays
+func (n *node) set(path string, value json.RawMessage) error {
+	if path == "" {
+		n.leaf = value
+		return nil
+	}
+	switch path[0] {
+	case '.':
+		var key strings.Builder
+		i := 1
+		for ; i < len(path) && path[i] != '.' && path[i] != '['; i++ {
+			if path[i] == '\\' && i+1 < len(path) {
+				i++
+			}
+			key.WriteByte(path[i])
+		}
+		if n.obj == nil {
+			n.obj = make(map[string]*node)
+		}
+		child := n.obj[key.String()]
+		if child == nil {
+			child = &node{}
+			n.obj[key.String()] = child
+		}
+		return child.set(path[i:], value)
+	case '[':
+		end := strings.IndexByte(path, ']')
+		if end < 0 {
+			return fmt.Errorf("bad path %q", path)
+		}
+		idx, err := strconv.Atoi(path[1:end])
+		if err != nil || idx < 0 || idx > 1<<16 {
+			return fmt.Errorf("bad index in path %q", path)
+		}
+		for len(n.arr) <= idx {
+			n.arr = append(n.arr, &node{})
+		}
+		return n.arr[idx].set(path[end+1:], value)
+	default:
+		return fmt.Errorf("bad path %q", path)
+	}
+}
+
+// MarshalJSON writes the rebuilt value
+func (n *node) MarshalJSON() ([]byte, error) {
+	switch {
+	case n.obj != nil:
+		return json.Marshal(n.obj)
+	case n.arr != nil:
+		return json.Marshal(n.arr)
+	case n.leaf != nil:
+		return n.leaf, nil
+	default:
+		return []byte("null"), nil
+	}
+}
+
+// encodeRuns stores raw values as runs of identical values
+func encodeRuns(values [][]byte) []byte {
+	var out []byte
+	for i := 0; i < len(values); {
+		j := i + 1
+		for j < len(values) && bytes.Equal(values[j], values[i]) {
+			j++
+		}
+		out = binary.AppendUvarint(out, uint64(j-i))
+		out = binary.AppendUvarint(out, uint64(len(values[i])))
+		out = append(out, values[i]...)
+		i = j
+	}
+	return out
+}
+
+// encodeFloats compresses values with Gorilla's XOR scheme: a repeated
+// value costs one bit, and a changed one only its meaningful bits
+func encodeFloats(values []float64) []byte {
+	w := &bitWriter{}
+	var prev uint64
+	var lead, trail int
+	window := false
+
+	for i, v := range values {
+		cur := math.Float64bits(v)
+		if i == 0 {
+			w.writeBits(cur, 64)
+			prev = cur
+			continue
+		}
+		xor := cur ^ prev
+		prev = cur
+		if xor == 0 {
+			w.writeBit(false)
+			continue
+		}
+		w.writeBit(true)
+
+		l, t := min(bits.LeadingZeros64(xor), 31), bits.TrailingZeros64(xor)
+		if window && l >= lead && t >= trail {
+			// Fits the previous meaningful-bit window
+			w.writeBit(false)
+			w.writeBits(xor>>trail, 64-lead-trail)
+			continue
+		}
+		lead, trail, window = l, t, true
+		sig := 64 - l - t
+		w.writeBit(true)
+		w.writeBits(uint64(l), 5)
+		w.writeBits(uint64(sig-1), 6)
+		w.writeBits(xor>>t, sig)
+	}
+	return w.buf
+}
+
+// decodeFloats reverses encodeFloats
+func decodeFloats(data []byte, count int) ([]float64, error) {
+	r := &bitReader{data: data}
+	values := make([]float64, 0, count)
+	var prev uint64
+	var lead, trail int
+
+	for i := 0; i < count; i++ {
+		if i == 0 {
+			prev = r.readBits(64)
+		} else if r.readBit() {
+			if r.readBit() {
+				lead = int(r.readBits(5))
+				sig := int(r.readBits(6)) + 1
+				trail = 64 - lead - sig
+				if trail < 0 {
+					return nil, errors.New("bad float window")
+				}
+			}
+			prev ^= r.readBits(64-lead-trail) << trail
+		}
+		if r.err != nil {
+			return nil, r.err
+		}
+		values = append(values, math.Float64frombits(prev))
+	}
+	return values, nil
+}
+
+// bitWriter appends bits most significant first
+type bitWriter struct {
+	buf  []byte
+	used uint // Bits used in the last byte (8 = full)
+}
+
+func (w *bitWriter) writeBit(bit bool) {
+	if w.used == 0 || w.used == 8 {
+		w.buf = append(w.buf, 0)
+		w.used = 0
+	}
+	if bit {
+		w.buf[len(w.buf)-1] |= 0x80 >> w.used
+	}
+	w.used++
+}
+
+func (w *bitWriter) writeBits(v uint64, n int) {
+	for i := n - 1; i >= 0; i-- {
+		w.writeBit(v>>uint(i)&1 == 1)
+	}
+}
+
+// bitReader reads bits written by bitWriter
+type bitReader struct {
+	data []byte
+	pos  uint // Bit position
+	err  error
+}
+
+func (r *bitReader) readBit() bool {
+	if r.pos >= uint(len(r.data))*8 {
+		r.err = errors.New("unexpected end of float data")
+		return false
+	}
+	bit := r.data[r.pos/8]&(0x80>>(r.pos%8)) != 0
+	r.pos++
+	return bit
+}
+
+func (r *bitReader) readBits(n int) uint64 {
+	var v uint64
+	for range n {
+		v <<= 1
+		if r.readBit() {
+			v |= 1
+		}
+	}
+	return v
+}
+
+// byteReader reads a segment, remembering the first error
+type byteReader struct {
+	data []byte
+	err  error
+}
+
+func (r *byteReader) next(n int) []byte {
+	if r.err != nil {
+		return nil
+	}
+	if n < 0 || n > len(r.data) {
+		r.err = errors.New("unexpected end of segment")
+		return nil
+	}
+	b := r.data[:n]
+	r.data = r.data[n:]
+	return b
+}
+
+func (r *byteReader) byte() byte {
+	if b := r.next(1); b != nil {
+		return b[0]
+	}
+	return 0
+}
+
+func (r *byteReader) uvarint() uint64 {
+	if r.err != nil {
+		return 0
+	}
+	v, n := binary.Uvarint(r.data)
+	if n <= 0 {
+		r.err = errors.New("bad varint")
+		return 0
+	}
+	r.data = r.data[n:]
+	return v
+}
+
+func (r *byteReader) varint() int64 {
+	if r.err != nil {
+		return 0
+	}
+	v, n := binary.Varint(r.data)
+	if n <= 0 {
+		r.err = errors.New("bad varint")
+		return 0
+	}
+	r.data = r.data[n:]
+	return v
+}
+
+// packBits packs a presence bitmap, first sample in the high bit
+func packBits(present []bool) []byte {
+	out := make([]byte, (len(present)+7)/8)
+	for i, p := range present {
+		if p {
+			out[i/8] |= 0x80 >> (i % 8)
+		}
+	}
+	return out
+}
+
+// unpackBits reverses packBits
+func unpackBits(data []byte, n int) []bool {
+	present := make([]bool, n)
+	for i := range present {
+		if i/8 < len(data) {
+			present[i] = data[i/8]&(0x80>>(i%8)) != 0
+		}
+	}
+	return present
+}
@@ -0,0 +1,439 @@
+package spool
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jcdorr003/windash-agent/internal/config"
+	"github.com/jcdorr003/windash-agent/internal/metrics"
+	"github.com/jcdorr003/windash-agent/internal/outbound"
+	"github.com/shirou/gopsutil/v4/disk"
+	"go.uber.org/zap"
+)
+
+const (
+	segmentSamples = 150             // Samples per segment (5 minutes at the default interval)
+	segmentAge     = 5 * time.Minute // Pending samples older than this are written even if fewer
+	replayInterval = 5 * time.Second // How often a segment is sent while connected
+	replayTimeout  = time.Minute     // Resend a segment whose backfill was never written
+	segmentExt     = ".wds"
+
+	defaultMaxMB       = 256
+	defaultHistoryDays = 7
+)
+
+// Stats describes the spool for the status message
+type Stats struct {
+	Segments         int    `json:"segments"`
+	Bytes            int64  `json:"bytes"`
+	DiscardedSamples uint64 `json:"discardedSamples,omitempty"` // Spooled samples deleted to respect the size and free-space limits
+	DiscardedSec     int64  `json:"discardedSec,omitempty"`     // Time span of history those samples covered
+}
+
+// Sender delivers replayed samples (implemented by ws.Client)
+type Sender interface {
+	outbound.Sender
+	Connected() bool
+	Queued(msgType string) int
+}
+
+// Backfill is a "backfill" message: samples collected while the agent
+// couldn't send them, oldest first
+type Backfill struct {
+	Type    string              `json:"type"` // always "backfill"
+	Samples []*metrics.SampleV1 `json:"samples"`
+
+	written func() // Deletes the replayed segment once the message is out
+}
+
+// Written is called by the client once the message has been written to
+// the socket
+func (b *Backfill) Written() {
+	if b.written != nil {
+		b.written()
+	}
+}
+
+// Spool keeps samples that couldn't be sent (outages, a full buffer) on disk
+// in compressed segments and replays them once the connection is back
+type Spool struct {
+	logger   *zap.SugaredLogger
+	dir      string
+	maxBytes int64         // Total size of all segments
+	minFree  uint64        // Free space to leave on the spool's volume
+	keep     time.Duration // Delete segments older than this (0 = until replayed)
+
+	mu        sync.Mutex
+	pending   []*metrics.SampleV1 // Not yet written to a segment
+	since     time.Time           // When the first pending sample arrived
+	discarded uint64              // Samples deleted to respect the limits
+	lostSpan  time.Duration       // History those samples covered
+	replaying string              // Segment sent but not yet written, kept until it is
+	sentAt    time.Time           // When replaying was sent
+}
+
+// Open creates the spool directory. Segments left by a previous run are
+// replayed too.
+func Open(logger *zap.SugaredLogger, dir string, cfg config.SpoolConfig) (*Spool, error) {
+	s, err := open(logger, dir, cfg.MaxMB, cfg.MinFreeMB, 0)
+	if err != nil {
+		return nil, err
+	}
+	if segments := s.segments(); len(segments) > 0 {
+		logger.Info("💾 Found spooled samples from a previous run", "segments", len(segments))
+	}
+	return s, nil
+}
+
+// OpenHistory opens the local history store: every sample, kept for
+// keepDays within the same kind of limits as the spool. It is never
+// replayed; feed it with Add and read it with Read.
+func OpenHistory(logger *zap.SugaredLogger, dir string, cfg config.HistoryConfig, minFreeMB int) (*Spool, error) {
+	days := cfg.KeepDays
+	if days <= 0 {
+		days = defaultHistoryDays
+	}
+	return open(logger, dir, cfg.MaxMB, minFreeMB, time.Duration(days)*24*time.Hour)
+}
+
+// open creates a segment store in dir
+func open(logger *zap.SugaredLogger, dir string, maxMB, minFreeMB int, keep time.Duration) (*Spool, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	if maxMB <= 0 {
+		maxMB = defaultMaxMB
+	}
+	return &Spool{
+		logger:   logger,
+		dir:      dir,
+		maxBytes: int64(maxMB) << 20,
+		minFree:  uint64(max(minFreeMB, 0)) << 20,
+		keep:     keep,
+	}, nil
+}
+
+// Add spools a sample. Implements ws.Spool.
+func (s *Spool) Add(sample *metrics.SampleV1) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if len(s.pending) == 0 {
+		s.since = time.Now()
+	}
+	s.pending = append(s.pending, sample)
+	if len(s.pending) >= segmentSamples {
+		s.writeLocked()
+	}
+}
+
+// Close writes pending samples to disk, e.g. on shutdown
+func (s *Spool) Close() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.writeLocked()
+}
+
+// Run replays spooled samples while connected, one segment at a time, and
+// writes out pending samples that have waited too long. sender is nil for
+// the history store, which is only written.
+func (s *Spool) Run(ctx context.Context, sender Sender) {
+	ticker := time.NewTicker(replayInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			s.Close()
+			return
+		case <-ticker.C:
+		}
+
+		s.mu.Lock()
+		if len(s.pending) > 0 && time.Since(s.since) >= segmentAge {
+			s.writeLocked()
+		}
+		s.mu.Unlock()
+
+		// Wait for the previous backfill to be written before sending more
+		if sender != nil && sender.Connected() && sender.Queued("backfill") == 0 {
+			s.replayOne(sender)
+		}
+	}
+}
+
+// replayOne sends the oldest segment, or the pending samples if nothing is
+// on disk. A segment is only deleted once its backfill has been written, so
+// samples survive a connection that drops with the message still queued.
+func (s *Spool) replayOne(sender Sender) {
+	s.mu.Lock()
+	waiting := s.replaying != "" && time.Since(s.sentAt) < replayTimeout
+	s.mu.Unlock()
+	if waiting {
+		return
+	}
+
+	segments := s.segments()
+	if len(segments) == 0 {
+		s.mu.Lock()
+		samples := s.pending
+		s.pending = nil
+		s.mu.Unlock()
+		if len(samples) > 0 {
+			sender.Send("backfill", &Backfill{Type: "backfill", Samples: samples})
+		}
+		return
+	}
+
+	path := segments[0].path
+	data, err := os.ReadFile(path)
+	if err == nil {
+		var samples []*metrics.SampleV1
+		if samples, err = decodeSegment(data); err == nil {
+			s.mu.Lock()
+			s.replaying, s.sentAt = path, time.Now()
+			s.mu.Unlock()
+			sender.Send("backfill", &Backfill{Type: "backfill", Samples: samples, written: func() { s.replayed(path) }})
+			s.logger.Info("📤 Replaying spooled samples", "count", len(samples), "remainingSegments", len(segments)-1)
+			return
+		}
+	}
+	s.logger.Warn("Discarding unreadable spool segment", "file", path, "error", err)
+	if err := os.Remove(path); err != nil {
+		s.logger.Warn("Failed to remove spool segment", "file", path, "error", err)
+	}
+}
+
+// replayed deletes a segment whose backfill has been written
+func (s *Spool) replayed(path string) {
+	s.mu.Lock()
+	if s.replaying == path {
+		s.replaying = ""
+	}
+	s.mu.Unlock()
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		s.logger.Warn("Failed to remove spool segment", "file", path, "error", err)
+	}
+}
+
+// writeLocked writes the pending samples as a new segment, first making
+// room for it within the size and free-space limits
+func (s *Spool) writeLocked() {
+	if len(s.pending) == 0 {
+		return
+	}
+	samples := s.pending
+	s.pending = nil
+
+	data, err := encodeSegment(samples)
+	if err != nil {
+		s.logger.Warn("Failed to encode spool segment, dropping samples", "count", len(samples), "error", err)
+		return
+	}
+	s.pruneExpired()
+	if !s.makeRoom(int64(len(data))) {
+		s.discard(len(samples), samples[len(samples)-1].TS.Sub(samples[0].TS))
+		s.logger.Warn("⚠️  Not enough disk space to spool samples, dropping them",
+			"count", len(samples), "minFreeMB", s.minFree>>20)
+		return
+	}
+
+	first, last := samples[0].TS.UnixNano(), samples[len(samples)-1].TS.UnixNano()
+	path := filepath.Join(s.dir, fmt.Sprintf("%020d-%020d%s", first, last, segmentExt))
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		s.logger.Warn("Failed to write spool segment, dropping samples", "count", len(samples), "error", err)
+		return
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		s.logger.Warn("Failed to write spool segment, dropping samples", "count", len(samples), "error", err)
+		return
+	}
+	s.logger.Debug("💾 Spooled samples to disk", "count", len(samples), "bytes", len(data))
+}
+
+// makeRoom deletes the oldest segments until size more bytes fit within
+// maxBytes and leave minFree on the volume. Returns false if they don't
+// fit even with the spool empty.
+func (s *Spool) makeRoom(size int64) bool {
+	segments := s.segments()
+	var total int64
+	for _, seg := range segments {
+		total += seg.size
+	}
+
+	deleted, samples := 0, 0
+	var span time.Duration
+	defer func() {
+		if deleted > 0 {
+			s.discard(samples, span)
+			s.logger.Warn("⚠️  Spool limit reached, deleted oldest segments",
+				"segments", deleted, "samples", samples, "history", span.Round(time.Second))
+		}
+	}()
+
+	for {
+		free, ok := freeSpace(s.dir)
+		fits := total+size <= s.maxBytes && (!ok || free >= s.minFree+uint64(size))
+		if fits {
+			return true
+		}
+		if len(segments) == 0 {
+			return false
+		}
+		oldest := segments[0]
+		segments = segments[1:]
+		count := oldest.samples()
+		if err := os.Remove(oldest.path); err != nil {
+			s.logger.Warn("Failed to remove spool segment", "file", oldest.path, "error", err)
+			return false
+		}
+		total -= oldest.size
+		deleted++
+		samples += count
+		span += oldest.span()
+	}
+}
+
+// pruneExpired deletes segments that ended before the retention period
+func (s *Spool) pruneExpired() {
+	if s.keep <= 0 {
+		return
+	}
+	cutoff := time.Now().Add(-s.keep)
+	for _, seg := range s.segments() {
+		if _, last, ok := seg.bounds(); ok && last.Before(cutoff) {
+			os.Remove(seg.path)
+		}
+	}
+}
+
+// discard records samples that were deleted or never written
+func (s *Spool) discard(samples int, span time.Duration) {
+	s.discarded += uint64(samples)
+	s.lostSpan += span
+}
+
+// Stats returns the spool's size and what it has discarded. Implements ws.Spool.
+func (s *Spool) Stats() Stats {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	st := Stats{DiscardedSamples: s.discarded, DiscardedSec: int64(s.lostSpan.Seconds())}
+	for _, seg := range s.segments() {
+		st.Segments++
+		st.Bytes += seg.size
+	}
+	return st
+}
+
+// freeSpace returns the free bytes on the volume holding dir
+func freeSpace(dir string) (uint64, bool) {
+	usage, err := disk.Usage(dir)
+	if err != nil {
+		return 0, false
+	}
+	return usage.Free, true
+}
+
+// segment is a segment file on disk
+type segment struct {
+	path string
+	size int64
+}
+
+// samples reads a segment's sample count from its header
+func (seg segment) samples() int {
+	f, err := os.Open(seg.path)
+	if err != nil {
+		return 0
+	}
+	defer f.Close()
+	header := make([]byte, len(segmentMagic)+binary.MaxVarintLen64)
+	n, _ := io.ReadFull(f, header)
+	count, k := binary.Uvarint(header[min(len(segmentMagic), n):n])
+	if k <= 0 {
+		return 0
+	}
+	return int(count)
+}
+
+// span returns the time covered by a segment
+func (seg segment) span() time.Duration {
+	first, last, _ := seg.bounds()
+	return last.Sub(first)
+}
+
+// bounds returns the first and last sample times of a segment, from its name
+func (seg segment) bounds() (first, last time.Time, ok bool) {
+	name := strings.TrimSuffix(filepath.Base(seg.path), segmentExt)
+	firstStr, lastStr, found := strings.Cut(name, "-")
+	if !found {
+		return time.Time{}, time.Time{}, false
+	}
+	firstNs, err1 := strconv.ParseInt(firstStr, 10, 64)
+	lastNs, err2 := strconv.ParseInt(lastStr, 10, 64)
+	if err1 != nil || err2 != nil {
+		return time.Time{}, time.Time{}, false
+	}
+	return time.Unix(0, firstNs), time.Unix(0, lastNs), true
+}
+
+// segments lists segment files, oldest first (names sort by first timestamp)
+func (s *Spool) segments() []segment {
+	entries, err := os.ReadDir(s.dir)
+	if err != nil {
+		return nil
+	}
+	var out []segment
+	for _, e := range entries {
+		if e.IsDir() || !strings.HasSuffix(e.Name(), segmentExt) {
+			continue
+		}
+		info, err := e.Info()
+		if err != nil {
+			continue
+		}
+		out = append(out, segment{path: filepath.Join(s.dir, e.Name()), size: info.Size()})
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].path < out[j].path })
+	return out
+}
+
+// Read returns the samples stored in dir (a spool or history directory)
+// taken between from and to, oldest first. Zero times leave that end open.
+func Read(dir string, from, to time.Time) ([]*metrics.SampleV1, error) {
+	s := &Spool{dir: dir}
+	var out []*metrics.SampleV1
+	for _, seg := range s.segments() {
+		if first, last, ok := seg.bounds(); ok {
+			if (!to.IsZero() && first.After(to)) || (!from.IsZero() && last.Before(from)) {
+				continue
+			}
+		}
+		data, err := os.ReadFile(seg.path)
+		if err != nil {
+			return nil, err
+		}
+		samples, err := decodeSegment(data)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", filepath.Base(seg.path), err)
+		}
+		for _, sample := range samples {
+			if (from.IsZero() || !sample.TS.Before(from)) && (to.IsZero() || !sample.TS.After(to)) {
+				out = append(out, sample)
+			}
+		}
+	}
+	return out, nil
+}
@@ -0,0 +1,79 @@
+package spool
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"github.com/jcdorr003/windash-agent/internal/config"
+	"github.com/jcdorr003/windash-agent/internal/metrics"
+	"go.uber.org/zap"
+)
+
+// testSender queues backfills without writing them
+type testSender struct {
+	sent []*Backfill
+}
+
+func (t *testSender) Send(msgType string, payload any) {
+	t.sent = append(t.sent, payload.(*Backfill))
+}
+func (t *testSender) Connected() bool           { return true }
+func (t *testSender) Queued(msgType string) int { return 0 }
+
+func TestReplayKeepsSegmentUntilWritten(t *testing.T) {
+	s, err := Open(zap.NewNop().Sugar(), t.TempDir(), config.SpoolConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	start := time.Date(2026, 10, 15, 12, 0, 0, 0, time.UTC)
+	for i := range 3 {
+		s.Add(metrics.NewSample("host-1", start.Add(time.Duration(i)*2*time.Second)))
+	}
+	s.Close()
+	segments := s.segments()
+	if len(segments) != 1 {
+		t.Fatalf("segments after Close = %d, want 1", len(segments))
+	}
+
+	sender := &testSender{}
+	s.replayOne(sender)
+	if len(sender.sent) != 1 || len(sender.sent[0].Samples) != 3 {
+		t.Fatalf("backfills sent = %+v, want one with 3 samples", sender.sent)
+	}
+	if _, err := os.Stat(segments[0].path); err != nil {
+		t.Fatalf("segment deleted before its backfill was written: %v", err)
+	}
+
+	// Not sent again while the first backfill may still be written
+	s.replayOne(sender)
+	if len(sender.sent) != 1 {
+		t.Fatalf("segment sent %d times before the first write", len(sender.sent))
+	}
+
+	sender.sent[0].Written()
+	if _, err := os.Stat(segments[0].path); !os.IsNotExist(err) {
+		t.Errorf("segment still on disk after its backfill was written: %v", err)
+	}
+	s.replayOne(sender)
+	if len(sender.sent) != 1 {
+		t.Errorf("nothing left to replay, but %d backfills were sent", len(sender.sent))
+	}
+}
+
+func TestReplayResendsLostSegment(t *testing.T) {
+	s, err := Open(zap.NewNop().Sugar(), t.TempDir(), config.SpoolConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.Add(metrics.NewSample("host-1", time.Now()))
+	s.Close()
+
+	sender := &testSender{}
+	s.replayOne(sender)
+	s.sentAt = time.Now().Add(-replayTimeout) // Never written
+	s.replayOne(sender)
+	if len(sender.sent) != 2 {
+		t.Errorf("backfills sent = %d, want the lost one sent again", len(sender.sent))
+	}
+}
@@ -0,0 +1,270 @@
+package state
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"slices"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+const (
+	// saveInterval is how often the state is written to disk
+	saveInterval = time.Minute
+
+	// A running agent saves at least every saveInterval, so a file older
+	// than staleAfter means the agent is stopped or hung
+	staleAfter = 5 * time.Minute
+
+	// A connected agent writes a status message at least every 5 minutes
+	uploadStaleAfter = 15 * time.Minute
+
+	// trafficDays is how many days of traffic are kept: this month and the
+	// whole of the previous one
+	trafficDays = 62
+)
+
+// State is the agent's persisted runtime record (state.json). Unlike
+// agent.json it is written by the agent only and never edited by users.
+type State struct {
+	UpdatedAt   time.Time `json:"updatedAt"`            // Last save by a running agent
+	StartedAt   time.Time `json:"startedAt"`            // Start of the current (or last) run
+	LastConnect time.Time `json:"lastConnect,omitzero"` // Last WebSocket connection
+	LastUpload  time.Time `json:"lastUpload,omitzero"`  // Last message written to the server
+	LastAck     time.Time `json:"lastAck,omitzero"`     // Last control message acknowledged
+
+	Starts         uint64 `json:"starts"`         // Agent runs
+	Connects       uint64 `json:"connects"`       // Successful connections
+	Reconnects     uint64 `json:"reconnects"`     // Connections after a drop within a run
+	DroppedSamples uint64 `json:"droppedSamples"` // Samples dropped by backpressure, all runs
+
+	Traffic []TrafficDay `json:"traffic,omitempty"` // Backend traffic per local day, oldest first
+}
+
+// Traffic counts bytes on the wire to and from the backend, after
+// compression and including TLS and WebSocket framing
+type Traffic struct {
+	Sent     uint64 `json:"sentBytes"`
+	Received uint64 `json:"receivedBytes"`
+}
+
+// TrafficDay is the traffic of one local calendar day
+type TrafficDay struct {
+	Day string `json:"day"` // YYYY-MM-DD
+	Traffic
+}
+
+// TrafficUsage is the traffic summary reported in status messages
+type TrafficUsage struct {
+	Today       Traffic `json:"today"`
+	Month       Traffic `json:"month"`                 // This calendar month, local time
+	BudgetBytes uint64  `json:"budgetBytes,omitempty"` // traffic.monthlyBudgetMB
+}
+
+// TrafficSince sums the traffic of the days from day (YYYY-MM-DD) on
+func (s *State) TrafficSince(day string) Traffic {
+	var t Traffic
+	for _, d := range s.Traffic {
+		if d.Day >= day {
+			t.Sent += d.Sent
+			t.Received += d.Received
+		}
+	}
+	return t
+}
+
+// TrafficUsage returns today's and this month's traffic as of now
+func (s *State) TrafficUsage(now time.Time) TrafficUsage {
+	return TrafficUsage{
+		Today: s.TrafficSince(now.Format(time.DateOnly)),
+		Month: s.TrafficSince(now.Format("2006-01") + "-01"),
+	}
+}
+
+// Healthy reports whether a watchdog should consider the agent healthy at
+// now, and why not if it isn't
+func (s *State) Healthy(now time.Time) (bool, string) {
+	if age := now.Sub(s.UpdatedAt); age > staleAfter {
+		return false, fmt.Sprintf("state not updated for %s (agent stopped or hung)", age.Round(time.Second))
+	}
+	if s.LastUpload.IsZero() {
+		if now.Sub(s.StartedAt) > uploadStaleAfter {
+			return false, "nothing uploaded since start"
+		}
+		return true, ""
+	}
+	if age := now.Sub(s.LastUpload); age > uploadStaleAfter {
+		return false, fmt.Sprintf("no upload for %s", age.Round(time.Second))
+	}
+	return true, ""
+}
+
+// Read loads the state file at path
+func Read(path string) (*State, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var s State
+	if err := json.Unmarshal(data, &s); err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+// Store tracks the running agent's state and saves it periodically
+type Store struct {
+	logger *zap.SugaredLogger
+	path   string
+
+	mu          sync.Mutex
+	state       State
+	droppedBase uint64 // DroppedSamples from previous runs
+	connected   bool   // Connected at least once in this run
+
+	budget     uint64 // Monthly traffic budget in bytes (0 = none)
+	overBudget string // Month (YYYY-MM) the budget warning was logged for
+}
+
+// Open loads the state at path (starting fresh if it is missing or
+// unreadable) and records the start of a new run
+func Open(logger *zap.SugaredLogger, path string) *Store {
+	st := &Store{logger: logger, path: path}
+	if s, err := Read(path); err == nil {
+		st.state = *s
+	} else if !os.IsNotExist(err) {
+		logger.Warn("Ignoring unreadable state file", "path", path, "error", err)
+	}
+
+	st.state.StartedAt = time.Now()
+	st.state.Starts++
+	st.droppedBase = st.state.DroppedSamples
+	return st
+}
+
+// Snapshot returns a copy of the current state
+func (st *Store) Snapshot() State {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	s := st.state
+	s.Traffic = slices.Clone(s.Traffic)
+	return s
+}
+
+// Connected records a successful connection
+func (st *Store) Connected() {
+	st.update(func(s *State) {
+		s.LastConnect = time.Now()
+		s.Connects++
+		if st.connected {
+			s.Reconnects++
+		}
+		st.connected = true
+	})
+}
+
+// Uploaded records a successful write and this run's dropped sample count
+func (st *Store) Uploaded(dropped uint64) {
+	st.update(func(s *State) {
+		s.LastUpload = time.Now()
+		s.DroppedSamples = st.droppedBase + dropped
+	})
+}
+
+// SetTrafficBudget sets the monthly traffic budget in bytes; a warning is
+// logged once a month when the traffic goes over it. 0 disables it.
+func (st *Store) SetTrafficBudget(bytes uint64) {
+	st.mu.Lock()
+	st.budget = bytes
+	st.mu.Unlock()
+}
+
+// AddTraffic counts bytes written to and read from the backend connection
+func (st *Store) AddTraffic(sent, received int) {
+	now := time.Now()
+	day := now.Format(time.DateOnly)
+
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	s := &st.state
+	if n := len(s.Traffic); n == 0 || s.Traffic[n-1].Day != day {
+		s.Traffic = append(s.Traffic, TrafficDay{Day: day})
+		if len(s.Traffic) > trafficDays {
+			s.Traffic = s.Traffic[len(s.Traffic)-trafficDays:]
+		}
+	}
+	today := &s.Traffic[len(s.Traffic)-1]
+	today.Sent += uint64(sent)
+	today.Received += uint64(received)
+
+	if month := now.Format("2006-01"); st.budget > 0 && st.overBudget != month {
+		if used := s.TrafficSince(month + "-01"); used.Sent+used.Received > st.budget {
+			st.overBudget = month
+			st.logger.Warn("📶 Monthly traffic budget exceeded",
+				"sentBytes", used.Sent, "receivedBytes", used.Received, "budgetBytes", st.budget)
+		}
+	}
+}
+
+// TrafficUsage returns today's and this month's traffic with the budget
+func (st *Store) TrafficUsage() TrafficUsage {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	usage := st.state.TrafficUsage(time.Now())
+	usage.BudgetBytes = st.budget
+	return usage
+}
+
+// Acked records that a control message was acknowledged
+func (st *Store) Acked() {
+	st.update(func(s *State) { s.LastAck = time.Now() })
+}
+
+// update applies fn to the state under the lock
+func (st *Store) update(fn func(s *State)) {
+	st.mu.Lock()
+	fn(&st.state)
+	st.mu.Unlock()
+}
+
+// Run saves the state every minute until ctx is cancelled, then once more
+func (st *Store) Run(ctx context.Context) {
+	ticker := time.NewTicker(saveInterval)
+	defer ticker.Stop()
+
+	st.save()
+	for {
+		select {
+		case <-ctx.Done():
+			st.save()
+			return
+		case <-ticker.C:
+			st.save()
+		}
+	}
+}
+
+// save writes the state file. It is rewritten every minute even when
+// nothing changed, since UpdatedAt is what tells a watchdog the agent is alive.
+func (st *Store) save() {
+	st.mu.Lock()
+	st.state.UpdatedAt = time.Now()
+	data, err := json.MarshalIndent(st.state, "", "  ")
+	st.mu.Unlock()
+	if err != nil {
+		st.logger.Warn("Failed to encode state", "error", err)
+		return
+	}
+
+	tmp := st.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err == nil {
+		err = os.Rename(tmp, st.path)
+	}
+	if err != nil {
+		st.logger.Warn("Failed to save state", "path", st.path, "error", err)
+	}
+}
@@ -0,0 +1,213 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jcdorr003/windash-agent/internal/alerts"
+	"github.com/jcdorr003/windash-agent/internal/config"
+	"github.com/jcdorr003/windash-agent/internal/outbound"
+	"go.uber.org/zap"
+)
+
+const defaultInterval = 5 * time.Minute
+
+// Health states, as reported by the Windows storage management API
+const (
+	HealthHealthy   = "healthy"
+	HealthWarning   = "warning"
+	HealthUnhealthy = "unhealthy"
+	HealthUnknown   = "unknown"
+)
+
+// errUnsupported is returned by queryStorage off Windows
+var errUnsupported = errors.New("storage health is only supported on Windows")
+
+// Report is the periodic "diskHealth" message
+type Report struct {
+	Type          string         `json:"type"` // always "diskHealth"
+	TS            time.Time      `json:"ts"`
+	HostID        string         `json:"hostId"`
+	Pools         []Pool         `json:"pools,omitempty"`
+	VirtualDisks  []VirtualDisk  `json:"virtualDisks,omitempty"`
+	Volumes       []Volume       `json:"volumes,omitempty"`
+	PhysicalDisks []PhysicalDisk `json:"physicalDisks,omitempty"`
+	Jobs          []Job          `json:"jobs,omitempty"` // Running repair/rebalance jobs
+}
+
+// PhysicalDisk is a drive's health as Windows rates it from the drive's
+// SMART failure prediction and reliability counters
+type PhysicalDisk struct {
+	Name      string   `json:"name"`
+	MediaType string   `json:"mediaType,omitempty"` // hdd, ssd or scm
+	Health    string   `json:"health"`
+	Status    []string `json:"status,omitempty"` // e.g. ["predictive failure"]
+	Size      uint64   `json:"size"`
+}
+
+// Pool is a Storage Spaces pool
+type Pool struct {
+	Name      string   `json:"name"`
+	Health    string   `json:"health"`
+	Status    []string `json:"status,omitempty"` // Operational status, e.g. ["degraded"]
+	ReadOnly  bool     `json:"readOnly,omitempty"`
+	Size      uint64   `json:"size"`      // Bytes
+	Allocated uint64   `json:"allocated"` // Bytes
+}
+
+// VirtualDisk is a Storage Spaces virtual disk (space)
+type VirtualDisk struct {
+	Name       string   `json:"name"`
+	Health     string   `json:"health"`
+	Status     []string `json:"status,omitempty"`
+	Resiliency string   `json:"resiliency,omitempty"` // Simple, Mirror or Parity
+	Copies     int      `json:"copies,omitempty"`     // Data copies
+	Redundancy int      `json:"redundancy,omitempty"` // Disk failures tolerated
+	Size       uint64   `json:"size"`
+}
+
+// Volume is a volume's health, including dynamic (software RAID) volumes
+// whose redundancy has failed
+type Volume struct {
+	Name   string   `json:"name"` // Drive letter or label
+	Health string   `json:"health"`
+	Status []string `json:"status,omitempty"`
+}
+
+// Job is a running storage job, e.g. a repair after a disk was replaced
+type Job struct {
+	Name    string `json:"name"`
+	State   string `json:"state"`
+	Percent int    `json:"percent"`
+}
+
+// Monitor periodically reports storage health and alerts on degradation
+type Monitor struct {
+	logger   *zap.SugaredLogger
+	hostID   string
+	interval time.Duration
+	health   map[string]string // Last health per alert key
+	open     *alerts.OpenSet   // Shared open-alert state (may be nil)
+}
+
+// NewMonitor creates a storage health monitor
+func NewMonitor(logger *zap.SugaredLogger, hostID string, cfg config.StorageConfig) *Monitor {
+	interval := time.Duration(cfg.IntervalSec) * time.Second
+	if interval <= 0 {
+		interval = defaultInterval
+	}
+	return &Monitor{logger: logger, hostID: hostID, interval: interval, health: make(map[string]string)}
+}
+
+// SetOpenAlerts shares open-alert state with other subsystems. Must be called before Run.
+func (m *Monitor) SetOpenAlerts(open *alerts.OpenSet) {
+	m.open = open
+}
+
+// Query reads storage health once, e.g. for a report outside the monitor's
+// schedule. Off Windows it returns an error.
+func Query(hostID string) (*Report, error) {
+	report, err := queryStorage()
+	if err != nil {
+		return nil, err
+	}
+	report.Type = "diskHealth"
+	report.TS = time.Now()
+	report.HostID = hostID
+	return report, nil
+}
+
+// Run reports on every interval until ctx is cancelled, stopping early if
+// storage health can't be queried on this machine
+func (m *Monitor) Run(ctx context.Context, sender outbound.Sender) {
+	m.logger.Info("💽 Storage health monitor started", "interval", m.interval)
+
+	ticker := time.NewTicker(m.interval)
+	defer ticker.Stop()
+
+	for {
+		report, err := Query(m.hostID)
+		switch {
+		case errors.Is(err, errUnsupported):
+			m.logger.Info("Storage health reporting unavailable, stopping", "error", err)
+			return
+		case err != nil:
+			m.logger.Warn("Storage health query failed", "error", err)
+		default:
+			m.check(report, sender)
+			sender.Send("diskHealth", report)
+		}
+
+		select {
+		case <-ctx.Done():
+			m.logger.Info("💽 Storage health monitor stopped")
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// check alerts when a pool, space, volume or drive becomes less healthy and clears
+// the alert once it is healthy again. Repair progress is logged.
+func (m *Monitor) check(report *Report, sender outbound.Sender) {
+	for _, p := range report.Pools {
+		m.transition(sender, "pool:"+p.Name, "Storage pool "+p.Name, p.Health, p.Status)
+	}
+	for _, d := range report.VirtualDisks {
+		m.transition(sender, "space:"+d.Name, "Storage space "+d.Name, d.Health, d.Status)
+	}
+	for _, v := range report.Volumes {
+		m.transition(sender, "volume:"+v.Name, "Volume "+v.Name, v.Health, v.Status)
+	}
+	for _, d := range report.PhysicalDisks {
+		m.transition(sender, "disk:"+d.Name, "Disk "+d.Name, d.Health, d.Status)
+	}
+	for _, j := range report.Jobs {
+		m.logger.Info("💽 Storage job running", "job", j.Name, "state", j.State, "percent", j.Percent)
+	}
+}
+
+// transition records health for key and alerts if it got worse
+func (m *Monitor) transition(sender outbound.Sender, key, what, health string, status []string) {
+	prev, seen := m.health[key]
+	m.health[key] = health
+	m.open.Set("storage:"+key, health == HealthWarning || health == HealthUnhealthy)
+
+	if severity(health) <= severity(prev) && seen {
+		if health == HealthHealthy && prev != HealthHealthy {
+			m.logger.Info("💽 Storage healthy again", "what", what)
+		}
+		return
+	}
+
+	var level string
+	switch health {
+	case HealthWarning:
+		level = alerts.SeverityWarning
+	case HealthUnhealthy:
+		level = alerts.SeverityCritical
+	default:
+		return
+	}
+	m.logger.Warn("💽 Storage health degraded", "what", what, "health", health, "status", status)
+	alert := alerts.New(m.hostID, "storage", level,
+		fmt.Sprintf("%s is %s", what, health), fmt.Sprintf("Operational status: %v", status))
+	alert.Labels = map[string]string{"key": key}
+	sender.Send("alert", alert)
+}
+
+// severity orders health states from best to worst
+func severity(health string) int {
+	switch health {
+	case HealthHealthy, "":
+		return 0
+	case HealthUnknown:
+		return 1
+	case HealthWarning:
+		return 2
+	default:
+		return 3
+	}
+}
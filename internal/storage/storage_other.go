@@ -0,0 +1,8 @@
+//go:build !windows
+
+package storage
+
+// queryStorage is not implemented outside Windows
+func queryStorage() (*Report, error) {
+	return nil, errUnsupported
+}
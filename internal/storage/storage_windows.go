@@ -0,0 +1,196 @@
+//go:build windows
+
+package storage
+
+import (
+	"fmt"
+
+	"github.com/yusufpapurcu/wmi"
+)
+
+// storageNamespace holds the Storage Management API (Windows 8+)
+const storageNamespace = `root\Microsoft\Windows\Storage`
+
+type msftStoragePool struct {
+	FriendlyName      string
+	HealthStatus      uint16
+	OperationalStatus []uint16
+	IsPrimordial      bool
+	IsReadOnly        bool
+	Size              uint64
+	AllocatedSize     uint64
+}
+
+type msftVirtualDisk struct {
+	FriendlyName           string
+	HealthStatus           uint16
+	OperationalStatus      []uint16
+	ResiliencySettingName  string
+	NumberOfDataCopies     uint16
+	PhysicalDiskRedundancy uint16
+	Size                   uint64
+}
+
+type msftVolume struct {
+	DriveLetter       uint16
+	FileSystemLabel   string
+	HealthStatus      uint16
+	OperationalStatus []uint16
+}
+
+type msftPhysicalDisk struct {
+	FriendlyName      string
+	MediaType         uint16
+	HealthStatus      uint16
+	OperationalStatus []uint16
+	Size              uint64
+}
+
+type msftStorageJob struct {
+	Name            string
+	JobState        uint16
+	PercentComplete uint16
+}
+
+// queryStorage reads pools, spaces, volumes, physical disks and running jobs. Primordial
+// pools (the pseudo-pool of unallocated disks) are skipped.
+func queryStorage() (*Report, error) {
+	var pools []msftStoragePool
+	if err := wmi.QueryNamespace("SELECT FriendlyName, HealthStatus, OperationalStatus, IsPrimordial, IsReadOnly, Size, AllocatedSize FROM MSFT_StoragePool", &pools, storageNamespace); err != nil {
+		return nil, fmt.Errorf("storage pools: %w", err)
+	}
+	var disks []msftVirtualDisk
+	if err := wmi.QueryNamespace("SELECT FriendlyName, HealthStatus, OperationalStatus, ResiliencySettingName, NumberOfDataCopies, PhysicalDiskRedundancy, Size FROM MSFT_VirtualDisk", &disks, storageNamespace); err != nil {
+		return nil, fmt.Errorf("virtual disks: %w", err)
+	}
+	var volumes []msftVolume
+	if err := wmi.QueryNamespace("SELECT DriveLetter, FileSystemLabel, HealthStatus, OperationalStatus FROM MSFT_Volume", &volumes, storageNamespace); err != nil {
+		return nil, fmt.Errorf("volumes: %w", err)
+	}
+	var physical []msftPhysicalDisk
+	if err := wmi.QueryNamespace("SELECT FriendlyName, MediaType, HealthStatus, OperationalStatus, Size FROM MSFT_PhysicalDisk", &physical, storageNamespace); err != nil {
+		return nil, fmt.Errorf("physical disks: %w", err)
+	}
+	var jobs []msftStorageJob
+	if err := wmi.QueryNamespace("SELECT Name, JobState, PercentComplete FROM MSFT_StorageJob", &jobs, storageNamespace); err != nil {
+		return nil, fmt.Errorf("storage jobs: %w", err)
+	}
+
+	report := &Report{}
+	for _, p := range pools {
+		if p.IsPrimordial {
+			continue
+		}
+		report.Pools = append(report.Pools, Pool{
+			Name:      p.FriendlyName,
+			Health:    healthName(p.HealthStatus),
+			Status:    statusNames(p.OperationalStatus),
+			ReadOnly:  p.IsReadOnly,
+			Size:      p.Size,
+			Allocated: p.AllocatedSize,
+		})
+	}
+	for _, d := range disks {
+		report.VirtualDisks = append(report.VirtualDisks, VirtualDisk{
+			Name:       d.FriendlyName,
+			Health:     healthName(d.HealthStatus),
+			Status:     statusNames(d.OperationalStatus),
+			Resiliency: d.ResiliencySettingName,
+			Copies:     int(d.NumberOfDataCopies),
+			Redundancy: int(d.PhysicalDiskRedundancy),
+			Size:       d.Size,
+		})
+	}
+	for _, v := range volumes {
+		name := v.FileSystemLabel
+		if v.DriveLetter != 0 {
+			name = string(rune(v.DriveLetter)) + ":"
+		}
+		if name == "" {
+			continue // Hidden system partitions
+		}
+		report.Volumes = append(report.Volumes, Volume{
+			Name:   name,
+			Health: healthName(v.HealthStatus),
+			Status: statusNames(v.OperationalStatus),
+		})
+	}
+	for _, d := range physical {
+		report.PhysicalDisks = append(report.PhysicalDisks, PhysicalDisk{
+			Name:      d.FriendlyName,
+			MediaType: mediaTypeName(d.MediaType),
+			Health:    healthName(d.HealthStatus),
+			Status:    statusNames(d.OperationalStatus),
+			Size:      d.Size,
+		})
+	}
+	for _, j := range jobs {
+		if state := jobStateName(j.JobState); state == "running" || state == "starting" {
+			report.Jobs = append(report.Jobs, Job{Name: j.Name, State: state, Percent: int(j.PercentComplete)})
+		}
+	}
+	return report, nil
+}
+
+// healthName maps the HealthStatus property
+func healthName(v uint16) string {
+	switch v {
+	case 0:
+		return HealthHealthy
+	case 1:
+		return HealthWarning
+	case 2:
+		return HealthUnhealthy
+	default:
+		return HealthUnknown
+	}
+}
+
+// mediaTypeName maps MSFT_PhysicalDisk.MediaType, "" when unspecified
+func mediaTypeName(v uint16) string {
+	switch v {
+	case 3:
+		return "hdd"
+	case 4:
+		return "ssd"
+	case 5:
+		return "scm"
+	default:
+		return ""
+	}
+}
+
+// operationalStatus names the OperationalStatus values of the storage classes
+var operationalStatus = map[uint16]string{
+	1: "other", 2: "ok", 3: "degraded", 4: "stressed", 5: "predictive failure",
+	6: "error", 7: "non-recoverable error", 8: "starting", 9: "stopping",
+	10: "stopped", 11: "in service", 12: "no contact", 13: "lost communication",
+	14: "aborted", 15: "dormant", 16: "supporting entity in error",
+	17: "completed", 18: "power mode",
+	0xD000: "detached", 0xD001: "incomplete",
+}
+
+// statusNames maps OperationalStatus values, leaving out a lone "ok"
+func statusNames(values []uint16) []string {
+	var names []string
+	for _, v := range values {
+		name, ok := operationalStatus[v]
+		if !ok {
+			name = fmt.Sprintf("code %d", v)
+		}
+		names = append(names, name)
+	}
+	if len(names) == 1 && names[0] == "ok" {
+		return nil
+	}
+	return names
+}
+
+// jobStateName maps the JobState property (CIM_ConcreteJob)
+func jobStateName(v uint16) string {
+	states := []string{2: "new", 3: "starting", 4: "running", 5: "suspended", 6: "shutting down", 7: "completed", 8: "terminated", 9: "killed", 10: "exception", 11: "service"}
+	if int(v) < len(states) && states[v] != "" {
+		return states[v]
+	}
+	return fmt.Sprintf("state %d", v)
+}
@@ -0,0 +1,76 @@
+package summary
+
+import (
+	htmltemplate "html/template"
+	"text/template"
+	"time"
+
+	"github.com/jcdorr003/windash-agent/pkg/units"
+)
+
+// funcs are shared by both summary templates
+var funcs = map[string]any{
+	"bytes":  units.Bytes,
+	"growth": units.Growth,
+	"num":    units.Number,
+	"clock":  func(t time.Time) string { return t.Local().Format("15:04") },
+	"uptime": func(sec uint64) string { return units.Duration(time.Duration(sec) * time.Second) },
+	"pct": func(used, total uint64) float64 {
+		if total == 0 {
+			return 0
+		}
+		return float64(used) / float64(total) * 100
+	},
+}
+
+var markdownTemplate = template.Must(template.New("md").Funcs(funcs).Parse(`# WinDash daily summary - {{.Date}}
+
+Host ` + "`{{.HostID}}`" + `, {{.Samples}} samples from {{clock .From}} to {{clock .To}}.
+
+| | |
+|---|---|
+| Peak CPU | {{num .CPUPeak 1}}% at {{clock .CPUPeakAt}} |
+| Average CPU | {{num .CPUAverage 1}}% |
+| Memory high-water mark | {{bytes .MemPeak}} of {{bytes .MemTotal}} ({{num (pct .MemPeak .MemTotal) 0}}%) at {{clock .MemPeakAt}} |
+| Uptime | {{uptime .UptimeSec}} |
+{{if .Disks}}
+## Disks
+
+| Volume | Used | Growth |
+|---|---|---|
+{{range .Disks}}| {{.Name}} | {{bytes .End}} of {{bytes .Total}} | {{growth .Growth}} |
+{{end}}{{end}}
+## Alerts
+{{if .Alerts}}
+{{range $sev, $n := .AlertCounts}}- {{$sev}}: {{$n}}
+{{end}}
+| Time | Severity | Source | Title |
+|---|---|---|---|
+{{range .Alerts}}| {{clock .TS}} | {{.Severity}} | {{.Source}} | {{.Title}} |
+{{end}}{{else}}
+No alerts.
+{{end}}`))
+
+var htmlTemplate = htmltemplate.Must(htmltemplate.New("html").Funcs(funcs).Parse(`<!DOCTYPE html>
+<html><head><meta charset="utf-8"><title>WinDash daily summary - {{.Date}}</title>
+<style>body{font-family:sans-serif;margin:2em}table{border-collapse:collapse;margin-bottom:1em}td,th{border:1px solid #ccc;padding:4px 8px;text-align:left}</style>
+</head><body>
+<h1>WinDash daily summary - {{.Date}}</h1>
+<p>Host <code>{{.HostID}}</code>, {{.Samples}} samples from {{clock .From}} to {{clock .To}}.</p>
+<table>
+<tr><th>Peak CPU</th><td>{{num .CPUPeak 1}}% at {{clock .CPUPeakAt}}</td></tr>
+<tr><th>Average CPU</th><td>{{num .CPUAverage 1}}%</td></tr>
+<tr><th>Memory high-water mark</th><td>{{bytes .MemPeak}} of {{bytes .MemTotal}} ({{num (pct .MemPeak .MemTotal) 0}}%) at {{clock .MemPeakAt}}</td></tr>
+<tr><th>Uptime</th><td>{{uptime .UptimeSec}}</td></tr>
+</table>
+{{if .Disks}}<h2>Disks</h2>
+<table><tr><th>Volume</th><th>Used</th><th>Growth</th></tr>
+{{range .Disks}}<tr><td>{{.Name}}</td><td>{{bytes .End}} of {{bytes .Total}}</td><td>{{growth .Growth}}</td></tr>
+{{end}}</table>{{end}}
+<h2>Alerts</h2>
+{{if .Alerts}}<ul>{{range $sev, $n := .AlertCounts}}<li>{{$sev}}: {{$n}}</li>{{end}}</ul>
+<table><tr><th>Time</th><th>Severity</th><th>Source</th><th>Title</th></tr>
+{{range .Alerts}}<tr><td>{{clock .TS}}</td><td>{{.Severity}}</td><td>{{.Source}}</td><td>{{.Title}}</td></tr>
+{{end}}</table>{{else}}<p>No alerts.</p>{{end}}
+</body></html>
+`))
@@ -0,0 +1,241 @@
+package summary
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jcdorr003/windash-agent/internal/alerts"
+	"github.com/jcdorr003/windash-agent/internal/config"
+	"github.com/jcdorr003/windash-agent/internal/metrics"
+	"github.com/jcdorr003/windash-agent/internal/outbound"
+	"go.uber.org/zap"
+)
+
+const (
+	defaultKeepDays = 30
+
+	// maxAlerts caps the alerts listed in one summary (all are counted)
+	maxAlerts = 50
+)
+
+// Summary is one day's digest, saved as a file and optionally sent as a
+// "summary" message
+type Summary struct {
+	Type   string    `json:"type"` // always "summary"
+	HostID string    `json:"hostId"`
+	Date   string    `json:"date"` // Local day, YYYY-MM-DD
+	From   time.Time `json:"from"` // First sample of the day
+	To     time.Time `json:"to"`   // Last sample of the day
+
+	Samples int `json:"samples"`
+
+	CPUPeak    float64   `json:"cpuPeak"` // Highest total CPU %
+	CPUPeakAt  time.Time `json:"cpuPeakAt"`
+	CPUAverage float64   `json:"cpuAverage"`
+
+	MemPeak   uint64    `json:"memPeak"` // Memory high-water mark in bytes
+	MemPeakAt time.Time `json:"memPeakAt"`
+	MemTotal  uint64    `json:"memTotal"`
+
+	Disks []DiskGrowth `json:"disks,omitempty"`
+
+	UptimeSec uint64 `json:"uptimeSec"` // System uptime at the last sample
+
+	AlertCounts map[string]int `json:"alertCounts,omitempty"` // By severity
+	Alerts      []AlertEntry   `json:"alerts,omitempty"`      // First maxAlerts alerts
+}
+
+// DiskGrowth is how much one volume's used space changed over the day
+type DiskGrowth struct {
+	Name   string `json:"name"`
+	Start  uint64 `json:"start"` // Used bytes at the first sample
+	End    uint64 `json:"end"`   // Used bytes at the last sample
+	Growth int64  `json:"growth"`
+	Total  uint64 `json:"total"`
+}
+
+// AlertEntry is one alert raised during the day
+type AlertEntry struct {
+	TS       time.Time `json:"ts"`
+	Source   string    `json:"source"`
+	Severity string    `json:"severity"`
+	Title    string    `json:"title"`
+}
+
+// Reporter accumulates samples and alerts over each local day and writes a
+// summary at midnight, so machines without dashboard access still get a
+// record of their day. It is also an outbound.Sender that records alerts on their way
+// to next.
+type Reporter struct {
+	logger *zap.SugaredLogger
+	hostID string
+	dir    string
+	cfg    config.SummaryConfig
+	next   outbound.Sender
+
+	mu     sync.Mutex
+	day    *Summary
+	cpuSum float64
+	disks  map[string]*DiskGrowth
+}
+
+// NewReporter creates a reporter writing summaries to dir and forwarding
+// messages to next
+func NewReporter(logger *zap.SugaredLogger, hostID, dir string, cfg config.SummaryConfig, next outbound.Sender) *Reporter {
+	r := &Reporter{logger: logger, hostID: hostID, dir: dir, cfg: cfg, next: next}
+	r.reset(time.Now())
+	return r
+}
+
+// Send records alerts, then forwards every message to next
+func (r *Reporter) Send(msgType string, payload any) {
+	if alert, ok := payload.(*alerts.Alert); ok && msgType == "alert" {
+		r.mu.Lock()
+		r.day.AlertCounts[alert.Severity]++
+		if len(r.day.Alerts) < maxAlerts {
+			r.day.Alerts = append(r.day.Alerts, AlertEntry{TS: alert.TS, Source: alert.Source, Severity: alert.Severity, Title: alert.Title})
+		}
+		r.mu.Unlock()
+	}
+	r.next.Send(msgType, payload)
+}
+
+// Observe adds a collected sample to the day (see metrics.Collector.Observe)
+func (r *Reporter) Observe(s *metrics.SampleV1) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	d := r.day
+	if d.Samples == 0 {
+		d.From = s.TS
+	}
+	d.To = s.TS
+	d.Samples++
+	r.cpuSum += s.CPU.Total
+	if s.CPU.Total > d.CPUPeak || d.CPUPeakAt.IsZero() {
+		d.CPUPeak, d.CPUPeakAt = s.CPU.Total, s.TS
+	}
+	if s.Mem.Used > d.MemPeak {
+		d.MemPeak, d.MemPeakAt = s.Mem.Used, s.TS
+	}
+	if s.Mem.Total > 0 {
+		d.MemTotal = s.Mem.Total
+	}
+	if s.UptimeSec > 0 {
+		d.UptimeSec = s.UptimeSec
+	}
+	for _, disk := range s.Disks {
+		g := r.disks[disk.Name]
+		if g == nil {
+			g = &DiskGrowth{Name: disk.Name, Start: disk.Used}
+			r.disks[disk.Name] = g
+		}
+		g.End, g.Total = disk.Used, disk.Total
+	}
+}
+
+// Run writes a summary at every local midnight until ctx is cancelled
+func (r *Reporter) Run(ctx context.Context) {
+	for {
+		now := time.Now()
+		midnight := time.Date(now.Year(), now.Month(), now.Day()+1, 0, 0, 0, 0, now.Location())
+		timer := time.NewTimer(time.Until(midnight))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+		r.finish(time.Now())
+	}
+}
+
+// finish closes the current day, starting a new one at now, and writes it
+func (r *Reporter) finish(now time.Time) {
+	r.mu.Lock()
+	day := r.day
+	if day.Samples > 0 {
+		day.CPUAverage = r.cpuSum / float64(day.Samples)
+	}
+	for _, g := range r.disks {
+		g.Growth = int64(g.End) - int64(g.Start)
+		day.Disks = append(day.Disks, *g)
+	}
+	sort.Slice(day.Disks, func(i, j int) bool { return day.Disks[i].Name < day.Disks[j].Name })
+	r.reset(now)
+	r.mu.Unlock()
+
+	if day.Samples == 0 {
+		return // Agent paused or not collecting all day
+	}
+
+	if r.dir != "" {
+		path, err := r.write(day)
+		if err != nil {
+			r.logger.Warn("Failed to write daily summary", "error", err)
+		} else {
+			r.logger.Info("📓 Daily summary written", "file", path)
+		}
+		r.prune(now)
+	}
+	if r.cfg.Upload {
+		r.next.Send("summary", day)
+	}
+}
+
+// reset starts a new day (caller holds mu, except in NewReporter)
+func (r *Reporter) reset(now time.Time) {
+	r.day = &Summary{
+		Type:        "summary",
+		HostID:      r.hostID,
+		Date:        now.Format(time.DateOnly),
+		AlertCounts: make(map[string]int),
+	}
+	r.cpuSum = 0
+	r.disks = make(map[string]*DiskGrowth)
+}
+
+// write renders day into the summaries directory and returns the file path
+func (r *Reporter) write(day *Summary) (string, error) {
+	if err := os.MkdirAll(r.dir, 0755); err != nil {
+		return "", err
+	}
+
+	var b strings.Builder
+	ext := ".md"
+	if r.cfg.Format == "html" {
+		ext = ".html"
+		if err := htmlTemplate.Execute(&b, day); err != nil {
+			return "", err
+		}
+	} else if err := markdownTemplate.Execute(&b, day); err != nil {
+		return "", err
+	}
+
+	path := filepath.Join(r.dir, "summary-"+day.Date+ext)
+	return path, os.WriteFile(path, []byte(b.String()), 0644)
+}
+
+// prune deletes summaries older than cfg.KeepDays
+func (r *Reporter) prune(now time.Time) {
+	keep := r.cfg.KeepDays
+	if keep <= 0 {
+		keep = defaultKeepDays
+	}
+	cutoff := now.AddDate(0, 0, -keep).Format(time.DateOnly)
+
+	files, _ := filepath.Glob(filepath.Join(r.dir, "summary-*"))
+	for _, f := range files {
+		date := strings.TrimSuffix(strings.TrimPrefix(filepath.Base(f), "summary-"), filepath.Ext(f))
+		if date < cutoff {
+			if err := os.Remove(f); err != nil {
+				r.logger.Debug("Failed to remove old summary", "file", f, "error", err)
+			}
+		}
+	}
+}
@@ -0,0 +1,16 @@
+//go:build !windows
+
+package tray
+
+import "errors"
+
+// autostartEnabled reports whether the agent starts when the user signs in.
+// Only Windows has a tray, so there is nothing to toggle elsewhere.
+func autostartEnabled() (bool, error) {
+	return false, errors.ErrUnsupported
+}
+
+// setAutostart adds or removes the agent's sign-in entry
+func setAutostart(enabled bool) error {
+	return errors.ErrUnsupported
+}
@@ -0,0 +1,54 @@
+//go:build windows
+
+package tray
+
+import (
+	"errors"
+	"os"
+
+	"golang.org/x/sys/windows/registry"
+)
+
+const (
+	// runKey lists the programs Windows starts when the current user signs in
+	runKey   = `Software\Microsoft\Windows\CurrentVersion\Run`
+	runValue = "WinDash Agent"
+)
+
+// autostartEnabled reports whether the agent starts when the user signs in
+func autostartEnabled() (bool, error) {
+	k, err := registry.OpenKey(registry.CURRENT_USER, runKey, registry.QUERY_VALUE)
+	if errors.Is(err, registry.ErrNotExist) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	defer k.Close()
+	_, _, err = k.GetStringValue(runValue)
+	if errors.Is(err, registry.ErrNotExist) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// setAutostart adds or removes the agent's Run entry, pointing it at the
+// running executable
+func setAutostart(enabled bool) error {
+	k, _, err := registry.CreateKey(registry.CURRENT_USER, runKey, registry.SET_VALUE)
+	if err != nil {
+		return err
+	}
+	defer k.Close()
+	if !enabled {
+		if err := k.DeleteValue(runValue); err != nil && !errors.Is(err, registry.ErrNotExist) {
+			return err
+		}
+		return nil
+	}
+	exe, err := os.Executable()
+	if err != nil {
+		return err
+	}
+	return k.SetStringValue(runValue, `"`+exe+`"`)
+}
@@ -0,0 +1,164 @@
+package tray
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"math"
+	"runtime"
+	"strings"
+
+	"github.com/jcdorr003/windash-agent/internal/metrics"
+	"github.com/jcdorr003/windash-agent/pkg/units"
+)
+
+const (
+	iconSize = 32
+
+	// bufferingThreshold is how many queued samples turn the icon yellow
+	bufferingThreshold = 20
+)
+
+// State is the connection state shown by the tray icon
+type State int
+
+const (
+	StateDisconnected State = iota // Red
+	StateBuffering                 // Yellow: connected but samples are piling up
+	StateConnected                 // Green
+)
+
+func (s State) String() string {
+	switch s {
+	case StateConnected:
+		return "connected"
+	case StateBuffering:
+		return "buffering"
+	default:
+		return "disconnected"
+	}
+}
+
+// stateOf derives the icon state from the connection and send queue
+func stateOf(connected bool, buffered int) State {
+	switch {
+	case !connected:
+		return StateDisconnected
+	case buffered >= bufferingThreshold:
+		return StateBuffering
+	default:
+		return StateConnected
+	}
+}
+
+// stateColors are the badge colors per state
+var stateColors = map[State]color.NRGBA{
+	StateDisconnected: {R: 0xD9, G: 0x3A, B: 0x3A, A: 0xFF},
+	StateBuffering:    {R: 0xF2, G: 0xB1, B: 0x2C, A: 0xFF},
+	StateConnected:    {R: 0x2E, G: 0xB8, B: 0x5C, A: 0xFF},
+}
+
+// drawIcon draws a round badge in the state's color with a darker rim
+func drawIcon(s State) *image.NRGBA {
+	img := image.NewNRGBA(image.Rect(0, 0, iconSize, iconSize))
+	fill := stateColors[s]
+	rim := color.NRGBA{R: fill.R / 2, G: fill.G / 2, B: fill.B / 2, A: 0xFF}
+
+	center := float64(iconSize-1) / 2
+	radius := float64(iconSize)/2 - 1
+	for y := range iconSize {
+		for x := range iconSize {
+			d := math.Hypot(float64(x)-center, float64(y)-center)
+			switch {
+			case d <= radius-2.5:
+				img.SetNRGBA(x, y, fill)
+			case d <= radius:
+				img.SetNRGBA(x, y, rim)
+			}
+		}
+	}
+	return img
+}
+
+// renderIcon returns the icon for s in the format systray expects: .ico on
+// Windows, PNG elsewhere
+func renderIcon(s State) []byte {
+	img := drawIcon(s)
+	if runtime.GOOS == "windows" {
+		return encodeICO(img)
+	}
+	var buf bytes.Buffer
+	png.Encode(&buf, img)
+	return buf.Bytes()
+}
+
+// encodeICO wraps img in a single-image .ico file using a 32-bit BMP entry,
+// which every Windows version can load
+func encodeICO(img *image.NRGBA) []byte {
+	w, h := img.Bounds().Dx(), img.Bounds().Dy()
+	maskStride := (w + 31) / 32 * 4
+	imageSize := 40 + w*h*4 + maskStride*h
+
+	var buf bytes.Buffer
+	le := binary.LittleEndian
+
+	// ICONDIR and the one ICONDIRENTRY
+	binary.Write(&buf, le, [3]uint16{0, 1, 1})
+	binary.Write(&buf, le, struct {
+		Width, Height, Colors, Reserved uint8
+		Planes, BitCount                uint16
+		Size, Offset                    uint32
+	}{uint8(w), uint8(h), 0, 0, 1, 32, uint32(imageSize), 6 + 16})
+
+	// BITMAPINFOHEADER; the height counts the XOR and AND masks together
+	binary.Write(&buf, le, struct {
+		Size                  uint32
+		Width, Height         int32
+		Planes, BitCount      uint16
+		Compression, Image    uint32
+		XPels, YPels          int32
+		ClrUsed, ClrImportant uint32
+	}{Size: 40, Width: int32(w), Height: int32(h * 2), Planes: 1, BitCount: 32})
+
+	// BGRA pixels, bottom-up
+	for y := h - 1; y >= 0; y-- {
+		for x := range w {
+			c := img.NRGBAAt(x, y)
+			buf.Write([]byte{c.B, c.G, c.R, c.A})
+		}
+	}
+	// AND mask: all zero, transparency comes from the alpha channel
+	buf.Write(make([]byte, maskStride*h))
+	return buf.Bytes()
+}
+
+// sparkBlocks draw a sparkline from 0% to 100%
+var sparkBlocks = []rune("▁▂▃▄▅▆▇█")
+
+// sparkline renders percentages as a row of block characters
+func sparkline(values []float64) string {
+	var b strings.Builder
+	for _, v := range values {
+		i := int(v / 100 * float64(len(sparkBlocks)))
+		b.WriteRune(sparkBlocks[max(0, min(i, len(sparkBlocks)-1))])
+	}
+	return b.String()
+}
+
+// tooltip describes the state and latest sample, with a short CPU history.
+// Windows truncates tooltips to 127 characters, so it is kept compact.
+func tooltip(s State, sample *metrics.SampleV1, cpuHistory []float64) string {
+	text := "WinDash Agent - " + s.String()
+	if sample == nil {
+		return text
+	}
+	memPct := 0.0
+	if sample.Mem.Total > 0 {
+		memPct = float64(sample.Mem.Used) / float64(sample.Mem.Total) * 100
+	}
+	return fmt.Sprintf("%s\nCPU %s %s\nMem %s of %s (%s)", text, units.Percent(sample.CPU.Total), sparkline(cpuHistory),
+		units.Bytes(sample.Mem.Used), units.Bytes(sample.Mem.Total), units.Percent(memPct))
+}
@@ -0,0 +1,23 @@
+//go:build !windows
+
+package tray
+
+import "errors"
+
+// noBackend stands in for the tray outside Windows. The systray library
+// needs cgo and GTK or Cocoa there, so it is not built in.
+type noBackend struct{}
+
+func newBackend() backend {
+	return noBackend{}
+}
+
+func (noBackend) available() error                                                 { return errors.ErrUnsupported }
+func (noBackend) run(onReady, onExit func())                                       {}
+func (noBackend) quit()                                                            {}
+func (noBackend) setIcon(ico []byte)                                               {}
+func (noBackend) setTitle(title string)                                            {}
+func (noBackend) setTooltip(tooltip string)                                        {}
+func (noBackend) addMenuItem(title, tooltip string) menuItem                       { return nil }
+func (noBackend) addMenuItemCheckbox(title, tooltip string, checked bool) menuItem { return nil }
+func (noBackend) addSeparator()                                                    {}
@@ -0,0 +1,79 @@
+//go:build windows
+
+package tray
+
+import (
+	"errors"
+	"runtime"
+	"unsafe"
+
+	"github.com/getlantern/systray"
+	"golang.org/x/sys/windows"
+)
+
+var (
+	moduser32       = windows.NewLazySystemDLL("user32.dll")
+	procFindWindowW = moduser32.NewProc("FindWindowW")
+)
+
+// systrayBackend is the Windows notification area via getlantern/systray
+type systrayBackend struct{}
+
+func newBackend() backend {
+	return systrayBackend{}
+}
+
+// available checks for a session with a taskbar. Services run in session 0
+// with no visible desktop, and Server Core has no Explorer shell; in both
+// there is no Shell_TrayWnd to add an icon to.
+func (systrayBackend) available() error {
+	var session uint32
+	if err := windows.ProcessIdToSessionId(windows.GetCurrentProcessId(), &session); err == nil && session == 0 {
+		return errors.New("running in the service session")
+	}
+	class, err := windows.UTF16PtrFromString("Shell_TrayWnd")
+	if err != nil {
+		return err
+	}
+	if hwnd, _, _ := procFindWindowW.Call(uintptr(unsafe.Pointer(class)), 0); hwnd == 0 {
+		return errors.New("no taskbar (Server Core, or Explorer is not running)")
+	}
+	return nil
+}
+
+// run owns the tray window, so the message loop must stay on the thread
+// that created it
+func (systrayBackend) run(onReady, onExit func()) {
+	runtime.LockOSThread()
+	defer runtime.UnlockOSThread()
+	systray.Run(onReady, onExit)
+}
+
+func (systrayBackend) quit()                     { systray.Quit() }
+func (systrayBackend) setIcon(ico []byte)        { systray.SetIcon(ico) }
+func (systrayBackend) setTitle(title string)     { systray.SetTitle(title) }
+func (systrayBackend) setTooltip(tooltip string) { systray.SetTooltip(tooltip) }
+func (systrayBackend) addSeparator()             { systray.AddSeparator() }
+
+func (systrayBackend) addMenuItem(title, tooltip string) menuItem {
+	return systrayItem{systray.AddMenuItem(title, tooltip)}
+}
+
+func (systrayBackend) addMenuItemCheckbox(title, tooltip string, checked bool) menuItem {
+	return systrayItem{systray.AddMenuItemCheckbox(title, tooltip, checked)}
+}
+
+// systrayItem adapts *systray.MenuItem to menuItem
+type systrayItem struct {
+	item *systray.MenuItem
+}
+
+func (i systrayItem) clicked() <-chan struct{}  { return i.item.ClickedCh }
+func (i systrayItem) setTitle(title string)     { i.item.SetTitle(title) }
+func (i systrayItem) setTooltip(tooltip string) { i.item.SetTooltip(tooltip) }
+func (i systrayItem) checked() bool             { return i.item.Checked() }
+func (i systrayItem) check()                    { i.item.Check() }
+func (i systrayItem) uncheck()                  { i.item.Uncheck() }
+func (i systrayItem) disabled() bool            { return i.item.Disabled() }
+func (i systrayItem) enable()                   { i.item.Enable() }
+func (i systrayItem) disable()                  { i.item.Disable() }
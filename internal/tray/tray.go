@@ -1,81 +1,188 @@
-//go:build tray
-// +build tray
-
 package tray
 
 import (
-	"github.com/getlantern/systray"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/jcdorr003/windash-agent/internal/links"
+	"github.com/jcdorr003/windash-agent/internal/metrics"
+	"github.com/jcdorr003/windash-agent/internal/ws"
 	"go.uber.org/zap"
 )
 
-// TODO: System tray implementation for post-MVP
-// This will allow users to:
-// - Open Dashboard
-// - Start with OS (autostart)
-// - Pause/Resume metrics collection
-// - Quit the agent
+const (
+	// refreshInterval is how often the icon and tooltip are updated
+	refreshInterval = 2 * time.Second
+
+	// historyLen is how many CPU readings the tooltip sparkline shows
+	historyLen = 16
+)
+
+// Uplink reports the connection state shown by the icon (implemented by ws.Client)
+type Uplink interface {
+	Connected() bool
+	Status() *ws.StatusMessage
+	Subscribe(fn func(ws.ConnectionState)) (unsubscribe func())
+}
+
+// Pauser pauses and resumes metrics collection (implemented by metrics.Collector)
+type Pauser interface {
+	Pause()
+	Resume()
+	Paused() bool
+}
+
+// backend is the platform's notification area. The Windows build wraps
+// getlantern/systray; elsewhere there is none and Start fails, so the agent
+// runs without a tray.
+type backend interface {
+	// available reports why no tray can be shown in this session (a
+	// service, Server Core, no desktop shell), or nil
+	available() error
+	run(onReady, onExit func()) // Blocks until quit
+	quit()
+	setIcon(ico []byte)
+	setTitle(title string)
+	setTooltip(tooltip string)
+	addMenuItem(title, tooltip string) menuItem
+	addMenuItemCheckbox(title, tooltip string, checked bool) menuItem
+	addSeparator()
+}
+
+// menuItem is one entry in the tray menu
+type menuItem interface {
+	clicked() <-chan struct{}
+	setTitle(title string)
+	setTooltip(tooltip string)
+	checked() bool
+	check()
+	uncheck()
+	disabled() bool
+	enable()
+	disable()
+}
 
 // Manager handles the system tray
 type Manager struct {
+	ui           backend
 	logger       *zap.SugaredLogger
 	dashboardURL string
+	hostID       string
+	uplink       Uplink
+	latest       func() *metrics.SampleV1
+	collection   Pauser
+
+	pauseMu    sync.Mutex // Menu and refresh goroutines both retitle pauseItem
+	pauseItem  menuItem   // Pause/Resume, titled after the collection state
+	pauseShown bool       // Whether pauseItem currently reads Resume
+
+	icons      map[State][]byte // Rendered once per state
+	cpuHistory []float64        // Recent CPU readings for the tooltip sparkline
+	lastSample *metrics.SampleV1
+
+	started  bool
+	done     chan struct{} // Closed when the tray exits
+	stopOnce sync.Once
 }
 
-// NewManager creates a new tray manager
-func NewManager(logger *zap.SugaredLogger, dashboardURL string) *Manager {
+// NewManager creates a new tray manager. The icon and tooltip follow uplink's
+// connection state and the samples returned by latest; the Pause item
+// pauses and resumes collection.
+func NewManager(logger *zap.SugaredLogger, dashboardURL, hostID string, uplink Uplink, latest func() *metrics.SampleV1, collection Pauser) *Manager {
 	return &Manager{
+		ui:           newBackend(),
 		logger:       logger,
 		dashboardURL: dashboardURL,
+		hostID:       hostID,
+		uplink:       uplink,
+		latest:       latest,
+		collection:   collection,
+		icons:        make(map[State][]byte),
+		done:         make(chan struct{}),
 	}
 }
 
-// Run starts the system tray (blocking)
-func (m *Manager) Run(onQuit func()) {
-	systray.Run(func() {
+// Start shows the tray icon in the background. It returns an error without
+// starting anything when this platform or session has no tray, in which
+// case the agent carries on without one. onQuit is called when the user
+// picks Quit from the menu.
+func (m *Manager) Start(onQuit func()) error {
+	if err := m.ui.available(); err != nil {
+		return fmt.Errorf("system tray unavailable: %w", err)
+	}
+	m.started = true
+	go m.ui.run(func() {
 		m.onReady(onQuit)
 	}, func() {
+		m.stopOnce.Do(func() { close(m.done) })
 		m.logger.Info("System tray exiting")
 	})
+	return nil
+}
+
+// Stop removes the tray icon. Safe to call when Start failed.
+func (m *Manager) Stop() {
+	if !m.started {
+		return
+	}
+	select {
+	case <-m.done:
+	default:
+		m.ui.quit()
+	}
 }
 
 func (m *Manager) onReady(onQuit func()) {
-	systray.SetTitle("WinDash")
-	systray.SetTooltip("WinDash Agent")
-	// TODO: Set icon (systray.SetIcon)
-
-	mOpen := systray.AddMenuItem("Open Dashboard", "Open WinDash dashboard in browser")
-	systray.AddSeparator()
-	mAutostart := systray.AddMenuItemCheckbox("Start with Windows", "Launch agent when Windows starts", false)
-	systray.AddSeparator()
-	mPause := systray.AddMenuItem("Pause", "Pause metrics collection")
-	systray.AddSeparator()
-	mQuit := systray.AddMenuItem("Quit", "Exit WinDash Agent")
+	m.ui.setTitle("WinDash")
+	m.ui.setTooltip("WinDash Agent")
+
+	mOpen := m.ui.addMenuItem("Open Dashboard", "Open WinDash dashboard in browser")
+	mHost := m.ui.addMenuItem("Open This Host", "Open this computer's page in the dashboard")
+	mAlerts := m.ui.addMenuItem("Open Alerts", "Open this computer's alerts in the dashboard")
+	m.ui.addSeparator()
+	// Without a readable Run key the item would only toggle a checkmark, so
+	// it is left out
+	var autostartClicked <-chan struct{}
+	var mAutostart menuItem
+	if enabled, err := autostartEnabled(); err != nil {
+		m.logger.Debug("Autostart setting unavailable, hiding it from the tray", "error", err)
+	} else {
+		mAutostart = m.ui.addMenuItemCheckbox("Start with Windows", "Launch agent when you sign in to Windows", enabled)
+		autostartClicked = mAutostart.clicked()
+		m.ui.addSeparator()
+	}
+	m.pauseItem = m.ui.addMenuItem("Pause", "Pause metrics collection")
+	m.updatePauseItem()
+	m.ui.addSeparator()
+	mQuit := m.ui.addMenuItem("Quit", "Exit WinDash Agent")
+	go m.refreshLoop()
 
 	go func() {
 		for {
 			select {
-			case <-mOpen.ClickedCh:
-				m.logger.Info("Opening dashboard...")
-				// TODO: Open browser
-			case <-mAutostart.ClickedCh:
-				// TODO: Toggle autostart
-				if mAutostart.Checked() {
-					mAutostart.Uncheck()
-				} else {
-					mAutostart.Check()
-				}
-			case <-mPause.ClickedCh:
-				// TODO: Toggle pause/resume
-				if mPause.Disabled() {
-					mPause.Enable()
-					mPause.SetTitle("Pause")
+			case <-m.done:
+				return
+			case <-mOpen.clicked():
+				m.open(links.Dashboard(m.dashboardURL))
+			case <-mHost.clicked():
+				m.open(links.Host(m.dashboardURL, m.hostID))
+			case <-mAlerts.clicked():
+				m.open(links.Alerts(m.dashboardURL, m.hostID))
+			case <-autostartClicked:
+				m.toggleAutostart(mAutostart)
+			case <-m.pauseItem.clicked():
+				if m.collection.Paused() {
+					m.logger.Info("Resume requested from tray")
+					m.collection.Resume()
 				} else {
-					mPause.Disable()
-					mPause.SetTitle("Resume")
+					m.logger.Info("Pause requested from tray")
+					m.collection.Pause()
 				}
-			case <-mQuit.ClickedCh:
+				m.updatePauseItem()
+			case <-mQuit.clicked():
 				m.logger.Info("Quit requested from tray")
-				systray.Quit()
+				m.ui.quit()
 				if onQuit != nil {
 					onQuit()
 				}
@@ -84,3 +191,99 @@ func (m *Manager) onReady(onQuit func()) {
 		}
 	}()
 }
+
+// toggleAutostart flips the sign-in entry, checking the item only once the
+// registry agrees
+func (m *Manager) toggleAutostart(item menuItem) {
+	enable := !item.checked()
+	if err := setAutostart(enable); err != nil {
+		m.logger.Warn("Failed to change autostart", "enable", enable, "error", err)
+		return
+	}
+	m.logger.Info("Autostart changed from tray", "enabled", enable)
+	if enable {
+		item.check()
+	} else {
+		item.uncheck()
+	}
+}
+
+// updatePauseItem titles the Pause item after the collection state, which
+// the server can also change with pause and resume commands
+func (m *Manager) updatePauseItem() {
+	m.pauseMu.Lock()
+	defer m.pauseMu.Unlock()
+	paused := m.collection.Paused()
+	if paused == m.pauseShown {
+		return
+	}
+	m.pauseShown = paused
+	if paused {
+		m.pauseItem.setTitle("Resume")
+		m.pauseItem.setTooltip("Resume metrics collection")
+	} else {
+		m.pauseItem.setTitle("Pause")
+		m.pauseItem.setTooltip("Pause metrics collection")
+	}
+}
+
+// open opens a dashboard link in the browser
+func (m *Manager) open(link string) {
+	m.logger.Info("Opening dashboard...", "url", link)
+	if err := links.Open(link); err != nil {
+		m.logger.Warn("Failed to open browser", "error", err)
+	}
+}
+
+// refreshLoop keeps the icon badge and tooltip current. The icon is only
+// replaced when the state changes. Connection changes refresh right away
+// rather than at the next tick.
+func (m *Manager) refreshLoop() {
+	ticker := time.NewTicker(refreshInterval)
+	defer ticker.Stop()
+
+	changed := make(chan struct{}, 1)
+	unsubscribe := m.uplink.Subscribe(func(ws.ConnectionState) {
+		select {
+		case changed <- struct{}{}:
+		default: // A refresh is already pending
+		}
+	})
+	defer unsubscribe()
+
+	shown := State(-1)
+	for {
+		state := stateOf(m.uplink.Connected(), m.uplink.Status().Buffered)
+		if state != shown {
+			m.ui.setIcon(m.icon(state))
+			shown = state
+		}
+
+		if sample := m.latest(); sample != nil && sample != m.lastSample {
+			m.lastSample = sample
+			m.cpuHistory = append(m.cpuHistory, sample.CPU.Total)
+			if len(m.cpuHistory) > historyLen {
+				m.cpuHistory = m.cpuHistory[1:]
+			}
+		}
+		m.ui.setTooltip(tooltip(state, m.lastSample, m.cpuHistory))
+		m.updatePauseItem()
+
+		select {
+		case <-m.done:
+			return
+		case <-ticker.C:
+		case <-changed:
+		}
+	}
+}
+
+// icon returns the rendered icon for state
+func (m *Manager) icon(state State) []byte {
+	if data, ok := m.icons[state]; ok {
+		return data
+	}
+	data := renderIcon(state)
+	m.icons[state] = data
+	return data
+}
@@ -0,0 +1,129 @@
+package watch
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jcdorr003/windash-agent/internal/alerts"
+	"github.com/shirou/gopsutil/v4/host"
+	"github.com/shirou/gopsutil/v4/process"
+)
+
+const defaultServiceGrace = 5 * time.Minute
+
+// errServicesUnsupported is returned by queryService off Windows
+var errServicesUnsupported = errors.New("service watching is only supported on Windows")
+
+// Service states and startup types as reported to the backend
+const (
+	StartTypeAutomatic        = "automatic"
+	StartTypeAutomaticDelayed = "automatic_delayed"
+	StartTypeManual           = "manual"
+	StartTypeDisabled         = "disabled"
+	StartTypeBoot             = "boot"
+	StartTypeSystem           = "system"
+
+	StateRunning = "running"
+	StateStopped = "stopped"
+)
+
+// ServiceStatus describes one watched Windows service
+type ServiceStatus struct {
+	Name         string    `json:"name"`
+	DisplayName  string    `json:"displayName,omitempty"`
+	State        string    `json:"state"`               // running, stopped, start_pending, ...
+	StartType    string    `json:"startType,omitempty"` // automatic, automatic_delayed, manual, disabled, ...
+	Account      string    `json:"account,omitempty"`   // Account the service runs as
+	Dependencies []string  `json:"dependencies,omitempty"`
+	UptimeSec    uint64    `json:"uptimeSec"`
+	LastStart    time.Time `json:"lastStart,omitzero"`
+	Restarts24h  int       `json:"restarts24h"`
+	DownSince    time.Time `json:"downSince,omitzero"`
+	Error        string    `json:"error,omitempty"` // e.g. not installed, access denied
+}
+
+// serviceInfo is what the platform query returns for a service
+type serviceInfo struct {
+	DisplayName  string
+	State        string
+	StartType    string
+	Account      string
+	Dependencies []string
+	PID          uint32
+}
+
+// checkServices queries every watched service, tracking restarts by PID
+// and alerting on automatic services that are down after the boot grace period
+func (w *Watcher) checkServices(now time.Time) []ServiceStatus {
+	var statuses []ServiceStatus
+	for _, name := range w.services {
+		info, err := queryService(name)
+		if err != nil {
+			statuses = append(statuses, ServiceStatus{Name: name, Error: err.Error()})
+			continue
+		}
+
+		status := ServiceStatus{
+			Name:         name,
+			DisplayName:  info.DisplayName,
+			State:        info.State,
+			StartType:    info.StartType,
+			Account:      info.Account,
+			Dependencies: info.Dependencies,
+		}
+
+		// Reuse process restart tracking keyed by the service's PID
+		var current []instance
+		if info.State == StateRunning && info.PID != 0 {
+			if p, err := process.NewProcess(int32(info.PID)); err == nil {
+				if created, err := p.CreateTime(); err == nil {
+					current = append(current, instance{pid: int32(info.PID), created: created})
+				}
+			}
+		}
+		t := w.serviceState[name]
+		ps := w.update(name, t, current, now)
+		status.UptimeSec = ps.UptimeSec
+		status.LastStart = ps.LastStart
+		status.Restarts24h = ps.Restarts24h
+		status.DownSince = ps.DownSince
+
+		w.checkAutoStart(status, now)
+		statuses = append(statuses, status)
+	}
+	return statuses
+}
+
+// checkAutoStart raises one alert per outage when an Automatic service isn't
+// running once the machine has been up longer than the grace period
+func (w *Watcher) checkAutoStart(status ServiceStatus, now time.Time) {
+	isAuto := status.StartType == StartTypeAutomatic || status.StartType == StartTypeAutomaticDelayed
+	if !isAuto || status.State == StateRunning {
+		delete(w.alerted, status.Name)
+		w.open.Set("watch:"+status.Name, false)
+		return
+	}
+	if w.alerted[status.Name] {
+		return
+	}
+
+	bootTime, err := host.BootTime()
+	if err != nil || now.Sub(time.Unix(int64(bootTime), 0)) < w.serviceGrace {
+		return
+	}
+
+	w.alerted[status.Name] = true
+	w.open.Set("watch:"+status.Name, true)
+	name := status.DisplayName
+	if name == "" {
+		name = status.Name
+	}
+	w.logger.Warn("🚨 Automatic service not running", "service", status.Name, "state", status.State)
+
+	alert := alerts.New(w.hostID, "watch", alerts.SeverityWarning,
+		fmt.Sprintf("%s is not running", name),
+		fmt.Sprintf("Service %s is set to start automatically but is still %s %s after boot", status.Name, status.State, w.serviceGrace))
+	alert.Labels = map[string]string{"service": status.Name, "startType": status.StartType}
+	w.sender.Send("alert", alert)
+}
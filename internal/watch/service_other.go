@@ -0,0 +1,8 @@
+//go:build !windows
+
+package watch
+
+// queryService is not implemented outside Windows
+func queryService(name string) (*serviceInfo, error) {
+	return nil, errServicesUnsupported
+}
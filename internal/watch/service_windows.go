@@ -0,0 +1,96 @@
+//go:build windows
+
+package watch
+
+import (
+	"fmt"
+
+	"golang.org/x/sys/windows"
+	"golang.org/x/sys/windows/svc"
+	"golang.org/x/sys/windows/svc/mgr"
+)
+
+// queryService reads a service's state and configuration with query-only
+// access rights, so it works without elevation (mgr.Connect requires admin)
+func queryService(name string) (*serviceInfo, error) {
+	scm, err := windows.OpenSCManager(nil, nil, windows.SC_MANAGER_CONNECT)
+	if err != nil {
+		return nil, fmt.Errorf("open service manager: %w", err)
+	}
+	defer windows.CloseServiceHandle(scm)
+
+	namePtr, err := windows.UTF16PtrFromString(name)
+	if err != nil {
+		return nil, err
+	}
+	h, err := windows.OpenService(scm, namePtr, windows.SERVICE_QUERY_CONFIG|windows.SERVICE_QUERY_STATUS)
+	if err != nil {
+		if err == windows.ERROR_SERVICE_DOES_NOT_EXIST {
+			return nil, fmt.Errorf("service not installed")
+		}
+		return nil, fmt.Errorf("open service: %w", err)
+	}
+	s := &mgr.Service{Name: name, Handle: h}
+	defer s.Close()
+
+	cfg, err := s.Config()
+	if err != nil {
+		return nil, fmt.Errorf("query config: %w", err)
+	}
+	status, err := s.Query()
+	if err != nil {
+		return nil, fmt.Errorf("query status: %w", err)
+	}
+
+	return &serviceInfo{
+		DisplayName:  cfg.DisplayName,
+		State:        stateName(status.State),
+		StartType:    startTypeName(cfg.StartType, cfg.DelayedAutoStart),
+		Account:      cfg.ServiceStartName,
+		Dependencies: cfg.Dependencies,
+		PID:          status.ProcessId,
+	}, nil
+}
+
+// stateName maps a service state to its reported name
+func stateName(state svc.State) string {
+	switch state {
+	case svc.Running:
+		return StateRunning
+	case svc.Stopped:
+		return StateStopped
+	case svc.StartPending:
+		return "start_pending"
+	case svc.StopPending:
+		return "stop_pending"
+	case svc.Paused:
+		return "paused"
+	case svc.PausePending:
+		return "pause_pending"
+	case svc.ContinuePending:
+		return "continue_pending"
+	default:
+		return fmt.Sprintf("unknown(%d)", state)
+	}
+}
+
+// startTypeName maps a service start type to its reported name
+func startTypeName(startType uint32, delayed bool) string {
+	switch startType {
+	case mgr.StartAutomatic:
+		if delayed {
+			return StartTypeAutomaticDelayed
+		}
+		return StartTypeAutomatic
+	case mgr.StartManual:
+		return StartTypeManual
+	case mgr.StartDisabled:
+		return StartTypeDisabled
+	case windows.SERVICE_BOOT_START:
+		return StartTypeBoot
+	case windows.SERVICE_SYSTEM_START:
+		return StartTypeSystem
+	default:
+		return fmt.Sprintf("unknown(%d)", startType)
+	}
+}
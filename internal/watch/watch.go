@@ -0,0 +1,230 @@
+package watch
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/jcdorr003/windash-agent/internal/alerts"
+	"github.com/jcdorr003/windash-agent/internal/config"
+	"github.com/jcdorr003/windash-agent/internal/outbound"
+	"github.com/shirou/gopsutil/v4/process"
+	"go.uber.org/zap"
+)
+
+const (
+	defaultInterval = 30 * time.Second
+	restartWindow   = 24 * time.Hour
+)
+
+// Report is the periodic "watch" message describing watched processes and services
+type Report struct {
+	Type      string          `json:"type"` // always "watch"
+	TS        time.Time       `json:"ts"`
+	HostID    string          `json:"hostId"`
+	Processes []ProcessStatus `json:"processes,omitempty"`
+	Services  []ServiceStatus `json:"services,omitempty"`
+}
+
+// ProcessStatus describes one watched process name
+type ProcessStatus struct {
+	Name        string    `json:"name"`
+	Running     bool      `json:"running"`
+	Instances   int       `json:"instances"`
+	UptimeSec   uint64    `json:"uptimeSec"`          // Age of the oldest running instance
+	LastStart   time.Time `json:"lastStart,omitzero"` // Start of the newest running instance
+	Restarts24h int       `json:"restarts24h"`        // Starts observed in the last 24h (crash-loop signal)
+	DownSince   time.Time `json:"downSince,omitzero"` // When the process was last seen stopping
+}
+
+// instance identifies a process across scans (PIDs get reused)
+type instance struct {
+	pid     int32
+	created int64 // Unix ms
+}
+
+// tracked holds restart history for one watched name
+type tracked struct {
+	seen      map[instance]struct{}
+	starts    []time.Time
+	downSince time.Time
+}
+
+// Watcher periodically checks watched processes and services and reports their status
+type Watcher struct {
+	logger   *zap.SugaredLogger
+	hostID   string
+	names    []string
+	interval time.Duration
+	state    map[string]*tracked
+	primed   bool // First scan establishes the baseline and counts no restarts
+
+	services     []string
+	serviceState map[string]*tracked
+	serviceGrace time.Duration   // Time after boot before a stopped Automatic service alerts
+	alerted      map[string]bool // Services with an open "not running" alert
+	open         *alerts.OpenSet // Shared open-alert state (may be nil)
+
+	sender outbound.Sender
+}
+
+// NewWatcher creates a watcher for the configured watch list
+func NewWatcher(logger *zap.SugaredLogger, hostID string, cfg config.WatchConfig) *Watcher {
+	interval := time.Duration(cfg.IntervalSec) * time.Second
+	if interval <= 0 {
+		interval = defaultInterval
+	}
+
+	state := make(map[string]*tracked, len(cfg.Processes))
+	for _, name := range cfg.Processes {
+		state[strings.ToLower(name)] = &tracked{seen: make(map[instance]struct{})}
+	}
+
+	serviceState := make(map[string]*tracked, len(cfg.Services))
+	for _, name := range cfg.Services {
+		serviceState[name] = &tracked{seen: make(map[instance]struct{})}
+	}
+	grace := time.Duration(cfg.ServiceGraceSec) * time.Second
+	if grace <= 0 {
+		grace = defaultServiceGrace
+	}
+
+	return &Watcher{
+		logger:       logger,
+		hostID:       hostID,
+		names:        cfg.Processes,
+		interval:     interval,
+		state:        state,
+		services:     cfg.Services,
+		serviceState: serviceState,
+		serviceGrace: grace,
+		alerted:      make(map[string]bool),
+	}
+}
+
+// SetOpenAlerts shares open-alert state with other subsystems. Must be called before Run.
+func (w *Watcher) SetOpenAlerts(open *alerts.OpenSet) {
+	w.open = open
+}
+
+// Run scans on every interval and sends a report until ctx is cancelled
+func (w *Watcher) Run(ctx context.Context, sender outbound.Sender) {
+	w.logger.Info("👀 Watch started", "processes", w.names, "services", w.services, "interval", w.interval)
+	w.sender = sender
+
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	for {
+		if report, err := w.scan(); err != nil {
+			w.logger.Warn("Watch scan failed", "error", err)
+		} else {
+			sender.Send("watch", report)
+		}
+
+		select {
+		case <-ctx.Done():
+			w.logger.Info("👀 Watch stopped")
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// scan checks watched processes and services and updates uptime/restart tracking
+func (w *Watcher) scan() (*Report, error) {
+	now := time.Now()
+	report := &Report{Type: "watch", TS: now, HostID: w.hostID}
+
+	if len(w.names) > 0 {
+		processes, err := w.checkProcesses(now)
+		if err != nil {
+			return nil, err
+		}
+		report.Processes = processes
+	}
+	if len(w.services) > 0 {
+		report.Services = w.checkServices(now)
+	}
+	w.primed = true
+
+	return report, nil
+}
+
+// checkProcesses lists running processes and reports each watched name
+func (w *Watcher) checkProcesses(now time.Time) ([]ProcessStatus, error) {
+	procs, err := process.Processes()
+	if err != nil {
+		return nil, err
+	}
+
+	running := make(map[string][]instance)
+	for _, p := range procs {
+		name, err := p.Name()
+		if err != nil {
+			continue
+		}
+		key := strings.ToLower(name)
+		if _, ok := w.state[key]; !ok {
+			continue
+		}
+		created, err := p.CreateTime()
+		if err != nil {
+			continue
+		}
+		running[key] = append(running[key], instance{pid: p.Pid, created: created})
+	}
+
+	var statuses []ProcessStatus
+	for _, name := range w.names {
+		key := strings.ToLower(name)
+		statuses = append(statuses, w.update(name, w.state[key], running[key], now))
+	}
+	return statuses, nil
+}
+
+// update folds one scan's instances into t and builds the status entry
+func (w *Watcher) update(name string, t *tracked, current []instance, now time.Time) ProcessStatus {
+	status := ProcessStatus{Name: name, Running: len(current) > 0, Instances: len(current)}
+
+	alive := make(map[instance]struct{}, len(current))
+	var oldest, newest int64
+	for _, inst := range current {
+		alive[inst] = struct{}{}
+		if _, known := t.seen[inst]; !known && w.primed {
+			t.starts = append(t.starts, time.UnixMilli(inst.created))
+			w.logger.Info("🔁 Watched item started", "name", name, "pid", inst.pid)
+		}
+		if oldest == 0 || inst.created < oldest {
+			oldest = inst.created
+		}
+		if inst.created > newest {
+			newest = inst.created
+		}
+	}
+
+	if len(current) == 0 && len(t.seen) > 0 {
+		t.downSince = now
+		w.logger.Warn("⚠️  Watched item stopped", "name", name)
+	}
+	if len(current) > 0 {
+		t.downSince = time.Time{}
+		status.UptimeSec = uint64(now.Sub(time.UnixMilli(oldest)).Seconds())
+		status.LastStart = time.UnixMilli(newest)
+	}
+	t.seen = alive
+
+	// Forget starts outside the window
+	cutoff := now.Add(-restartWindow)
+	kept := t.starts[:0]
+	for _, start := range t.starts {
+		if start.After(cutoff) {
+			kept = append(kept, start)
+		}
+	}
+	t.starts = kept
+
+	status.Restarts24h = len(t.starts)
+	status.DownSince = t.downSince
+	return status
+}
@@ -1,48 +1,67 @@
 package ws
 
 import (
-	"context"
 	"sync"
 
+	"github.com/jcdorr003/windash-agent/internal/budget"
 	"github.com/jcdorr003/windash-agent/internal/metrics"
 	"go.uber.org/zap"
 )
 
 // BackpressureBuffer manages a buffered channel with backpressure handling
-// Drops oldest samples if the buffer is full to prevent blocking
+// Drops oldest samples if the buffer is full to prevent blocking. When the
+// shared memory budget is exceeded it downsamples once (keeps every other
+// sample, preserving time coverage) and then sheds the oldest sample for
+// each new one until the budget is relieved.
 type BackpressureBuffer struct {
 	logger     *zap.SugaredLogger
 	buffer     chan *metrics.SampleV1
 	bufferSize int
+	budget     *budget.Budget
+	ready      chan struct{}
+	spill      func(*metrics.SampleV1) // Receives evicted samples instead of dropping them (optional)
 	mu         sync.Mutex
 	dropped    uint64
+	degraded   bool   // Downsampled in the current over-budget episode
+	shed       uint64 // Samples shed over budget, for rate-limited warnings
 }
 
-// NewBackpressureBuffer creates a new backpressure buffer
-func NewBackpressureBuffer(logger *zap.SugaredLogger, size int) *BackpressureBuffer {
+// NewBackpressureBuffer creates a new backpressure buffer charging its
+// contents to mem (nil for unlimited)
+func NewBackpressureBuffer(logger *zap.SugaredLogger, size int, mem *budget.Budget) *BackpressureBuffer {
 	return &BackpressureBuffer{
 		logger:     logger,
 		buffer:     make(chan *metrics.SampleV1, size),
 		bufferSize: size,
+		budget:     mem,
+		ready:      make(chan struct{}, 1),
 	}
 }
 
 // Push adds a sample to the buffer, dropping the oldest if full
 func (b *BackpressureBuffer) Push(sample *metrics.SampleV1) {
+	defer b.signal()
+	b.budget.Add(sample.ApproxSize())
+	defer b.enforceBudget()
+
 	select {
 	case b.buffer <- sample:
 		// Successfully added to buffer
 	default:
-		// Buffer is full - drop oldest and add new
-		b.mu.Lock()
-		b.dropped++
-		droppedCount := b.dropped
-		b.mu.Unlock()
+		// Buffer is full - evict oldest and add new
+		var droppedCount uint64
+		if b.spill == nil {
+			b.mu.Lock()
+			b.dropped++
+			droppedCount = b.dropped
+			b.mu.Unlock()
+		}
 
 		// Try to remove oldest
 		select {
-		case <-b.buffer:
+		case oldest := <-b.buffer:
 			// Successfully removed oldest
+			b.evict(oldest)
 		default:
 			// Should not happen, but handle gracefully
 		}
@@ -51,31 +70,24 @@ func (b *BackpressureBuffer) Push(sample *metrics.SampleV1) {
 		select {
 		case b.buffer <- sample:
 		default:
+			b.budget.Add(-sample.ApproxSize())
 			b.logger.Warn("⚠️  Failed to add sample even after dropping oldest")
 		}
 
-		if droppedCount%10 == 0 {
+		if droppedCount > 0 && droppedCount%10 == 0 {
 			b.logger.Warn("⚠️  Backpressure: dropped samples", "totalDropped", droppedCount)
 		}
 	}
 }
 
-// PopBatch retrieves up to maxCount samples from the buffer
-func (b *BackpressureBuffer) PopBatch(ctx context.Context, maxCount int) []*metrics.SampleV1 {
-	samples := make([]*metrics.SampleV1, 0, maxCount)
-
-	// Get first sample (blocking)
-	select {
-	case sample := <-b.buffer:
-		samples = append(samples, sample)
-	case <-ctx.Done():
-		return nil
-	}
+// PopBatch retrieves up to maxCount samples from the buffer without blocking
+func (b *BackpressureBuffer) PopBatch(maxCount int) []*metrics.SampleV1 {
+	var samples []*metrics.SampleV1
 
-	// Get additional samples (non-blocking, for batching)
-	for i := 1; i < maxCount; i++ {
+	for len(samples) < maxCount {
 		select {
 		case sample := <-b.buffer:
+			b.budget.Add(-sample.ApproxSize())
 			samples = append(samples, sample)
 		default:
 			// No more samples available
@@ -86,6 +98,148 @@ func (b *BackpressureBuffer) PopBatch(ctx context.Context, maxCount int) []*metr
 	return samples
 }
 
+// Requeue puts a popped batch back at the front of the buffer, e.g. after
+// writing it failed. If the buffer has filled up meanwhile, the oldest
+// samples are evicted as in Push.
+func (b *BackpressureBuffer) Requeue(samples []*metrics.SampleV1) {
+	defer b.signal()
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	var held []*metrics.SampleV1
+	for drained := false; !drained; {
+		select {
+		case sample := <-b.buffer:
+			held = append(held, sample)
+		default:
+			drained = true
+		}
+	}
+
+	all := append(samples[:len(samples):len(samples)], held...)
+	for _, sample := range samples {
+		b.budget.Add(sample.ApproxSize())
+	}
+	if over := len(all) - b.bufferSize; over > 0 {
+		for _, sample := range all[:over] {
+			b.evict(sample)
+		}
+		if b.spill == nil {
+			b.dropped += uint64(over)
+		}
+		all = all[over:]
+	}
+	for _, sample := range all {
+		b.buffer <- sample
+	}
+}
+
+// enforceBudget degrades the buffer while the memory budget is exceeded.
+// The first call of an over-budget episode halves resolution; later calls
+// shed the oldest sample so the buffer stops growing, since the rest of
+// the budget (e.g. the outbox) may keep it exceeded whatever the buffer
+// does. The episode ends once the budget is relieved.
+func (b *BackpressureBuffer) enforceBudget() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if !b.budget.Exceeded() {
+		if b.degraded && b.budget.Relieved() {
+			b.degraded = false
+			b.logger.Info("✅ Memory budget relieved, sample buffer back to full resolution")
+		}
+		return
+	}
+
+	if b.degraded {
+		if len(b.buffer) <= 1 {
+			return
+		}
+		select {
+		case oldest := <-b.buffer:
+			b.evict(oldest)
+			b.budget.RecordShed(1)
+			b.shed++
+			if b.shed%10 == 1 {
+				b.logger.Warn("⚠️  Memory budget still exceeded, shedding oldest samples",
+					"totalShed", b.shed, "remaining", len(b.buffer))
+			}
+		default:
+		}
+		return
+	}
+	b.degraded = true
+
+	// Drain, keeping every other sample (always including the newest)
+	var held []*metrics.SampleV1
+	for drained := false; !drained; {
+		select {
+		case sample := <-b.buffer:
+			held = append(held, sample)
+		default:
+			drained = true
+		}
+	}
+
+	kept := held[:0]
+	thinned := 0
+	for i, sample := range held {
+		if (len(held)-1-i)%2 == 0 {
+			kept = append(kept, sample)
+			continue
+		}
+		b.evict(sample)
+		thinned++
+	}
+	b.budget.RecordDownsampled(thinned)
+
+	for _, sample := range kept {
+		b.buffer <- sample
+	}
+
+	b.logger.Warn("⚠️  Memory budget exceeded, downsampled sample buffer",
+		"downsampled", thinned, "remaining", len(kept))
+}
+
+// evict releases a sample taken out of the buffer, handing it to the spill
+// function if there is one
+func (b *BackpressureBuffer) evict(sample *metrics.SampleV1) {
+	b.budget.Add(-sample.ApproxSize())
+	if b.spill != nil {
+		b.spill(sample)
+	}
+}
+
+// SetSpill hands samples evicted from a full buffer (or shed over budget)
+// to fn instead of dropping them. Must be called before the first Push.
+func (b *BackpressureBuffer) SetSpill(fn func(*metrics.SampleV1)) {
+	b.spill = fn
+}
+
+// SpillAll hands every buffered sample to the spill function, e.g. on
+// shutdown. Does nothing without one.
+func (b *BackpressureBuffer) SpillAll() {
+	if b.spill == nil {
+		return
+	}
+	for _, sample := range b.PopBatch(b.bufferSize) {
+		b.spill(sample)
+	}
+}
+
+// Ready is signaled whenever a sample is pushed
+func (b *BackpressureBuffer) Ready() <-chan struct{} {
+	return b.ready
+}
+
+// signal wakes the writer without blocking
+func (b *BackpressureBuffer) signal() {
+	select {
+	case b.ready <- struct{}{}:
+	default:
+	}
+}
+
 // Len returns the current buffer length
 func (b *BackpressureBuffer) Len() int {
 	return len(b.buffer)
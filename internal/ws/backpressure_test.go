@@ -0,0 +1,62 @@
+package ws
+
+import (
+	"testing"
+	"time"
+
+	"github.com/jcdorr003/windash-agent/internal/budget"
+	"github.com/jcdorr003/windash-agent/internal/metrics"
+	"go.uber.org/zap"
+)
+
+func TestBackpressureBudgetEpisode(t *testing.T) {
+	sampleSize := metrics.NewSample("host-1", time.Now()).ApproxSize()
+	mem := budget.New(100 * sampleSize)
+	b := NewBackpressureBuffer(zap.NewNop().Sugar(), 64, mem)
+	var spilled int
+	b.SetSpill(func(*metrics.SampleV1) { spilled++ })
+
+	for range 20 {
+		b.Push(metrics.NewSample("host-1", time.Now()))
+	}
+	if b.Len() != 20 || spilled != 0 {
+		t.Fatalf("under budget: len %d, spilled %d", b.Len(), spilled)
+	}
+
+	// Something else (e.g. the outbox) pushes the budget over on its own
+	mem.Add(100 * sampleSize)
+	b.Push(metrics.NewSample("host-1", time.Now()))
+	if b.Len() != 11 || spilled != 10 {
+		t.Fatalf("first push over budget: len %d, spilled %d, want 11 and 10", b.Len(), spilled)
+	}
+
+	// Later pushes shed one each instead of halving again
+	for range 5 {
+		b.Push(metrics.NewSample("host-1", time.Now()))
+	}
+	if b.Len() != 11 || spilled != 15 {
+		t.Errorf("later pushes over budget: len %d, spilled %d, want 11 and 15", b.Len(), spilled)
+	}
+	if usage := mem.Usage(); usage.Downsampled != 10 || usage.Shed != 5 {
+		t.Errorf("usage = %+v, want 10 downsampled and 5 shed", usage)
+	}
+
+	// Just under the limit is not relieved yet, so nothing is halved again
+	// when it is exceeded once more
+	mem.Add(-12 * sampleSize)
+	b.Push(metrics.NewSample("host-1", time.Now()))
+	mem.Add(12 * sampleSize)
+	b.Push(metrics.NewSample("host-1", time.Now()))
+	if b.Len() != 12 || spilled != 16 {
+		t.Errorf("around the limit: len %d, spilled %d, want 12 and 16", b.Len(), spilled)
+	}
+
+	// Relieved: the next episode downsamples again
+	mem.Add(-100 * sampleSize)
+	b.Push(metrics.NewSample("host-1", time.Now()))
+	mem.Add(100 * sampleSize)
+	b.Push(metrics.NewSample("host-1", time.Now()))
+	if b.Len() != 7 || spilled != 23 {
+		t.Errorf("second episode: len %d, spilled %d, want 7 and 23", b.Len(), spilled)
+	}
+}
@@ -0,0 +1,202 @@
+package ws
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"math"
+)
+
+// CBOR (RFC 8949) major types
+const (
+	cborUint   = 0
+	cborNeg    = 1
+	cborBytes  = 2
+	cborText   = 3
+	cborArray  = 4
+	cborMap    = 5
+	cborTag    = 6
+	cborSimple = 7
+)
+
+// encodeCBOR writes a JSON tree as CBOR, using the shortest integer and
+// float encodings
+func encodeCBOR(buf *bytes.Buffer, v any) error {
+	switch v := v.(type) {
+	case nil:
+		buf.WriteByte(0xf6)
+	case bool:
+		if v {
+			buf.WriteByte(0xf5)
+		} else {
+			buf.WriteByte(0xf4)
+		}
+	case json.Number:
+		i, u, f, kind, err := jsonNumber(v)
+		if err != nil {
+			return err
+		}
+		switch {
+		case kind == 'u':
+			cborHead(buf, cborUint, u)
+		case kind == 'i' && i >= 0:
+			cborHead(buf, cborUint, uint64(i))
+		case kind == 'i':
+			cborHead(buf, cborNeg, uint64(-1-i))
+		case shortFloat(f):
+			buf.WriteByte(0xfa)
+			buf.Write(binary.BigEndian.AppendUint32(nil, math.Float32bits(float32(f))))
+		default:
+			buf.WriteByte(0xfb)
+			buf.Write(binary.BigEndian.AppendUint64(nil, math.Float64bits(f)))
+		}
+	case string:
+		cborHead(buf, cborText, uint64(len(v)))
+		buf.WriteString(v)
+	case []any:
+		cborHead(buf, cborArray, uint64(len(v)))
+		for _, item := range v {
+			if err := encodeCBOR(buf, item); err != nil {
+				return err
+			}
+		}
+	case map[string]any:
+		cborHead(buf, cborMap, uint64(len(v)))
+		for _, k := range sortedKeys(v) {
+			cborHead(buf, cborText, uint64(len(k)))
+			buf.WriteString(k)
+			if err := encodeCBOR(buf, v[k]); err != nil {
+				return err
+			}
+		}
+	default:
+		return fmt.Errorf("unsupported value %T", v)
+	}
+	return nil
+}
+
+// cborHead writes a major type with its argument
+func cborHead(buf *bytes.Buffer, major byte, n uint64) {
+	m := major << 5
+	switch {
+	case n < 24:
+		buf.WriteByte(m | byte(n))
+	case n <= math.MaxUint8:
+		buf.Write([]byte{m | 24, byte(n)})
+	case n <= math.MaxUint16:
+		buf.WriteByte(m | 25)
+		buf.Write(binary.BigEndian.AppendUint16(nil, uint16(n)))
+	case n <= math.MaxUint32:
+		buf.WriteByte(m | 26)
+		buf.Write(binary.BigEndian.AppendUint32(nil, uint32(n)))
+	default:
+		buf.WriteByte(m | 27)
+		buf.Write(binary.BigEndian.AppendUint64(nil, n))
+	}
+}
+
+// decodeCBOR reads one CBOR item as a JSON tree. Tags are dropped (the
+// tagged value is kept), byte strings become base64 and undefined becomes
+// null. Indefinite lengths aren't supported.
+func decodeCBOR(d *treeDecoder) (any, error) {
+	b, err := d.next(1)
+	if err != nil {
+		return nil, err
+	}
+	major, info := b[0]>>5, b[0]&0x1f
+
+	if major == cborSimple {
+		switch info {
+		case 20:
+			return false, nil
+		case 21:
+			return true, nil
+		case 22, 23:
+			return nil, nil
+		case 25:
+			h, err := d.uint(2)
+			if err != nil {
+				return nil, err
+			}
+			return float16(uint16(h)), nil
+		case 26:
+			bits, err := d.uint(4)
+			if err != nil {
+				return nil, err
+			}
+			return float64(math.Float32frombits(uint32(bits))), nil
+		case 27:
+			bits, err := d.uint(8)
+			if err != nil {
+				return nil, err
+			}
+			return math.Float64frombits(bits), nil
+		}
+		return nil, fmt.Errorf("unsupported simple value %d", info)
+	}
+
+	var n uint64
+	switch {
+	case info < 24:
+		n = uint64(info)
+	case info <= 27:
+		if n, err = d.uint(1 << (info - 24)); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("unsupported length encoding %d at byte %d", info, d.pos-1)
+	}
+
+	switch major {
+	case cborUint:
+		return n, nil
+	case cborNeg:
+		if n > math.MaxInt64 {
+			return -1 - float64(n), nil
+		}
+		return -1 - int64(n), nil
+	case cborBytes:
+		raw, err := d.next(n)
+		if err != nil {
+			return nil, err
+		}
+		return binaryString(raw), nil
+	case cborText:
+		raw, err := d.next(n)
+		if err != nil {
+			return nil, err
+		}
+		return string(raw), nil
+	case cborArray:
+		return d.collection(n, false, decodeCBOR)
+	case cborMap:
+		return d.collection(n, true, decodeCBOR)
+	default: // cborTag
+		if err := d.enter(); err != nil {
+			return nil, err
+		}
+		defer func() { d.depth-- }()
+		return decodeCBOR(d)
+	}
+}
+
+// float16 converts an IEEE 754 half-precision value
+func float16(h uint16) float64 {
+	sign := 1.0
+	if h&0x8000 != 0 {
+		sign = -1
+	}
+	exp := int(h>>10) & 0x1f
+	frac := float64(h & 0x3ff)
+	switch exp {
+	case 0:
+		return sign * math.Ldexp(frac, -24)
+	case 0x1f:
+		if frac == 0 {
+			return math.Inf(int(sign))
+		}
+		return math.NaN()
+	}
+	return sign * math.Ldexp(frac+1024, exp-25)
+}
@@ -0,0 +1,48 @@
+package ws
+
+import (
+	"context"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/jcdorr003/windash-agent/internal/chaos"
+)
+
+// handleChaos replaces the simulated failures. Only accepted when the agent
+// was started with --chaos, so a backend can't break a normal install.
+func (c *Client) handleChaos(msg *ControlMessage) (any, error) {
+	faults, err := chaos.Parse(msg.Faults)
+	if err != nil {
+		return nil, err
+	}
+	result := map[string]string{"faults": faults.String()}
+	if c.dryRun {
+		return result, nil
+	}
+	if err := chaos.Set(faults); err != nil {
+		return nil, err
+	}
+	c.logger.Warn("🐒 Chaos faults changed by server", "faults", faults)
+	return result, nil
+}
+
+// chaosDrop closes conn's socket once the drop fault's time is up, like a
+// network failure: no close frame, so both loops see an I/O error and the
+// client goes through its normal reconnect
+func (c *Client) chaosDrop(ctx context.Context, conn *websocket.Conn) {
+	opened := time.Now()
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		if d := chaos.Current().Drop; d > 0 && time.Since(opened) >= d {
+			c.logger.Warn("🐒 Chaos: dropping the connection", "after", d)
+			conn.UnderlyingConn().Close()
+			return
+		}
+	}
+}
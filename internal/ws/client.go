@@ -3,14 +3,27 @@ package ws
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"maps"
 	"math/rand/v2"
+	"net/http"
 	"net/url"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/gorilla/websocket"
+	"github.com/jcdorr003/windash-agent/internal/budget"
+	"github.com/jcdorr003/windash-agent/internal/chaos"
+	"github.com/jcdorr003/windash-agent/internal/config"
+	"github.com/jcdorr003/windash-agent/internal/httpx"
 	"github.com/jcdorr003/windash-agent/internal/metrics"
+	"github.com/jcdorr003/windash-agent/internal/peers"
+	"github.com/jcdorr003/windash-agent/internal/spool"
+	"github.com/jcdorr003/windash-agent/internal/state"
 	"go.uber.org/zap"
 )
 
@@ -19,6 +32,7 @@ const (
 	writeWait      = 10 * time.Second
 	pongWait       = 60 * time.Second
 	pingPeriod     = 10 * time.Second
+	statusPeriod   = 5 * time.Minute
 	maxMessageSize = 512 * 1024 // 512 KB
 
 	// Reconnect configuration
@@ -30,35 +44,213 @@ const (
 	// Buffer configuration
 	bufferSize = 100
 	batchSize  = 10
+
+	// High-resolution mode produces up to 10 samples a second, so more are
+	// buffered and sent per message to keep the per-message overhead down
+	highResBufferSize = 1000
+	highResBatchSize  = 50
 )
 
+// Controller applies server commands to the metrics pipeline
+type Controller interface {
+	SetInterval(interval time.Duration) error
+	Pause()
+	Resume()
+}
+
+// Notifier shows server-sent notices to the user
+type Notifier interface {
+	Notify(title, body, severity, link string) error
+}
+
+// PeerMesh measures latency to the peers the server assigns (implemented
+// by peers.Mesh)
+type PeerMesh interface {
+	SetPeers(list []peers.Peer) error
+	Validate(list []peers.Peer) error
+}
+
+// Tracker records connection milestones and traffic for the persisted
+// agent state (implemented by state.Store)
+type Tracker interface {
+	Connected()
+	Uploaded(dropped uint64)
+	Acked()
+	AddTraffic(sent, received int)
+	TrafficUsage() state.TrafficUsage
+}
+
+// Spool persists samples the buffer can't hold (implemented by spool.Spool)
+type Spool interface {
+	Add(sample *metrics.SampleV1)
+	Stats() spool.Stats
+}
+
 // Client manages the WebSocket connection to the WinDash backend
 type Client struct {
-	apiURL string
-	token  string
-	hostID string
-	logger *zap.SugaredLogger
-
-	conn   *websocket.Conn
-	buffer *BackpressureBuffer
+	mu         sync.Mutex         // Guards apiURL, disconnect, migration and tags
+	apiURL     string             // Changed by migrateEndpoint
+	disconnect context.CancelFunc // Closes the current connection
+	migration  *time.Timer        // Scheduled migrateEndpoint switch
+	tags       map[string]string  // Host tags reported in status
+	token      string
+	hostID     string
+	siteID     string // Fleet placement reported in status
+	groupID    string
+	headers    http.Header
+	connection config.ConnectionConfig
+	logger     *zap.SugaredLogger
+	controller Controller
+	notifier   Notifier // Optional desktop notifications for notices
+	mesh       PeerMesh // Optional peer latency measurements
+	limiter    *rateLimiter
+	replayTS   time.Time // Replaying: when the message being replayed was received
+	recorder   *Recorder // Optional capture of control messages
+	tracker    Tracker   // Optional persisted state
+	dryRun     bool      // Replaying: validate commands without side effects outside the client
+	strict     bool      // Reject control messages with unknown fields
+	readOnly   bool      // security.readOnly: refuse every remote action
+	onConnect  []func()  // Run each time a connection opens
+
+	version string
+	started time.Time
+	upgrade atomic.Pointer[string]        // Minimum version, set while the server requires an upgrade
+	refused atomic.Bool                   // Server closed the connection because this version is too old
+	proto   atomic.Pointer[protocol]      // Protocol version of the current connection
+	compact atomic.Bool                   // Server accepted compactSamples on this connection
+	wire    atomic.Pointer[serializerRef] // Wire format of the current connection (nil = JSON)
+	state   *connState                    // Connection phase, shared with subscribers
+
+	conn      *websocket.Conn
+	memory    *budget.Budget      // Shared cap on queued data
+	batchSize int                 // Samples per message
+	buffer    *BackpressureBuffer // Live samples
+	outbox    *Outbox             // Everything else (acks, alerts, status, ...)
+	spool     Spool               // Samples the buffer evicts (optional)
+	latency   latencyTracker      // Sample batch delays
 }
 
 // NewClient creates a new WebSocket client
-func NewClient(apiURL, token, hostID string, logger *zap.SugaredLogger) *Client {
+func NewClient(cfg *config.Config, token, hostID string, controller Controller, logger *zap.SugaredLogger) *Client {
+	mem := budget.New(int64(cfg.MemoryBudgetMB) << 20)
+	limiter, errs := newRateLimiter(cfg.ControlLimits)
+	for _, err := range errs {
+		logger.Warn("Invalid control message limit, using the default", "error", err)
+	}
+	for _, p := range cfg.Connection.Query {
+		if p.Name == "hostId" {
+			logger.Warn("Ignoring connection.query parameter hostId; the agent always sends its own host ID")
+		}
+	}
+	buffered, batch := bufferSize, batchSize
+	if cfg.HighResolution {
+		buffered, batch = highResBufferSize, highResBatchSize
+	}
 	return &Client{
-		apiURL: apiURL,
-		token:  token,
-		hostID: hostID,
-		logger: logger,
-		buffer: NewBackpressureBuffer(logger, bufferSize),
+		controller: controller,
+		limiter:    limiter,
+		apiURL:     cfg.APIURL,
+		tags:       maps.Clone(cfg.Tags),
+		siteID:     cfg.SiteID,
+		groupID:    cfg.GroupID,
+		token:      token,
+		hostID:     hostID,
+		headers:    cfg.RequestHeaders(),
+		connection: cfg.Connection,
+		strict:     cfg.StrictDecode,
+		readOnly:   cfg.Security.ReadOnly,
+		logger:     logger,
+		version:    cfg.AgentVersion,
+		started:    time.Now(),
+		memory:     mem,
+		batchSize:  batch,
+		buffer:     NewBackpressureBuffer(logger, buffered, mem),
+		outbox:     NewOutbox(logger, mem),
+		state:      newConnState(),
 	}
 }
 
+// Send queues a typed message for delivery. Messages survive reconnects (a
+// message whose write fails is queued again) and are written by the single
+// writer in priority order.
+func (c *Client) Send(msgType string, payload any) {
+	c.outbox.Push(msgType, payload)
+}
+
+// Status returns the agent's current status report
+func (c *Client) Status() *StatusMessage {
+	return c.status()
+}
+
+// Connected reports whether a connection to the server is currently open
+func (c *Client) Connected() bool {
+	return c.state.get().Phase == PhaseConnected
+}
+
+// SetTags replaces the host tags and reports them to the server right away
+func (c *Client) SetTags(tags map[string]string) {
+	c.mu.Lock()
+	c.tags = maps.Clone(tags)
+	c.mu.Unlock()
+	c.Send("status", c.status())
+}
+
+// SetNotifier enables desktop notifications for server notices, required
+// upgrades and long outages. Must be called before Run.
+func (c *Client) SetNotifier(n Notifier) {
+	c.notifier = n
+	c.Subscribe(c.outageNotifier())
+}
+
+// SetPeerMesh accepts setPeers commands, handing the peer list to m.
+// Without a mesh they are rejected.
+func (c *Client) SetPeerMesh(m PeerMesh) {
+	c.mesh = m
+}
+
+// SetTracker records connections, uploads and acks in the persisted agent
+// state. Must be called before Run.
+func (c *Client) SetTracker(t Tracker) {
+	c.tracker = t
+}
+
+// SetSpool sends samples the buffer evicts (outages, slow links) and
+// batches whose write failed to sp instead of dropping them. Must be called before Run.
+func (c *Client) SetSpool(sp Spool) {
+	c.spool = sp
+	c.buffer.SetSpill(sp.Add)
+}
+
+// SpillBuffered moves samples still waiting to be sent to the spool, so
+// they survive a shutdown
+func (c *Client) SpillBuffered() {
+	c.buffer.SpillAll()
+}
+
+// Queued returns the number of queued messages of msgType
+func (c *Client) Queued(msgType string) int {
+	return c.outbox.Queued(msgType)
+}
+
+// OnConnect registers fn to run each time a connection opens, after the
+// status report is queued. fn must not block. Must be called before Run.
+func (c *Client) OnConnect(fn func()) {
+	c.onConnect = append(c.onConnect, fn)
+}
+
 // Run starts the WebSocket client (reconnects automatically on failure)
 func (c *Client) Run(ctx context.Context, sampleChan <-chan *metrics.SampleV1) {
 	c.logger.Info("🌐 WebSocket client starting")
+	defer c.stopMigration()
+
+	// Buffer samples from the collector, also while disconnected
+	go c.bufferSamples(ctx, sampleChan)
 
 	backoff := initialBackoff
+	c.state.update(func(st *ConnectionState) { st.DownSince = time.Now() })
+	defer c.state.update(func(st *ConnectionState) {
+		st.Phase, st.RetryAt = PhaseStopped, time.Time{}
+	})
 
 	for {
 		select {
@@ -69,12 +261,40 @@ func (c *Client) Run(ctx context.Context, sampleChan <-chan *metrics.SampleV1) {
 		}
 
 		// Connect to WebSocket
-		if err := c.connect(ctx); err != nil {
-			c.logger.Warn("Failed to connect to WebSocket", "error", err, "retryIn", backoff)
+		c.mu.Lock()
+		endpoint := endpointOf(c.apiURL)
+		c.mu.Unlock()
+		c.state.update(func(st *ConnectionState) {
+			st.Phase, st.Endpoint, st.RetryAt = PhaseConnecting, endpoint, time.Time{}
+		})
+		err := c.connect(ctx)
+		var upgradeErr *UpgradeRequiredError
+		if errors.As(err, &upgradeErr) {
+			c.requireUpgrade(upgradeErr.MinVersion)
+			c.state.update(func(st *ConnectionState) {
+				st.Phase, st.LastError = PhaseUpgradeRequired, err.Error()
+				st.RetryAt = time.Now().Add(upgradeRetryDelay)
+			})
+			if !c.waitForUpgrade(ctx) {
+				return
+			}
+			continue
+		}
+		if err != nil {
+			// Exponential backoff with jitter, but never sooner than the
+			// server asked for via Retry-After
+			wait := addJitter(backoff, jitter)
+			var rateLimited *httpx.RetryAfterError
+			if errors.As(err, &rateLimited) && rateLimited.Delay > wait {
+				wait = min(rateLimited.Delay, maxBackoff)
+			}
 
-			// Exponential backoff with jitter
-			jitteredBackoff := addJitter(backoff, jitter)
-			time.Sleep(jitteredBackoff)
+			c.logger.Warn("Failed to connect to WebSocket", "error", err, "retryIn", wait)
+			c.state.update(func(st *ConnectionState) {
+				st.Phase, st.LastError, st.RetryAt = PhaseDisconnected, err.Error(), time.Now().Add(wait)
+				st.Attempts++
+			})
+			time.Sleep(wait)
 
 			backoff = time.Duration(float64(backoff) * backoffFactor)
 			if backoff > maxBackoff {
@@ -84,10 +304,17 @@ func (c *Client) Run(ctx context.Context, sampleChan <-chan *metrics.SampleV1) {
 		}
 
 		c.logger.Info("✅ Connected to WebSocket")
+		c.state.update(func(st *ConnectionState) {
+			st.Phase, st.DownSince, st.Attempts, st.LastError = PhaseConnected, time.Time{}, 0, ""
+			st.Connects++
+		})
+		if c.tracker != nil {
+			c.tracker.Connected()
+		}
 		backoff = initialBackoff // Reset backoff on successful connection
 
 		// Run send and receive loops
-		c.runLoop(ctx, sampleChan)
+		c.runLoop(ctx)
 
 		// Close connection
 		if c.conn != nil {
@@ -95,31 +322,81 @@ func (c *Client) Run(ctx context.Context, sampleChan <-chan *metrics.SampleV1) {
 			c.conn = nil
 		}
 
+		if c.refused.Swap(false) {
+			c.state.update(func(st *ConnectionState) {
+				st.Phase, st.DownSince = PhaseUpgradeRequired, time.Now()
+				st.RetryAt = time.Now().Add(upgradeRetryDelay)
+			})
+			if !c.waitForUpgrade(ctx) {
+				return
+			}
+			continue
+		}
+
 		c.logger.Warn("🔄 WebSocket disconnected, reconnecting...")
+		c.state.update(func(st *ConnectionState) {
+			st.Phase, st.DownSince = PhaseDisconnected, time.Now()
+		})
+	}
+}
+
+// waitForUpgrade holds off reconnecting for a long time after the server
+// refused this version, instead of reconnect-looping. Returns false if ctx
+// was cancelled.
+func (c *Client) waitForUpgrade(ctx context.Context) bool {
+	minVersion, _ := c.UpgradeRequired()
+	c.logger.Warn("⛔ Upgrade required, pausing reconnects", "minVersion", minVersion, "retryIn", upgradeRetryDelay)
+	select {
+	case <-ctx.Done():
+		return false
+	case <-time.After(upgradeRetryDelay):
+		return true
 	}
 }
 
 // connect establishes a WebSocket connection
 func (c *Client) connect(ctx context.Context) error {
 	// Build WebSocket URL with hostID
-	u, err := url.Parse(c.apiURL)
+	c.mu.Lock()
+	apiURL := c.apiURL
+	c.mu.Unlock()
+	u, err := url.Parse(apiURL)
 	if err != nil {
 		return fmt.Errorf("invalid API URL: %w", err)
 	}
 
+	if c.connection.Path != "" {
+		u.Path = c.connection.Path
+	}
+
+	// hostId goes last so a configured parameter can't replace the
+	// agent's identity
 	q := u.Query()
+	for _, p := range c.connection.Query {
+		q.Set(p.Name, config.ExpandEnv(p.Value))
+	}
 	q.Set("hostId", c.hostID)
 	u.RawQuery = q.Encode()
 
-	c.logger.Debug("Connecting to WebSocket", "url", u.String())
+	// Log without query values, which may carry secrets
+	c.logger.Debug("Connecting to WebSocket", "url", u.Scheme+"://"+u.Host+u.Path)
 
 	// Set up headers
-	header := make(map[string][]string)
-	header["Authorization"] = []string{fmt.Sprintf("Bearer %s", c.token)}
+	header := c.headers.Clone()
+	for name, value := range c.connection.Headers {
+		header.Set(name, config.ExpandEnv(value))
+	}
+	header.Set("Authorization", fmt.Sprintf("Bearer %s", c.token))
+	header.Set(protocolHeader, offeredProtocol())
+	header.Set(featuresHeader, strings.Join(supportedFeatures, ","))
 
 	// Create dialer with compression
-	dialer := websocket.DefaultDialer
+	dialer := *websocket.DefaultDialer
 	dialer.EnableCompression = true
+	dialer.Subprotocols = offeredSubprotocols()
+	if c.tracker != nil {
+		dialer.NetDialContext = countingDialer(c.tracker.AddTraffic)
+	}
 
 	// Connect
 	conn, resp, err := dialer.DialContext(ctx, u.String(), header)
@@ -127,6 +404,15 @@ func (c *Client) connect(ctx context.Context) error {
 		if resp != nil {
 			body, _ := io.ReadAll(resp.Body)
 			c.logger.Debug("WebSocket connection failed", "status", resp.StatusCode, "body", string(body))
+			if resp.StatusCode == http.StatusUpgradeRequired {
+				return &UpgradeRequiredError{MinVersion: resp.Header.Get(minVersionHeader)}
+			}
+			if httpx.ShouldRetry(resp) {
+				return &httpx.RetryAfterError{
+					StatusCode: resp.StatusCode,
+					Delay:      httpx.RetryAfter(resp, initialBackoff),
+				}
+			}
 			return fmt.Errorf("WebSocket dial failed (HTTP %d): %w", resp.StatusCode, err)
 		}
 		return fmt.Errorf("WebSocket dial failed: %w", err)
@@ -134,24 +420,37 @@ func (c *Client) connect(ctx context.Context) error {
 
 	c.conn = conn
 	c.conn.SetReadLimit(maxMessageSize)
+	c.proto.Store(nil) // Legacy until the hello says otherwise
+	c.setFeatures(nil) // Off until the hello accepts them
+	c.wire.Store(&serializerRef{serializerForSubprotocol(conn.Subprotocol())})
 
 	return nil
 }
 
 // runLoop manages the send and receive loops
-func (c *Client) runLoop(ctx context.Context, sampleChan <-chan *metrics.SampleV1) {
+func (c *Client) runLoop(ctx context.Context) {
 	// Context for this connection
 	connCtx, cancel := context.WithCancel(ctx)
 	defer cancel()
 
+	c.mu.Lock()
+	c.disconnect = cancel
+	c.mu.Unlock()
+	defer func() {
+		c.mu.Lock()
+		c.disconnect = nil
+		c.mu.Unlock()
+	}()
+
 	// Start reader goroutine (for control messages and pings)
 	go c.readLoop(connCtx, cancel)
 
 	// Start writer goroutine
 	go c.writeLoop(connCtx, cancel)
 
-	// Buffer samples from the collector
-	go c.bufferSamples(connCtx, sampleChan)
+	if chaos.Enabled() {
+		go c.chaosDrop(connCtx, c.conn)
+	}
 
 	// Wait for context cancellation
 	<-connCtx.Done()
@@ -174,31 +473,90 @@ func (c *Client) readLoop(ctx context.Context, cancel context.CancelFunc) {
 		default:
 		}
 
-		_, message, err := c.conn.ReadMessage()
+		frameType, message, err := c.conn.ReadMessage()
 		if err != nil {
+			var closeErr *websocket.CloseError
+			if errors.As(err, &closeErr) && closeErr.Code == closeUpgradeRequired {
+				// The close reason carries the minimum version
+				c.requireUpgrade(closeErr.Text)
+				c.refused.Store(true)
+				return
+			}
 			c.logger.Warn("WebSocket read error", "error", err)
 			return
 		}
 
-		// Parse control message
-		var ctrl ControlMessage
-		if err := json.Unmarshal(message, &ctrl); err != nil {
-			c.logger.Warn("Failed to parse control message", "error", err)
-			continue
+		// Binary frames are in the negotiated format; text frames are
+		// always JSON
+		if frameType == websocket.BinaryMessage {
+			if message, err = c.serializer().Decode(message); err != nil {
+				c.logger.Warn("Failed to decode control message", "contentType", c.serializer().ContentType(), "error", err)
+				continue
+			}
+		}
+
+		if c.recorder != nil {
+			if err := c.recorder.Record(message); err != nil {
+				c.logger.Warn("Failed to record control message", "error", err)
+			}
 		}
 
-		c.handleControlMessage(&ctrl)
+		c.handleRaw(message)
+	}
+}
+
+// handleRaw parses and handles a single control message from the server.
+// A panic while handling it is logged and nacked rather than taking the
+// agent down.
+func (c *Client) handleRaw(message []byte) {
+	ctrl, unknown, err := decodeControl(message, c.strict)
+	if ctrl == nil {
+		c.logger.Warn("Failed to parse control message", "error", err)
+		return
+	}
+	if err != nil {
+		// Strict mode: a field this version doesn't understand is rejected
+		c.logger.Warn("Rejected control message", "type", ctrl.Type, "id", ctrl.ID, "error", err)
+		c.sendAck(ctrl, nil, fmt.Errorf("%w: %w", errInvalidMessage, err))
+		return
 	}
+	if unknown != nil {
+		c.logger.Warn("Control message has fields this agent version ignores", "type", ctrl.Type, "error", unknown)
+	}
+
+	defer func() {
+		if r := recover(); r != nil {
+			c.logger.Error("Control message handler panicked", "type", ctrl.Type, "id", ctrl.ID, "panic", r)
+			c.sendAck(ctrl, nil, fmt.Errorf("internal error handling %q", ctrl.Type))
+		}
+	}()
+	c.handleControlMessage(ctrl)
 }
 
-// writeLoop sends metrics and heartbeats to the server
+// writeLoop is the only writer on the connection: it drains the outbox and
+// sample buffer in priority order and sends heartbeats
 func (c *Client) writeLoop(ctx context.Context, cancel context.CancelFunc) {
 	defer cancel()
 
 	ticker := time.NewTicker(pingPeriod)
 	defer ticker.Stop()
 
+	statusTicker := time.NewTicker(statusPeriod)
+	defer statusTicker.Stop()
+
+	// Report status as soon as we connect
+	c.Send("status", c.status())
+	for _, fn := range c.onConnect {
+		fn()
+	}
+
 	for {
+		// Flush everything queued, highest priority first
+		if err := c.flush(); err != nil {
+			c.logger.Warn("Failed to send message", "error", err)
+			return
+		}
+
 		select {
 		case <-ctx.Done():
 			// Send close message
@@ -211,27 +569,52 @@ func (c *Client) writeLoop(ctx context.Context, cancel context.CancelFunc) {
 
 		case <-ticker.C:
 			// Send ping
-			c.conn.SetWriteDeadline(time.Now().Add(writeWait))
-			if err := c.conn.WriteMessage(websocket.PingMessage, nil); err != nil {
+			if err := c.write(websocket.PingMessage, nil); err != nil {
 				c.logger.Warn("Failed to send ping", "error", err)
 				return
 			}
 			c.logger.Debug("📡 Sent ping")
 
-		default:
-			// Try to send batched samples
-			samples := c.buffer.PopBatch(ctx, batchSize)
-			if len(samples) > 0 {
-				if err := c.sendSamples(samples); err != nil {
-					c.logger.Warn("Failed to send samples", "error", err)
-					return
-				}
-				c.logger.Debug("📤 Sent samples", "count", len(samples), "buffered", c.buffer.Len())
-			} else {
-				// No samples available, sleep briefly
-				time.Sleep(100 * time.Millisecond)
+		case <-statusTicker.C:
+			c.Send("status", c.status())
+
+		case <-c.outbox.Ready():
+		case <-c.buffer.Ready():
+		}
+	}
+}
+
+// flush writes queued messages until both queues are empty: messages that
+// outrank metrics first, then sample batches, then bulk messages. A message
+// or batch whose write fails is put back at the front for the next
+// connection.
+func (c *Client) flush() error {
+	for {
+		if msg := c.outbox.TryPop(PriorityMetrics); msg != nil {
+			if err := c.sendMessage(msg); err != nil {
+				c.outbox.Requeue(msg)
+				return err
+			}
+			continue
+		}
+
+		if samples := c.buffer.PopBatch(c.batchSize); len(samples) > 0 {
+			if err := c.sendSamples(samples); err != nil {
+				return err
+			}
+			c.logger.Debug("📤 Sent samples", "count", len(samples), "buffered", c.buffer.Len())
+			continue
+		}
+
+		if msg := c.outbox.TryPop(numPriorities); msg != nil {
+			if err := c.sendMessage(msg); err != nil {
+				c.outbox.Requeue(msg)
+				return err
 			}
+			continue
 		}
+
+		return nil
 	}
 }
 
@@ -247,45 +630,260 @@ func (c *Client) bufferSamples(ctx context.Context, sampleChan <-chan *metrics.S
 	}
 }
 
-// sendSamples sends a batch of samples to the server
+// sendSamples sends a batch of samples to the server, compact if the
+// server accepted that. A batch that can't be written goes to the spool,
+// or back in the buffer without one; one that can't be marshaled never
+// could be and is dropped.
 func (c *Client) sendSamples(samples []*metrics.SampleV1) error {
-	msg := AgentMessage{
-		Type:    "metrics",
-		Samples: samples,
+	sentAt := time.Now()
+	id := c.latency.sent(sentAt, samples[0].TS)
+
+	var data []byte
+	var err error
+	if c.compact.Load() {
+		data, err = marshalCompact(id, sentAt, samples)
+	} else {
+		data, err = json.Marshal(AgentMessage{Type: "metrics", BatchID: id, SentAt: sentAt, Samples: samples})
 	}
-
-	data, err := json.Marshal(msg)
 	if err != nil {
 		return fmt.Errorf("failed to marshal samples: %w", err)
 	}
 
-	c.conn.SetWriteDeadline(time.Now().Add(writeWait))
-	if err := c.conn.WriteMessage(websocket.TextMessage, data); err != nil {
+	if err := c.writeEncoded(data); err != nil {
+		if c.spool != nil {
+			for _, s := range samples {
+				c.spool.Add(s)
+			}
+		} else {
+			c.buffer.Requeue(samples)
+		}
 		return fmt.Errorf("failed to write message: %w", err)
 	}
 
 	return nil
 }
 
-// handleControlMessage processes control messages from the server
+// marshalCompact encodes a metrics message with compact samples
+func marshalCompact(id uint64, sentAt time.Time, samples []*metrics.SampleV1) ([]byte, error) {
+	msg := compactMessage{Type: "metrics", BatchID: id, SentAt: sentAt, Samples: make([]json.RawMessage, len(samples))}
+	for i, s := range samples {
+		data, err := s.MarshalCompact()
+		if err != nil {
+			return nil, err
+		}
+		msg.Samples[i] = data
+	}
+	return json.Marshal(msg)
+}
+
+// writeNotifier is implemented by payloads that must know when they have
+// been written to the socket, e.g. to delete their copy on disk
+type writeNotifier interface {
+	Written()
+}
+
+// sendMessage writes a queued outbound message
+func (c *Client) sendMessage(msg *OutboundMessage) error {
+	if err := c.writeEncoded(msg.data); err != nil {
+		return fmt.Errorf("failed to write %s message: %w", msg.Type, err)
+	}
+	if n, ok := msg.Payload.(writeNotifier); ok {
+		n.Written()
+	}
+	return nil
+}
+
+// writeEncoded sends a JSON message in the connection's wire format. Only
+// the writer goroutine may call it.
+func (c *Client) writeEncoded(data []byte) error {
+	s := c.serializer()
+	frame, err := s.Encode(data)
+	if err != nil {
+		return fmt.Errorf("encode as %s: %w", s.ContentType(), err)
+	}
+	return c.write(s.FrameType(), frame)
+}
+
+// write sends a single frame. Only the writer goroutine may call it.
+func (c *Client) write(messageType int, data []byte) error {
+	c.conn.SetWriteDeadline(time.Now().Add(writeWait))
+	if err := c.conn.WriteMessage(messageType, data); err != nil {
+		return err
+	}
+	if messageType != websocket.PingMessage && c.tracker != nil {
+		c.tracker.Uploaded(c.buffer.DroppedCount())
+	}
+	return nil
+}
+
+// status builds a status report for the server
+func (c *Client) status() *StatusMessage {
+	c.mu.Lock()
+	tags := maps.Clone(c.tags)
+	c.mu.Unlock()
+	var spooled *spool.Stats
+	if c.spool != nil {
+		st := c.spool.Stats()
+		spooled = &st
+	}
+	var traffic *state.TrafficUsage
+	if c.tracker != nil {
+		usage := c.tracker.TrafficUsage()
+		traffic = &usage
+	}
+	return &StatusMessage{
+		Type:      "status",
+		Version:   c.version,
+		Uptime:    int64(time.Since(c.started).Seconds()),
+		Timestamp: time.Now(),
+		Buffered:  c.buffer.Len(),
+		Dropped:   c.buffer.DroppedCount(),
+		Memory:    c.memory.Usage(),
+		Spool:     spooled,
+		Traffic:   traffic,
+		ReadOnly:  c.readOnly,
+		Latency:   c.latency.snapshot(),
+		SiteID:    c.siteID,
+		GroupID:   c.groupID,
+		Tags:      tags,
+	}
+}
+
+// sendAck reports the outcome of a control message to the server
+func (c *Client) sendAck(msg *ControlMessage, result any, cmdErr error) {
+	ack := AckMessage{
+		Type:    "ack",
+		ID:      msg.ID,
+		Command: msg.Type,
+		Result:  result,
+	}
+	if cmdErr != nil {
+		ack.Type = "nack"
+		ack.Error = cmdErr.Error()
+		if c.protocol().ackCodes {
+			ack.Code = nackCode(cmdErr)
+		}
+	}
+
+	c.Send(ack.Type, ack)
+	if c.tracker != nil {
+		c.tracker.Acked()
+	}
+}
+
+// handleControlMessage processes control messages from the server and
+// acknowledges every command so the dashboard knows whether it took effect
 func (c *Client) handleControlMessage(msg *ControlMessage) {
-	c.logger.Info("📥 Received control message", "type", msg.Type)
+	// Batch acks arrive with every metrics message, too often to log
+	if msg.Type == "batchAck" {
+		c.latency.acked(msg.BatchID, msg.ReceivedAt, time.Now())
+		return
+	}
 
-	switch msg.Type {
-	case "connected":
+	c.logger.Info("📥 Received control message", "type", msg.Type, "id", msg.ID)
+
+	// Server notifications are not commands and get no ack
+	if msg.Type == "connected" {
 		c.logger.Info("✅ Server acknowledged connection")
+		c.checkMinVersion(msg.MinVersion)
+		c.setProtocol(msg.Protocol)
+		c.setFeatures(msg.Features)
+		c.setContentType(msg.ContentType)
+		return
+	}
+
+	if c.readOnly {
+		if err := checkReadOnly(msg.Type); err != nil {
+			c.logger.Warn("🔒 Remote action refused in read-only mode", "type", msg.Type, "id", msg.ID)
+			c.sendAck(msg, nil, err)
+			return
+		}
+	}
+
+	now := time.Now()
+	if !c.replayTS.IsZero() {
+		now = c.replayTS
+	}
+	if err := c.limiter.allow(msg.Type, now); err != nil {
+		c.logger.Warn("⚠️  Control command rejected", "type", msg.Type, "id", msg.ID, "error", err)
+		c.sendAck(msg, nil, err)
+		return
+	}
+
+	result, err := c.dispatchCommand(msg)
+	if err != nil {
+		c.logger.Warn("Control command failed", "type", msg.Type, "id", msg.ID, "error", err)
+	}
+	c.sendAck(msg, result, err)
+}
+
+// dispatchCommand applies a server command, returning a result for the ack
+func (c *Client) dispatchCommand(msg *ControlMessage) (any, error) {
+	switch msg.Type {
 	case "setRate":
-		c.logger.Info("🔧 [TODO] Change metrics interval", "intervalMs", msg.IntervalMs)
-		// TODO: Implement runtime interval adjustment
+		interval := time.Duration(msg.IntervalMs) * time.Millisecond
+		if err := c.controller.SetInterval(interval); err != nil {
+			return nil, err
+		}
+		c.logger.Info("🔧 Metrics interval changed by server", "intervalMs", msg.IntervalMs)
+		return map[string]int{"intervalMs": msg.IntervalMs}, nil
 	case "pause":
-		c.logger.Info("⏸️  [TODO] Pause metrics collection")
-		// TODO: Implement pause
+		c.controller.Pause()
+		return nil, nil
 	case "resume":
-		c.logger.Info("▶️  [TODO] Resume metrics collection")
-		// TODO: Implement resume
+		c.controller.Resume()
+		return nil, nil
+	case "notice":
+		return c.handleNotice(msg)
+	case "migrateEndpoint":
+		return c.handleMigrateEndpoint(msg)
+	case "setPeers":
+		return c.handleSetPeers(msg)
+	case "chaos":
+		return c.handleChaos(msg)
 	default:
-		c.logger.Warn("Unknown control message type", "type", msg.Type)
+		return nil, fmt.Errorf("%w %q", errUnknownCommand, msg.Type)
+	}
+}
+
+// handleNotice logs a server notice and shows it to the user. The result
+// reports whether a desktop notification was displayed.
+func (c *Client) handleNotice(msg *ControlMessage) (any, error) {
+	if msg.Title == "" && msg.Body == "" {
+		return nil, errors.New("notice has no title or body")
+	}
+
+	switch msg.Severity {
+	case "critical", "warning":
+		c.logger.Warn("📢 Notice from server", "title", msg.Title, "body", msg.Body, "severity", msg.Severity, "url", msg.URL)
+	default:
+		c.logger.Info("📢 Notice from server", "title", msg.Title, "body", msg.Body, "url", msg.URL)
+	}
+
+	displayed := false
+	if c.notifier != nil {
+		if err := c.notifier.Notify(msg.Title, msg.Body, msg.Severity, msg.URL); err != nil {
+			c.logger.Warn("Failed to display notice", "error", err)
+		} else {
+			displayed = true
+		}
+	}
+	return map[string]bool{"displayed": displayed}, nil
+}
+
+// handleSetPeers replaces the peers measured by the latency mesh
+func (c *Client) handleSetPeers(msg *ControlMessage) (any, error) {
+	if c.mesh == nil {
+		return nil, errors.New("peer latency is not enabled (peers.enabled)")
+	}
+	if c.dryRun {
+		if err := c.mesh.Validate(msg.Peers); err != nil {
+			return nil, err
+		}
+	} else if err := c.mesh.SetPeers(msg.Peers); err != nil {
+		return nil, err
 	}
+	return map[string]int{"peers": len(msg.Peers)}, nil
 }
 
 // addJitter adds random jitter to a duration
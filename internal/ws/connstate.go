@@ -0,0 +1,145 @@
+package ws
+
+import (
+	"fmt"
+	"net/url"
+	"sync"
+	"time"
+)
+
+// Connection phases
+const (
+	PhaseConnecting      = "connecting"      // Dialing the server
+	PhaseConnected       = "connected"       // Connection open
+	PhaseDisconnected    = "disconnected"    // Waiting to reconnect
+	PhaseUpgradeRequired = "upgradeRequired" // The server refused this version; retrying rarely
+	PhaseStopped         = "stopped"         // Run has returned
+)
+
+// outageNotice is how long the server has to be unreachable before the
+// user gets a desktop notification about it
+const outageNotice = 15 * time.Minute
+
+// ConnectionState describes the connection to the server. Snapshots are
+// values; the client never changes one after handing it out.
+type ConnectionState struct {
+	Phase     string    `json:"phase"`
+	Since     time.Time `json:"since"`               // When Phase was entered
+	DownSince time.Time `json:"downSince,omitzero"`  // When the last connection was lost (or Run started); zero while connected
+	Endpoint  string    `json:"endpoint,omitempty"`  // API URL without query or credentials
+	Attempts  int       `json:"attempts,omitempty"`  // Failed connects since the last connection
+	LastError string    `json:"lastError,omitempty"` // Why the last connect failed
+	RetryAt   time.Time `json:"retryAt,omitzero"`    // When the next connect is due, while disconnected
+	Connects  int       `json:"connects"`            // Connections opened since start
+}
+
+// connState holds the current ConnectionState and tells subscribers about
+// every change. Only the Run goroutine updates it, so subscribers see
+// changes in order.
+type connState struct {
+	mu    sync.Mutex
+	state ConnectionState
+	subs  map[int]func(ConnectionState)
+	next  int
+}
+
+func newConnState() *connState {
+	return &connState{
+		state: ConnectionState{Phase: PhaseDisconnected, Since: time.Now()},
+		subs:  make(map[int]func(ConnectionState)),
+	}
+}
+
+// get returns the current state
+func (s *connState) get() ConnectionState {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.state
+}
+
+// update applies fn to the state, stamps Since when the phase changes and
+// notifies the subscribers if anything changed
+func (s *connState) update(fn func(st *ConnectionState)) {
+	s.mu.Lock()
+	prev := s.state
+	fn(&s.state)
+	if s.state.Phase != prev.Phase {
+		s.state.Since = time.Now()
+	}
+	st := s.state
+	subs := make([]func(ConnectionState), 0, len(s.subs))
+	for _, fn := range s.subs {
+		subs = append(subs, fn)
+	}
+	s.mu.Unlock()
+
+	if st == prev {
+		return
+	}
+	for _, fn := range subs {
+		fn(st)
+	}
+}
+
+// subscribe registers fn and returns a function that removes it
+func (s *connState) subscribe(fn func(ConnectionState)) func() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	id := s.next
+	s.next++
+	s.subs[id] = fn
+	return func() {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		delete(s.subs, id)
+	}
+}
+
+// ConnectionState returns the current state of the connection to the server
+func (c *Client) ConnectionState() ConnectionState {
+	return c.state.get()
+}
+
+// Subscribe calls fn with the new state every time the connection state
+// changes, until the returned function is called. fn runs on the client's
+// connection goroutine and must not block.
+func (c *Client) Subscribe(fn func(ConnectionState)) (unsubscribe func()) {
+	return c.state.subscribe(fn)
+}
+
+// endpointOf returns apiURL without its query and credentials, for display
+func endpointOf(apiURL string) string {
+	u, err := url.Parse(apiURL)
+	if err != nil {
+		return ""
+	}
+	return u.Scheme + "://" + u.Host + u.Path
+}
+
+// outageNotifier returns a subscriber that tells the user once when the
+// server has been unreachable for outageNotice, and again when the
+// connection is back
+func (c *Client) outageNotifier() func(ConnectionState) {
+	notified := false
+	return func(st ConnectionState) {
+		switch {
+		case st.Phase == PhaseConnected && notified:
+			notified = false
+			c.notify("WinDash Agent reconnected", "Metrics are being sent to the dashboard again.", "info")
+		case st.Phase == PhaseDisconnected && !notified && !st.DownSince.IsZero() && time.Since(st.DownSince) >= outageNotice:
+			notified = true
+			body := fmt.Sprintf("The server has been unreachable since %s. Metrics are kept and sent once it is back.", st.DownSince.Local().Format(time.Kitchen))
+			if st.LastError != "" {
+				body += " Last error: " + st.LastError
+			}
+			c.notify("WinDash Agent offline", body, "warning")
+		}
+	}
+}
+
+// notify shows a desktop notification, logging failures
+func (c *Client) notify(title, body, severity string) {
+	if err := c.notifier.Notify(title, body, severity, ""); err != nil {
+		c.logger.Warn("Failed to display notification", "title", title, "error", err)
+	}
+}
@@ -0,0 +1,42 @@
+package ws
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// decodeControl parses a control message. Unknown fields are always
+// detected: in strict mode they are an error, otherwise they are returned
+// in unknown so the caller can report the schema drift and carry on.
+func decodeControl(data []byte, strict bool) (msg *ControlMessage, unknown error, err error) {
+	msg = &ControlMessage{}
+	if err := json.Unmarshal(data, msg); err != nil {
+		return nil, nil, describeDecodeError(err)
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&ControlMessage{}); err != nil {
+		if strict {
+			return msg, nil, describeDecodeError(err)
+		}
+		return msg, describeDecodeError(err), nil
+	}
+	return msg, nil, nil
+}
+
+// describeDecodeError adds the position or field of a JSON decoding error
+func describeDecodeError(err error) error {
+	var syntaxErr *json.SyntaxError
+	var typeErr *json.UnmarshalTypeError
+	switch {
+	case errors.As(err, &syntaxErr):
+		return fmt.Errorf("invalid JSON at offset %d: %w", syntaxErr.Offset, err)
+	case errors.As(err, &typeErr):
+		return fmt.Errorf("field %q at offset %d: expected %s, got %s", typeErr.Field, typeErr.Offset, typeErr.Type, typeErr.Value)
+	default:
+		return err
+	}
+}
@@ -0,0 +1,31 @@
+package ws
+
+import (
+	"context"
+	"time"
+)
+
+// drainPoll is how often Drain checks whether the queues are empty
+const drainPoll = 100 * time.Millisecond
+
+// Drain waits until everything queued has been written to the server, e.g.
+// before a planned exit. Returns false if ctx ends first or no connection is
+// open to drain into.
+func (c *Client) Drain(ctx context.Context) bool {
+	ticker := time.NewTicker(drainPoll)
+	defer ticker.Stop()
+
+	for {
+		if c.outbox.Len() == 0 && c.buffer.Len() == 0 {
+			return true
+		}
+		if !c.Connected() {
+			return false
+		}
+		select {
+		case <-ctx.Done():
+			return false
+		case <-ticker.C:
+		}
+	}
+}
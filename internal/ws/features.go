@@ -0,0 +1,44 @@
+package ws
+
+import "slices"
+
+// Optional protocol features (protocol 2 and later). The agent lists those
+// it supports in the handshake's X-WinDash-Features header; the server
+// turns on the ones it accepts by listing them in the connected hello's
+// features. Until the hello arrives every connection starts with all of
+// them off.
+const (
+	featuresHeader = "X-WinDash-Features"
+
+	// featureCompactSamples sends samples without empty sections and with
+	// rounded numbers (metrics.SampleV1.MarshalCompact)
+	featureCompactSamples = "compactSamples"
+
+	// featureTrimmedCores lets the collector replace perCore with topCores
+	// and coreHistogram on many-core machines (collectors.cpu)
+	featureTrimmedCores = "trimmedCores"
+)
+
+// supportedFeatures is what this agent version offers in the handshake
+var supportedFeatures = []string{featureCompactSamples, featureTrimmedCores}
+
+// coreTrimmer is implemented by controllers that can trim per-core CPU
+// data (metrics.Collector)
+type coreTrimmer interface {
+	SetPerCoreTrimming(accepted bool)
+}
+
+// setFeatures applies the features the server accepted in its hello.
+// Protocols without features ignore them.
+func (c *Client) setFeatures(accepted []string) {
+	if !c.protocol().features {
+		accepted = nil
+	}
+	compact := slices.Contains(accepted, featureCompactSamples)
+	if c.compact.Swap(compact) != compact {
+		c.logger.Debug("Sample encoding negotiated", "compact", compact)
+	}
+	if t, ok := c.controller.(coreTrimmer); ok {
+		t.SetPerCoreTrimming(slices.Contains(accepted, featureTrimmedCores))
+	}
+}
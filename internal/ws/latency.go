@@ -0,0 +1,119 @@
+package ws
+
+import (
+	"math"
+	"slices"
+	"sync"
+	"time"
+)
+
+const (
+	// latencyWindow is how many recent batches the percentiles cover
+	latencyWindow = 256
+
+	// maxPendingBatches caps the batches waiting for a batchAck; older ones
+	// are forgotten, so a server that never acks costs nothing
+	maxPendingBatches = 64
+)
+
+// LatencyStats summarizes one stage of the sample path over recent batches
+type LatencyStats struct {
+	P50Ms   float64 `json:"p50Ms"`
+	P95Ms   float64 `json:"p95Ms"`
+	Batches int     `json:"batches"` // Batches measured
+}
+
+// BatchLatency breaks the delay between collecting a sample and the server
+// having it into stages, to tell agent lag from network and backend lag
+type BatchLatency struct {
+	Queue     *LatencyStats `json:"queue,omitempty"`     // Oldest sample's wait in the agent before its batch was sent
+	Upload    *LatencyStats `json:"upload,omitempty"`    // sentAt to the server's receivedAt; needs roughly synced clocks
+	RoundTrip *LatencyStats `json:"roundTrip,omitempty"` // sentAt to the batchAck arriving back
+}
+
+// latencyRing keeps the most recent latencyWindow values
+type latencyRing struct {
+	values []float64
+	next   int
+}
+
+func (r *latencyRing) add(v float64) {
+	if len(r.values) < latencyWindow {
+		r.values = append(r.values, v)
+		return
+	}
+	r.values[r.next] = v
+	r.next = (r.next + 1) % latencyWindow
+}
+
+// stats returns the percentiles, or nil before the first value
+func (r *latencyRing) stats() *LatencyStats {
+	if len(r.values) == 0 {
+		return nil
+	}
+	sorted := slices.Clone(r.values)
+	slices.Sort(sorted)
+	return &LatencyStats{P50Ms: percentile(sorted, 50), P95Ms: percentile(sorted, 95), Batches: len(sorted)}
+}
+
+// percentile returns the nearest-rank percentile p of sorted values
+func percentile(sorted []float64, p float64) float64 {
+	rank := int(math.Ceil(p/100*float64(len(sorted)))) - 1
+	return math.Round(sorted[max(rank, 0)]*10) / 10
+}
+
+// latencyTracker measures every sample batch: sent batches are numbered and
+// remembered until the server echoes the number back in a batchAck
+type latencyTracker struct {
+	mu      sync.Mutex
+	lastID  uint64
+	pending map[uint64]time.Time // sentAt by batch ID
+
+	queue, upload, roundTrip latencyRing
+}
+
+// sent records a batch about to be written, with the collection time of
+// its oldest sample, and returns its ID
+func (l *latencyTracker) sent(sentAt, oldest time.Time) uint64 {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.pending == nil {
+		l.pending = make(map[uint64]time.Time)
+	}
+	l.lastID++
+	l.pending[l.lastID] = sentAt
+	delete(l.pending, l.lastID-maxPendingBatches)
+	l.queue.add(msSince(oldest, sentAt))
+	return l.lastID
+}
+
+// acked records the server's batchAck. Upload times below zero come from
+// clock skew and are left out; the round trip doesn't depend on it.
+func (l *latencyTracker) acked(id uint64, receivedAt, now time.Time) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	sentAt, ok := l.pending[id]
+	if !ok {
+		return
+	}
+	delete(l.pending, id)
+	l.roundTrip.add(msSince(sentAt, now))
+	if !receivedAt.IsZero() && !receivedAt.Before(sentAt) {
+		l.upload.add(msSince(sentAt, receivedAt))
+	}
+}
+
+// snapshot returns the current percentiles, or nil before the first batch
+func (l *latencyTracker) snapshot() *BatchLatency {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if len(l.queue.values) == 0 {
+		return nil
+	}
+	return &BatchLatency{Queue: l.queue.stats(), Upload: l.upload.stats(), RoundTrip: l.roundTrip.stats()}
+}
+
+// msSince returns b - a in milliseconds
+func msSince(a, b time.Time) float64 {
+	return float64(b.Sub(a)) / float64(time.Millisecond)
+}
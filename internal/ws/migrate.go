@@ -0,0 +1,135 @@
+package ws
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"time"
+
+	"github.com/jcdorr003/windash-agent/internal/config"
+)
+
+const (
+	// minMigrateDelay lets the ack for a migration flush on the old
+	// connection before it is closed
+	minMigrateDelay = 2 * time.Second
+
+	// migrateDrainTimeout caps how long a migration waits for queued
+	// messages and samples to go out on the old connection
+	migrateDrainTimeout = 10 * time.Second
+)
+
+// handleMigrateEndpoint validates a migrateEndpoint command, persists the
+// new endpoints to agent.json and schedules a reconnect to the new API URL
+// at the requested time. An agent on wss only moves to wss (and https), so
+// the token is never sent in cleartext. A later command replaces a
+// scheduled one.
+func (c *Client) handleMigrateEndpoint(msg *ControlMessage) (any, error) {
+	c.mu.Lock()
+	current, err := url.Parse(c.apiURL)
+	c.mu.Unlock()
+	secure := err == nil && current.Scheme == "wss"
+	wsSchemes, httpSchemes := []string{"ws", "wss"}, []string{"http", "https"}
+	if secure {
+		wsSchemes, httpSchemes = []string{"wss"}, []string{"https"}
+	}
+	if err := validateEndpoint(msg.APIURL, wsSchemes...); err != nil {
+		return nil, fmt.Errorf("apiUrl: %w", err)
+	}
+	if msg.DashboardURL != "" {
+		if err := validateEndpoint(msg.DashboardURL, httpSchemes...); err != nil {
+			return nil, fmt.Errorf("dashboardUrl: %w", err)
+		}
+	}
+
+	delay := max(time.Until(msg.EffectiveAt), minMigrateDelay)
+	result := map[string]any{"apiUrl": msg.APIURL, "effectiveAt": time.Now().Add(delay)}
+	if c.dryRun {
+		return result, nil
+	}
+
+	values := map[string]any{"apiUrl": msg.APIURL}
+	if msg.DashboardURL != "" {
+		values["dashboardUrl"] = msg.DashboardURL
+	}
+	if err := config.UpdateFile(values); err != nil {
+		return nil, fmt.Errorf("persist endpoints: %w", err)
+	}
+
+	c.logger.Info("🚚 Endpoint migration scheduled", "apiUrl", msg.APIURL, "dashboardUrl", msg.DashboardURL, "in", delay)
+	c.mu.Lock()
+	if c.migration != nil && c.migration.Stop() {
+		c.logger.Info("🚚 Replaced the previously scheduled migration")
+	}
+	c.migration = time.AfterFunc(delay, func() { c.switchEndpoint(msg.APIURL) })
+	c.mu.Unlock()
+
+	return result, nil
+}
+
+// stopMigration cancels a scheduled migration, e.g. on shutdown. The new
+// endpoint is already in agent.json and is used from the next start.
+func (c *Client) stopMigration() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.migration != nil {
+		c.migration.Stop()
+		c.migration = nil
+	}
+}
+
+// switchEndpoint drains the queues on the current connection, points the
+// client at apiURL and drops the connection so the run loop reconnects
+// there
+func (c *Client) switchEndpoint(apiURL string) {
+	c.drain(migrateDrainTimeout)
+
+	c.mu.Lock()
+	if c.migration == nil {
+		c.mu.Unlock()
+		return // Stopped while draining
+	}
+	c.migration = nil
+	c.apiURL = apiURL
+	disconnect := c.disconnect
+	c.mu.Unlock()
+
+	c.logger.Info("🚚 Switching to new endpoint", "apiUrl", apiURL)
+	if disconnect != nil {
+		disconnect()
+	}
+}
+
+// drain waits until the outbox and sample buffer are empty, the connection
+// is lost or timeout passes, whichever comes first
+func (c *Client) drain(timeout time.Duration) {
+	deadline := time.Now().Add(timeout)
+	for c.Connected() && (c.outbox.Len() > 0 || c.buffer.Len() > 0) {
+		if time.Now().After(deadline) {
+			c.logger.Warn("🚚 Switching endpoints with messages still queued; they go to the new endpoint",
+				"queued", c.outbox.Len(), "buffered", c.buffer.Len())
+			return
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+}
+
+// validateEndpoint checks that raw is an absolute URL with one of schemes
+func validateEndpoint(raw string, schemes ...string) error {
+	if raw == "" {
+		return errors.New("missing")
+	}
+	u, err := url.Parse(raw)
+	if err != nil {
+		return err
+	}
+	if u.Host == "" {
+		return fmt.Errorf("%q has no host", raw)
+	}
+	for _, s := range schemes {
+		if u.Scheme == s {
+			return nil
+		}
+	}
+	return fmt.Errorf("%q must use %v", raw, schemes)
+}
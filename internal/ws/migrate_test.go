@@ -0,0 +1,76 @@
+package ws
+
+import (
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jcdorr003/windash-agent/internal/config"
+)
+
+func TestMigrateEndpointSchemes(t *testing.T) {
+	tests := []struct {
+		name      string
+		current   string
+		apiURL    string
+		dashboard string
+		allowed   bool
+	}{
+		{"wss to wss", "wss://old.example/agent", "wss://new.example/agent", "https://new.example", true},
+		{"wss to ws", "wss://old.example/agent", "ws://new.example/agent", "", false},
+		{"wss with an http dashboard", "wss://old.example/agent", "wss://new.example/agent", "http://new.example", false},
+		{"ws to wss", "ws://127.0.0.1:8080/agent", "wss://new.example/agent", "", true},
+		{"ws to ws", "ws://127.0.0.1:8080/agent", "ws://127.0.0.1:9090/agent", "http://127.0.0.1:9090", true},
+		{"not a websocket URL", "ws://127.0.0.1:8080/agent", "https://new.example/agent", "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, _ := newTestClient(t, tt.current)
+			c.dryRun = true
+			_, err := c.handleMigrateEndpoint(&ControlMessage{Type: "migrateEndpoint", APIURL: tt.apiURL, DashboardURL: tt.dashboard})
+			if tt.allowed && err != nil {
+				t.Errorf("rejected: %v", err)
+			}
+			if !tt.allowed && err == nil {
+				t.Error("accepted")
+			}
+		})
+	}
+}
+
+func TestMigrateEndpointSchedule(t *testing.T) {
+	t.Setenv("LOCALAPPDATA", t.TempDir())
+	if err := os.MkdirAll(config.GetConfigDir(), 0700); err != nil {
+		t.Fatal(err)
+	}
+	c, _ := newTestClient(t, "wss://old.example/agent")
+	later := time.Now().Add(time.Hour)
+
+	for _, apiURL := range []string{"wss://first.example/agent", "wss://second.example/agent"} {
+		if _, err := c.handleMigrateEndpoint(&ControlMessage{Type: "migrateEndpoint", APIURL: apiURL, EffectiveAt: later}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if c.migration == nil {
+		t.Fatal("no migration scheduled")
+	}
+
+	// The second command replaced the first timer; switching runs the latest
+	c.switchEndpoint("wss://second.example/agent")
+	if c.apiURL != "wss://second.example/agent" || c.migration != nil {
+		t.Errorf("after the switch: apiUrl %s, migration %v", c.apiURL, c.migration)
+	}
+
+	if _, err := c.handleMigrateEndpoint(&ControlMessage{Type: "migrateEndpoint", APIURL: "wss://third.example/agent", EffectiveAt: later}); err != nil {
+		t.Fatal(err)
+	}
+	c.stopMigration()
+	if c.migration != nil {
+		t.Error("migration still scheduled after stopMigration")
+	}
+	c.switchEndpoint("wss://third.example/agent") // A timer that fired just before the stop
+	if !strings.Contains(c.apiURL, "second.example") {
+		t.Errorf("stopped migration switched to %s", c.apiURL)
+	}
+}
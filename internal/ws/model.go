@@ -1,29 +1,98 @@
 package ws
 
 import (
+	"encoding/json"
 	"time"
 
+	"github.com/jcdorr003/windash-agent/internal/budget"
 	"github.com/jcdorr003/windash-agent/internal/metrics"
+	"github.com/jcdorr003/windash-agent/internal/peers"
+	"github.com/jcdorr003/windash-agent/internal/spool"
+	"github.com/jcdorr003/windash-agent/internal/state"
 )
 
 // ControlMessage represents a message from server to agent
 type ControlMessage struct {
-	Type string `json:"type"` // e.g., "setRate", "pause", "resume"
+	Type string `json:"type"`         // e.g., "setRate", "pause", "resume"
+	ID   string `json:"id,omitempty"` // Correlation ID echoed back in the ack
 
 	// For setRate command
 	IntervalMs int `json:"intervalMs,omitempty"`
+
+	// For the "connected" hello
+	MinVersion  string   `json:"minVersion,omitempty"`  // Oldest agent version the server supports
+	Protocol    int      `json:"protocol,omitempty"`    // Protocol version for this connection (default 1, see protocol.go)
+	Features    []string `json:"features,omitempty"`    // Optional protocol features the server accepts (protocol 2, see features.go)
+	ContentType string   `json:"contentType,omitempty"` // Wire format for the rest of the connection (protocol 2, see serializer.go)
+
+	// For migrateEndpoint
+	APIURL       string    `json:"apiUrl,omitempty"`
+	DashboardURL string    `json:"dashboardUrl,omitempty"`
+	EffectiveAt  time.Time `json:"effectiveAt,omitzero"` // When to switch (default: now)
+
+	// For notice messages
+	Title    string `json:"title,omitempty"`
+	Body     string `json:"body,omitempty"`
+	Severity string `json:"severity,omitempty"` // info, warning or critical
+	URL      string `json:"url,omitempty"`      // Opened when the notification is clicked
+
+	// For setPeers
+	Peers []peers.Peer `json:"peers,omitempty"`
+
+	// For chaos (only with --chaos): e.g. "drop=30s,buffers", or "off"
+	Faults string `json:"faults,omitempty"`
+
+	// For batchAck: the metrics message's batchId and when the server got it
+	BatchID    uint64    `json:"batchId,omitempty"`
+	ReceivedAt time.Time `json:"receivedAt,omitzero"`
 }
 
 // AgentMessage wraps messages sent from agent to server
 type AgentMessage struct {
-	Type    string              `json:"type"` // "metrics", "heartbeat", "status"
+	Type    string              `json:"type"`              // "metrics", "heartbeat", "status"
+	BatchID uint64              `json:"batchId,omitempty"` // Metrics: echoed back in the server's batchAck
+	SentAt  time.Time           `json:"sentAt,omitzero"`   // Metrics: when the batch was written
 	Samples []*metrics.SampleV1 `json:"samples,omitempty"`
 }
 
+// compactMessage is a metrics message with compact samples, for servers
+// that accept the compactSamples feature
+type compactMessage struct {
+	Type    string            `json:"type"` // always "metrics"
+	BatchID uint64            `json:"batchId"`
+	SentAt  time.Time         `json:"sentAt"`
+	Samples []json.RawMessage `json:"samples"`
+}
+
 // StatusMessage represents agent status information
 type StatusMessage struct {
 	Type      string    `json:"type"` // always "status"
 	Version   string    `json:"version"`
 	Uptime    int64     `json:"uptime"` // seconds
 	Timestamp time.Time `json:"timestamp"`
+
+	Buffered int          `json:"buffered"`        // Samples waiting to be sent
+	Dropped  uint64       `json:"dropped"`         // Samples dropped by backpressure
+	Memory   budget.Usage `json:"memory"`          // Memory budget usage and degradation
+	Spool    *spool.Stats `json:"spool,omitempty"` // On-disk spool size and discarded history
+
+	Traffic *state.TrafficUsage `json:"traffic,omitempty"` // Bytes on the wire today and this month
+
+	ReadOnly bool `json:"readOnly,omitempty"` // security.readOnly: remote actions are refused
+
+	Latency *BatchLatency `json:"latency,omitempty"` // Sample batch delays over recent batches
+
+	SiteID  string            `json:"siteId,omitempty"`  // Fleet site from agent.json
+	GroupID string            `json:"groupId,omitempty"` // Fleet group from agent.json
+	Tags    map[string]string `json:"tags,omitempty"`    // Host tags set in agent.json or over IPC
+}
+
+// AckMessage reports the outcome of a control message back to the server
+type AckMessage struct {
+	Type    string `json:"type"`    // "ack" (applied) or "nack" (rejected/failed)
+	ID      string `json:"id"`      // ID of the control message being acknowledged
+	Command string `json:"command"` // Type of the control message
+	Result  any    `json:"result,omitempty"`
+	Error   string `json:"error,omitempty"`
+	Code    string `json:"code,omitempty"` // Why a nack failed (protocol 2): invalid, unknownCommand, rateLimited, readOnly or failed
 }
@@ -0,0 +1,212 @@
+package ws
+
+import (
+	"encoding/json"
+	"sync"
+
+	"github.com/jcdorr003/windash-agent/internal/budget"
+	"go.uber.org/zap"
+)
+
+// Priority orders outbound messages; lower values are sent first
+type Priority int
+
+const (
+	PriorityControl Priority = iota // Acks and command results
+	PriorityAlert                   // Alerts and events
+	PriorityStatus                  // Status and capability reports
+	PriorityMetrics                 // Live samples (queued in BackpressureBuffer)
+	PriorityBulk                    // Backfill, inventory and other large payloads
+
+	numPriorities
+)
+
+// messageClass describes how a message type is queued
+type messageClass struct {
+	priority Priority
+	limit    int // Max queued messages of this type; oldest dropped beyond it
+}
+
+// messageClasses maps outbound message types to their queueing policy.
+// Types not listed here use defaultClass.
+var messageClasses = map[string]messageClass{
+	"ack":            {priority: PriorityControl, limit: 100},
+	"nack":           {priority: PriorityControl, limit: 100},
+	"alert":          {priority: PriorityAlert, limit: 200},
+	"event":          {priority: PriorityAlert, limit: 200},
+	"incident":       {priority: PriorityAlert, limit: 20},
+	"agentError":     {priority: PriorityAlert, limit: 20},
+	"status":         {priority: PriorityStatus, limit: 5},
+	"watch":          {priority: PriorityStatus, limit: 5},
+	"sessions":       {priority: PriorityStatus, limit: 5},
+	"users":          {priority: PriorityStatus, limit: 5},
+	"securityStatus": {priority: PriorityStatus, limit: 2},
+	"logons":         {priority: PriorityStatus, limit: 20},
+	"diskHealth":     {priority: PriorityStatus, limit: 5},
+	"printers":       {priority: PriorityStatus, limit: 5},
+	"handles":        {priority: PriorityStatus, limit: 5},
+	"peers":          {priority: PriorityStatus, limit: 5},
+	"netprobe":       {priority: PriorityStatus, limit: 5},
+	"checks":         {priority: PriorityStatus, limit: 20},
+	"selftest":       {priority: PriorityStatus, limit: 5},
+	"backfill":       {priority: PriorityBulk, limit: 20},
+	"report":         {priority: PriorityBulk, limit: 3},
+	"summary":        {priority: PriorityBulk, limit: 3},
+	"inventory":      {priority: PriorityBulk, limit: 4}, // Hardware and software
+	"fields":         {priority: PriorityBulk, limit: 1},
+}
+
+var defaultClass = messageClass{priority: PriorityStatus, limit: 50}
+
+// OutboundMessage is a typed message waiting to be written to the socket
+type OutboundMessage struct {
+	Type     string
+	Priority Priority
+	Payload  any // Marshaled to JSON as-is; must include its own "type" field
+
+	data []byte // Payload marshaled at push time
+}
+
+// Outbox is a priority queue of non-sample outbound messages with
+// per-type size limits. A single writer drains it.
+type Outbox struct {
+	logger *zap.SugaredLogger
+	budget *budget.Budget
+
+	mu      sync.Mutex
+	queues  [numPriorities][]*OutboundMessage
+	counts  map[string]int
+	dropped map[string]uint64
+	ready   chan struct{}
+}
+
+// NewOutbox creates an empty outbox charging queued bytes to mem (nil for unlimited)
+func NewOutbox(logger *zap.SugaredLogger, mem *budget.Budget) *Outbox {
+	return &Outbox{
+		logger:  logger,
+		budget:  mem,
+		counts:  make(map[string]int),
+		dropped: make(map[string]uint64),
+		ready:   make(chan struct{}, 1),
+	}
+}
+
+// Push queues a message, dropping the oldest of the same type if its limit is reached
+func (o *Outbox) Push(msgType string, payload any) {
+	class, ok := messageClasses[msgType]
+	if !ok {
+		class = defaultClass
+	}
+
+	// Marshal up front so the queued size is known and bad payloads are
+	// rejected here rather than in the writer
+	data, err := json.Marshal(payload)
+	if err != nil {
+		o.logger.Warn("Failed to marshal outbound message", "type", msgType, "error", err)
+		return
+	}
+
+	o.mu.Lock()
+	if o.counts[msgType] >= class.limit {
+		o.dropOldestLocked(msgType, class.priority)
+	}
+	o.queues[class.priority] = append(o.queues[class.priority], &OutboundMessage{
+		Type:     msgType,
+		Priority: class.priority,
+		Payload:  payload,
+		data:     data,
+	})
+	o.counts[msgType]++
+	o.budget.Add(int64(len(data)))
+	o.mu.Unlock()
+
+	select {
+	case o.ready <- struct{}{}:
+	default:
+	}
+}
+
+// TryPop removes the highest-priority message with priority below limit,
+// returning nil if none is queued
+func (o *Outbox) TryPop(limit Priority) *OutboundMessage {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	for p := Priority(0); p < limit && p < numPriorities; p++ {
+		if len(o.queues[p]) == 0 {
+			continue
+		}
+		msg := o.queues[p][0]
+		o.queues[p][0] = nil
+		o.queues[p] = o.queues[p][1:]
+		o.counts[msg.Type]--
+		o.budget.Add(-int64(len(msg.data)))
+		return msg
+	}
+	return nil
+}
+
+// Requeue puts a popped message back at the front of its queue, e.g. after
+// writing it failed, so it goes out first on the next connection
+func (o *Outbox) Requeue(msg *OutboundMessage) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	o.queues[msg.Priority] = append([]*OutboundMessage{msg}, o.queues[msg.Priority]...)
+	o.counts[msg.Type]++
+	o.budget.Add(int64(len(msg.data)))
+}
+
+// Ready is signaled whenever a message is pushed
+func (o *Outbox) Ready() <-chan struct{} {
+	return o.ready
+}
+
+// Len returns the number of queued messages
+func (o *Outbox) Len() int {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	n := 0
+	for _, q := range o.queues {
+		n += len(q)
+	}
+	return n
+}
+
+// Queued returns the number of queued messages of msgType
+func (o *Outbox) Queued(msgType string) int {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return o.counts[msgType]
+}
+
+// DroppedCounts returns the number of dropped messages per type
+func (o *Outbox) DroppedCounts() map[string]uint64 {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	out := make(map[string]uint64, len(o.dropped))
+	for t, n := range o.dropped {
+		out[t] = n
+	}
+	return out
+}
+
+// dropOldestLocked removes the oldest queued message of msgType
+func (o *Outbox) dropOldestLocked(msgType string, priority Priority) {
+	q := o.queues[priority]
+	for i, msg := range q {
+		if msg.Type != msgType {
+			continue
+		}
+		o.queues[priority] = append(q[:i], q[i+1:]...)
+		o.counts[msgType]--
+		o.budget.Add(-int64(len(msg.data)))
+		o.dropped[msgType]++
+		if n := o.dropped[msgType]; n%10 == 1 {
+			o.logger.Warn("⚠️  Outbox full, dropping oldest message", "type", msgType, "totalDropped", n)
+		}
+		return
+	}
+}
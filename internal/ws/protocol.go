@@ -0,0 +1,98 @@
+package ws
+
+import (
+	"errors"
+	"strconv"
+)
+
+const (
+	// protocolHeader offers the newest protocol version the agent speaks
+	// on the handshake; the server picks the version for the connection
+	// (no newer than offered) and names it in the hello's protocol field
+	protocolHeader = "X-WinDash-Protocol"
+
+	// protocolLatest is the newest protocol version this agent speaks
+	protocolLatest = 2
+
+	// protocolLegacy is assumed until the hello names a version, and for
+	// servers from before versioning whose hello never does
+	protocolLegacy = 1
+)
+
+// protocol holds the behavior that differs between protocol versions.
+// Code checks these switches, never the version number, so a version is
+// added by adding a row to protocols.
+type protocol struct {
+	version  int
+	features bool // Optional features (features.go) can be negotiated
+	ackCodes bool // Nacks carry a machine-readable code
+}
+
+// protocols lists every version this agent speaks
+var protocols = map[int]protocol{
+	1: {version: 1},
+	2: {version: 2, features: true, ackCodes: true},
+}
+
+// Nack codes (protocol 2 and later)
+const (
+	nackInvalid        = "invalid"        // The message could not be decoded
+	nackUnknownCommand = "unknownCommand" // This agent version has no such command
+	nackRateLimited    = "rateLimited"    // Over the command's limit, retry later
+	nackReadOnly       = "readOnly"       // Refused by security.readOnly
+	nackFailed         = "failed"         // The command was understood but failed
+)
+
+var (
+	errInvalidMessage = errors.New("strict decoding")
+	errUnknownCommand = errors.New("unknown command")
+	errRateLimited    = errors.New("rate limited")
+	errReadOnly       = errors.New("read-only mode")
+)
+
+// nackCode classifies a command error for the ack's code field
+func nackCode(err error) string {
+	switch {
+	case errors.Is(err, errInvalidMessage):
+		return nackInvalid
+	case errors.Is(err, errUnknownCommand):
+		return nackUnknownCommand
+	case errors.Is(err, errRateLimited):
+		return nackRateLimited
+	case errors.Is(err, errReadOnly):
+		return nackReadOnly
+	default:
+		return nackFailed
+	}
+}
+
+// offeredProtocol is the protocol header value
+func offeredProtocol() string {
+	return strconv.Itoa(protocolLatest)
+}
+
+// protocol returns the protocol in use on the current connection
+func (c *Client) protocol() protocol {
+	if p := c.proto.Load(); p != nil {
+		return *p
+	}
+	return protocols[protocolLegacy]
+}
+
+// setProtocol switches to the version the server chose in its hello. A
+// hello without one comes from a server older than versioning; a version
+// this agent doesn't speak breaks the server's side of the handshake, so
+// the legacy behavior is kept.
+func (c *Client) setProtocol(version int) {
+	if version == 0 {
+		version = protocolLegacy
+	}
+	p, ok := protocols[version]
+	if !ok {
+		c.logger.Warn("Server chose a protocol version this agent doesn't speak, using the legacy one",
+			"protocol", version, "offered", protocolLatest)
+		p = protocols[protocolLegacy]
+	}
+	c.proto.Store(&p)
+	c.logger.Debug("Protocol negotiated", "protocol", p.version)
+}
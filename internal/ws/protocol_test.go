@@ -0,0 +1,236 @@
+package ws
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/jcdorr003/windash-agent/internal/config"
+	"github.com/jcdorr003/windash-agent/internal/metrics"
+	"github.com/jcdorr003/windash-agent/internal/spool"
+	"go.uber.org/zap"
+)
+
+// testController records the commands a client applies
+type testController struct {
+	interval  time.Duration
+	paused    bool
+	trimCores bool
+}
+
+func (t *testController) SetInterval(interval time.Duration) error {
+	t.interval = interval
+	return nil
+}
+func (t *testController) Pause()  { t.paused = true }
+func (t *testController) Resume() { t.paused = false }
+
+func (t *testController) SetPerCoreTrimming(accepted bool) { t.trimCores = accepted }
+
+// newTestClient returns an unconnected client for apiURL
+func newTestClient(t *testing.T, apiURL string) (*Client, *testController) {
+	t.Helper()
+	ctrl := &testController{}
+	cfg := &config.Config{APIURL: apiURL, MemoryBudgetMB: 32, AgentVersion: "1.0.0"}
+	return NewClient(cfg, "token", "host-1", ctrl, zap.NewNop().Sugar()), ctrl
+}
+
+// queuedAcks pops every queued message and returns the acks and nacks
+func queuedAcks(c *Client) []AckMessage {
+	var acks []AckMessage
+	for msg := c.outbox.TryPop(numPriorities); msg != nil; msg = c.outbox.TryPop(numPriorities) {
+		if ack, ok := msg.Payload.(AckMessage); ok {
+			acks = append(acks, ack)
+		}
+	}
+	return acks
+}
+
+func TestProtocolNegotiation(t *testing.T) {
+	tests := []struct {
+		name      string
+		hello     string
+		version   int
+		compact   bool
+		trimCores bool
+		nackCode  string
+	}{
+		{"no version", `{"type":"connected","features":["compactSamples","trimmedCores"]}`, 1, false, false, ""},
+		{"protocol 1", `{"type":"connected","protocol":1,"features":["compactSamples","trimmedCores"]}`, 1, false, false, ""},
+		{"protocol 2", `{"type":"connected","protocol":2,"features":["compactSamples","trimmedCores"]}`, 2, true, true, nackUnknownCommand},
+		{"protocol 2, compact only", `{"type":"connected","protocol":2,"features":["compactSamples"]}`, 2, true, false, nackUnknownCommand},
+		{"protocol 2 without features", `{"type":"connected","protocol":2}`, 2, false, false, nackUnknownCommand},
+		{"unknown version", `{"type":"connected","protocol":99,"features":["compactSamples","trimmedCores"]}`, 1, false, false, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, ctrl := newTestClient(t, "ws://127.0.0.1:1/agent")
+			c.handleRaw([]byte(tt.hello))
+			if got := c.protocol().version; got != tt.version {
+				t.Errorf("protocol = %d, want %d", got, tt.version)
+			}
+			if got := c.compact.Load(); got != tt.compact {
+				t.Errorf("compact samples = %v, want %v", got, tt.compact)
+			}
+			if ctrl.trimCores != tt.trimCores {
+				t.Errorf("per-core trimming = %v, want %v", ctrl.trimCores, tt.trimCores)
+			}
+
+			c.handleRaw([]byte(`{"type":"selfDestruct","id":"cmd-1"}`))
+			acks := queuedAcks(c)
+			if len(acks) != 1 || acks[0].Type != "nack" || acks[0].ID != "cmd-1" {
+				t.Fatalf("replies = %+v, want one nack for cmd-1", acks)
+			}
+			if acks[0].Code != tt.nackCode {
+				t.Errorf("nack code = %q, want %q", acks[0].Code, tt.nackCode)
+			}
+		})
+	}
+}
+
+func TestNackCode(t *testing.T) {
+	tests := []struct {
+		err  error
+		want string
+	}{
+		{errInvalidMessage, nackInvalid},
+		{errUnknownCommand, nackUnknownCommand},
+		{errRateLimited, nackRateLimited},
+		{errReadOnly, nackReadOnly},
+		{context.DeadlineExceeded, nackFailed},
+	}
+	for _, tt := range tests {
+		if got := nackCode(tt.err); got != tt.want {
+			t.Errorf("nackCode(%v) = %q, want %q", tt.err, got, tt.want)
+		}
+	}
+}
+
+func TestHandshakeHeaders(t *testing.T) {
+	headers := make(chan http.Header, 1)
+	upgrader := websocket.Upgrader{Subprotocols: []string{"windash.cbor"}}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		headers <- r.Header.Clone()
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		conn.Close()
+	}))
+	defer srv.Close()
+
+	c, _ := newTestClient(t, "ws"+strings.TrimPrefix(srv.URL, "http")+"/agent")
+	if err := c.connect(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	defer c.conn.Close()
+
+	h := <-headers
+	if got := h.Get(protocolHeader); got != offeredProtocol() {
+		t.Errorf("%s = %q, want %q", protocolHeader, got, offeredProtocol())
+	}
+	if got, want := h.Get(featuresHeader), "compactSamples,trimmedCores"; got != want {
+		t.Errorf("%s = %q, want %q", featuresHeader, got, want)
+	}
+	if got := c.serializer().ContentType(); got != ContentTypeCBOR {
+		t.Errorf("serializer after the server picked windash.cbor = %s", got)
+	}
+	if got := c.protocol().version; got != protocolLegacy {
+		t.Errorf("protocol before the hello = %d, want legacy", got)
+	}
+}
+
+func TestFlushRequeuesFailedWrites(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var upgrader websocket.Upgrader
+		if conn, err := upgrader.Upgrade(w, r, nil); err == nil {
+			conn.Close()
+		}
+	}))
+	defer srv.Close()
+
+	c, _ := newTestClient(t, "ws"+strings.TrimPrefix(srv.URL, "http")+"/agent")
+	if err := c.connect(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	c.conn.Close() // Every write fails from here on
+
+	c.Send("alert", map[string]string{"type": "alert", "id": "first"})
+	c.Send("alert", map[string]string{"type": "alert", "id": "second"})
+	if err := c.flush(); err == nil {
+		t.Fatal("flush on a closed connection succeeded")
+	}
+	if got := c.outbox.Queued("alert"); got != 2 {
+		t.Fatalf("queued alerts after a failed write = %d, want 2", got)
+	}
+	if msg := c.outbox.TryPop(numPriorities); msg.Payload.(map[string]string)["id"] != "first" {
+		t.Errorf("requeued message went behind the next one")
+	}
+	c.outbox.TryPop(numPriorities)
+
+	c.buffer.Push(testSample())
+	c.buffer.Push(testSample())
+	if err := c.flush(); err == nil {
+		t.Fatal("flush on a closed connection succeeded")
+	}
+	if got := c.buffer.Len(); got != 2 {
+		t.Errorf("buffered samples after a failed write = %d, want 2", got)
+	}
+
+	// With a spool the failed batch goes to disk instead
+	sp := &testSpool{}
+	c.SetSpool(sp)
+	if err := c.flush(); err == nil {
+		t.Fatal("flush on a closed connection succeeded")
+	}
+	if c.buffer.Len() != 0 || len(sp.samples) != 2 {
+		t.Errorf("after a failed write with a spool: %d buffered, %d spooled, want 0 and 2", c.buffer.Len(), len(sp.samples))
+	}
+}
+
+// testSpool collects spilled samples
+type testSpool struct {
+	samples []*metrics.SampleV1
+}
+
+func (t *testSpool) Add(sample *metrics.SampleV1) { t.samples = append(t.samples, sample) }
+func (t *testSpool) Stats() spool.Stats           { return spool.Stats{Segments: len(t.samples)} }
+
+func TestHandshakeQueryKeepsHostID(t *testing.T) {
+	queries := make(chan url.Values, 1)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		queries <- r.URL.Query()
+		var upgrader websocket.Upgrader
+		if conn, err := upgrader.Upgrade(w, r, nil); err == nil {
+			conn.Close()
+		}
+	}))
+	defer srv.Close()
+
+	cfg := &config.Config{
+		APIURL:         "ws" + strings.TrimPrefix(srv.URL, "http") + "/agent",
+		MemoryBudgetMB: 32,
+		Connection: config.ConnectionConfig{Query: []config.QueryParam{
+			{Name: "tenantId", Value: "tenant-1"},
+			{Name: "hostId", Value: "someone-else"},
+		}},
+	}
+	c := NewClient(cfg, "token", "host-1", &testController{}, zap.NewNop().Sugar())
+	if err := c.connect(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	defer c.conn.Close()
+
+	q := <-queries
+	if got := q["hostId"]; len(got) != 1 || got[0] != "host-1" {
+		t.Errorf("hostId = %v, want only the agent's own", got)
+	}
+	if got := q.Get("tenantId"); got != "tenant-1" {
+		t.Errorf("tenantId = %q, want the configured value", got)
+	}
+}
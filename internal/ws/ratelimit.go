@@ -0,0 +1,102 @@
+package ws
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// commandLimit allows at most max commands of one type per window
+type commandLimit struct {
+	max    int
+	window time.Duration
+}
+
+// defaultCommandLimits protect the agent from a buggy or compromised backend
+// flooding it with commands. Types not listed share defaultCommandLimit.
+var defaultCommandLimits = map[string]commandLimit{
+	"setRate":         {max: 10, window: time.Minute},
+	"pause":           {max: 10, window: time.Minute},
+	"resume":          {max: 10, window: time.Minute},
+	"notice":          {max: 10, window: time.Hour},
+	"migrateEndpoint": {max: 2, window: time.Hour},
+	"setPeers":        {max: 10, window: time.Hour},
+	"chaos":           {max: 10, window: time.Minute},
+}
+
+var defaultCommandLimit = commandLimit{max: 30, window: time.Minute}
+
+// rateLimiter enforces per-type command limits over a sliding window
+type rateLimiter struct {
+	limits map[string]commandLimit // By lowercased type
+
+	mu   sync.Mutex
+	seen map[string][]time.Time // Accepted commands per type, oldest first
+}
+
+// newRateLimiter applies overrides ("setRate": "5/1m", or "*" for all
+// other types) on top of the defaults. Invalid overrides are returned as
+// errors and ignored.
+func newRateLimiter(overrides map[string]string) (*rateLimiter, []error) {
+	limits := make(map[string]commandLimit, len(defaultCommandLimits)+len(overrides))
+	for k, v := range defaultCommandLimits {
+		limits[strings.ToLower(k)] = v
+	}
+	limits["*"] = defaultCommandLimit
+	var errs []error
+	for k, v := range overrides {
+		limit, err := parseCommandLimit(v)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("controlLimits.%s: %w", k, err))
+			continue
+		}
+		limits[strings.ToLower(k)] = limit // viper lowercases map keys, so types match case-insensitively
+	}
+	return &rateLimiter{limits: limits, seen: make(map[string][]time.Time)}, errs
+}
+
+// parseCommandLimit parses "N/duration", e.g. "1/10m"
+func parseCommandLimit(s string) (commandLimit, error) {
+	count, window, found := strings.Cut(strings.TrimSpace(s), "/")
+	if !found {
+		return commandLimit{}, fmt.Errorf("%q is not N/duration (e.g. 1/10m)", s)
+	}
+	n, err := strconv.Atoi(count)
+	if err != nil || n < 1 {
+		return commandLimit{}, fmt.Errorf("%q: count must be a positive number", s)
+	}
+	d, err := time.ParseDuration(window)
+	if err != nil || d <= 0 {
+		return commandLimit{}, fmt.Errorf("%q: invalid duration", s)
+	}
+	return commandLimit{max: n, window: d}, nil
+}
+
+// allow records a command of type msgType at now if it is within its
+// limit, and otherwise returns an error saying when to retry
+func (r *rateLimiter) allow(msgType string, now time.Time) error {
+	key := strings.ToLower(msgType)
+	limit, ok := r.limits[key]
+	if !ok {
+		key = "*" // One bucket, so unknown types can't grow the map
+		limit = r.limits[key]
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	seen := r.seen[key]
+	cutoff := now.Add(-limit.window)
+	for len(seen) > 0 && !seen[0].After(cutoff) {
+		seen = seen[1:]
+	}
+	if len(seen) >= limit.max {
+		retry := seen[0].Add(limit.window).Sub(now).Round(time.Second)
+		r.seen[key] = seen
+		return fmt.Errorf("%w: at most %d %s per %s, retry in %s", errRateLimited, limit.max, msgType, limit.window, retry)
+	}
+	r.seen[key] = append(seen, now)
+	return nil
+}
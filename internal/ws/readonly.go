@@ -0,0 +1,39 @@
+package ws
+
+import "fmt"
+
+// Remote action categories. With security.readOnly every command in one of
+// them is nacked before it is applied, whatever else is configured.
+const (
+	actionConfig  = "config"  // Changes agent settings or behavior
+	actionCommand = "command" // Runs commands or scripts
+	actionPower   = "power"   // Reboots, shuts down or suspends the machine
+	actionProcess = "process" // Starts or stops processes and services
+	actionLogs    = "logs"    // Uploads logs or files from the machine
+)
+
+// commandActions maps each command to its action category; "" marks a
+// command that changes nothing. Commands missing here are refused in
+// read-only mode, so a new command is blocked until it is classified.
+var commandActions = map[string]string{
+	"setRate":         actionConfig,
+	"pause":           actionConfig,
+	"resume":          actionConfig,
+	"migrateEndpoint": actionConfig,
+	"setPeers":        actionConfig,
+	"chaos":           actionConfig,
+	"notice":          "",
+}
+
+// checkReadOnly returns an error if msgType is a remote action, which
+// read-only mode refuses
+func checkReadOnly(msgType string) error {
+	action, ok := commandActions[msgType]
+	switch {
+	case !ok:
+		return fmt.Errorf("%w: %q is not allowed", errReadOnly, msgType)
+	case action != "":
+		return fmt.Errorf("%w: %s actions are disabled", errReadOnly, action)
+	}
+	return nil
+}
@@ -0,0 +1,93 @@
+package ws
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+)
+
+// RecordedMessage is one control message captured from the server.
+// The raw text is kept verbatim so malformed traffic replays faithfully.
+type RecordedMessage struct {
+	TS      time.Time `json:"ts"`
+	Message string    `json:"message"`
+}
+
+// Recorder appends received control messages to a JSONL file
+type Recorder struct {
+	mu  sync.Mutex
+	f   *os.File
+	enc *json.Encoder
+}
+
+// NewRecorder opens (or creates) a recording file in append mode
+func NewRecorder(path string) (*Recorder, error) {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open recording file: %w", err)
+	}
+	return &Recorder{f: f, enc: json.NewEncoder(f)}, nil
+}
+
+// Record appends a single raw control message
+func (r *Recorder) Record(raw []byte) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.enc.Encode(RecordedMessage{TS: time.Now(), Message: string(raw)})
+}
+
+// Close closes the recording file
+func (r *Recorder) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.f.Close()
+}
+
+// SetRecorder captures every control message received from now on
+func (c *Client) SetRecorder(r *Recorder) {
+	c.recorder = r
+}
+
+// Replay feeds a recording through the client's control handler and returns
+// the acks and other messages it queued, in send order. Used to
+// regression-test protocol handling against traffic captured from real
+// backends.
+func Replay(ctx context.Context, c *Client, path string) ([]*OutboundMessage, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open recording: %w", err)
+	}
+	defer f.Close()
+
+	// Commands must not touch agent.json or the live connection
+	c.dryRun = true
+
+	var out []*OutboundMessage
+
+	// A decoder rather than a line scanner: an entry holds the message
+	// escaped, so it can be several times longer than maxMessageSize
+	dec := json.NewDecoder(bufio.NewReader(f))
+	for entry := 1; ; entry++ {
+		if err := ctx.Err(); err != nil {
+			return out, err
+		}
+		var rec RecordedMessage
+		if err := dec.Decode(&rec); err == io.EOF {
+			return out, nil
+		} else if err != nil {
+			return out, fmt.Errorf("entry %d: invalid recording entry: %w", entry, err)
+		}
+
+		c.replayTS = rec.TS // Rate limits follow the recorded timeline
+		c.handleRaw([]byte(rec.Message))
+
+		for msg := c.outbox.TryPop(numPriorities); msg != nil; msg = c.outbox.TryPop(numPriorities) {
+			out = append(out, msg)
+		}
+	}
+}
@@ -0,0 +1,97 @@
+package ws
+
+import (
+	"context"
+	"encoding/json"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestReplayRecording(t *testing.T) {
+	c, ctrl := newTestClient(t, "ws://127.0.0.1:1/agent")
+	replies, err := Replay(context.Background(), c, filepath.Join("testdata", "control.jsonl"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []AckMessage{
+		{Type: "ack", ID: "rate-1", Command: "setRate", Result: map[string]int{"intervalMs": 5000}},
+		{Type: "ack", ID: "pause-1", Command: "pause"},
+		{Type: "ack", ID: "resume-1", Command: "resume"},
+		{Type: "nack", ID: "unknown-1", Command: "selfDestruct", Error: `unknown command "selfDestruct"`, Code: nackUnknownCommand},
+		{Type: "ack", ID: "pause-2", Command: "pause"},
+	}
+	var got []AckMessage
+	for _, msg := range replies {
+		ack, ok := msg.Payload.(AckMessage)
+		if !ok {
+			t.Fatalf("unexpected %s reply: %+v", msg.Type, msg.Payload)
+		}
+		got = append(got, ack)
+	}
+	if !reflect.DeepEqual(got, want) {
+		gotJSON, _ := json.Marshal(got)
+		wantJSON, _ := json.Marshal(want)
+		t.Errorf("replies\n got %s\nwant %s", gotJSON, wantJSON)
+	}
+	if ctrl.interval != 5*time.Second || !ctrl.paused {
+		t.Errorf("controller interval %s, paused %v; want 5s and paused", ctrl.interval, ctrl.paused)
+	}
+	if c.protocol().version != 2 || !c.compact.Load() {
+		t.Errorf("the recorded hello was not applied")
+	}
+}
+
+func TestReplayLargeMessage(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "large.jsonl")
+	r, err := NewRecorder(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Quotes are escaped in the frame and escaped again in the recording,
+	// so the entry is about twice as long as the frame
+	padding := strings.Repeat(`"`, (maxMessageSize-100)/2)
+	msg, err := json.Marshal(map[string]string{"type": "selfDestruct", "id": "big-1", "padding": padding})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(msg) > maxMessageSize {
+		t.Fatalf("test message is %d bytes, over the %d byte frame limit", len(msg), maxMessageSize)
+	}
+	if err := r.Record(msg); err != nil {
+		t.Fatal(err)
+	}
+	r.Close()
+
+	c, _ := newTestClient(t, "ws://127.0.0.1:1/agent")
+	replies, err := Replay(context.Background(), c, path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(replies) != 1 || replies[0].Payload.(AckMessage).ID != "big-1" {
+		t.Errorf("replies = %+v, want the nack for big-1", replies)
+	}
+}
+
+func TestReplayInvalidEntry(t *testing.T) {
+	c, _ := newTestClient(t, "ws://127.0.0.1:1/agent")
+	path := filepath.Join(t.TempDir(), "broken.jsonl")
+	r, err := NewRecorder(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.Record([]byte(`{"type":"pause","id":"p-1"}`))
+	r.f.WriteString("{truncated\n")
+	r.Close()
+
+	replies, err := Replay(context.Background(), c, path)
+	if err == nil || !strings.Contains(err.Error(), "entry 2") {
+		t.Errorf("error = %v, want one naming entry 2", err)
+	}
+	if len(replies) != 1 {
+		t.Errorf("got %d replies before the bad entry, want 1", len(replies))
+	}
+}
@@ -0,0 +1,300 @@
+package ws
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math"
+	"slices"
+	"strconv"
+	"sync"
+
+	"github.com/gorilla/websocket"
+)
+
+// Content types of the built-in serializers
+const (
+	ContentTypeJSON    = "application/json"
+	ContentTypeCBOR    = "application/cbor"
+	ContentTypeMsgPack = "application/msgpack"
+)
+
+// subprotocolPrefix starts the WebSocket subprotocol offering each
+// serializer, e.g. windash.cbor
+const subprotocolPrefix = "windash."
+
+// maxDecodeDepth bounds nesting in binary frames from the server
+const maxDecodeDepth = 64
+
+// Serializer is one wire format for frames. Every message is built as JSON
+// first, so a format only converts to and from JSON and the JSON field
+// names stay the schema in all of them. The client loop never sees
+// anything but JSON.
+type Serializer interface {
+	ContentType() string // Registry key and the hello's contentType, e.g. application/cbor
+	Name() string        // Short name for the subprotocol (windash.<name>), e.g. cbor
+	FrameType() int      // websocket.TextMessage or websocket.BinaryMessage
+
+	// Encode converts a JSON message to this format
+	Encode(jsonData []byte) ([]byte, error)
+
+	// Decode converts a frame in this format back to JSON
+	Decode(data []byte) ([]byte, error)
+}
+
+var (
+	serializersMu sync.RWMutex
+	serializers   = make(map[string]Serializer) // By content type
+	serializerSeq []string                      // Content types in registration order (offer order)
+)
+
+func init() {
+	RegisterSerializer(jsonSerializer{})
+	RegisterSerializer(treeSerializer{contentType: ContentTypeCBOR, name: "cbor", encode: encodeCBOR, decode: decodeCBOR})
+	RegisterSerializer(treeSerializer{contentType: ContentTypeMsgPack, name: "msgpack", encode: encodeMsgPack, decode: decodeMsgPack})
+}
+
+// RegisterSerializer adds a wire format. The agent offers every registered
+// format in the handshake; registering a content type twice panics.
+func RegisterSerializer(s Serializer) {
+	serializersMu.Lock()
+	defer serializersMu.Unlock()
+	if _, dup := serializers[s.ContentType()]; dup {
+		panic("ws: serializer registered twice for " + s.ContentType())
+	}
+	serializers[s.ContentType()] = s
+	serializerSeq = append(serializerSeq, s.ContentType())
+}
+
+// SerializerFor returns the serializer registered for contentType
+func SerializerFor(contentType string) (Serializer, bool) {
+	serializersMu.RLock()
+	defer serializersMu.RUnlock()
+	s, ok := serializers[contentType]
+	return s, ok
+}
+
+// Serializers lists the registered content types in registration order
+func Serializers() []string {
+	serializersMu.RLock()
+	defer serializersMu.RUnlock()
+	return slices.Clone(serializerSeq)
+}
+
+// offeredSubprotocols is the Sec-WebSocket-Protocol list of the handshake.
+// A server that picks one frames its messages in that format from the
+// start; one that picks none gets JSON.
+func offeredSubprotocols() []string {
+	serializersMu.RLock()
+	defer serializersMu.RUnlock()
+	offered := make([]string, 0, len(serializerSeq))
+	for _, contentType := range serializerSeq {
+		offered = append(offered, subprotocolPrefix+serializers[contentType].Name())
+	}
+	return offered
+}
+
+// serializerForSubprotocol returns the serializer the server picked in the
+// handshake, JSON if it picked none
+func serializerForSubprotocol(subprotocol string) Serializer {
+	serializersMu.RLock()
+	defer serializersMu.RUnlock()
+	for _, s := range serializers {
+		if subprotocolPrefix+s.Name() == subprotocol {
+			return s
+		}
+	}
+	return jsonSerializer{}
+}
+
+// serializerRef boxes the current serializer for atomic.Pointer
+type serializerRef struct{ Serializer }
+
+// serializer returns the wire format of the current connection
+func (c *Client) serializer() Serializer {
+	if s := c.wire.Load(); s != nil {
+		return s.Serializer
+	}
+	return jsonSerializer{}
+}
+
+// setContentType switches to the format the server named in its hello.
+// Protocols without features, and formats this agent doesn't have, keep
+// the current one.
+func (c *Client) setContentType(contentType string) {
+	if contentType == "" || !c.protocol().features {
+		return
+	}
+	s, ok := SerializerFor(contentType)
+	if !ok {
+		c.logger.Warn("Server chose a content type this agent doesn't have, keeping the current one",
+			"contentType", contentType, "current", c.serializer().ContentType())
+		return
+	}
+	if c.wire.Swap(&serializerRef{s}).Serializer.ContentType() != contentType {
+		c.logger.Debug("Content type negotiated", "contentType", contentType)
+	}
+}
+
+// jsonSerializer sends JSON as is, in text frames
+type jsonSerializer struct{}
+
+func (jsonSerializer) ContentType() string                { return ContentTypeJSON }
+func (jsonSerializer) Name() string                       { return "json" }
+func (jsonSerializer) FrameType() int                     { return websocket.TextMessage }
+func (jsonSerializer) Encode(data []byte) ([]byte, error) { return data, nil }
+func (jsonSerializer) Decode(data []byte) ([]byte, error) { return data, nil }
+
+// treeSerializer is a binary format converted through the JSON data model
+// (null, bool, number, string, array, object)
+type treeSerializer struct {
+	contentType string
+	name        string
+	encode      func(buf *bytes.Buffer, v any) error
+	decode      func(d *treeDecoder) (any, error)
+}
+
+func (t treeSerializer) ContentType() string { return t.contentType }
+func (t treeSerializer) Name() string        { return t.name }
+func (t treeSerializer) FrameType() int      { return websocket.BinaryMessage }
+
+// Encode parses the JSON with numbers kept exact and writes the tree
+func (t treeSerializer) Encode(jsonData []byte) ([]byte, error) {
+	dec := json.NewDecoder(bytes.NewReader(jsonData))
+	dec.UseNumber()
+	var tree any
+	if err := dec.Decode(&tree); err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	buf.Grow(len(jsonData))
+	if err := t.encode(&buf, tree); err != nil {
+		return nil, fmt.Errorf("%s: %w", t.name, err)
+	}
+	return buf.Bytes(), nil
+}
+
+// Decode reads the tree and writes it as JSON
+func (t treeSerializer) Decode(data []byte) ([]byte, error) {
+	d := &treeDecoder{data: data}
+	tree, err := t.decode(d)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", t.name, err)
+	}
+	if len(d.data) != d.pos {
+		return nil, fmt.Errorf("%s: %d trailing bytes", t.name, len(d.data)-d.pos)
+	}
+	return json.Marshal(tree)
+}
+
+// jsonNumber splits a JSON number into the integer or float it holds
+func jsonNumber(n json.Number) (i int64, u uint64, f float64, kind byte, err error) {
+	if i, err := strconv.ParseInt(string(n), 10, 64); err == nil {
+		return i, 0, 0, 'i', nil
+	}
+	if u, err := strconv.ParseUint(string(n), 10, 64); err == nil {
+		return 0, u, 0, 'u', nil
+	}
+	f, err = n.Float64()
+	return 0, 0, f, 'f', err
+}
+
+// sortedKeys returns an object's keys in order, so encoding is repeatable
+func sortedKeys(m map[string]any) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	slices.Sort(keys)
+	return keys
+}
+
+// treeDecoder reads a binary frame
+type treeDecoder struct {
+	data  []byte
+	pos   int
+	depth int
+}
+
+// next returns the next n bytes
+func (d *treeDecoder) next(n uint64) ([]byte, error) {
+	if n > uint64(len(d.data)-d.pos) {
+		return nil, fmt.Errorf("truncated at byte %d", d.pos)
+	}
+	b := d.data[d.pos : d.pos+int(n)]
+	d.pos += int(n)
+	return b, nil
+}
+
+// uint reads a big-endian unsigned integer of n bytes
+func (d *treeDecoder) uint(n int) (uint64, error) {
+	b, err := d.next(uint64(n))
+	if err != nil {
+		return 0, err
+	}
+	var v uint64
+	for _, c := range b {
+		v = v<<8 | uint64(c)
+	}
+	return v, nil
+}
+
+// enter guards against nesting deeper than maxDecodeDepth
+func (d *treeDecoder) enter() error {
+	d.depth++
+	if d.depth > maxDecodeDepth {
+		return fmt.Errorf("nested deeper than %d", maxDecodeDepth)
+	}
+	return nil
+}
+
+// collection reads n values (a map if pairs is set) with item
+func (d *treeDecoder) collection(n uint64, pairs bool, item func(d *treeDecoder) (any, error)) (any, error) {
+	if err := d.enter(); err != nil {
+		return nil, err
+	}
+	defer func() { d.depth-- }()
+	// Every item takes at least one byte
+	if n > uint64(len(d.data)-d.pos) {
+		return nil, fmt.Errorf("truncated at byte %d", d.pos)
+	}
+	if !pairs {
+		list := make([]any, 0, n)
+		for range n {
+			v, err := item(d)
+			if err != nil {
+				return nil, err
+			}
+			list = append(list, v)
+		}
+		return list, nil
+	}
+	m := make(map[string]any, n)
+	for range n {
+		k, err := item(d)
+		if err != nil {
+			return nil, err
+		}
+		v, err := item(d)
+		if err != nil {
+			return nil, err
+		}
+		key, ok := k.(string)
+		if !ok {
+			key = fmt.Sprint(k)
+		}
+		m[key] = v
+	}
+	return m, nil
+}
+
+// binaryString turns a byte string into the base64 JSON uses for []byte
+func binaryString(b []byte) string {
+	return base64.StdEncoding.EncodeToString(b)
+}
+
+// shortFloat reports whether f survives a round trip through float32
+func shortFloat(f float64) bool {
+	return float64(float32(f)) == f || math.IsInf(f, 0)
+}
@@ -0,0 +1,237 @@
+package ws
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jcdorr003/windash-agent/internal/metrics"
+)
+
+// testSample is a sample with values that exercise every integer and float
+// width
+func testSample() *metrics.SampleV1 {
+	s := metrics.NewSample("host-1", time.Date(2026, 10, 15, 12, 0, 0, 123456789, time.UTC))
+	s.CPU.Total = 12.5
+	s.CPU.PerCore = []float64{1.25, 99.9, 0}
+	s.Mem = metrics.MemStats{Used: 8 << 30, Total: 16 << 30}
+	s.Disks = []metrics.DiskUsage{{Name: "C:", Used: 1 << 40, Total: 2 << 40}}
+	s.Net = metrics.NetStats{TxBps: 1234, RxBps: math.MaxUint64}
+	s.UptimeSec = 86400
+	s.ProcCount = 312
+	s.Health = 97
+	return s
+}
+
+// roundTripMessages are the JSON messages every serializer must carry
+// unchanged
+func roundTripMessages(t *testing.T) map[string][]byte {
+	t.Helper()
+	ts := time.Date(2026, 10, 15, 12, 0, 1, 0, time.UTC)
+	sample, err := json.Marshal(AgentMessage{Type: "metrics", BatchID: 42, SentAt: ts, Samples: []*metrics.SampleV1{testSample(), testSample()}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	compact, err := marshalCompact(43, ts, []*metrics.SampleV1{testSample()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	hello, err := json.Marshal(ControlMessage{Type: "connected", MinVersion: "1.2.0", Protocol: 2,
+		Features: []string{featureCompactSamples}, ContentType: ContentTypeCBOR})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Enough keys, items and characters to need the wide length heads
+	wide := map[string]any{
+		"string16": strings.Repeat("x", 300),
+		"string32": strings.Repeat("y", 70000),
+		"array32":  make([]int, 70000),
+	}
+	for i := range 20 {
+		wide[fmt.Sprintf("key%02d", i)] = i
+	}
+	wideJSON, err := json.Marshal(wide)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return map[string][]byte{
+		"sample":  sample,
+		"compact": compact,
+		"hello":   hello,
+		"nested":  []byte(`{"a":{"b":{"c":[1,{"d":null},[true,false]],"e":{}}},"f":[],"g":"héllo ✓"}`),
+		"integers": []byte(`{"zero":0,"fix":127,"u8":255,"u16":65535,"u32":4294967295,"neg":-1,"neg8":-33,"neg16":-129,` +
+			`"neg32":-40000,"neg64":-5000000000,"min":-9223372036854775808,"max":9223372036854775807,` +
+			`"overInt64":9223372036854775808,"maxUint64":18446744073709551615}`),
+		"floats": []byte(`{"f32":1.5,"f32neg":-0.25,"f32big":3.4028234663852886e38,"f64":0.1,"f64neg":-123.456,` +
+			`"tiny":5e-324,"huge":1.7976931348623157e308}`),
+		"wide":     wideJSON,
+		"topArray": []byte(`[1,"two",3.5,null]`),
+	}
+}
+
+// normalize decodes JSON with integers kept exact, so documents compare
+// equal regardless of how their numbers were formatted
+func normalize(t *testing.T, data []byte) any {
+	t.Helper()
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var v any
+	if err := dec.Decode(&v); err != nil {
+		t.Fatalf("invalid JSON %q: %v", data, err)
+	}
+	return normalizeValue(t, v)
+}
+
+func normalizeValue(t *testing.T, v any) any {
+	switch v := v.(type) {
+	case json.Number:
+		if _, err := strconv.ParseInt(string(v), 10, 64); err == nil {
+			return "int:" + string(v)
+		}
+		if _, err := strconv.ParseUint(string(v), 10, 64); err == nil {
+			return "int:" + string(v)
+		}
+		f, err := v.Float64()
+		if err != nil {
+			t.Fatalf("number %s: %v", v, err)
+		}
+		return f
+	case []any:
+		for i := range v {
+			v[i] = normalizeValue(t, v[i])
+		}
+	case map[string]any:
+		for k := range v {
+			v[k] = normalizeValue(t, v[k])
+		}
+	}
+	return v
+}
+
+func TestSerializerRoundTrip(t *testing.T) {
+	messages := roundTripMessages(t)
+	for _, contentType := range Serializers() {
+		s, _ := SerializerFor(contentType)
+		for name, msg := range messages {
+			t.Run(s.Name()+"/"+name, func(t *testing.T) {
+				frame, err := s.Encode(msg)
+				if err != nil {
+					t.Fatalf("Encode: %v", err)
+				}
+				back, err := s.Decode(frame)
+				if err != nil {
+					t.Fatalf("Decode: %v", err)
+				}
+				if want, got := normalize(t, msg), normalize(t, back); !reflect.DeepEqual(want, got) {
+					t.Errorf("round trip changed the message\nwant %s\ngot  %s", msg, back)
+				}
+			})
+		}
+	}
+}
+
+func TestSerializerKnownEncodings(t *testing.T) {
+	tests := []struct {
+		contentType string
+		json        string
+		want        []byte
+	}{
+		{ContentTypeCBOR, `{"a":1,"b":[2,3]}`, []byte{0xa2, 0x61, 'a', 0x01, 0x61, 'b', 0x82, 0x02, 0x03}},
+		{ContentTypeCBOR, `-1`, []byte{0x20}},
+		{ContentTypeCBOR, `1.5`, []byte{0xfa, 0x3f, 0xc0, 0x00, 0x00}},
+		{ContentTypeMsgPack, `{"a":1}`, []byte{0x81, 0xa1, 'a', 0x01}},
+		{ContentTypeMsgPack, `-1`, []byte{0xff}},
+		{ContentTypeMsgPack, `18446744073709551615`, []byte{0xcf, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff}},
+	}
+	for _, tt := range tests {
+		s, ok := SerializerFor(tt.contentType)
+		if !ok {
+			t.Fatalf("%s not registered", tt.contentType)
+		}
+		got, err := s.Encode([]byte(tt.json))
+		if err != nil {
+			t.Errorf("%s %s: %v", s.Name(), tt.json, err)
+			continue
+		}
+		if !bytes.Equal(got, tt.want) {
+			t.Errorf("%s %s = % x, want % x", s.Name(), tt.json, got, tt.want)
+		}
+	}
+}
+
+// binaryFormats are the registered binary serializers with the byte that
+// starts a one-item array in each
+var binaryFormats = []struct {
+	contentType string
+	oneItem     byte
+}{
+	{ContentTypeCBOR, 0x81},
+	{ContentTypeMsgPack, 0x91},
+}
+
+func TestSerializerTruncated(t *testing.T) {
+	msg := roundTripMessages(t)["sample"]
+	for _, f := range binaryFormats {
+		s, _ := SerializerFor(f.contentType)
+		frame, err := s.Encode(msg)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for n := range len(frame) {
+			if _, err := s.Decode(frame[:n]); err == nil {
+				t.Errorf("%s: decoding the first %d of %d bytes succeeded", s.Name(), n, len(frame))
+			}
+		}
+	}
+}
+
+func TestSerializerTrailingBytes(t *testing.T) {
+	for _, f := range binaryFormats {
+		s, _ := SerializerFor(f.contentType)
+		frame, err := s.Encode([]byte(`{"a":1}`))
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = s.Decode(append(frame, 0x00))
+		if err == nil || !strings.Contains(err.Error(), "trailing") {
+			t.Errorf("%s: Decode with a trailing byte = %v, want a trailing bytes error", s.Name(), err)
+		}
+	}
+}
+
+func TestSerializerMaxDepth(t *testing.T) {
+	nested := func(head byte, depth int) []byte {
+		return append(bytes.Repeat([]byte{head}, depth), 0x00)
+	}
+	for _, f := range binaryFormats {
+		s, _ := SerializerFor(f.contentType)
+		if _, err := s.Decode(nested(f.oneItem, maxDecodeDepth)); err != nil {
+			t.Errorf("%s: %d levels: %v", s.Name(), maxDecodeDepth, err)
+		}
+		_, err := s.Decode(nested(f.oneItem, maxDecodeDepth+1))
+		if err == nil || !strings.Contains(err.Error(), "nested deeper") {
+			t.Errorf("%s: %d levels = %v, want a depth error", s.Name(), maxDecodeDepth+1, err)
+		}
+	}
+}
+
+func TestOfferedSubprotocols(t *testing.T) {
+	want := []string{"windash.json", "windash.cbor", "windash.msgpack"}
+	if got := offeredSubprotocols(); !reflect.DeepEqual(got, want) {
+		t.Errorf("offeredSubprotocols() = %v, want %v", got, want)
+	}
+	if got := serializerForSubprotocol("windash.msgpack").ContentType(); got != ContentTypeMsgPack {
+		t.Errorf("windash.msgpack selects %s", got)
+	}
+	if got := serializerForSubprotocol("").ContentType(); got != ContentTypeJSON {
+		t.Errorf("no subprotocol selects %s, want JSON", got)
+	}
+}
@@ -0,0 +1,42 @@
+package ws
+
+import (
+	"context"
+	"net"
+)
+
+// countingConn reports every read and write on a connection to count
+type countingConn struct {
+	net.Conn
+	count func(sent, received int)
+}
+
+func (c *countingConn) Read(p []byte) (int, error) {
+	n, err := c.Conn.Read(p)
+	if n > 0 {
+		c.count(0, n)
+	}
+	return n, err
+}
+
+func (c *countingConn) Write(p []byte) (int, error) {
+	n, err := c.Conn.Write(p)
+	if n > 0 {
+		c.count(n, 0)
+	}
+	return n, err
+}
+
+// countingDialer dials TCP connections that report their traffic to count.
+// The dialer sits below TLS and compression, so the counts are what
+// actually crosses the network.
+func countingDialer(count func(sent, received int)) func(ctx context.Context, network, addr string) (net.Conn, error) {
+	var d net.Dialer
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		conn, err := d.DialContext(ctx, network, addr)
+		if err != nil {
+			return nil, err
+		}
+		return &countingConn{Conn: conn, count: count}, nil
+	}
+}
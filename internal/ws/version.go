@@ -0,0 +1,120 @@
+package ws
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// closeUpgradeRequired is the close code the server uses to drop an
+	// agent that is older than its minimum supported version
+	closeUpgradeRequired = 4426
+
+	// upgradeRetryDelay replaces reconnect backoff once the server has
+	// refused this version; retrying sooner cannot succeed
+	upgradeRetryDelay = time.Hour
+
+	// minVersionHeader carries the minimum version on a refused handshake
+	minVersionHeader = "X-WinDash-Min-Version"
+)
+
+// UpgradeRequiredError is returned when the server refuses this agent version
+type UpgradeRequiredError struct {
+	MinVersion string
+}
+
+func (e *UpgradeRequiredError) Error() string {
+	if e.MinVersion == "" {
+		return "server requires a newer agent version"
+	}
+	return fmt.Sprintf("server requires agent version %s or newer", e.MinVersion)
+}
+
+// UpgradeRequired reports whether the server has said this agent is too
+// old, and the minimum version it asked for
+func (c *Client) UpgradeRequired() (minVersion string, required bool) {
+	if p := c.upgrade.Load(); p != nil {
+		return *p, true
+	}
+	return "", false
+}
+
+// checkMinVersion compares the running version with the server's minimum
+// from the hello message and flags an upgrade if it is older
+func (c *Client) checkMinVersion(minVersion string) {
+	if minVersion == "" || !versionBelow(c.version, minVersion) {
+		c.upgrade.Store(nil)
+		return
+	}
+	c.requireUpgrade(minVersion)
+}
+
+// requireUpgrade records the upgrade-required state, telling the user the
+// first time each minimum version is seen. The agent has no updater, so
+// installing the new version is left to the user.
+func (c *Client) requireUpgrade(minVersion string) {
+	if prev := c.upgrade.Swap(&minVersion); prev != nil && *prev == minVersion {
+		return
+	}
+
+	c.logger.Error("⛔ This agent version is no longer supported by the server - please upgrade",
+		"version", c.version, "minVersion", minVersion)
+	if c.notifier != nil {
+		body := "Please install the latest WinDash Agent to keep sending metrics."
+		if minVersion != "" {
+			body = fmt.Sprintf("Version %s or newer is required (running %s). %s", minVersion, c.version, body)
+		}
+		if err := c.notifier.Notify("WinDash Agent upgrade required", body, "critical", ""); err != nil {
+			c.logger.Warn("Failed to display upgrade notice", "error", err)
+		}
+	}
+}
+
+// versionBelow reports whether version is older than minVersion. Versions
+// are dotted numbers with an optional "v" prefix and "-suffix"; a version
+// that can't be parsed (e.g. "dev" builds) is never considered older.
+func versionBelow(version, minVersion string) bool {
+	v, ok := parseVersion(version)
+	if !ok {
+		return false
+	}
+	m, ok := parseVersion(minVersion)
+	if !ok {
+		return false
+	}
+	for i := range max(len(v), len(m)) {
+		var a, b int
+		if i < len(v) {
+			a = v[i]
+		}
+		if i < len(m) {
+			b = m[i]
+		}
+		if a != b {
+			return a < b
+		}
+	}
+	return false
+}
+
+// parseVersion splits "v1.2.3-rc1" into [1 2 3]
+func parseVersion(s string) ([]int, bool) {
+	s = strings.TrimPrefix(s, "v")
+	if i := strings.IndexAny(s, "-+"); i >= 0 {
+		s = s[:i]
+	}
+	if s == "" {
+		return nil, false
+	}
+	var parts []int
+	for _, field := range strings.Split(s, ".") {
+		n, err := strconv.Atoi(field)
+		if err != nil {
+			return nil, false
+		}
+		parts = append(parts, n)
+	}
+	return parts, true
+}
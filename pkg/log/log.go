@@ -4,27 +4,29 @@ import (
 	"os"
 	"path/filepath"
 
-	"github.com/jcdorr003/windash-agent/internal/config"
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
 	"gopkg.in/natefinch/lumberjack.v2"
 )
 
-// New creates a new logger with console and file output
-func New(debug bool) *zap.SugaredLogger {
-	// Get log directory
-	logDir := config.GetLogDir()
-	logFile := filepath.Join(logDir, "agent.log")
-
-	// Lumberjack for log rotation
-	fileWriter := &lumberjack.Logger{
-		Filename:   logFile,
-		MaxSize:    10, // MB
-		MaxBackups: 7,  // Keep last 7 files
-		MaxAge:     7,  // days
-		Compress:   true,
-	}
+// Options configures New
+type Options struct {
+	Debug    bool   // Log at debug level
+	Dir      string // Log file directory; empty logs to stdout only
+	Compress bool   // Gzip rotated log files
+	Quiet    bool   // Only warnings and errors on the console; the file still gets everything
+}
 
+// Logger is the agent's logger. It embeds the zap logger used everywhere
+// and owns the rotating log file (if any), which Close releases.
+type Logger struct {
+	*zap.SugaredLogger
+	file *lumberjack.Logger
+}
+
+// New creates a new logger with console output, plus JSON file output
+// when opts.Dir is set
+func New(opts Options) *Logger {
 	// Console encoder (pretty, colorful)
 	consoleEncoder := zapcore.NewConsoleEncoder(zapcore.EncoderConfig{
 		TimeKey:        "T",
@@ -44,18 +46,44 @@ func New(debug bool) *zap.SugaredLogger {
 
 	// Set log level
 	level := zapcore.InfoLevel
-	if debug {
+	if opts.Debug {
 		level = zapcore.DebugLevel
 	}
 
 	// Create multi-output core (console + file)
-	core := zapcore.NewTee(
-		zapcore.NewCore(consoleEncoder, zapcore.AddSync(os.Stdout), level),
-		zapcore.NewCore(fileEncoder, zapcore.AddSync(fileWriter), level),
-	)
+	consoleLevel := level
+	if opts.Quiet {
+		consoleLevel = zapcore.WarnLevel
+	}
+	core := zapcore.NewCore(consoleEncoder, zapcore.AddSync(os.Stdout), consoleLevel)
+
+	var fileWriter *lumberjack.Logger
+	if opts.Dir != "" {
+		// Lumberjack for log rotation. Compression can be turned off because
+		// gzipping a rotated file briefly spikes CPU on small machines.
+		fileWriter = &lumberjack.Logger{
+			Filename:   filepath.Join(opts.Dir, "agent.log"),
+			MaxSize:    10, // MB
+			MaxBackups: 7,  // Keep last 7 files
+			MaxAge:     7,  // days
+			Compress:   opts.Compress,
+		}
+		core = zapcore.NewTee(core, zapcore.NewCore(fileEncoder, zapcore.AddSync(fileWriter), level))
+	}
 
 	// Create logger with caller info and stack traces on errors
 	logger := zap.New(core, zap.AddCaller(), zap.AddStacktrace(zapcore.ErrorLevel))
 
-	return logger.Sugar()
+	return &Logger{SugaredLogger: logger.Sugar(), file: fileWriter}
+}
+
+// Close flushes buffered entries and closes the log file. The logger must
+// not be used afterwards.
+func (l *Logger) Close() error {
+	// Sync on a console fd fails harmlessly (EINVAL/ENOTTY); ignore it
+	_ = l.Sync()
+	if l.file == nil {
+		return nil
+	}
+	return l.file.Close()
 }
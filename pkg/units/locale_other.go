@@ -0,0 +1,35 @@
+//go:build !windows
+
+package units
+
+import (
+	"os"
+	"strings"
+)
+
+// commaLanguages use a decimal comma
+var commaLanguages = map[string]bool{
+	"bg": true, "ca": true, "cs": true, "da": true, "de": true, "el": true, "es": true,
+	"et": true, "eu": true, "fi": true, "fr": true, "hr": true, "hu": true, "id": true,
+	"it": true, "lt": true, "lv": true, "nb": true, "nl": true, "nn": true, "no": true,
+	"pl": true, "pt": true, "ro": true, "ru": true, "sk": true, "sl": true, "sr": true,
+	"sv": true, "tr": true, "uk": true, "vi": true,
+}
+
+// localeDecimalSeparator derives the decimal separator from the POSIX
+// locale variables (LC_ALL, LC_NUMERIC, LANG), e.g. de_DE.UTF-8 gives ","
+func localeDecimalSeparator() string {
+	for _, name := range []string{"LC_ALL", "LC_NUMERIC", "LANG"} {
+		locale := os.Getenv(name)
+		if locale == "" {
+			continue
+		}
+		lang, _, _ := strings.Cut(strings.ToLower(locale), "_")
+		lang, _, _ = strings.Cut(lang, ".")
+		if commaLanguages[lang] {
+			return ","
+		}
+		return "."
+	}
+	return "."
+}
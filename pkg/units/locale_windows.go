@@ -0,0 +1,27 @@
+//go:build windows
+
+package units
+
+import (
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+)
+
+var (
+	modkernel32         = windows.NewLazySystemDLL("kernel32.dll")
+	procGetLocaleInfoEx = modkernel32.NewProc("GetLocaleInfoEx")
+)
+
+const localeSDecimal = 0x0E // LOCALE_SDECIMAL
+
+// localeDecimalSeparator reads the decimal separator from the user's
+// regional settings
+func localeDecimalSeparator() string {
+	buf := make([]uint16, 8)
+	r, _, _ := procGetLocaleInfoEx.Call(0, localeSDecimal, uintptr(unsafe.Pointer(&buf[0])), uintptr(len(buf)))
+	if r == 0 {
+		return ""
+	}
+	return windows.UTF16ToString(buf)
+}
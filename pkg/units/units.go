@@ -0,0 +1,144 @@
+// Package units formats byte counts, rates and durations for people:
+// console output, the tray tooltip and the daily summary. Numbers use the
+// user's decimal separator; byte units follow the configured System.
+package units
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// System selects how byte counts are scaled
+type System int32
+
+const (
+	Binary  System = iota // Powers of 1024: KiB, MiB, GiB (what Windows shows, labelled KB, MB, GB)
+	Decimal               // Powers of 1000: kB, MB, GB (what drive makers and macOS use)
+)
+
+var (
+	system atomic.Int32
+
+	separatorOnce sync.Once
+	separator     string // Decimal separator, "." or the locale's
+)
+
+// ParseSystem parses a units setting: "binary" (default when empty) or
+// "decimal"
+func ParseSystem(s string) (System, error) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "", "binary", "iec":
+		return Binary, nil
+	case "decimal", "si":
+		return Decimal, nil
+	default:
+		return Binary, fmt.Errorf("unknown units %q (want binary or decimal)", s)
+	}
+}
+
+// SetSystem sets the byte units used by Bytes and Growth
+func SetSystem(s System) {
+	system.Store(int32(s))
+}
+
+// decimalSeparator returns the locale's decimal separator, detecting it on
+// first use
+func decimalSeparator() string {
+	separatorOnce.Do(func() {
+		separator = localeDecimalSeparator()
+		if separator == "" {
+			separator = "."
+		}
+	})
+	return separator
+}
+
+// Number formats v with prec decimals using the locale's separator
+func Number(v float64, prec int) string {
+	s := strconv.FormatFloat(v, 'f', prec, 64)
+	if sep := decimalSeparator(); sep != "." {
+		s = strings.Replace(s, ".", sep, 1)
+	}
+	return s
+}
+
+// Percent formats a percentage without decimals, e.g. "42%"
+func Percent(v float64) string {
+	return Number(v, 0) + "%"
+}
+
+// Bytes formats a byte count, e.g. "1.5 GiB" (binary) or "1.6 GB" (decimal)
+func Bytes(n uint64) string {
+	unit, prefixes, suffix := uint64(1024), "KMGTPE", "iB"
+	if System(system.Load()) == Decimal {
+		unit, prefixes, suffix = 1000, "kMGTPE", "B"
+	}
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := unit, 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%s %c%s", Number(float64(n)/float64(div), 1), prefixes[exp], suffix)
+}
+
+// Growth formats a signed byte delta, e.g. "+1.2 GiB"
+func Growth(n int64) string {
+	if n < 0 {
+		return "-" + Bytes(uint64(-n))
+	}
+	return "+" + Bytes(uint64(n))
+}
+
+// BitsPerSec formats a byte rate as a network bit rate, e.g. "12.3 Mbps".
+// Network rates are always decimal, whatever the byte units.
+func BitsPerSec(bytesPerSec uint64) string {
+	bits := float64(bytesPerSec) * 8
+	for _, u := range []struct {
+		div  float64
+		name string
+	}{{1e9, "Gbps"}, {1e6, "Mbps"}, {1e3, "kbps"}} {
+		if bits >= u.div {
+			return Number(bits/u.div, 1) + " " + u.name
+		}
+	}
+	return Number(bits, 0) + " bps"
+}
+
+// Duration formats d with its two largest units, e.g. "3d 4h", "5h 12m",
+// "2m 5s" or "42s"
+func Duration(d time.Duration) string {
+	if d < 0 {
+		return "-" + Duration(-d)
+	}
+	if d < time.Second {
+		return d.Round(time.Millisecond).String()
+	}
+	d = d.Round(time.Second)
+	parts := []struct {
+		n    int64
+		unit string
+	}{
+		{int64(d / (24 * time.Hour)), "d"},
+		{int64(d/time.Hour) % 24, "h"},
+		{int64(d/time.Minute) % 60, "m"},
+		{int64(d/time.Second) % 60, "s"},
+	}
+	for i, p := range parts {
+		if p.n == 0 {
+			continue
+		}
+		s := strconv.FormatInt(p.n, 10) + p.unit
+		if i+1 < len(parts) && parts[i+1].n > 0 {
+			s += " " + strconv.FormatInt(parts[i+1].n, 10) + parts[i+1].unit
+		}
+		return s
+	}
+	return "0s"
+}